@@ -0,0 +1,661 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"google-form-submitter/config"
+	"google-form-submitter/models"
+)
+
+// command 命令列子指令
+type command struct {
+	name        string
+	description string
+	run         func(cfg *config.Config, args []string) error
+}
+
+// commands 可用的子指令
+var commands []command
+
+func init() {
+	commands = []command{
+		{name: "schema-version", description: "顯示資料庫結構版本", run: runSchemaVersion},
+		{name: "import", description: "匯入儲存資料（CSV / JSONL，預設僅試跑）", run: runImport},
+		{name: "export", description: "匯出儲存資料（CSV / JSONL）", run: runExport},
+		{name: "submit-batch", description: "批次提交請假資料（JSONL / JSON 陣列）", run: runSubmitBatch},
+		{name: "calendar", description: "匯入假日行事曆（ICS / CSV，--list 列出已匯入日期）", run: runCalendar},
+		{name: "rehearse", description: "以加速或平移的時鐘演練排程，送到本機模擬表單", run: runRehearse},
+		{name: "mockform", description: "啟動模擬的 Google Form（開放時間、延遲、錯誤注入）", run: runMockForm},
+		{name: "backup", description: "建立資料庫線上備份（--list 列出既有備份）", run: runBackup},
+		{name: "restore", description: "從備份還原資料庫（需先停止服務）", run: runRestore},
+		{name: "help", description: "顯示子指令說明", run: runHelp},
+	}
+}
+
+// findCommand 依名稱尋找子指令
+func findCommand(name string) (*command, bool) {
+	for i := range commands {
+		if commands[i].name == name {
+			return &commands[i], true
+		}
+	}
+	return nil, false
+}
+
+// runHelp 顯示子指令說明
+func runHelp(cfg *config.Config, args []string) error {
+	fmt.Println("用法: google-form-submitter [子指令] [參數]")
+	fmt.Println("未指定子指令時啟動 Web Server。")
+	fmt.Println()
+	fmt.Println("子指令:")
+	for _, c := range commands {
+		fmt.Printf("  %-16s %s\n", c.name, c.description)
+	}
+	return nil
+}
+
+// runSchemaVersion 顯示資料庫結構版本
+func runSchemaVersion(cfg *config.Config, args []string) error {
+	version, applied, err := models.ReadSchemaVersion(cfg.DBPath)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("資料庫路徑: %s\n", cfg.DBPath)
+	fmt.Printf("目前版本: %d\n", version)
+	fmt.Printf("程式支援版本: %d\n", models.LatestSchemaVersion())
+	if version < models.LatestSchemaVersion() {
+		fmt.Println("下次啟動時將自動升級（升級前會先備份）")
+	}
+
+	for _, m := range applied {
+		fmt.Printf("  v%d  %s  %s\n", m.Version, m.AppliedAt.Format("2006-01-02 15:04:05"), m.Description)
+	}
+
+	return nil
+}
+
+// openStorage 為子指令開啟資料庫（會套用遷移並需要加密金鑰）
+func openStorage(cfg *config.Config) (*models.Storage, error) {
+	secret, err := loadSecretKey(cfg)
+	if err != nil {
+		return nil, err
+	}
+	storage, err := models.NewStorage(cfg.DBPath, secret)
+	if err != nil {
+		return nil, err
+	}
+	if err := storage.ActivateCalendar(); err != nil {
+		storage.Close()
+		return nil, err
+	}
+	return storage, nil
+}
+
+// runImport 匯入儲存資料，未指定 --commit 時只驗證不寫入
+func runImport(cfg *config.Config, args []string) error {
+	fs := flag.NewFlagSet("import", flag.ContinueOnError)
+	format := fs.String("format", "", "檔案格式 csv 或 jsonl（預設依副檔名判斷）")
+	commit := fs.Bool("commit", false, "驗證全部通過後實際寫入資料庫")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return fmt.Errorf("用法: import [--format csv|jsonl] [--commit] <檔案>")
+	}
+
+	path := fs.Arg(0)
+	if *format == "" {
+		detected, err := models.DetectFormat(path)
+		if err != nil {
+			return err
+		}
+		*format = detected
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("開啟檔案失敗: %w", err)
+	}
+	defer file.Close()
+
+	storage, err := openStorage(cfg)
+	if err != nil {
+		return err
+	}
+	defer storage.Close()
+
+	report, importErr := storage.ImportSavedForms(file, *format, !*commit)
+	if report != nil {
+		for _, row := range report.Rows {
+			switch {
+			case row.Error != "":
+				fmt.Printf("  第 %d 行 ✗ %s\n", row.Line, row.Error)
+			case row.Warning != "":
+				fmt.Printf("  第 %d 行 ! %s：%s\n", row.Line, row.Label, row.Warning)
+			}
+		}
+		fmt.Printf("共 %d 筆，有效 %d 筆，錯誤 %d 筆\n", report.Total, report.Valid, report.Invalid)
+	}
+	if importErr != nil {
+		return importErr
+	}
+
+	if !*commit {
+		fmt.Println("試跑完成，未寫入資料；確認無誤後加上 --commit 匯入")
+		return nil
+	}
+	fmt.Printf("已匯入 %d 筆資料\n", report.Imported)
+	return nil
+}
+
+// runExport 匯出儲存資料，預設不包含密碼
+func runExport(cfg *config.Config, args []string) error {
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	format := fs.String("format", "", "檔案格式 csv 或 jsonl（預設依輸出檔副檔名判斷，否則為 csv）")
+	out := fs.String("out", "", "輸出檔案（預設輸出到標準輸出）")
+	withPasswords := fs.Bool("with-passwords", false, "包含解密後的明文密碼")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *format == "" {
+		*format = models.FormatCSV
+		if *out != "" {
+			if detected, err := models.DetectFormat(*out); err == nil {
+				*format = detected
+			}
+		}
+	}
+
+	storage, err := openStorage(cfg)
+	if err != nil {
+		return err
+	}
+	defer storage.Close()
+
+	var w io.Writer = os.Stdout
+	if *out != "" {
+		// 含密碼時僅允許擁有者讀取
+		perm := os.FileMode(0644)
+		if *withPasswords {
+			perm = 0600
+		}
+		file, err := os.OpenFile(*out, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+		if err != nil {
+			return fmt.Errorf("建立輸出檔失敗: %w", err)
+		}
+		defer file.Close()
+		w = file
+	}
+
+	count, err := storage.ExportSavedForms(w, *format, *withPasswords)
+	if err != nil {
+		return err
+	}
+
+	if *out != "" {
+		fmt.Printf("已匯出 %d 筆資料至: %s\n", count, *out)
+		if *withPasswords {
+			fmt.Println("注意：檔案包含明文密碼，請妥善保管")
+		}
+	}
+	return nil
+}
+
+// runSubmitBatch 批次提交請假資料並輸出逐筆報告
+func runSubmitBatch(cfg *config.Config, args []string) error {
+	fs := flag.NewFlagSet("submit-batch", flag.ContinueOnError)
+	concurrency := fs.Int("concurrency", 2, "同時送出的數量（上限 10）")
+	rate := fs.Float64("rate", 2, "每秒最多開始送出的筆數")
+	retryCount := fs.Int("retry-count", 3, "單筆失敗重試次數")
+	retryInterval := fs.Int("retry-interval", 100, "重試間隔（毫秒）")
+	dryRun := fs.Bool("dry-run", false, "只驗證不送出")
+	reportPath := fs.String("report", "", "報告輸出檔（.json 或 .csv，預設輸出 JSON 到標準輸出）")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return fmt.Errorf("用法: submit-batch [參數] <檔案>")
+	}
+
+	file, err := os.Open(fs.Arg(0))
+	if err != nil {
+		return fmt.Errorf("開啟檔案失敗: %w", err)
+	}
+	defer file.Close()
+
+	items, err := models.ParseBatchRequests(file)
+	if err != nil {
+		return err
+	}
+
+	storage, err := openStorage(cfg)
+	if err != nil {
+		return err
+	}
+	defer storage.Close()
+
+	// Ctrl+C 時停止送出尚未開始的項目，仍輸出報告
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	submitter := models.NewGoogleFormSubmitter(cfg.FormURL, cfg.EntryMap)
+	report, runErr := models.NewBatchSubmitter(submitter, storage).Run(ctx, items, models.BatchOptions{
+		Concurrency: *concurrency,
+		Rate:        *rate,
+		Retry:       models.RetryPolicy{Count: *retryCount, Interval: *retryInterval},
+		DryRun:      *dryRun,
+	})
+
+	if err := writeBatchReport(report, *reportPath); err != nil {
+		return err
+	}
+	if runErr != nil {
+		return runErr
+	}
+
+	fmt.Fprintf(os.Stderr, "共 %d 筆，成功 %d 筆，失敗 %d 筆\n", report.Total, report.Succeeded, report.Failed)
+	if report.Failed > 0 {
+		return fmt.Errorf("有 %d 筆提交失敗", report.Failed)
+	}
+	return nil
+}
+
+// writeBatchReport 依副檔名輸出批次報告
+func writeBatchReport(report *models.BatchReport, path string) error {
+	if path == "" {
+		return report.WriteJSON(os.Stdout)
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("建立報告檔失敗: %w", err)
+	}
+	defer file.Close()
+
+	if strings.EqualFold(filepath.Ext(path), ".csv") {
+		err = report.WriteCSV(file)
+	} else {
+		err = report.WriteJSON(file)
+	}
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(os.Stderr, "報告已寫入: %s\n", path)
+	return nil
+}
+
+// runCalendar 匯入假日行事曆，或以 --list 列出已匯入的日期
+func runCalendar(cfg *config.Config, args []string) error {
+	fs := flag.NewFlagSet("calendar", flag.ContinueOnError)
+	format := fs.String("format", "", "檔案格式 ics 或 csv（預設依副檔名判斷）")
+	replace := fs.Bool("replace", false, "先清除匯入檔涵蓋年份的既有資料")
+	list := fs.Bool("list", false, "只列出已匯入的日期")
+	year := fs.String("year", "", "搭配 --list 只列出指定年份")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	storage, err := openStorage(cfg)
+	if err != nil {
+		return err
+	}
+	defer storage.Close()
+
+	if *list {
+		from, to := "", ""
+		if *year != "" {
+			from, to = *year+"-01-01", *year+"-12-31"
+		}
+		days, err := storage.ListCalendarDays(from, to)
+		if err != nil {
+			return err
+		}
+		if len(days) == 0 {
+			fmt.Println("尚未匯入任何假日資料")
+		}
+		for _, d := range days {
+			fmt.Printf("  %s  %-7s  %s\n", d.Date, d.Kind, d.Name)
+		}
+		return nil
+	}
+
+	if fs.NArg() != 1 {
+		return fmt.Errorf("用法: calendar [--format ics|csv] [--replace] <檔案> 或 calendar --list [--year YYYY]")
+	}
+
+	path := fs.Arg(0)
+	if *format == "" {
+		detected, err := models.DetectCalendarFormat(path)
+		if err != nil {
+			return err
+		}
+		*format = detected
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("開啟檔案失敗: %w", err)
+	}
+	defer file.Close()
+
+	days, err := models.ParseCalendar(file, *format)
+	if err != nil {
+		return err
+	}
+	if err := storage.ImportCalendar(days, filepath.Base(path), *replace); err != nil {
+		return err
+	}
+
+	holidays := 0
+	for _, d := range days {
+		if d.Kind == models.CalendarHoliday {
+			holidays++
+		}
+	}
+	fmt.Printf("已匯入 %d 筆（假日 %d 筆、補班日 %d 筆）\n", len(days), holidays, len(days)-holidays)
+	return nil
+}
+
+// runRehearse 演練排程：從目標時間前 --lead 開始，以 --speed 倍速跑完準備、等待與送出，
+// 送出內容送到本機的模擬表單，不會送到正式表單也不寫入提交記錄
+func runRehearse(cfg *config.Config, args []string) error {
+	fs := flag.NewFlagSet("rehearse", flag.ContinueOnError)
+	savedFormID := fs.Int64("saved-form-id", 0, "要演練的儲存資料 ID")
+	at := fs.String("at", "", "模擬的目標時間 YYYY-MM-DD 或 \"YYYY-MM-DD HH:MM:SS\"（預設明天 00:00）")
+	lead := fs.Duration("lead", 10*time.Second, "演練開始時距目標時間的模擬時間")
+	speed := fs.Float64("speed", 1, "時鐘倍速（例如 10 表示 10 秒的流程 1 秒跑完）")
+	prepareSeconds := fs.Int("prepare-seconds", 5, "提前準備秒數")
+	retryCount := fs.Int("retry-count", 3, "失敗重試次數")
+	retryInterval := fs.Int("retry-interval", 100, "重試間隔（毫秒）")
+	mock := mockFormFlags(fs)
+	asJSON := fs.Bool("json", false, "以 JSON 輸出演練結果")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *savedFormID <= 0 {
+		return fmt.Errorf("用法: rehearse --saved-form-id <ID> [--at 時間] [--lead 10s] [--speed 1]")
+	}
+
+	target, err := parseRehearsalTime(*at)
+	if err != nil {
+		return err
+	}
+	mockOpts, err := mock.options(cfg.EntryMap)
+	if err != nil {
+		return err
+	}
+
+	storage, err := openStorage(cfg)
+	if err != nil {
+		return err
+	}
+	defer storage.Close()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	fmt.Fprintf(os.Stderr, "演練目標時間 %s，模擬時鐘從 %s 開始（%g 倍速）\n",
+		target.Format("2006-01-02 15:04:05"), target.Add(-*lead).Format("2006-01-02 15:04:05"), *speed)
+	result, err := models.RunRehearsal(ctx, storage, cfg.EntryMap, models.RehearsalOptions{
+		SavedFormID:    *savedFormID,
+		At:             target,
+		Lead:           *lead,
+		Speed:          *speed,
+		PrepareSeconds: *prepareSeconds,
+		RetryCount:     *retryCount,
+		RetryInterval:  *retryInterval,
+		Mock:           mockOpts,
+	}, log.New(io.Discard, "", 0))
+	if err != nil {
+		return err
+	}
+
+	if *asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(result)
+	}
+
+	for _, e := range result.Events {
+		line := fmt.Sprintf("  %s  %-14s", e.Time.Format("15:04:05.000"), e.Type)
+		if e.Attempt > 0 {
+			line += fmt.Sprintf("  第 %d 次", e.Attempt)
+		}
+		if e.StatusCode > 0 {
+			line += fmt.Sprintf("  HTTP %d", e.StatusCode)
+		}
+		if e.Message != "" {
+			line += "  " + e.Message
+		}
+		fmt.Println(strings.TrimRight(line, " "))
+	}
+	for _, sub := range result.Submissions {
+		line := fmt.Sprintf("  模擬表單 #%d  %s  HTTP %d", sub.Seq, sub.At.Format("15:04:05.000"), sub.StatusCode)
+		if sub.Reason != "" {
+			line += "  " + sub.Reason
+		}
+		fmt.Println(line)
+	}
+	fmt.Printf("模擬表單收到 %d 次送出，結果: %s\n", len(result.Submissions), result.State)
+	if result.State != models.JobSucceeded {
+		return fmt.Errorf("演練未成功完成（%s）", result.State)
+	}
+	return nil
+}
+
+// parseRehearsalTime 解析演練目標時間，未指定時為明天 00:00
+func parseRehearsalTime(value string) (time.Time, error) {
+	if value == "" {
+		y, m, d := time.Now().AddDate(0, 0, 1).Date()
+		return time.Date(y, m, d, 0, 0, 0, 0, time.Local), nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02 15:04:05", value, time.Local); err == nil {
+		return t, nil
+	}
+	t, err := models.ParseScheduleDate(value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("演練目標時間格式錯誤: %w", err)
+	}
+	return t, nil
+}
+
+// mockFormSettings 模擬表單的共用命令列參數
+type mockFormSettings struct {
+	entries   *string
+	opensAt   *string
+	closed    *bool
+	latency   *int
+	errorRate *float64
+	failFirst *int
+	status    *int
+}
+
+// mockFormFlags 在 fs 註冊模擬表單參數
+func mockFormFlags(fs *flag.FlagSet) *mockFormSettings {
+	return &mockFormSettings{
+		entries:   fs.String("entries", "", "覆寫 entry ID，以逗號分隔的 欄位=entry.N（預設使用 entry_map）"),
+		opensAt:   fs.String("opens-at", "", "開放時間 \"YYYY-MM-DD HH:MM:SS\"，之前顯示已停止接受回應"),
+		closed:    fs.Bool("closed", false, "一律顯示已停止接受回應"),
+		latency:   fs.Int("latency", 0, "每次送出的回應延遲（毫秒）"),
+		errorRate: fs.Float64("error-rate", 0, "隨機回應錯誤的機率（0 ~ 1）"),
+		failFirst: fs.Int("fail-first", 0, "前 N 次送出回應錯誤，用於演練重試"),
+		status:    fs.Int("error-status", 500, "注入錯誤的 HTTP 狀態碼"),
+	}
+}
+
+// options 組成模擬表單設定
+func (m *mockFormSettings) options(entryMap map[string]string) (models.MockFormOptions, error) {
+	entryIDs, err := models.MockEntryIDs(entryMap, *m.entries)
+	if err != nil {
+		return models.MockFormOptions{}, err
+	}
+	opts := models.MockFormOptions{
+		EntryIDs:    entryIDs,
+		Closed:      *m.closed,
+		LatencyMs:   *m.latency,
+		ErrorRate:   *m.errorRate,
+		FailFirst:   *m.failFirst,
+		ErrorStatus: *m.status,
+	}
+	if *m.opensAt != "" {
+		opts.OpensAt, err = time.ParseInLocation("2006-01-02 15:04:05", *m.opensAt, time.Local)
+		if err != nil {
+			return models.MockFormOptions{}, fmt.Errorf("開放時間格式錯誤: %w", err)
+		}
+	}
+	return opts, nil
+}
+
+// runMockForm 啟動模擬的 Google Form 直到中斷，並即時列出收到的送出
+func runMockForm(cfg *config.Config, args []string) error {
+	fs := flag.NewFlagSet("mockform", flag.ContinueOnError)
+	addr := fs.String("addr", "127.0.0.1:8090", "監聽位址")
+	mock := mockFormFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	opts, err := mock.options(cfg.EntryMap)
+	if err != nil {
+		return err
+	}
+
+	server, err := models.StartMockFormServer(models.NewMockForm(opts), *addr)
+	if err != nil {
+		return err
+	}
+	defer server.Close()
+
+	fmt.Printf("模擬表單頁面: %s\n", server.ViewFormURL())
+	fmt.Printf("送出網址（設定為 form_url 或 FORM_URL）: %s\n", server.FormURL())
+	fmt.Printf("收到的送出: %s/_mock/submissions\n", server.BaseURL)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	ticker := time.NewTicker(200 * time.Millisecond)
+	defer ticker.Stop()
+	printed := 0
+	for {
+		select {
+		case <-ctx.Done():
+			fmt.Printf("共收到 %d 次送出\n", len(server.Submissions()))
+			return nil
+		case <-ticker.C:
+		}
+		subs := server.Submissions()
+		if len(subs) < printed {
+			printed = 0 // 已透過 DELETE /_mock/submissions 清除
+		}
+		for _, sub := range subs[printed:] {
+			line := fmt.Sprintf("#%d  %s  HTTP %d", sub.Seq, sub.At.Format("15:04:05.000"), sub.StatusCode)
+			if sub.Reason != "" {
+				line += "  " + sub.Reason
+			}
+			if len(sub.Missing) > 0 {
+				line += "  缺少 " + strings.Join(sub.Missing, ", ")
+			}
+			fmt.Println(line)
+		}
+		printed = len(subs)
+	}
+}
+
+// runBackup 建立線上備份並清除超出保留份數的舊備份
+func runBackup(cfg *config.Config, args []string) error {
+	fs := flag.NewFlagSet("backup", flag.ContinueOnError)
+	dir := fs.String("dir", cfg.Backup.Dir, "備份目錄")
+	keep := fs.Int("keep", cfg.Backup.Keep, "保留的備份份數（0 表示不清除）")
+	list := fs.Bool("list", false, "只列出既有備份")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *list {
+		backups, err := models.ListBackups(*dir, cfg.DBPath)
+		if err != nil {
+			return err
+		}
+		if len(backups) == 0 {
+			fmt.Printf("%s 中沒有備份\n", *dir)
+		}
+		for _, b := range backups {
+			fmt.Printf("  %s  %8d bytes  %s\n", b.CreatedAt.Format("2006-01-02 15:04:05"), b.Size, b.Path)
+		}
+		return nil
+	}
+
+	storage, err := openStorage(cfg)
+	if err != nil {
+		return err
+	}
+	defer storage.Close()
+
+	info, err := storage.Backup(*dir)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("已備份至: %s（%d bytes）\n", info.Path, info.Size)
+
+	removed, err := models.PruneBackups(*dir, cfg.DBPath, *keep)
+	for _, name := range removed {
+		fmt.Printf("已刪除舊備份: %s\n", name)
+	}
+	return err
+}
+
+// runRestore 驗證備份的結構版本與完整性後取代目前的資料庫
+func runRestore(cfg *config.Config, args []string) error {
+	fs := flag.NewFlagSet("restore", flag.ContinueOnError)
+	yes := fs.Bool("yes", false, "不詢問直接還原")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return fmt.Errorf("用法: restore [--yes] <備份檔>")
+	}
+	backupPath := fs.Arg(0)
+
+	version, err := models.VerifyBackup(backupPath)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("備份檔: %s（結構版本 %d，程式支援版本 %d）\n", backupPath, version, models.LatestSchemaVersion())
+
+	if !*yes {
+		fmt.Printf("將以備份取代 %s，請確認服務已停止。繼續？[y/N] ", cfg.DBPath)
+		var answer string
+		fmt.Scanln(&answer)
+		if !strings.EqualFold(answer, "y") {
+			fmt.Println("已取消")
+			return nil
+		}
+	}
+
+	previous, err := models.RestoreDatabase(backupPath, cfg.DBPath)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("已還原 %s\n", cfg.DBPath)
+	if previous != "" {
+		fmt.Printf("原資料庫已保留為: %s\n", previous)
+	}
+	if version < models.LatestSchemaVersion() {
+		fmt.Println("下次啟動時將自動升級資料庫結構")
+	}
+	return nil
+}
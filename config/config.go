@@ -15,15 +15,54 @@ type ScheduleConfig struct {
 	PrepareSeconds int    `json:"prepare_seconds"` // 提前準備秒數，預設 5
 	RetryCount     int    `json:"retry_count"`     // 失敗重試次數，預設 3
 	RetryInterval  int    `json:"retry_interval"`  // 重試間隔毫秒，預設 100
+	MissedPolicy   string `json:"missed_policy"`   // 錯過排程時間的處理策略：skip（預設）/ grace / notify
+	GraceSeconds   int    `json:"grace_seconds"`   // grace 策略的寬限秒數，預設 60
+
+	// 送出策略：fixed（預設）或 poll（探測到表單開放即送出）
+	Strategy            string `json:"strategy"`
+	PollLeadSeconds     int    `json:"poll_lead_seconds"`     // 目標時間前幾秒開始探測，預設 10
+	PollIntervalMs      int    `json:"poll_interval_ms"`      // 探測間隔毫秒，預設 250
+	PollDeadlineSeconds int    `json:"poll_deadline_seconds"` // 目標時間後最多再探測幾秒，預設 60
+}
+
+// EncryptionConfig 密碼加密配置
+type EncryptionConfig struct {
+	Key     string `json:"-"`        // base64 金鑰，僅能由環境變數 ENCRYPTION_KEY 提供
+	KeyFile string `json:"key_file"` // 金鑰檔路徑，不存在時自動產生
+}
+
+// BackupConfig 資料庫備份配置
+type BackupConfig struct {
+	Dir           string `json:"dir"`            // 備份目錄，預設 backups
+	IntervalHours int    `json:"interval_hours"` // 自動備份間隔小時，0 表示停用
+	Keep          int    `json:"keep"`           // 保留的備份份數，預設 7
+}
+
+// BookingWindowConfig 預約窗口規則：請假起點日期前 N 天的指定時間開放申請
+type BookingWindowConfig struct {
+	OpensDaysBefore  int    `json:"opens_days_before"`  // 起點日期前幾天開放，0 表示未設定
+	OpenTime         string `json:"open_time"`          // 開放時間 HH:MM，預設 00:00
+	ClosesDaysBefore int    `json:"closes_days_before"` // 起點日期前幾天截止（當天 00:00），0 表示起點當天
+	Timezone         string `json:"timezone"`           // IANA 時區，預設 Asia/Taipei
+}
+
+// FormWatchConfig 表單結構檢查配置
+type FormWatchConfig struct {
+	IntervalMinutes int `json:"interval_minutes"` // 檢查間隔分鐘，0 表示停用，預設 60
 }
 
 // Config 應用程式配置
 type Config struct {
-	Port     string            `json:"port"`
-	FormURL  string            `json:"form_url"`
-	EntryMap map[string]string `json:"entry_map"`
-	DBPath   string            `json:"db_path"`
-	Schedule ScheduleConfig    `json:"schedule"`
+	Port       string            `json:"port"`
+	FormURL    string            `json:"form_url"`
+	EntryMap   map[string]string `json:"entry_map"`
+	DBPath     string            `json:"db_path"`
+	Schedule   ScheduleConfig    `json:"schedule"`
+	Encryption EncryptionConfig  `json:"encryption"`
+	Backup     BackupConfig      `json:"backup"`
+
+	BookingWindow BookingWindowConfig `json:"booking_window"`
+	FormWatch     FormWatchConfig     `json:"form_watch"`
 }
 
 // DefaultConfig 返回預設配置
@@ -48,6 +87,18 @@ func DefaultConfig() *Config {
 			RetryCount:     3,
 			RetryInterval:  100,
 		},
+		Backup: BackupConfig{
+			Dir:           "backups",
+			IntervalHours: 0,
+			Keep:          7,
+		},
+		BookingWindow: BookingWindowConfig{
+			OpenTime: "00:00",
+			Timezone: "Asia/Taipei",
+		},
+		FormWatch: FormWatchConfig{
+			IntervalMinutes: 60,
+		},
 	}
 }
 
@@ -98,6 +149,18 @@ func applyEnvOverrides(cfg *Config) {
 		cfg.DBPath = dbPath
 	}
 
+	if key := os.Getenv("ENCRYPTION_KEY"); key != "" {
+		cfg.Encryption.Key = key
+	}
+
+	if keyFile := os.Getenv("ENCRYPTION_KEY_FILE"); keyFile != "" {
+		cfg.Encryption.KeyFile = keyFile
+	}
+
+	if backupDir := os.Getenv("BACKUP_DIR"); backupDir != "" {
+		cfg.Backup.Dir = backupDir
+	}
+
 	if scheduleEnabled := os.Getenv("SCHEDULE_ENABLED"); scheduleEnabled != "" {
 		cfg.Schedule.Enabled = scheduleEnabled == "true" || scheduleEnabled == "1"
 	}
@@ -137,5 +200,15 @@ func (c *Config) Validate() error {
 		}
 	}
 
+	if c.FormWatch.IntervalMinutes < 0 {
+		return fmt.Errorf("配置錯誤: form_watch.interval_minutes 不可為負數")
+	}
+
+	if w := c.BookingWindow; w.OpensDaysBefore < 0 || w.ClosesDaysBefore < 0 {
+		return fmt.Errorf("配置錯誤: booking_window 天數不可為負數")
+	} else if w.OpensDaysBefore > 0 && w.ClosesDaysBefore >= w.OpensDaysBefore {
+		return fmt.Errorf("配置錯誤: booking_window.closes_days_before 必須小於 opens_days_before")
+	}
+
 	return nil
 }
@@ -0,0 +1,68 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"google-form-submitter/config"
+	"google-form-submitter/models"
+)
+
+// BackupController 資料庫備份控制器
+type BackupController struct {
+	storage *models.Storage
+	config  *config.Config
+}
+
+// NewBackupController 建立新的 BackupController
+func NewBackupController(cfg *config.Config, storage *models.Storage) *BackupController {
+	return &BackupController{
+		storage: storage,
+		config:  cfg,
+	}
+}
+
+// BackupResponse 備份回應結構
+type BackupResponse struct {
+	Success bool                `json:"success"`
+	Backup  *models.BackupInfo  `json:"backup,omitempty"`
+	Backups []models.BackupInfo `json:"backups,omitempty"`
+	Removed []string            `json:"removed,omitempty"`
+	Message string              `json:"message,omitempty"`
+}
+
+// ListBackups 列出既有備份
+// GET /api/backup
+func (c *BackupController) ListBackups(ctx *gin.Context) {
+	backups, err := models.ListBackups(c.config.Backup.Dir, c.config.DBPath)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, BackupResponse{Success: false, Message: err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, BackupResponse{Success: true, Backups: backups})
+}
+
+// CreateBackup 立即建立線上備份並依保留份數清除舊備份
+// POST /api/backup
+func (c *BackupController) CreateBackup(ctx *gin.Context) {
+	info, err := c.storage.Backup(c.config.Backup.Dir)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, BackupResponse{Success: false, Message: err.Error()})
+		return
+	}
+
+	removed, err := models.PruneBackups(c.config.Backup.Dir, c.config.DBPath, c.config.Backup.Keep)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, BackupResponse{Success: false, Backup: info, Message: err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, BackupResponse{
+		Success: true,
+		Backup:  info,
+		Removed: removed,
+		Message: "備份完成",
+	})
+}
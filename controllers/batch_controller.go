@@ -0,0 +1,169 @@
+package controllers
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"google-form-submitter/models"
+)
+
+// maxBatchBodySize 批次提交內容大小上限
+const maxBatchBodySize = 5 << 20
+
+// SubmitBatchResponse 批次提交回應結構
+type SubmitBatchResponse struct {
+	Success bool                `json:"success"`
+	Message string              `json:"message"`
+	Report  *models.BatchReport `json:"report,omitempty"`
+}
+
+// parseBatchOptions 解析批次提交的查詢參數
+func parseBatchOptions(ctx *gin.Context) (models.BatchOptions, error) {
+	opts := models.BatchOptions{
+		DryRun: ctx.Query("dry_run") == "true" || ctx.Query("dry_run") == "1",
+	}
+
+	intParams := []struct {
+		name   string
+		target *int
+	}{
+		{"concurrency", &opts.Concurrency},
+		{"retry_count", &opts.Retry.Count},
+		{"retry_interval", &opts.Retry.Interval},
+	}
+	for _, p := range intParams {
+		if v := ctx.Query(p.name); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 0 {
+				return opts, fmt.Errorf("%s 必須為非負整數", p.name)
+			}
+			*p.target = n
+		}
+	}
+
+	if v := ctx.Query("rate"); v != "" {
+		rate, err := strconv.ParseFloat(v, 64)
+		if err != nil || (rate != 0 && (rate < models.MinBatchRate || rate > models.MaxBatchRate)) {
+			return opts, fmt.Errorf("rate 必須介於 %g 到 %g（0 為預設值）", models.MinBatchRate, models.MaxBatchRate)
+		}
+		opts.Rate = rate
+	}
+
+	return opts, nil
+}
+
+// SubmitBatch 批次提交（JSONL 或 JSON 陣列），先驗證全部資料再以有限並行數與速率送出
+// POST /api/submit/batch?concurrency=2&rate=2&dry_run=true&report=csv
+func (c *FormController) SubmitBatch(ctx *gin.Context) {
+	opts, err := parseBatchOptions(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, SubmitBatchResponse{Success: false, Message: err.Error()})
+		return
+	}
+
+	reportFormat := ctx.DefaultQuery("report", "json")
+	if reportFormat != "json" && reportFormat != "csv" {
+		ctx.JSON(http.StatusBadRequest, SubmitBatchResponse{Success: false, Message: "report 必須為 json 或 csv"})
+		return
+	}
+
+	data, err := io.ReadAll(http.MaxBytesReader(ctx.Writer, ctx.Request.Body, maxBatchBodySize))
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		ctx.JSON(http.StatusRequestEntityTooLarge, SubmitBatchResponse{
+			Success: false,
+			Message: fmt.Sprintf("上傳內容超過 %d MiB，請分批提交", maxBatchBodySize>>20),
+		})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, SubmitBatchResponse{Success: false, Message: "讀取上傳內容失敗"})
+		return
+	}
+
+	items, err := models.ParseBatchRequests(bytes.NewReader(data))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, SubmitBatchResponse{Success: false, Message: err.Error()})
+		return
+	}
+
+	// 資料驗證失敗回傳 400，其餘錯誤（例如批次中止）回傳 500；兩者都依 report 參數附上報告
+	report, runErr := models.NewBatchSubmitter(c.submitter, c.storage).Run(ctx.Request.Context(), items, opts)
+	status := http.StatusOK
+	if runErr != nil {
+		status = http.StatusInternalServerError
+		var validationErr *models.ValidationError
+		if errors.As(runErr, &validationErr) {
+			status = http.StatusBadRequest
+		}
+	}
+
+	if reportFormat == "csv" && report != nil {
+		var buf bytes.Buffer
+		if err := report.WriteCSV(&buf); err != nil {
+			ctx.JSON(http.StatusInternalServerError, SubmitBatchResponse{Success: false, Message: err.Error()})
+			return
+		}
+		ctx.Header("Content-Disposition", `attachment; filename="`+report.BatchID+`.csv"`)
+		ctx.Data(status, "text/csv; charset=utf-8", buf.Bytes())
+		return
+	}
+
+	if runErr != nil {
+		ctx.JSON(status, SubmitBatchResponse{Success: false, Message: runErr.Error(), Report: report})
+		return
+	}
+
+	message := fmt.Sprintf("批次提交完成：成功 %d 筆，失敗 %d 筆", report.Succeeded, report.Failed)
+	if report.DryRun {
+		message = fmt.Sprintf("試跑完成：%d 筆資料驗證通過，未送出", report.Total)
+	}
+
+	ctx.JSON(http.StatusOK, SubmitBatchResponse{
+		Success: report.Failed == 0,
+		Message: message,
+		Report:  report,
+	})
+}
+
+// ListHistoryResponse 提交記錄回應結構
+type ListHistoryResponse struct {
+	Success bool                       `json:"success"`
+	Records []*models.SubmissionRecord `json:"records"`
+	Message string                     `json:"message,omitempty"`
+
+	// Precision 列出的記錄中排程送出的精確度，沒有送出時間時省略
+	Precision *models.FirePrecisionStats `json:"precision,omitempty"`
+}
+
+// ListHistory 列出提交記錄
+// GET /api/history?source=batch&batch_id=...&limit=50
+func (c *FormController) ListHistory(ctx *gin.Context) {
+	q := models.HistoryQuery{
+		Source:  ctx.Query("source"),
+		BatchID: ctx.Query("batch_id"),
+		Limit:   50,
+	}
+	if v := ctx.Query("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 || n > 500 {
+			ctx.JSON(http.StatusBadRequest, ListHistoryResponse{Success: false, Message: "limit 必須介於 1 到 500"})
+			return
+		}
+		q.Limit = n
+	}
+
+	records, err := c.storage.ListHistory(q)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, ListHistoryResponse{Success: false, Message: err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, ListHistoryResponse{Success: true, Records: records, Precision: models.SummarizeFirePrecision(records)})
+}
@@ -0,0 +1,65 @@
+package controllers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"google-form-submitter/models"
+)
+
+// CalendarController 假日行事曆控制器
+type CalendarController struct {
+	storage *models.Storage
+}
+
+// NewCalendarController 建立新的 CalendarController
+func NewCalendarController(storage *models.Storage) *CalendarController {
+	return &CalendarController{storage: storage}
+}
+
+// CalendarResponse 假日行事曆回應
+type CalendarResponse struct {
+	Success bool                     `json:"success"`
+	From    string                   `json:"from,omitempty"`
+	To      string                   `json:"to,omitempty"`
+	Days    []*models.CalendarDay    `json:"days"`
+	Summary *models.LeaveDaysSummary `json:"summary,omitempty"` // 指定 from 與 to 時的工作日統計
+	Message string                   `json:"message,omitempty"`
+}
+
+// GetCalendar 列出期間內匯入的假日與補班日，供前端標示；同時指定 from 與 to 時附上工作日統計
+// GET /api/calendar?from=YYYY-MM-DD&to=YYYY-MM-DD（預設為今年）
+func (c *CalendarController) GetCalendar(ctx *gin.Context) {
+	year := time.Now().Format("2006")
+	from := ctx.DefaultQuery("from", year+"-01-01")
+	to := ctx.DefaultQuery("to", year+"-12-31")
+
+	start, err := time.Parse("2006-01-02", from)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, CalendarResponse{Success: false, Message: "from 日期格式錯誤，請使用 YYYY-MM-DD"})
+		return
+	}
+	end, err := time.Parse("2006-01-02", to)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, CalendarResponse{Success: false, Message: "to 日期格式錯誤，請使用 YYYY-MM-DD"})
+		return
+	}
+	if end.Before(start) {
+		ctx.JSON(http.StatusBadRequest, CalendarResponse{Success: false, Message: "to 不可早於 from"})
+		return
+	}
+
+	days, err := c.storage.ListCalendarDays(from, to)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, CalendarResponse{Success: false, Message: err.Error()})
+		return
+	}
+
+	resp := CalendarResponse{Success: true, From: from, To: to, Days: days}
+	if ctx.Query("from") != "" && ctx.Query("to") != "" {
+		resp.Summary = c.storage.Calendar().Summarize(start, end)
+	}
+	ctx.JSON(http.StatusOK, resp)
+}
@@ -0,0 +1,237 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"google-form-submitter/models"
+)
+
+// EmployeeController 員工資料控制器
+type EmployeeController struct {
+	storage *models.Storage
+}
+
+// NewEmployeeController 建立新的 EmployeeController
+func NewEmployeeController(storage *models.Storage) *EmployeeController {
+	return &EmployeeController{storage: storage}
+}
+
+// EmployeeRequest 新增或更新員工請求結構（更新時密碼留空則保留原密碼）
+type EmployeeRequest struct {
+	Name       string `json:"name" binding:"required"`
+	EmployeeID string `json:"employee_id" binding:"required"`
+	Password   string `json:"password"`
+}
+
+// EmployeeResponse 員工回應結構
+type EmployeeResponse struct {
+	Success bool               `json:"success"`
+	ID      int64              `json:"id,omitempty"`
+	Data    *models.Employee   `json:"data,omitempty"`
+	List    []*models.Employee `json:"employees,omitempty"`
+	Message string             `json:"message,omitempty"`
+}
+
+// parseEmployeeID 解析路徑中的員工 ID，失敗時直接回應 400
+func parseEmployeeID(ctx *gin.Context) (int64, bool) {
+	id, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, EmployeeResponse{
+			Success: false,
+			Message: "無效的 ID 格式",
+		})
+		return 0, false
+	}
+	return id, true
+}
+
+// ShowEmployees 顯示員工資料管理頁面
+// GET /employees
+func (c *EmployeeController) ShowEmployees(ctx *gin.Context) {
+	ctx.HTML(http.StatusOK, "employees.html", nil)
+}
+
+// ListEmployees 列出所有員工
+// GET /api/employees
+func (c *EmployeeController) ListEmployees(ctx *gin.Context) {
+	employees, err := c.storage.ListEmployees()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, EmployeeResponse{
+			Success: false,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, EmployeeResponse{
+		Success: true,
+		List:    employees,
+	})
+}
+
+// GetEmployee 取得單一員工
+// GET /api/employees/:id
+func (c *EmployeeController) GetEmployee(ctx *gin.Context) {
+	id, ok := parseEmployeeID(ctx)
+	if !ok {
+		return
+	}
+
+	e, err := c.storage.GetEmployee(id)
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, EmployeeResponse{
+			Success: false,
+			Message: "找不到指定的員工",
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, EmployeeResponse{
+		Success: true,
+		Data:    e,
+	})
+}
+
+// CreateEmployee 新增員工
+// POST /api/employees
+func (c *EmployeeController) CreateEmployee(ctx *gin.Context) {
+	var req EmployeeRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, EmployeeResponse{
+			Success: false,
+			Message: "JSON 格式錯誤或缺少必填欄位",
+		})
+		return
+	}
+
+	id, err := c.storage.CreateEmployee(&models.Employee{
+		Name:       req.Name,
+		EmployeeID: req.EmployeeID,
+		Password:   req.Password,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusConflict, EmployeeResponse{
+			Success: false,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, EmployeeResponse{
+		Success: true,
+		ID:      id,
+		Message: "員工已新增",
+	})
+}
+
+// UpdateEmployee 更新員工資料
+// PUT /api/employees/:id
+func (c *EmployeeController) UpdateEmployee(ctx *gin.Context) {
+	id, ok := parseEmployeeID(ctx)
+	if !ok {
+		return
+	}
+
+	var req EmployeeRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, EmployeeResponse{
+			Success: false,
+			Message: "JSON 格式錯誤或缺少必填欄位",
+		})
+		return
+	}
+
+	if _, err := c.storage.GetEmployee(id); err != nil {
+		ctx.JSON(http.StatusNotFound, EmployeeResponse{
+			Success: false,
+			Message: "找不到指定的員工",
+		})
+		return
+	}
+
+	err := c.storage.UpdateEmployee(&models.Employee{
+		ID:         id,
+		Name:       req.Name,
+		EmployeeID: req.EmployeeID,
+		Password:   req.Password,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusConflict, EmployeeResponse{
+			Success: false,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, EmployeeResponse{
+		Success: true,
+		ID:      id,
+		Message: "員工資料已更新",
+	})
+}
+
+// UpdateEmployeePassword 更換員工密碼（所有參照此員工的儲存資料一併生效）
+// PUT /api/employees/:id/password
+func (c *EmployeeController) UpdateEmployeePassword(ctx *gin.Context) {
+	id, ok := parseEmployeeID(ctx)
+	if !ok {
+		return
+	}
+
+	var req UpdatePasswordRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, EmployeeResponse{
+			Success: false,
+			Message: "JSON 格式錯誤或缺少密碼",
+		})
+		return
+	}
+
+	if err := c.storage.UpdateEmployeePassword(id, req.Password); err != nil {
+		ctx.JSON(http.StatusNotFound, EmployeeResponse{
+			Success: false,
+			Message: "找不到指定的員工",
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, EmployeeResponse{
+		Success: true,
+		ID:      id,
+		Message: "密碼已更新",
+	})
+}
+
+// DeleteEmployee 刪除員工（仍有儲存資料參照時拒絕）
+// DELETE /api/employees/:id
+func (c *EmployeeController) DeleteEmployee(ctx *gin.Context) {
+	id, ok := parseEmployeeID(ctx)
+	if !ok {
+		return
+	}
+
+	err := c.storage.DeleteEmployee(id)
+	if errors.Is(err, models.ErrEmployeeInUse) {
+		ctx.JSON(http.StatusConflict, EmployeeResponse{
+			Success: false,
+			Message: err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, EmployeeResponse{
+			Success: false,
+			Message: "找不到指定的員工",
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, EmployeeResponse{
+		Success: true,
+		Message: "員工已刪除",
+	})
+}
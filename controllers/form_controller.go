@@ -1,8 +1,11 @@
 package controllers
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 
@@ -14,15 +17,17 @@ import (
 type FormController struct {
 	submitter *models.GoogleFormSubmitter
 	storage   *models.Storage
+	scheduler *models.Scheduler
 	config    *config.Config
 }
 
-// NewFormController 建立新的 FormController
-func NewFormController(cfg *config.Config, storage *models.Storage) *FormController {
-	submitter := models.NewGoogleFormSubmitter(cfg.FormURL, cfg.EntryMap)
+// NewFormController 建立新的 FormController（scheduler 可為 nil）；
+// submitter 應與排程器共用，表單重新對應 entry_map 後立即提交也使用新的對應
+func NewFormController(cfg *config.Config, submitter *models.GoogleFormSubmitter, storage *models.Storage, scheduler *models.Scheduler) *FormController {
 	return &FormController{
 		submitter: submitter,
 		storage:   storage,
+		scheduler: scheduler,
 		config:    cfg,
 	}
 }
@@ -33,6 +38,12 @@ func (c *FormController) ShowForm(ctx *gin.Context) {
 	ctx.HTML(http.StatusOK, "index.html", nil)
 }
 
+// ShowSavedForms 顯示儲存資料管理頁面
+// GET /saved
+func (c *FormController) ShowSavedForms(ctx *gin.Context) {
+	ctx.HTML(http.StatusOK, "saved.html", nil)
+}
+
 // SubmitForm 處理網頁表單提交
 // POST /submit
 func (c *FormController) SubmitForm(ctx *gin.Context) {
@@ -48,7 +59,7 @@ func (c *FormController) SubmitForm(ctx *gin.Context) {
 	}
 
 	// 驗證表單資料
-	if err := models.Validate(&req); err != nil {
+	if err := models.Validate(&req, c.storage.Calendar()); err != nil {
 		ctx.HTML(http.StatusBadRequest, "result.html", models.SubmitResult{
 			Success: false,
 			Message: err.Error(),
@@ -75,13 +86,24 @@ func (c *FormController) SubmitForm(ctx *gin.Context) {
 	ctx.HTML(statusCode, "result.html", result)
 }
 
+// SubmitAPIRequest API 提交請求結構（指定 employee_ref 時姓名、員工代號與密碼取自員工資料）
+type SubmitAPIRequest struct {
+	EmployeeRef int64  `json:"employee_ref"`
+	Name        string `json:"name"`
+	EmployeeID  string `json:"employee_id"`
+	StartDate   string `json:"start_date"`
+	EndDate     string `json:"end_date"`
+	LeaveType   string `json:"leave_type"`
+	Password    string `json:"password"`
+}
+
 // SubmitAPI 處理 API JSON 提交
 // POST /api/submit
 func (c *FormController) SubmitAPI(ctx *gin.Context) {
-	var req models.LeaveRequest
+	var body SubmitAPIRequest
 
 	// 綁定 JSON 資料
-	if err := ctx.ShouldBindJSON(&req); err != nil {
+	if err := ctx.ShouldBindJSON(&body); err != nil {
 		ctx.JSON(http.StatusBadRequest, models.SubmitResult{
 			Success: false,
 			Message: "JSON 格式錯誤",
@@ -89,8 +111,28 @@ func (c *FormController) SubmitAPI(ctx *gin.Context) {
 		return
 	}
 
+	req := models.LeaveRequest{
+		Name:       body.Name,
+		EmployeeID: body.EmployeeID,
+		StartDate:  body.StartDate,
+		EndDate:    body.EndDate,
+		LeaveType:  body.LeaveType,
+		Password:   body.Password,
+	}
+
+	// 指定員工時帶入員工資料
+	if body.EmployeeRef > 0 {
+		if err := c.storage.FillLeaveRequest(&req, body.EmployeeRef); err != nil {
+			ctx.JSON(http.StatusBadRequest, models.SubmitResult{
+				Success: false,
+				Message: err.Error(),
+			})
+			return
+		}
+	}
+
 	// 驗證表單資料
-	if err := models.Validate(&req); err != nil {
+	if err := models.Validate(&req, c.storage.Calendar()); err != nil {
 		ctx.JSON(http.StatusBadRequest, models.SubmitResult{
 			Success: false,
 			Message: err.Error(),
@@ -118,14 +160,18 @@ func (c *FormController) SubmitAPI(ctx *gin.Context) {
 }
 
 // SaveFormRequest 儲存表單請求結構
+// 指定 employee_ref 時只需提供請假欄位；否則需提供姓名、員工代號與密碼，並依員工代號建立或更新員工。
+// 提供 date_template 時不需 start_date 與 end_date，日期於排程觸發時計算。
 type SaveFormRequest struct {
-	Label      string `json:"label" binding:"required"`
-	Name       string `json:"name" binding:"required"`
-	EmployeeID string `json:"employee_id" binding:"required"`
-	StartDate  string `json:"start_date" binding:"required"`
-	EndDate    string `json:"end_date" binding:"required"`
-	LeaveType  string `json:"leave_type" binding:"required"`
-	Password   string `json:"password" binding:"required"`
+	Label        string               `json:"label" binding:"required"`
+	EmployeeRef  int64                `json:"employee_ref"`
+	Name         string               `json:"name"`
+	EmployeeID   string               `json:"employee_id"`
+	StartDate    string               `json:"start_date"`
+	EndDate      string               `json:"end_date"`
+	LeaveType    string               `json:"leave_type" binding:"required"`
+	DateTemplate *models.DateTemplate `json:"date_template"`
+	Password     string               `json:"password"`
 }
 
 // SaveFormResponse 儲存表單回應結構
@@ -139,6 +185,10 @@ type SaveFormResponse struct {
 type ListSavedFormsResponse struct {
 	Success bool                `json:"success"`
 	Data    []*models.SavedForm `json:"data"`
+	Total   int                 `json:"total"`
+	Limit   int                 `json:"limit,omitempty"`
+	Offset  int                 `json:"offset,omitempty"`
+	Message string              `json:"message,omitempty"`
 }
 
 // GetSavedFormResponse 取得單筆表單回應結構
@@ -148,6 +198,58 @@ type GetSavedFormResponse struct {
 	Message string            `json:"message,omitempty"`
 }
 
+// UpdateSavedFormRequest 更新表單請求結構（PUT 全部替換；員工以 employee_ref 或姓名與員工代號指定，密碼留空則保留原密碼）
+type UpdateSavedFormRequest struct {
+	Label        string               `json:"label" binding:"required"`
+	EmployeeRef  int64                `json:"employee_ref"`
+	Name         string               `json:"name"`
+	EmployeeID   string               `json:"employee_id"`
+	StartDate    string               `json:"start_date"`
+	EndDate      string               `json:"end_date"`
+	LeaveType    string               `json:"leave_type" binding:"required"`
+	DateTemplate *models.DateTemplate `json:"date_template"`
+	Password     string               `json:"password"`
+}
+
+// PatchSavedFormRequest 部分更新表單請求結構（僅更新有提供的欄位；修改固定日期會取消日期範本）
+type PatchSavedFormRequest struct {
+	Label        *string              `json:"label"`
+	EmployeeRef  *int64               `json:"employee_ref"`
+	Name         *string              `json:"name"`
+	EmployeeID   *string              `json:"employee_id"`
+	StartDate    *string              `json:"start_date"`
+	EndDate      *string              `json:"end_date"`
+	LeaveType    *string              `json:"leave_type"`
+	DateTemplate *models.DateTemplate `json:"date_template"`
+	Password     *string              `json:"password"`
+}
+
+// PreviewDateTemplateRequest 日期範本預覽請求（fire_date 省略時為今天）
+type PreviewDateTemplateRequest struct {
+	DateTemplate *models.DateTemplate `json:"date_template" binding:"required"`
+	FireDate     string               `json:"fire_date"`
+}
+
+// PreviewDatesResponse 日期預覽回應
+type PreviewDatesResponse struct {
+	Success   bool   `json:"success"`
+	FireDate  string `json:"fire_date,omitempty"`
+	StartDate string `json:"start_date,omitempty"`
+	EndDate   string `json:"end_date,omitempty"`
+	Template  string `json:"template,omitempty"` // 範本的文字描述
+	Message   string `json:"message,omitempty"`
+}
+
+// CloneSavedFormRequest 複製表單請求結構
+type CloneSavedFormRequest struct {
+	Label string `json:"label"`
+}
+
+// UpdatePasswordRequest 更換密碼請求結構
+type UpdatePasswordRequest struct {
+	Password string `json:"password" binding:"required"`
+}
+
 // DeleteSavedFormResponse 刪除表單回應結構
 type DeleteSavedFormResponse struct {
 	Success bool   `json:"success"`
@@ -170,17 +272,44 @@ func (c *FormController) SaveForm(ctx *gin.Context) {
 
 	// 建立 SavedForm
 	savedForm := &models.SavedForm{
-		Label:      req.Label,
-		Name:       req.Name,
-		EmployeeID: req.EmployeeID,
-		StartDate:  req.StartDate,
-		EndDate:    req.EndDate,
-		LeaveType:  req.LeaveType,
-		Password:   req.Password,
+		Label:        req.Label,
+		EmployeeRef:  req.EmployeeRef,
+		Name:         req.Name,
+		EmployeeID:   req.EmployeeID,
+		StartDate:    req.StartDate,
+		EndDate:      req.EndDate,
+		LeaveType:    req.LeaveType,
+		DateTemplate: req.DateTemplate,
+		Password:     req.Password,
+	}
+
+	// 參照員工時帶入員工資料
+	if err := c.storage.FillEmployee(savedForm); err != nil {
+		ctx.JSON(http.StatusBadRequest, SaveFormResponse{
+			Success: false,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	// 驗證表單資料
+	if err := c.storage.ValidateSavedForm(savedForm); err != nil {
+		ctx.JSON(http.StatusBadRequest, SaveFormResponse{
+			Success: false,
+			Message: err.Error(),
+		})
+		return
 	}
 
 	// 儲存到資料庫
 	id, err := c.storage.Save(savedForm)
+	if errors.Is(err, models.ErrEmployeeMismatch) {
+		ctx.JSON(http.StatusConflict, SaveFormResponse{
+			Success: false,
+			Message: models.ErrEmployeeMismatch.Error(),
+		})
+		return
+	}
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, SaveFormResponse{
 			Success: false,
@@ -196,26 +325,73 @@ func (c *FormController) SaveForm(ctx *gin.Context) {
 	})
 }
 
-// ListSavedForms 列出已儲存的表單
-// GET /api/saved
+// parseSavedFormQuery 從查詢參數建立搜尋條件
+func parseSavedFormQuery(ctx *gin.Context) (*models.SavedFormQuery, error) {
+	q := &models.SavedFormQuery{
+		Label:      ctx.Query("q"),
+		EmployeeID: ctx.Query("employee_id"),
+		LeaveType:  ctx.Query("leave_type"),
+		From:       ctx.Query("from"),
+		To:         ctx.Query("to"),
+		Sort:       ctx.Query("sort"),
+		Order:      ctx.Query("order"),
+	}
+
+	if upcoming := ctx.Query("upcoming"); upcoming != "" {
+		q.UpcomingOnly = upcoming == "true" || upcoming == "1"
+	}
+
+	if limit := ctx.Query("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil {
+			return nil, fmt.Errorf("limit 必須為整數")
+		}
+		q.Limit = n
+	}
+
+	if offset := ctx.Query("offset"); offset != "" {
+		n, err := strconv.Atoi(offset)
+		if err != nil {
+			return nil, fmt.Errorf("offset 必須為整數")
+		}
+		q.Offset = n
+	}
+
+	if err := q.Validate(); err != nil {
+		return nil, err
+	}
+
+	return q, nil
+}
+
+// ListSavedForms 列出已儲存的表單（支援搜尋、篩選、排序與分頁）
+// GET /api/saved?q=&employee_id=&leave_type=&from=&to=&upcoming=&sort=&order=&limit=&offset=
 func (c *FormController) ListSavedForms(ctx *gin.Context) {
-	forms, err := c.storage.List()
+	q, err := parseSavedFormQuery(ctx)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, ListSavedFormsResponse{
+		ctx.JSON(http.StatusBadRequest, ListSavedFormsResponse{
 			Success: false,
 			Data:    nil,
+			Message: err.Error(),
 		})
 		return
 	}
 
-	// 確保回傳空陣列而非 null
-	if forms == nil {
-		forms = []*models.SavedForm{}
+	page, err := c.storage.Search(q)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, ListSavedFormsResponse{
+			Success: false,
+			Data:    nil,
+		})
+		return
 	}
 
 	ctx.JSON(http.StatusOK, ListSavedFormsResponse{
 		Success: true,
-		Data:    forms,
+		Data:    page.Forms,
+		Total:   page.Total,
+		Limit:   q.Limit,
+		Offset:  q.Offset,
 	})
 }
 
@@ -260,17 +436,400 @@ func (c *FormController) DeleteSavedForm(ctx *gin.Context) {
 		return
 	}
 
-	err = c.storage.Delete(id)
-	if err != nil {
+	// 排程使用中的資料不可刪除（由排程器在同一把鎖內檢查並刪除）
+	if c.scheduler != nil {
+		err = c.scheduler.DeleteSavedForm(id)
+	} else {
+		err = c.storage.Delete(id)
+	}
+	if errors.Is(err, models.ErrSavedFormScheduled) || errors.Is(err, models.ErrSavedFormInUse) {
+		ctx.JSON(http.StatusConflict, DeleteSavedFormResponse{
+			Success: false,
+			Message: err.Error(),
+		})
+		return
+	}
+	if errors.Is(err, models.ErrSavedFormNotFound) {
 		ctx.JSON(http.StatusNotFound, DeleteSavedFormResponse{
 			Success: false,
 			Message: "找不到指定的資料",
 		})
 		return
 	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, DeleteSavedFormResponse{
+			Success: false,
+			Message: err.Error(),
+		})
+		return
+	}
 
 	ctx.JSON(http.StatusOK, DeleteSavedFormResponse{
 		Success: true,
 		Message: "資料刪除成功",
 	})
 }
+
+// UpdateSavedFormPassword 更換已儲存表單所屬員工的密碼（密碼僅可寫入，不會回傳）
+// PUT /api/saved/:id/password
+func (c *FormController) UpdateSavedFormPassword(ctx *gin.Context) {
+	idStr := ctx.Param("id")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, SaveFormResponse{
+			Success: false,
+			Message: "無效的 ID 格式",
+		})
+		return
+	}
+
+	var req UpdatePasswordRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, SaveFormResponse{
+			Success: false,
+			Message: "JSON 格式錯誤或缺少密碼",
+		})
+		return
+	}
+
+	err = c.storage.UpdatePassword(id, req.Password)
+	if errors.Is(err, models.ErrSavedFormNotFound) {
+		ctx.JSON(http.StatusNotFound, SaveFormResponse{
+			Success: false,
+			Message: "找不到指定的資料",
+		})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, SaveFormResponse{
+			Success: false,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, SaveFormResponse{
+		Success: true,
+		ID:      id,
+		Message: "密碼已更新",
+	})
+}
+
+// parseSavedFormID 解析路徑中的 ID，失敗時直接回應 400
+func parseSavedFormID(ctx *gin.Context) (int64, bool) {
+	id, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, SaveFormResponse{
+			Success: false,
+			Message: "無效的 ID 格式",
+		})
+		return 0, false
+	}
+	return id, true
+}
+
+// saveUpdatedForm 驗證並寫回更新後的表單
+func (c *FormController) saveUpdatedForm(ctx *gin.Context, form *models.SavedForm) {
+	if err := c.storage.FillEmployee(form); err != nil {
+		ctx.JSON(http.StatusBadRequest, SaveFormResponse{
+			Success: false,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	if err := c.storage.ValidateSavedForm(form); err != nil {
+		ctx.JSON(http.StatusBadRequest, SaveFormResponse{
+			Success: false,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	err := c.storage.Update(form)
+	if errors.Is(err, models.ErrEmployeeMismatch) {
+		ctx.JSON(http.StatusConflict, SaveFormResponse{
+			Success: false,
+			Message: models.ErrEmployeeMismatch.Error(),
+		})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, SaveFormResponse{
+			Success: false,
+			Message: "資料更新失敗",
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, SaveFormResponse{
+		Success: true,
+		ID:      form.ID,
+		Message: "資料更新成功",
+	})
+}
+
+// clearPasswordOnEmployeeChange 改為其他員工時清除讀取時帶入的原員工密碼（密文），
+// 避免新建立的員工沿用原員工的密碼，或與既有員工的密碼比對不符
+func clearPasswordOnEmployeeChange(form *models.SavedForm, loadedRef int64, loadedEmployeeID string) {
+	if form.EmployeeRef != loadedRef || form.EmployeeID != loadedEmployeeID {
+		form.Password = ""
+	}
+}
+
+// UpdateSavedForm 更新已儲存的表單（ID 不變，排程參照不受影響）
+// PUT /api/saved/:id
+func (c *FormController) UpdateSavedForm(ctx *gin.Context) {
+	id, ok := parseSavedFormID(ctx)
+	if !ok {
+		return
+	}
+
+	var req UpdateSavedFormRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, SaveFormResponse{
+			Success: false,
+			Message: "JSON 格式錯誤或缺少必填欄位",
+		})
+		return
+	}
+
+	form, err := c.storage.GetByID(id)
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, SaveFormResponse{
+			Success: false,
+			Message: "找不到指定的資料",
+		})
+		return
+	}
+
+	loadedRef, loadedEmployeeID := form.EmployeeRef, form.EmployeeID
+	form.Label = req.Label
+	form.EmployeeRef = req.EmployeeRef
+	if req.EmployeeRef == 0 {
+		form.Name = req.Name
+		form.EmployeeID = req.EmployeeID
+	}
+	form.StartDate = req.StartDate
+	form.EndDate = req.EndDate
+	form.LeaveType = req.LeaveType
+	form.DateTemplate = req.DateTemplate
+	clearPasswordOnEmployeeChange(form, loadedRef, loadedEmployeeID)
+	if req.Password != "" {
+		form.Password = req.Password
+	}
+
+	c.saveUpdatedForm(ctx, form)
+}
+
+// PatchSavedForm 部分更新已儲存的表單
+// PATCH /api/saved/:id
+func (c *FormController) PatchSavedForm(ctx *gin.Context) {
+	id, ok := parseSavedFormID(ctx)
+	if !ok {
+		return
+	}
+
+	var req PatchSavedFormRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, SaveFormResponse{
+			Success: false,
+			Message: "JSON 格式錯誤",
+		})
+		return
+	}
+
+	form, err := c.storage.GetByID(id)
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, SaveFormResponse{
+			Success: false,
+			Message: "找不到指定的資料",
+		})
+		return
+	}
+
+	loadedRef, loadedEmployeeID := form.EmployeeRef, form.EmployeeID
+	if req.Label != nil {
+		form.Label = *req.Label
+	}
+	if req.EmployeeRef != nil {
+		form.EmployeeRef = *req.EmployeeRef
+	}
+	// 直接修改姓名或員工代號時改依員工代號尋找或建立員工
+	if req.Name != nil || req.EmployeeID != nil {
+		form.EmployeeRef = 0
+	}
+	if req.Name != nil {
+		form.Name = *req.Name
+	}
+	if req.EmployeeID != nil {
+		form.EmployeeID = *req.EmployeeID
+	}
+	if req.StartDate != nil {
+		form.StartDate = *req.StartDate
+		form.DateTemplate = nil
+	}
+	if req.EndDate != nil {
+		form.EndDate = *req.EndDate
+		form.DateTemplate = nil
+	}
+	if req.DateTemplate != nil {
+		form.DateTemplate = req.DateTemplate
+	}
+	if req.LeaveType != nil {
+		form.LeaveType = *req.LeaveType
+	}
+	clearPasswordOnEmployeeChange(form, loadedRef, loadedEmployeeID)
+	if req.Password != nil && *req.Password != "" {
+		form.Password = *req.Password
+	}
+
+	c.saveUpdatedForm(ctx, form)
+}
+
+// CloneSavedForm 複製已儲存的表單（含加密後的密碼）
+// POST /api/saved/:id/clone
+func (c *FormController) CloneSavedForm(ctx *gin.Context) {
+	id, ok := parseSavedFormID(ctx)
+	if !ok {
+		return
+	}
+
+	// body 可省略
+	var req CloneSavedFormRequest
+	if ctx.Request.ContentLength > 0 {
+		if err := ctx.ShouldBindJSON(&req); err != nil {
+			ctx.JSON(http.StatusBadRequest, SaveFormResponse{
+				Success: false,
+				Message: "JSON 格式錯誤",
+			})
+			return
+		}
+	}
+
+	form, err := c.storage.GetByID(id)
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, SaveFormResponse{
+			Success: false,
+			Message: "找不到指定的資料",
+		})
+		return
+	}
+
+	if req.Label != "" {
+		form.Label = req.Label
+	} else {
+		form.Label = form.Label + "（複本）"
+	}
+
+	newID, err := c.storage.Save(form)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, SaveFormResponse{
+			Success: false,
+			Message: "資料複製失敗",
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, SaveFormResponse{
+		Success: true,
+		ID:      newID,
+		Message: "資料複製成功",
+	})
+}
+
+// parseFireDate 解析觸發日（YYYY-MM-DD），空字串為今天
+func (c *FormController) parseFireDate(value string) (time.Time, error) {
+	if value == "" {
+		return c.storage.Clock().Now(), nil
+	}
+	return models.ParseScheduleDate(value)
+}
+
+// PreviewDateTemplate 以指定觸發日試算日期範本
+// POST /api/saved/preview
+func (c *FormController) PreviewDateTemplate(ctx *gin.Context) {
+	var req PreviewDateTemplateRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, PreviewDatesResponse{
+			Success: false,
+			Message: "JSON 格式錯誤或缺少 date_template",
+		})
+		return
+	}
+
+	fireDate, err := c.parseFireDate(req.FireDate)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, PreviewDatesResponse{
+			Success: false,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	startDate, endDate, err := req.DateTemplate.Resolve(fireDate, c.storage.Calendar())
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, PreviewDatesResponse{
+			Success: false,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, PreviewDatesResponse{
+		Success:   true,
+		FireDate:  fireDate.Format("2006-01-02"),
+		StartDate: startDate,
+		EndDate:   endDate,
+		Template:  req.DateTemplate.String(),
+	})
+}
+
+// PreviewSavedForm 預覽儲存資料在指定觸發日（?date=，預設今天）會提交的實際日期
+// GET /api/saved/:id/preview
+func (c *FormController) PreviewSavedForm(ctx *gin.Context) {
+	id, ok := parseSavedFormID(ctx)
+	if !ok {
+		return
+	}
+
+	form, err := c.storage.GetByID(id)
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, PreviewDatesResponse{
+			Success: false,
+			Message: "找不到指定的資料",
+		})
+		return
+	}
+
+	fireDate, err := c.parseFireDate(ctx.Query("date"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, PreviewDatesResponse{
+			Success: false,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	resp := PreviewDatesResponse{
+		Success:  true,
+		FireDate: fireDate.Format("2006-01-02"),
+	}
+	if form.DateTemplate != nil {
+		resp.Template = form.DateTemplate.String()
+	}
+
+	// 計算並驗證實際日期，無效時仍回傳計算結果供修正
+	req, err := form.LeaveRequestFor(fireDate, c.storage.Calendar())
+	if err != nil {
+		resp.Success = false
+		resp.StartDate, resp.EndDate, _ = form.DateTemplate.Resolve(fireDate, c.storage.Calendar())
+		resp.Message = err.Error()
+		ctx.JSON(http.StatusUnprocessableEntity, resp)
+		return
+	}
+
+	resp.StartDate = req.StartDate
+	resp.EndDate = req.EndDate
+	ctx.JSON(http.StatusOK, resp)
+}
@@ -1,14 +1,18 @@
 package controllers
 
 import (
+	"bufio"
 	"bytes"
+	"context"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
 	"os"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
 
@@ -33,6 +37,11 @@ func setupTestConfig() *config.Config {
 	}
 }
 
+// testSecretKey 測試用固定加密金鑰
+func testSecretKey() *models.SecretKey {
+	return &models.SecretKey{Key: bytes.Repeat([]byte{0x42}, models.EncryptionKeySize)}
+}
+
 // 設定測試環境
 func setupTestRouter(t *testing.T) (*gin.Engine, *FormController, *models.Storage, func()) {
 	gin.SetMode(gin.TestMode)
@@ -44,28 +53,88 @@ func setupTestRouter(t *testing.T) (*gin.Engine, *FormController, *models.Storag
 	}
 	tmpFile.Close()
 
-	storage, err := models.NewStorage(tmpFile.Name())
+	storage, err := models.NewStorage(tmpFile.Name(), testSecretKey())
 	if err != nil {
 		os.Remove(tmpFile.Name())
 		t.Fatalf("無法初始化 Storage: %v", err)
 	}
 
 	cfg := setupTestConfig()
-	controller := NewFormController(cfg, storage)
+	submitter := models.NewGoogleFormSubmitter(cfg.FormURL, cfg.EntryMap)
+	scheduler := models.NewScheduler(&models.ScheduleConfig{}, submitter, storage)
+	controller := NewFormController(cfg, submitter, storage, scheduler)
 
 	router := gin.New()
 	router.LoadHTMLGlob("../views/*.html")
 
 	// 註冊路由
 	router.GET("/", controller.ShowForm)
+	router.GET("/saved", controller.ShowSavedForms)
 	router.POST("/submit", controller.SubmitForm)
 	router.POST("/api/submit", controller.SubmitAPI)
+	router.POST("/api/submit/batch", controller.SubmitBatch)
+	router.GET("/api/history", controller.ListHistory)
 	router.GET("/api/saved", controller.ListSavedForms)
 	router.POST("/api/saved", controller.SaveForm)
+	router.POST("/api/saved/import", controller.ImportSavedForms)
+	router.GET("/api/saved/export", controller.ExportSavedForms)
 	router.GET("/api/saved/:id", controller.GetSavedForm)
 	router.DELETE("/api/saved/:id", controller.DeleteSavedForm)
+	router.PUT("/api/saved/:id", controller.UpdateSavedForm)
+	router.PATCH("/api/saved/:id", controller.PatchSavedForm)
+	router.POST("/api/saved/:id/clone", controller.CloneSavedForm)
+	router.POST("/api/saved/preview", controller.PreviewDateTemplate)
+	router.GET("/api/saved/:id/preview", controller.PreviewSavedForm)
+	router.PUT("/api/saved/:id/password", controller.UpdateSavedFormPassword)
+
+	bookingWindow, err := models.NewBookingWindow(30, "00:00", 0, "Asia/Taipei")
+	if err != nil {
+		t.Fatalf("建立預約窗口規則失敗: %v", err)
+	}
+	scheduleController := NewScheduleController(scheduler, storage, bookingWindow)
+	router.GET("/api/schedule", scheduleController.GetScheduleStatus)
+	router.POST("/api/schedule", scheduleController.CreateSchedule)
+	router.GET("/api/schedule/events", scheduleController.StreamScheduleEvents)
+	router.GET("/api/schedule/booking", scheduleController.GetBookingWindow)
+	router.POST("/api/schedule/booking", scheduleController.CreateBookingSchedule)
+
+	employeeController := NewEmployeeController(storage)
+	router.GET("/employees", employeeController.ShowEmployees)
+	router.GET("/api/employees", employeeController.ListEmployees)
+	router.POST("/api/employees", employeeController.CreateEmployee)
+	router.GET("/api/employees/:id", employeeController.GetEmployee)
+	router.PUT("/api/employees/:id", employeeController.UpdateEmployee)
+	router.DELETE("/api/employees/:id", employeeController.DeleteEmployee)
+	router.PUT("/api/employees/:id/password", employeeController.UpdateEmployeePassword)
+
+	teamScheduler := models.NewTeamScheduler(submitter, storage)
+	teamController := NewTeamController(teamScheduler, storage)
+	router.GET("/api/rosters", teamController.ListRosters)
+	router.POST("/api/rosters", teamController.CreateRoster)
+	router.GET("/api/rosters/:id", teamController.GetRoster)
+	router.PUT("/api/rosters/:id", teamController.UpdateRoster)
+	router.DELETE("/api/rosters/:id", teamController.DeleteRoster)
+	router.GET("/api/schedule/team", teamController.GetTeamSchedule)
+	router.POST("/api/schedule/team", teamController.CreateTeamSchedule)
+	router.DELETE("/api/schedule/team", teamController.StopTeamSchedule)
+
+	recurringScheduler := models.NewRecurringScheduler(submitter, storage)
+	recurringController := NewRecurringController(recurringScheduler, storage)
+	router.GET("/api/schedule/recurring", recurringController.ListRecurringSchedules)
+	router.POST("/api/schedule/recurring", recurringController.CreateRecurringSchedule)
+	router.POST("/api/schedule/recurring/preview", recurringController.PreviewRecurrence)
+	router.GET("/api/schedule/recurring/:id", recurringController.GetRecurringSchedule)
+	router.PUT("/api/schedule/recurring/:id", recurringController.UpdateRecurringSchedule)
+	router.DELETE("/api/schedule/recurring/:id", recurringController.DeleteRecurringSchedule)
+	router.GET("/api/schedule/recurring/:id/history", recurringController.GetRecurringHistory)
+
+	calendarController := NewCalendarController(storage)
+	router.GET("/api/calendar", calendarController.GetCalendar)
 
 	cleanup := func() {
+		scheduler.Stop()
+		teamScheduler.Stop()
+		recurringScheduler.Stop()
 		storage.Close()
 		os.Remove(tmpFile.Name())
 	}
@@ -435,3 +504,1343 @@ func TestDeleteSavedFormNotFound(t *testing.T) {
 		t.Errorf("刪除不存在的資料應回傳 404，實際 %d", w.Code)
 	}
 }
+
+// TestSavedFormPasswordNotExposed 測試 API 不回傳密碼
+func TestSavedFormPasswordNotExposed(t *testing.T) {
+	router, _, storage, cleanup := setupTestRouter(t)
+	defer cleanup()
+
+	storage.Save(&models.SavedForm{
+		Label:      "測試資料",
+		Name:       "測試員工",
+		EmployeeID: "A12345",
+		StartDate:  "2026-02-01",
+		EndDate:    "2026-02-03",
+		LeaveType:  "近假",
+		Password:   "testpass",
+	})
+
+	for _, path := range []string{"/api/saved", "/api/saved/1"} {
+		req, _ := http.NewRequest("GET", path, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		body := w.Body.String()
+		if strings.Contains(body, "testpass") || strings.Contains(body, `"password"`) {
+			t.Errorf("%s 不應回傳密碼: %s", path, body)
+		}
+		if !strings.Contains(body, `"has_password":true`) {
+			t.Errorf("%s 應回傳 has_password: %s", path, body)
+		}
+	}
+}
+
+// TestUpdateSavedFormPassword 測試 PUT /api/saved/:id/password
+func TestUpdateSavedFormPassword(t *testing.T) {
+	router, _, storage, cleanup := setupTestRouter(t)
+	defer cleanup()
+
+	id, _ := storage.Save(&models.SavedForm{
+		Label:      "測試資料",
+		Name:       "測試員工",
+		EmployeeID: "A12345",
+		StartDate:  "2026-02-01",
+		EndDate:    "2026-02-03",
+		LeaveType:  "近假",
+		Password:   "testpass",
+	})
+
+	jsonBody, _ := json.Marshal(map[string]string{"password": "newpass"})
+	req, _ := http.NewRequest("PUT", "/api/saved/1/password", bytes.NewBuffer(jsonBody))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("更換密碼應回傳 200，實際 %d", w.Code)
+	}
+
+	form, _ := storage.GetByID(id)
+	plain, err := storage.DecryptPassword(form.Password)
+	if err != nil || plain != "newpass" {
+		t.Errorf("密碼應更新為 newpass，實際 %q (%v)", plain, err)
+	}
+
+	// 缺少密碼
+	req2, _ := http.NewRequest("PUT", "/api/saved/1/password", strings.NewReader("{}"))
+	req2.Header.Set("Content-Type", "application/json")
+	w2 := httptest.NewRecorder()
+	router.ServeHTTP(w2, req2)
+
+	if w2.Code != http.StatusBadRequest {
+		t.Errorf("缺少密碼應回傳 400，實際 %d", w2.Code)
+	}
+
+	// 不存在的資料
+	req3, _ := http.NewRequest("PUT", "/api/saved/999/password", bytes.NewBuffer(jsonBody))
+	req3.Header.Set("Content-Type", "application/json")
+	w3 := httptest.NewRecorder()
+	router.ServeHTTP(w3, req3)
+
+	if w3.Code != http.StatusNotFound {
+		t.Errorf("不存在的資料應回傳 404，實際 %d", w3.Code)
+	}
+
+	// 資料庫錯誤不應回報為找不到資料
+	storage.Close()
+	req4, _ := http.NewRequest("PUT", "/api/saved/1/password", bytes.NewBuffer(jsonBody))
+	req4.Header.Set("Content-Type", "application/json")
+	w4 := httptest.NewRecorder()
+	router.ServeHTTP(w4, req4)
+
+	if w4.Code != http.StatusInternalServerError {
+		t.Errorf("資料庫錯誤應回傳 500，實際 %d", w4.Code)
+	}
+}
+
+// newTestSavedForm 建立測試用儲存資料
+func newTestSavedForm() *models.SavedForm {
+	return &models.SavedForm{
+		Label:      "測試資料",
+		Name:       "測試員工",
+		EmployeeID: "A12345",
+		StartDate:  "2026-02-01",
+		EndDate:    "2026-02-03",
+		LeaveType:  "近假",
+		Password:   "testpass",
+	}
+}
+
+// TestUpdateSavedFormAPI 測試 PUT /api/saved/:id 保留 ID 與密碼
+func TestUpdateSavedFormAPI(t *testing.T) {
+	router, _, storage, cleanup := setupTestRouter(t)
+	defer cleanup()
+
+	id, _ := storage.Save(newTestSavedForm())
+
+	reqBody := map[string]string{
+		"label":       "已修改",
+		"name":        "測試員工",
+		"employee_id": "A12345",
+		"start_date":  "2026-03-01",
+		"end_date":    "2026-03-02",
+		"leave_type":  "長假",
+	}
+	jsonBody, _ := json.Marshal(reqBody)
+	req, _ := http.NewRequest("PUT", "/api/saved/1", bytes.NewBuffer(jsonBody))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("更新應回傳 200，實際 %d: %s", w.Code, w.Body.String())
+	}
+
+	form, err := storage.GetByID(id)
+	if err != nil {
+		t.Fatalf("更新後應可用相同 ID 取得: %v", err)
+	}
+	if form.Label != "已修改" || form.StartDate != "2026-03-01" || form.LeaveType != "長假" {
+		t.Errorf("欄位未更新: %+v", form)
+	}
+	if plain, _ := storage.DecryptPassword(form.Password); plain != "testpass" {
+		t.Errorf("未提供密碼時應保留原密碼，實際 %q", plain)
+	}
+
+	// 驗證失敗
+	reqBody["end_date"] = "2026-02-01"
+	jsonBody, _ = json.Marshal(reqBody)
+	req2, _ := http.NewRequest("PUT", "/api/saved/1", bytes.NewBuffer(jsonBody))
+	req2.Header.Set("Content-Type", "application/json")
+	w2 := httptest.NewRecorder()
+	router.ServeHTTP(w2, req2)
+
+	if w2.Code != http.StatusBadRequest {
+		t.Errorf("終點早於起點應回傳 400，實際 %d", w2.Code)
+	}
+}
+
+// TestPatchSavedFormAPI 測試 PATCH /api/saved/:id 僅更新提供的欄位
+func TestPatchSavedFormAPI(t *testing.T) {
+	router, _, storage, cleanup := setupTestRouter(t)
+	defer cleanup()
+
+	id, _ := storage.Save(newTestSavedForm())
+
+	req, _ := http.NewRequest("PATCH", "/api/saved/1", strings.NewReader(`{"label":"只改標籤"}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("部分更新應回傳 200，實際 %d", w.Code)
+	}
+
+	form, _ := storage.GetByID(id)
+	if form.Label != "只改標籤" || form.Name != "測試員工" || form.StartDate != "2026-02-01" {
+		t.Errorf("部分更新結果不正確: %+v", form)
+	}
+}
+
+// TestSavedFormChangeEmployee 測試改為其他員工代號時不沿用原員工的密碼
+func TestSavedFormChangeEmployee(t *testing.T) {
+	router, _, storage, cleanup := setupTestRouter(t)
+	defer cleanup()
+
+	id, _ := storage.Save(newTestSavedForm())
+	other, err := storage.CreateEmployee(&models.Employee{Name: "李大華", EmployeeID: "B002", Password: "otherpass"})
+	if err != nil {
+		t.Fatalf("建立員工失敗: %v", err)
+	}
+
+	do := func(method, body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, fmt.Sprintf("/api/saved/%d", id), strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	passwordOf := func(employeeID string) string {
+		e, err := storage.GetEmployeeByEmployeeID(employeeID)
+		if err != nil {
+			t.Fatalf("讀取員工 %s 失敗: %v", employeeID, err)
+		}
+		plain, _ := storage.DecryptPassword(e.Password)
+		return plain
+	}
+
+	// 改為尚不存在的員工代號：會建立新員工，必須提供密碼
+	update := `{"label":"新員工","name":"陳小美","employee_id":"C003","start_date":"2026-03-01","end_date":"2026-03-02","leave_type":"近假"}`
+	if w := do("PUT", update); w.Code != http.StatusBadRequest {
+		t.Fatalf("建立新員工卻未提供密碼應回傳 400，實際 %d: %s", w.Code, w.Body.String())
+	}
+	if _, err := storage.GetEmployeeByEmployeeID("C003"); err == nil {
+		t.Fatal("驗證失敗時不應建立員工")
+	}
+	update = `{"label":"新員工","name":"陳小美","employee_id":"C003","start_date":"2026-03-01","end_date":"2026-03-02","leave_type":"近假","password":"newpass"}`
+	if w := do("PUT", update); w.Code != http.StatusOK {
+		t.Fatalf("提供密碼後應可改為新員工，實際 %d: %s", w.Code, w.Body.String())
+	}
+	if got := passwordOf("C003"); got != "newpass" {
+		t.Errorf("新員工應使用提供的密碼，實際 %q", got)
+	}
+
+	// 改為既有員工的代號：不需密碼，沿用該員工的密碼
+	if w := do("PATCH", `{"name":"李大華","employee_id":"B002"}`); w.Code != http.StatusOK {
+		t.Fatalf("改為既有員工應回傳 200，實際 %d: %s", w.Code, w.Body.String())
+	}
+	form, _ := storage.GetByID(id)
+	if form.EmployeeRef != other {
+		t.Errorf("應參照既有員工 %d，實際 %d", other, form.EmployeeRef)
+	}
+	if got := passwordOf("B002"); got != "otherpass" {
+		t.Errorf("既有員工的密碼不應改變，實際 %q", got)
+	}
+	if got := passwordOf("A12345"); got != "testpass" {
+		t.Errorf("原員工的密碼不應改變，實際 %q", got)
+	}
+}
+
+// TestCloneSavedFormAPI 測試 POST /api/saved/:id/clone
+func TestCloneSavedFormAPI(t *testing.T) {
+	router, _, storage, cleanup := setupTestRouter(t)
+	defer cleanup()
+
+	storage.Save(newTestSavedForm())
+
+	req, _ := http.NewRequest("POST", "/api/saved/1/clone", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("複製應回傳 200，實際 %d", w.Code)
+	}
+
+	var result SaveFormResponse
+	json.Unmarshal(w.Body.Bytes(), &result)
+	if result.ID == 1 {
+		t.Fatal("複本應有新的 ID")
+	}
+
+	clone, err := storage.GetByID(result.ID)
+	if err != nil {
+		t.Fatalf("取得複本失敗: %v", err)
+	}
+	if clone.Label != "測試資料（複本）" {
+		t.Errorf("複本標籤不正確: %s", clone.Label)
+	}
+	if plain, _ := storage.DecryptPassword(clone.Password); plain != "testpass" {
+		t.Errorf("複本應保留密碼，實際 %q", plain)
+	}
+}
+
+// TestDeleteSavedFormInUse 測試刪除排程使用中的資料
+func TestDeleteSavedFormInUse(t *testing.T) {
+	router, controller, storage, cleanup := setupTestRouter(t)
+	defer cleanup()
+
+	storage.Save(newTestSavedForm())
+
+	err := controller.scheduler.StartWithConfig(&models.ScheduleConfig{
+		Enabled:     true,
+		Date:        time.Now().AddDate(1, 0, 0).Format("2006-01-02"),
+		SavedFormID: 1,
+	})
+	if err != nil {
+		t.Fatalf("啟動排程器失敗: %v", err)
+	}
+
+	req, _ := http.NewRequest("DELETE", "/api/saved/1", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusConflict {
+		t.Errorf("排程使用中的資料應回傳 409，實際 %d", w.Code)
+	}
+
+	controller.scheduler.Stop()
+
+	req2, _ := http.NewRequest("DELETE", "/api/saved/1", nil)
+	w2 := httptest.NewRecorder()
+	router.ServeHTTP(w2, req2)
+
+	if w2.Code != http.StatusOK {
+		t.Errorf("停止排程後應可刪除，實際 %d", w2.Code)
+	}
+}
+
+// TestListSavedFormsQuery 測試 GET /api/saved 搜尋、篩選、排序與分頁
+func TestListSavedFormsQuery(t *testing.T) {
+	router, _, storage, cleanup := setupTestRouter(t)
+	defer cleanup()
+
+	forms := []*models.SavedForm{
+		{Label: "王小明 春假", Name: "王小明", EmployeeID: "A001", StartDate: "2026-03-01", EndDate: "2026-03-03", LeaveType: "近假", Password: "p"},
+		{Label: "王小明 暑假", Name: "王小明", EmployeeID: "A001", StartDate: "2026-07-10", EndDate: "2026-07-20", LeaveType: "長假", Password: "p"},
+		{Label: "李大華 春假", Name: "李大華", EmployeeID: "B002", StartDate: "2099-03-02", EndDate: "2099-03-02", LeaveType: "近假", Password: "p"},
+		{Label: "過期_資料", Name: "李大華", EmployeeID: "B002", StartDate: "2020-01-01", EndDate: "2020-01-02", LeaveType: "近假", Password: "p"},
+	}
+	for _, f := range forms {
+		storage.Save(f)
+	}
+
+	tests := []struct {
+		name    string
+		query   string
+		wantIDs []int64
+		total   int
+	}{
+		{"標籤搜尋", "?q=春假&sort=id&order=asc", []int64{1, 3}, 2},
+		{"萬用字元跳脫", "?q=_", []int64{4}, 1},
+		{"員工代號", "?employee_id=B002&sort=id&order=asc", []int64{3, 4}, 2},
+		{"假別", "?leave_type=長假", []int64{2}, 1},
+		{"期間重疊", "?from=2026-03-03&to=2026-07-10&sort=start_date&order=asc", []int64{1, 2}, 2},
+		{"僅未來", "?upcoming=true&employee_id=B002", []int64{3}, 1},
+		{"分頁", "?sort=id&order=asc&limit=2&offset=2", []int64{3, 4}, 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest("GET", "/api/saved"+tt.query, nil)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != http.StatusOK {
+				t.Fatalf("應回傳 200，實際 %d: %s", w.Code, w.Body.String())
+			}
+
+			var result ListSavedFormsResponse
+			json.Unmarshal(w.Body.Bytes(), &result)
+
+			if result.Total != tt.total {
+				t.Errorf("total 應為 %d，實際 %d", tt.total, result.Total)
+			}
+			var ids []int64
+			for _, f := range result.Data {
+				ids = append(ids, f.ID)
+			}
+			if len(ids) != len(tt.wantIDs) {
+				t.Fatalf("應回傳 %v，實際 %v", tt.wantIDs, ids)
+			}
+			for i := range ids {
+				if ids[i] != tt.wantIDs[i] {
+					t.Errorf("應回傳 %v，實際 %v", tt.wantIDs, ids)
+					break
+				}
+			}
+		})
+	}
+
+	// 未到期篩選依 Storage 的時鐘判斷
+	storage.SetClock(models.NewFakeClock(time.Date(2100, 1, 1, 0, 0, 0, 0, time.Local)))
+	req, _ := http.NewRequest("GET", "/api/saved?upcoming=true", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	var later ListSavedFormsResponse
+	json.Unmarshal(w.Body.Bytes(), &later)
+	if later.Total != 0 {
+		t.Errorf("時鐘在 2100 年時不應有未到期資料，實際 %d 筆", later.Total)
+	}
+
+	// 無效參數
+	for _, query := range []string{"?sort=password", "?order=up", "?limit=abc", "?from=2026/01/01"} {
+		req, _ := http.NewRequest("GET", "/api/saved"+query, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s 應回傳 400，實際 %d", query, w.Code)
+		}
+	}
+}
+
+// TestImportSavedFormsAPI 測試 POST /api/saved/import 試跑與實際匯入
+func TestImportSavedFormsAPI(t *testing.T) {
+	router, _, storage, cleanup := setupTestRouter(t)
+	defer cleanup()
+
+	csvData := "label,name,employee_id,start_date,end_date,leave_type,password\n" +
+		"甲,王小明,A001,2026-02-01,2026-02-03,近假,pass1\n" +
+		"乙,李小華,A002,2026-03-01,2026-03-02,長假,\n"
+
+	// 預設為試跑，不寫入
+	req, _ := http.NewRequest("POST", "/api/saved/import?format=csv", strings.NewReader(csvData))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("試跑應回傳 200，實際 %d: %s", w.Code, w.Body.String())
+	}
+	var resp ImportSavedFormsResponse
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	if !resp.Report.DryRun || resp.Report.Valid != 2 || resp.Report.Imported != 0 {
+		t.Errorf("試跑報告不正確: %+v", resp.Report)
+	}
+	if resp.Report.Rows[1].Warning == "" {
+		t.Error("未設定密碼的資料應有警告")
+	}
+	if forms, _ := storage.List(); len(forms) != 0 {
+		t.Fatalf("試跑不應寫入資料，實際 %d 筆", len(forms))
+	}
+
+	// 實際匯入
+	req2, _ := http.NewRequest("POST", "/api/saved/import?format=csv&dry_run=false", strings.NewReader(csvData))
+	w2 := httptest.NewRecorder()
+	router.ServeHTTP(w2, req2)
+
+	if w2.Code != http.StatusOK {
+		t.Fatalf("匯入應回傳 200，實際 %d: %s", w2.Code, w2.Body.String())
+	}
+	forms, _ := storage.List()
+	if len(forms) != 2 {
+		t.Fatalf("應匯入 2 筆，實際 %d 筆", len(forms))
+	}
+	for _, f := range forms {
+		if f.Label == "甲" && !f.HasPassword {
+			t.Error("甲 應有密碼")
+		}
+		if f.Label == "乙" && f.HasPassword {
+			t.Error("乙 不應有密碼")
+		}
+	}
+}
+
+// TestImportSavedFormsRowErrors 測試有錯誤列時回報行號且不寫入任何資料
+func TestImportSavedFormsRowErrors(t *testing.T) {
+	router, _, storage, cleanup := setupTestRouter(t)
+	defer cleanup()
+
+	jsonl := `{"label":"甲","name":"王小明","employee_id":"A001","start_date":"2026-02-01","end_date":"2026-02-03","leave_type":"近假","password":"p"}
+{"label":"乙","name":"李小華","employee_id":"A002","start_date":"2026-03-05","end_date":"2026-03-01","leave_type":"近假","password":"p"}
+not json
+`
+
+	req, _ := http.NewRequest("POST", "/api/saved/import?format=jsonl&dry_run=false", strings.NewReader(jsonl))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("有錯誤列應回傳 400，實際 %d", w.Code)
+	}
+	var resp ImportSavedFormsResponse
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	if resp.Report == nil || resp.Report.Invalid != 2 {
+		t.Fatalf("應有 2 筆錯誤: %+v", resp.Report)
+	}
+	if resp.Report.Rows[1].Line != 2 || resp.Report.Rows[2].Line != 3 {
+		t.Errorf("錯誤行號不正確: %+v", resp.Report.Rows)
+	}
+	if forms, _ := storage.List(); len(forms) != 0 {
+		t.Errorf("有錯誤時不應寫入任何資料，實際 %d 筆", len(forms))
+	}
+}
+
+// TestExportSavedFormsAPI 測試 GET /api/saved/export 不輸出密碼
+func TestExportSavedFormsAPI(t *testing.T) {
+	router, _, storage, cleanup := setupTestRouter(t)
+	defer cleanup()
+
+	storage.Save(newTestSavedForm())
+
+	for _, format := range []string{"csv", "jsonl"} {
+		req, _ := http.NewRequest("GET", "/api/saved/export?format="+format, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s 匯出應回傳 200，實際 %d", format, w.Code)
+		}
+		body := w.Body.String()
+		if !strings.Contains(body, "測試員工") {
+			t.Errorf("%s 匯出內容缺少資料: %s", format, body)
+		}
+		if strings.Contains(body, "password") || strings.Contains(body, "testpass") {
+			t.Errorf("%s 匯出不應包含密碼: %s", format, body)
+		}
+	}
+}
+
+// TestSubmitBatchAPI 測試 POST /api/submit/batch 試跑與驗證錯誤
+func TestSubmitBatchAPI(t *testing.T) {
+	router, _, _, cleanup := setupTestRouter(t)
+	defer cleanup()
+
+	valid := `[{"name":"甲","employee_id":"A1","start_date":"2026-02-01","end_date":"2026-02-02","leave_type":"近假","password":"p"}]`
+
+	req, _ := http.NewRequest("POST", "/api/submit/batch?dry_run=true", strings.NewReader(valid))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("試跑應回傳 200，實際 %d: %s", w.Code, w.Body.String())
+	}
+	var resp SubmitBatchResponse
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	if resp.Report == nil || !resp.Report.DryRun || resp.Report.Items[0].Status != models.BatchStatusValidated {
+		t.Errorf("試跑報告不正確: %+v", resp.Report)
+	}
+
+	invalid := `{"name":"甲","employee_id":"A1","start_date":"2026-02-05","end_date":"2026-02-01","leave_type":"近假","password":"p"}
+{"name":"乙"}
+`
+	req2, _ := http.NewRequest("POST", "/api/submit/batch", strings.NewReader(invalid))
+	w2 := httptest.NewRecorder()
+	router.ServeHTTP(w2, req2)
+
+	if w2.Code != http.StatusBadRequest {
+		t.Fatalf("驗證失敗應回傳 400，實際 %d", w2.Code)
+	}
+	var resp2 SubmitBatchResponse
+	json.Unmarshal(w2.Body.Bytes(), &resp2)
+	if resp2.Report == nil || resp2.Report.Invalid != 2 {
+		t.Errorf("應有 2 筆驗證錯誤: %+v", resp2.Report)
+	}
+
+	// 驗證失敗時仍依 report 參數回傳 CSV 報告
+	req3, _ := http.NewRequest("POST", "/api/submit/batch?report=csv", strings.NewReader(invalid))
+	w3 := httptest.NewRecorder()
+	router.ServeHTTP(w3, req3)
+	if w3.Code != http.StatusBadRequest || !strings.HasPrefix(w3.Header().Get("Content-Type"), "text/csv") || !strings.Contains(w3.Body.String(), models.BatchStatusInvalid) {
+		t.Errorf("驗證失敗且 report=csv 應回傳 400 與 CSV 報告，實際 %d %s: %s", w3.Code, w3.Header().Get("Content-Type"), w3.Body.String())
+	}
+
+	// 超過大小上限不可截斷後照常解析
+	oversized := valid + strings.Repeat(" ", maxBatchBodySize)
+	req4, _ := http.NewRequest("POST", "/api/submit/batch?dry_run=true", strings.NewReader(oversized))
+	w4 := httptest.NewRecorder()
+	router.ServeHTTP(w4, req4)
+	if w4.Code != http.StatusRequestEntityTooLarge || !strings.Contains(w4.Body.String(), "超過") {
+		t.Errorf("超過大小上限應回傳 413，實際 %d", w4.Code)
+	}
+
+	// 速率超出範圍
+	for _, rate := range []string{"1e-300", "0.01", "100"} {
+		req, _ := http.NewRequest("POST", "/api/submit/batch?dry_run=true&rate="+rate, strings.NewReader(valid))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("rate=%s 應回傳 400，實際 %d", rate, w.Code)
+		}
+	}
+}
+
+// TestEmployeeAPI 測試員工建立、以 employee_ref 儲存請假資料與刪除限制
+func TestEmployeeAPI(t *testing.T) {
+	router, _, storage, cleanup := setupTestRouter(t)
+	defer cleanup()
+
+	body, _ := json.Marshal(map[string]string{
+		"name":        "測試員工",
+		"employee_id": "A12345",
+		"password":    "testpass",
+	})
+	req, _ := http.NewRequest("POST", "/api/employees", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	var created EmployeeResponse
+	json.Unmarshal(w.Body.Bytes(), &created)
+	if w.Code != http.StatusOK || !created.Success || created.ID <= 0 {
+		t.Fatalf("建立員工應成功，實際 %d: %s", w.Code, w.Body.String())
+	}
+	if strings.Contains(w.Body.String(), "testpass") {
+		t.Error("回應不應包含密碼")
+	}
+
+	// 重複的員工代號
+	req, _ = http.NewRequest("POST", "/api/employees", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusConflict {
+		t.Errorf("重複的員工代號應回傳 409，實際 %d", w.Code)
+	}
+
+	// 新的請假資料只需員工與請假欄位
+	formBody, _ := json.Marshal(map[string]any{
+		"label":        "只填請假欄位",
+		"employee_ref": created.ID,
+		"start_date":   "2026-02-01",
+		"end_date":     "2026-02-03",
+		"leave_type":   "近假",
+	})
+	req, _ = http.NewRequest("POST", "/api/saved", bytes.NewBuffer(formBody))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	var saved SaveFormResponse
+	json.Unmarshal(w.Body.Bytes(), &saved)
+	if w.Code != http.StatusOK || !saved.Success {
+		t.Fatalf("以 employee_ref 儲存應成功，實際 %d: %s", w.Code, w.Body.String())
+	}
+
+	form, err := storage.GetByID(saved.ID)
+	if err != nil {
+		t.Fatalf("讀取儲存資料失敗: %v", err)
+	}
+	if form.Name != "測試員工" || form.EmployeeID != "A12345" || !form.HasPassword {
+		t.Errorf("儲存資料應帶入員工資料，實際 %+v", form)
+	}
+
+	// 舊版 API 以員工代號儲存：與員工資料相同時共用，不同時不可覆寫
+	legacy := func(name, password string) *httptest.ResponseRecorder {
+		legacyBody, _ := json.Marshal(map[string]any{
+			"label":       "舊版欄位",
+			"name":        name,
+			"employee_id": "A12345",
+			"password":    password,
+			"start_date":  "2026-02-01",
+			"end_date":    "2026-02-03",
+			"leave_type":  "近假",
+		})
+		req, _ := http.NewRequest("POST", "/api/saved", bytes.NewBuffer(legacyBody))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	if w := legacy("測試員工", "testpass"); w.Code != http.StatusOK {
+		t.Errorf("與員工資料相同時應可儲存，實際 %d: %s", w.Code, w.Body.String())
+	}
+	if w := legacy("測試員工", "other"); w.Code != http.StatusConflict {
+		t.Errorf("密碼不同時應回傳 409，實際 %d: %s", w.Code, w.Body.String())
+	}
+	if w := legacy("改名", "testpass"); w.Code != http.StatusConflict {
+		t.Errorf("姓名不同時應回傳 409，實際 %d: %s", w.Code, w.Body.String())
+	}
+	employee, _ := storage.GetEmployee(created.ID)
+	if plain, _ := storage.DecryptPassword(employee.Password); plain != "testpass" || employee.Name != "測試員工" {
+		t.Errorf("員工資料不應被舊版 API 修改，實際 %q / %q", employee.Name, plain)
+	}
+
+	req, _ = http.NewRequest("DELETE", fmt.Sprintf("/api/employees/%d", created.ID), nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusConflict {
+		t.Errorf("仍被參照的員工應回傳 409，實際 %d", w.Code)
+	}
+}
+
+// TestTeamScheduleAPI 測試建立團隊名單並啟動團隊排程
+func TestTeamScheduleAPI(t *testing.T) {
+	router, _, storage, cleanup := setupTestRouter(t)
+	defer cleanup()
+
+	var refs []int64
+	for _, id := range []string{"A1", "A2"} {
+		ref, err := storage.CreateEmployee(&models.Employee{Name: "員工" + id, EmployeeID: id, Password: "p"})
+		if err != nil {
+			t.Fatalf("建立員工失敗: %v", err)
+		}
+		refs = append(refs, ref)
+	}
+
+	doJSON := func(method, path string, body any) *httptest.ResponseRecorder {
+		jsonBody, _ := json.Marshal(body)
+		req, _ := http.NewRequest(method, path, bytes.NewBuffer(jsonBody))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := doJSON("POST", "/api/rosters", map[string]any{"name": "早班", "members": []int64{refs[1], refs[0]}})
+	var created RosterResponse
+	json.Unmarshal(w.Body.Bytes(), &created)
+	if w.Code != http.StatusOK || created.ID <= 0 {
+		t.Fatalf("建立名單應成功，實際 %d: %s", w.Code, w.Body.String())
+	}
+
+	w = doJSON("POST", "/api/rosters", map[string]any{"name": "重複", "members": []int64{refs[0], refs[0]}})
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("重複成員應回傳 400，實際 %d", w.Code)
+	}
+
+	w = doJSON("POST", "/api/schedule/team", map[string]any{
+		"roster_id":  created.ID,
+		"date":       time.Now().AddDate(1, 0, 0).Format("2006-01-02"),
+		"start_date": "2026-02-01",
+		"end_date":   "2026-02-02",
+		"leave_type": "近假",
+		"stagger_ms": 20,
+	})
+	var result TeamScheduleResponse
+	json.Unmarshal(w.Body.Bytes(), &result)
+	if w.Code != http.StatusOK || !result.Success {
+		t.Fatalf("啟動團隊排程應成功，實際 %d: %s", w.Code, w.Body.String())
+	}
+	if !result.Status.Running || len(result.Status.Members) != 2 || result.Status.Members[0].EmployeeID != "A2" {
+		t.Errorf("成員應依名單順序排程: %+v", result.Status)
+	}
+
+	w = doJSON("DELETE", "/api/schedule/team", nil)
+	json.Unmarshal(w.Body.Bytes(), &result)
+	if result.Status.Running || result.Status.Members[0].Status != models.TeamMemberStopped {
+		t.Errorf("停止後成員狀態應為 stopped: %+v", result.Status)
+	}
+}
+
+// TestDateTemplateAPI 測試以日期範本儲存並預覽實際日期
+func TestDateTemplateAPI(t *testing.T) {
+	router, _, _, cleanup := setupTestRouter(t)
+	defer cleanup()
+
+	body, _ := json.Marshal(map[string]any{
+		"label":         "每期近假",
+		"name":          "測試員工",
+		"employee_id":   "A12345",
+		"leave_type":    "近假",
+		"password":      "testpass",
+		"date_template": map[string]any{"offset_days": 30, "length_days": 3, "align_weekday": "monday"},
+	})
+	req, _ := http.NewRequest("POST", "/api/saved", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	var saved SaveFormResponse
+	json.Unmarshal(w.Body.Bytes(), &saved)
+	if w.Code != http.StatusOK || !saved.Success {
+		t.Fatalf("以日期範本儲存應成功，實際 %d: %s", w.Code, w.Body.String())
+	}
+
+	req, _ = http.NewRequest("GET", fmt.Sprintf("/api/saved/%d/preview?date=2026-03-04", saved.ID), nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	var preview PreviewDatesResponse
+	json.Unmarshal(w.Body.Bytes(), &preview)
+	if w.Code != http.StatusOK || preview.StartDate != "2026-04-06" || preview.EndDate != "2026-04-08" {
+		t.Errorf("預覽結果不正確，實際 %d: %s", w.Code, w.Body.String())
+	}
+
+	body, _ = json.Marshal(map[string]any{
+		"date_template": map[string]any{"offset_days": 1, "length_days": 0},
+	})
+	req, _ = http.NewRequest("POST", "/api/saved/preview", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("無效的範本應回傳 400，實際 %d", w.Code)
+	}
+}
+
+// TestRecurringScheduleAPI 測試週期排程的建立、預覽、參照保護與刪除
+func TestRecurringScheduleAPI(t *testing.T) {
+	router, _, storage, cleanup := setupTestRouter(t)
+	defer cleanup()
+
+	formID, err := storage.Save(&models.SavedForm{
+		Label:        "每月近假",
+		Name:         "王小明",
+		EmployeeID:   "A12345",
+		LeaveType:    "近假",
+		Password:     "p",
+		DateTemplate: &models.DateTemplate{OffsetDays: 30, LengthDays: 2},
+	})
+	if err != nil {
+		t.Fatalf("儲存資料失敗: %v", err)
+	}
+
+	doJSON := func(method, path string, body any) *httptest.ResponseRecorder {
+		jsonBody, _ := json.Marshal(body)
+		req, _ := http.NewRequest(method, path, bytes.NewBuffer(jsonBody))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := doJSON("POST", "/api/schedule/recurring/preview", map[string]any{
+		"kind": "rrule", "spec": "FREQ=MONTHLY;BYMONTHDAY=1", "timezone": "Asia/Taipei", "count": 3,
+	})
+	var preview PreviewRecurrenceResponse
+	json.Unmarshal(w.Body.Bytes(), &preview)
+	if w.Code != http.StatusOK || len(preview.Times) != 3 || preview.Times[0].Day() != 1 {
+		t.Fatalf("預覽應回傳 3 次每月 1 號，實際 %d: %s", w.Code, w.Body.String())
+	}
+
+	w = doJSON("POST", "/api/schedule/recurring", map[string]any{
+		"label": "錯誤", "kind": "cron", "spec": "not a cron", "saved_form_id": formID,
+	})
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("無效的 cron 應回傳 400，實際 %d", w.Code)
+	}
+
+	w = doJSON("POST", "/api/schedule/recurring", map[string]any{
+		"label": "每月一號", "kind": "cron", "spec": "0 0 1 * *", "timezone": "Asia/Taipei",
+		"saved_form_id": formID, "end_count": 12,
+	})
+	var created RecurringScheduleResponse
+	json.Unmarshal(w.Body.Bytes(), &created)
+	if w.Code != http.StatusOK || created.ID <= 0 {
+		t.Fatalf("建立週期排程應成功，實際 %d: %s", w.Code, w.Body.String())
+	}
+	if created.Data.Status != models.RecurringArmed || created.Data.NextRunAt == nil || created.Data.RetryCount != 3 {
+		t.Errorf("應已排定下一次觸發並套用預設值: %+v", created.Data)
+	}
+
+	w = doJSON("DELETE", fmt.Sprintf("/api/saved/%d", formID), nil)
+	if w.Code != http.StatusConflict {
+		t.Errorf("被週期排程使用的資料應回傳 409，實際 %d", w.Code)
+	}
+
+	path := fmt.Sprintf("/api/schedule/recurring/%d", created.ID)
+	w = doJSON("PUT", path, map[string]any{
+		"label": "每月一號", "kind": "cron", "spec": "0 0 1 * *", "saved_form_id": formID, "enabled": false,
+	})
+	json.Unmarshal(w.Body.Bytes(), &created)
+	if w.Code != http.StatusOK || created.Data.Status != models.RecurringDisabled {
+		t.Errorf("停用後狀態應為 disabled，實際 %d: %s", w.Code, w.Body.String())
+	}
+
+	w = doJSON("GET", path+"/history", nil)
+	if w.Code != http.StatusOK {
+		t.Errorf("查詢觸發記錄應成功，實際 %d", w.Code)
+	}
+
+	w = doJSON("DELETE", path, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("刪除週期排程應成功，實際 %d", w.Code)
+	}
+	w = doJSON("DELETE", fmt.Sprintf("/api/saved/%d", formID), nil)
+	if w.Code != http.StatusOK {
+		t.Errorf("刪除週期排程後應可刪除資料，實際 %d: %s", w.Code, w.Body.String())
+	}
+}
+
+// TestBookingScheduleAPI 測試依預約窗口排程：未開放時排程、已截止時拒絕
+func TestBookingScheduleAPI(t *testing.T) {
+	router, _, storage, cleanup := setupTestRouter(t)
+	defer cleanup()
+
+	taipei, _ := time.LoadLocation("Asia/Taipei")
+	save := func(startDate string) int64 {
+		id, err := storage.Save(&models.SavedForm{
+			Label:      "請假 " + startDate,
+			Name:       "王小明",
+			EmployeeID: "A12345",
+			StartDate:  startDate,
+			EndDate:    startDate,
+			LeaveType:  "近假",
+			Password:   "p",
+		})
+		if err != nil {
+			t.Fatalf("儲存資料失敗: %v", err)
+		}
+		return id
+	}
+	future := save(time.Now().In(taipei).AddDate(0, 0, 60).Format("2006-01-02"))
+	open := save(time.Now().In(taipei).AddDate(0, 0, 10).Format("2006-01-02"))
+	past := save(time.Now().In(taipei).AddDate(0, 0, -1).Format("2006-01-02"))
+
+	doJSON := func(method, path string, body any) *httptest.ResponseRecorder {
+		jsonBody, _ := json.Marshal(body)
+		req, _ := http.NewRequest(method, path, bytes.NewBuffer(jsonBody))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := doJSON("GET", fmt.Sprintf("/api/schedule/booking?saved_form_id=%d", future), nil)
+	var preview BookingWindowResponse
+	json.Unmarshal(w.Body.Bytes(), &preview)
+	if w.Code != http.StatusOK || preview.Window.Status != models.BookingWindowPending || preview.Window.CountdownSeconds <= 0 {
+		t.Fatalf("60 天後的假應尚未開放並有倒數，實際 %d: %s", w.Code, w.Body.String())
+	}
+
+	w = doJSON("POST", "/api/schedule/booking", map[string]any{"saved_form_id": past})
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("已截止的假應回傳 422，實際 %d: %s", w.Code, w.Body.String())
+	}
+
+	w = doJSON("POST", "/api/schedule/booking", map[string]any{"saved_form_id": open})
+	if w.Code != http.StatusConflict {
+		t.Errorf("已開放的假應回傳 409，實際 %d: %s", w.Code, w.Body.String())
+	}
+
+	w = doJSON("POST", "/api/schedule/booking", map[string]any{"saved_form_id": future})
+	var armed BookingWindowResponse
+	json.Unmarshal(w.Body.Bytes(), &armed)
+	if w.Code != http.StatusOK || !armed.Success {
+		t.Fatalf("未開放的假應可排程，實際 %d: %s", w.Code, w.Body.String())
+	}
+
+	w = doJSON("GET", "/api/schedule", nil)
+	var status ScheduleStatusResponse
+	json.Unmarshal(w.Body.Bytes(), &status)
+	if !status.Running || status.NextRunAt == nil || !status.NextRunAt.Equal(armed.Window.OpensAt) {
+		t.Errorf("排程目標應為窗口開放時間 %v，實際 %+v", armed.Window.OpensAt, status)
+	}
+	if got := status.NextRunAt.In(taipei); got.Hour() != 0 || got.Minute() != 0 {
+		t.Errorf("應於台北時間 00:00 觸發，實際 %v", got)
+	}
+}
+
+// TestCalendarAPI 測試假日行事曆查詢與全假日期間的請假被拒絕
+func TestScheduleMissedPolicyAPI(t *testing.T) {
+	router, _, storage, cleanup := setupTestRouter(t)
+	defer cleanup()
+
+	id, err := storage.Save(&models.SavedForm{
+		Label:      "錯過",
+		Name:       "王小明",
+		EmployeeID: "A12345",
+		StartDate:  "2030-01-02",
+		EndDate:    "2030-01-03",
+		LeaveType:  "近假",
+		Password:   "p",
+	})
+	if err != nil {
+		t.Fatalf("儲存資料失敗: %v", err)
+	}
+
+	doJSON := func(body any) *httptest.ResponseRecorder {
+		jsonBody, _ := json.Marshal(body)
+		req, _ := http.NewRequest("POST", "/api/schedule", bytes.NewBuffer(jsonBody))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := doJSON(map[string]any{"date": "2020-01-01", "saved_form_id": id, "missed_policy": "retry"})
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("無效的錯過策略應回傳 400，實際 %d: %s", w.Code, w.Body.String())
+	}
+
+	w = doJSON(map[string]any{"date": "2020-01-01", "saved_form_id": id, "missed_policy": models.MissedPolicyNotify})
+	var resp ScheduleStatusResponse
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	if w.Code != http.StatusOK || resp.State != models.JobMissed || resp.Running || resp.NextRunAt != nil {
+		t.Fatalf("已過的排程應回傳 missed 狀態，實際 %d: %s", w.Code, w.Body.String())
+	}
+	if resp.LastResult == nil || resp.LastResult.MissedDecision != models.MissedNotified || resp.Message != resp.LastResult.Message {
+		t.Errorf("應回傳錯過處理結果，實際 %+v", resp.LastResult)
+	}
+
+	records, err := storage.ListHistory(models.HistoryQuery{Limit: 1})
+	if err != nil || len(records) != 1 || records[0].MissedDecision != models.MissedNotified || records[0].Success {
+		t.Errorf("提交記錄應包含錯過處理結果，實際 %+v: %v", records, err)
+	}
+}
+
+func TestSchedulePreflightAPI(t *testing.T) {
+	_, _, storage, cleanup := setupTestRouter(t)
+	defer cleanup()
+
+	cfg := setupTestConfig()
+	mock, err := models.StartMockFormServer(models.NewMockForm(models.MockFormOptions{EntryIDs: cfg.EntryMap}), "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("啟動模擬表單失敗: %v", err)
+	}
+	defer mock.Close()
+
+	scheduler := models.NewScheduler(&models.ScheduleConfig{}, models.NewGoogleFormSubmitter(mock.FormURL(), cfg.EntryMap), storage)
+	scheduleController := NewScheduleController(scheduler, storage, nil)
+	router := gin.New()
+	router.POST("/api/schedule/preflight", scheduleController.CheckPreflight)
+
+	id, err := storage.Save(&models.SavedForm{
+		Label:      "檢查",
+		Name:       "王小明",
+		EmployeeID: "A12345",
+		StartDate:  "2030-01-02",
+		EndDate:    "2030-01-03",
+		LeaveType:  "近假",
+		Password:   "p",
+	})
+	if err != nil {
+		t.Fatalf("儲存資料失敗: %v", err)
+	}
+
+	doJSON := func(body any) *httptest.ResponseRecorder {
+		jsonBody, _ := json.Marshal(body)
+		req, _ := http.NewRequest("POST", "/api/schedule/preflight", bytes.NewBuffer(jsonBody))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := doJSON(map[string]any{"date": "2029-12-01", "saved_form_id": id})
+	var resp PreflightResponse
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	if w.Code != http.StatusOK || resp.Report == nil || !resp.Report.Passed || len(resp.Report.Checks) != 6 {
+		t.Fatalf("檢查應全部通過，實際 %d: %s", w.Code, w.Body.String())
+	}
+	// 預檢為唯讀：沒有指紋時只警告，不建立指紋
+	if c := resp.Report.Check(models.PreflightCheckFormStructure); c == nil || c.Status != models.PreflightWarn {
+		t.Errorf("尚未建立指紋時表單結構檢查應為警告，實際 %+v", c)
+	}
+	if fp, _ := storage.GetFormFingerprint(mock.FormURL()); fp != nil {
+		t.Errorf("預檢不應建立指紋，實際 %+v", fp)
+	}
+
+	w = doJSON(map[string]any{"date": "2029-12-01", "saved_form_id": 999})
+	if w.Code != http.StatusNotFound {
+		t.Errorf("不存在的儲存資料應回傳 404，實際 %d", w.Code)
+	}
+
+	w = doJSON(map[string]any{"saved_form_id": id})
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("未指定日期且未設定預約窗口應回傳 400，實際 %d", w.Code)
+	}
+}
+
+func TestCalendarAPI(t *testing.T) {
+	router, _, storage, cleanup := setupTestRouter(t)
+	defer cleanup()
+
+	days := []*models.CalendarDay{
+		{Date: "2025-01-27", Kind: models.CalendarHoliday, Name: "農曆除夕前一日"},
+		{Date: "2025-01-28", Kind: models.CalendarHoliday, Name: "農曆除夕"},
+		{Date: "2025-02-08", Kind: models.CalendarWorkday, Name: "補班"},
+	}
+	if err := storage.ImportCalendar(days, "test.csv", false); err != nil {
+		t.Fatalf("匯入行事曆失敗: %v", err)
+	}
+
+	req, _ := http.NewRequest("GET", "/api/calendar?from=2025-01-25&to=2025-01-31", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	var resp CalendarResponse
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	if w.Code != http.StatusOK || len(resp.Days) != 2 || resp.Summary == nil {
+		t.Fatalf("應回傳期間內的 2 個假日與統計，實際 %d: %s", w.Code, w.Body.String())
+	}
+	if resp.Summary.CalendarDays != 7 || resp.Summary.WorkingDays != 3 {
+		t.Errorf("1/25~1/31 應為 7 天、3 個工作日，實際 %+v", resp.Summary)
+	}
+
+	req, _ = http.NewRequest("GET", "/api/calendar?from=2025-13-01", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("日期格式錯誤應回傳 400，實際 %d", w.Code)
+	}
+
+	body, _ := json.Marshal(models.LeaveRequest{
+		Name:       "王小明",
+		EmployeeID: "A12345",
+		StartDate:  "2025-01-25",
+		EndDate:    "2025-01-28",
+		LeaveType:  "近假",
+		Password:   "p",
+	})
+	req, _ = http.NewRequest("POST", "/api/submit", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "皆為假日") {
+		t.Errorf("週末加連假的請假應被拒絕，實際 %d: %s", w.Code, w.Body.String())
+	}
+}
+
+// TestScheduleEventStream 測試 SSE 連線時先送出最近的事件，並即時推送新事件
+func TestScheduleEventStream(t *testing.T) {
+	router, controller, storage, cleanup := setupTestRouter(t)
+	defer cleanup()
+
+	id, err := storage.Save(&models.SavedForm{
+		Label:      "SSE",
+		Name:       "王小明",
+		EmployeeID: "A12345",
+		StartDate:  "2030-01-02",
+		EndDate:    "2030-01-03",
+		LeaveType:  "近假",
+		Password:   "p",
+	})
+	if err != nil {
+		t.Fatalf("儲存資料失敗: %v", err)
+	}
+	if err := controller.scheduler.StartWithConfig(&models.ScheduleConfig{
+		Enabled:     true,
+		At:          time.Now().Add(time.Hour),
+		SavedFormID: id,
+	}); err != nil {
+		t.Fatalf("啟動排程器失敗: %v", err)
+	}
+
+	w := httptest.NewRecorder()
+	statusReq, _ := http.NewRequest("GET", "/api/schedule", nil)
+	router.ServeHTTP(w, statusReq)
+	var status ScheduleStatusResponse
+	json.Unmarshal(w.Body.Bytes(), &status)
+	if status.State != models.JobArmed || status.StateSince == nil || len(status.States) != 1 {
+		t.Errorf("排程狀態應為 armed 並附上進入時間，實際 %s", w.Body.String())
+	}
+
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	reqCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(reqCtx, "GET", server.URL+"/api/schedule/events", nil)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("連線失敗: %v", err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); !strings.HasPrefix(ct, "text/event-stream") {
+		t.Fatalf("Content-Type 應為 text/event-stream，實際 %s", ct)
+	}
+
+	reader := bufio.NewReader(resp.Body)
+	nextEvent := func() models.ScheduleEvent {
+		var e models.ScheduleEvent
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				t.Fatalf("讀取事件失敗: %v", err)
+			}
+			if data, ok := strings.CutPrefix(strings.TrimSpace(line), "data:"); ok {
+				if err := json.Unmarshal([]byte(data), &e); err != nil {
+					t.Fatalf("事件格式錯誤: %v", err)
+				}
+				// 排程前檢查在背景連線，結果與通知的先後不固定
+				if e.Type == models.ScheduleEventPreflight || e.Type == models.ScheduleEventNotice {
+					e = models.ScheduleEvent{}
+					continue
+				}
+				return e
+			}
+		}
+	}
+
+	if e := nextEvent(); e.Type != models.ScheduleEventArmed || e.TargetTime == nil {
+		t.Errorf("連線後應先收到已發生的 armed 事件，實際 %+v", e)
+	}
+
+	controller.scheduler.Stop()
+	if e := nextEvent(); e.Type != models.ScheduleEventStopped {
+		t.Errorf("停止排程後應收到 stopped 事件，實際 %+v", e)
+	}
+}
+
+func TestSchedulePollStrategyAPI(t *testing.T) {
+	router, _, storage, cleanup := setupTestRouter(t)
+	defer cleanup()
+
+	id, err := storage.Save(&models.SavedForm{
+		Label:      "探測",
+		Name:       "王小明",
+		EmployeeID: "A12345",
+		StartDate:  "2030-01-02",
+		EndDate:    "2030-01-03",
+		LeaveType:  "近假",
+		Password:   "p",
+	})
+	if err != nil {
+		t.Fatalf("儲存資料失敗: %v", err)
+	}
+
+	for _, body := range []map[string]any{
+		{"date": "2030-01-01", "saved_form_id": id, "strategy": "early"},
+		{"date": "2030-01-01", "saved_form_id": id, "strategy": models.StrategyPoll, "poll_interval_ms": 10},
+		{"date": "2030-01-01", "saved_form_id": id, "strategy": models.StrategyPoll, "poll_lead_seconds": 601},
+	} {
+		jsonBody, _ := json.Marshal(body)
+		req, _ := http.NewRequest("POST", "/api/schedule", bytes.NewBuffer(jsonBody))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "poll") && !strings.Contains(w.Body.String(), "strategy") {
+			t.Errorf("%v 應回傳 400 並說明策略參數錯誤，實際 %d: %s", body, w.Code, w.Body.String())
+		}
+	}
+}
+
+func TestFormStructureAPI(t *testing.T) {
+	_, _, storage, cleanup := setupTestRouter(t)
+	defer cleanup()
+
+	cfg := setupTestConfig()
+	form := models.NewMockForm(models.MockFormOptions{EntryIDs: cfg.EntryMap})
+	mock, err := models.StartMockFormServer(form, "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("啟動模擬表單失敗: %v", err)
+	}
+	defer mock.Close()
+
+	entryMap := make(map[string]string)
+	for field, entryID := range cfg.EntryMap {
+		entryMap[field] = entryID
+	}
+	monitor := models.NewFormMonitor(models.NewGoogleFormSubmitter(mock.FormURL(), entryMap), storage, 0)
+	fc := NewFormStructureController(monitor)
+	router := gin.New()
+	router.GET("/api/form/structure", fc.GetFormStructure)
+	router.POST("/api/form/structure/check", fc.CheckFormStructure)
+	router.POST("/api/form/structure/baseline", fc.AcceptFormStructure)
+	router.PUT("/api/form/entry-map", fc.RemapEntryMap)
+
+	do := func(method, path string, body any) (int, FormStructureResponse) {
+		var reader *bytes.Reader
+		if body != nil {
+			jsonBody, _ := json.Marshal(body)
+			reader = bytes.NewReader(jsonBody)
+		} else {
+			reader = bytes.NewReader(nil)
+		}
+		req, _ := http.NewRequest(method, path, reader)
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		var resp FormStructureResponse
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		return w.Code, resp
+	}
+
+	code, resp := do("GET", "/api/form/structure", nil)
+	if code != http.StatusOK || resp.LastCheck != nil || resp.Baseline != nil || resp.EntryMap["end_date"] != "entry.012" {
+		t.Fatalf("尚未檢查時應只回傳 entry_map，實際 %d: %+v", code, resp)
+	}
+
+	code, resp = do("POST", "/api/form/structure/check", nil)
+	if code != http.StatusOK || resp.LastCheck.Status != models.FormCheckNoBaseline || resp.Baseline != nil {
+		t.Fatalf("檢查不應自動建立指紋，實際 %d: %+v", code, resp)
+	}
+	if resp.LastCheck.Timing == nil || resp.LastCheck.Timing.TotalUs <= 0 {
+		t.Errorf("檢查結果應附上讀取表單頁面的網路耗時，實際 %+v", resp.LastCheck.Timing)
+	}
+
+	code, resp = do("POST", "/api/form/structure/baseline", nil)
+	if code != http.StatusOK || resp.LastCheck.Status != models.FormCheckOK || resp.Baseline == nil {
+		t.Fatalf("接受目前結構應建立指紋，實際 %d: %+v", code, resp)
+	}
+
+	// 表單上的假別題目被重建
+	changed := make(map[string]string)
+	for field, entryID := range cfg.EntryMap {
+		changed[field] = entryID
+	}
+	changed["leave_type"] = "entry.999"
+	form.SetOptions(models.MockFormOptions{EntryIDs: changed})
+	code, resp = do("POST", "/api/form/structure/check", nil)
+	if code != http.StatusOK || resp.LastCheck.Status != models.FormCheckDrift {
+		t.Fatalf("應偵測到表單結構變更，實際 %d: %+v", code, resp)
+	}
+
+	code, resp = do("PUT", "/api/form/entry-map", map[string]any{"entry_map": map[string]string{"leave_type": "entry.404"}})
+	if code != http.StatusBadRequest || resp.Success {
+		t.Errorf("對應到不存在的 entry 應回傳 400，實際 %d: %+v", code, resp)
+	}
+	code, resp = do("PUT", "/api/form/entry-map", map[string]any{"entry_map": map[string]string{"leave_type": "entry.999"}})
+	if code != http.StatusOK || resp.LastCheck.Status != models.FormCheckOK || resp.EntryMap["leave_type"] != "entry.999" {
+		t.Errorf("重新對應後應相符，實際 %d: %+v", code, resp)
+	}
+
+	form.SetOptions(models.MockFormOptions{EntryIDs: changed, Closed: true})
+	if code, resp = do("POST", "/api/form/structure/baseline", nil); code != http.StatusConflict {
+		t.Errorf("表單未開放時更新指紋應回傳 409，實際 %d: %+v", code, resp)
+	}
+}
+
+func TestFirePrecisionAPI(t *testing.T) {
+	router, _, storage, cleanup := setupTestRouter(t)
+	defer cleanup()
+
+	get := func(path string, v any) {
+		req, _ := http.NewRequest("GET", path, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s 應回傳 200，實際 %d: %s", path, w.Code, w.Body.String())
+		}
+		json.Unmarshal(w.Body.Bytes(), v)
+	}
+
+	var status ScheduleStatusResponse
+	get("/api/schedule", &status)
+	if status.Precision != nil {
+		t.Errorf("沒有送出記錄時不應有精確度統計，實際 %+v", status.Precision)
+	}
+
+	intended := time.Date(2026, 3, 1, 0, 0, 0, 0, time.Local)
+	for i, delayUs := range []int64{400, -200, 3000} {
+		sent := intended.Add(time.Duration(delayUs) * time.Microsecond)
+		if _, err := storage.RecordSubmission(&models.SubmissionRecord{
+			Source:      models.HistorySourceSchedule,
+			Name:        "王小明",
+			Success:     true,
+			StartedAt:   sent.Add(time.Duration(i) * time.Minute),
+			FinishedAt:  sent.Add(time.Duration(i) * time.Minute),
+			IntendedAt:  &intended,
+			SentAt:      &sent,
+			FireDelayUs: &delayUs,
+
+			AttemptDetails: []models.SubmissionAttempt{{Attempt: 1, StatusCode: http.StatusOK, SentAt: sent,
+				Timing: &models.RequestTiming{WaitUs: 80000, ReadUs: 300, TotalUs: 80500, Reused: true}}},
+		}); err != nil {
+			t.Fatalf("寫入提交記錄失敗: %v", err)
+		}
+	}
+	// 批次送出沒有送出時間，不列入統計
+	storage.RecordSubmission(&models.SubmissionRecord{Source: models.HistorySourceBatch, Name: "李小華", StartedAt: intended, FinishedAt: intended})
+
+	get("/api/schedule", &status)
+	if p := status.Precision; p == nil || p.Count != 3 || p.OnTime != 2 || p.LastUs != 3000 || p.MaxAbsUs != 3000 {
+		t.Errorf("排程狀態的精確度統計不符，實際 %+v", p)
+	}
+
+	var history ListHistoryResponse
+	get("/api/history", &history)
+	if p := history.Precision; len(history.Records) != 4 || p == nil || p.Count != 3 || p.SampleSize != 4 || p.MinUs != -200 {
+		t.Errorf("提交記錄的精確度統計不符，實際 %d 筆 %+v", len(history.Records), p)
+	}
+	if rec := history.Records[0]; rec.FireDelayUs == nil || *rec.FireDelayUs != 3000 || !rec.IntendedAt.Equal(intended) ||
+		len(rec.AttemptDetails) != 1 || rec.AttemptDetails[0].Timing.WaitUs != 80000 || !rec.AttemptDetails[0].Timing.Reused {
+		t.Errorf("提交記錄應包含送出時間，實際 %+v", rec)
+	}
+
+	var batch ListHistoryResponse
+	get("/api/history?source=batch", &batch)
+	if len(batch.Records) != 1 || batch.Precision != nil {
+		t.Errorf("沒有送出時間的記錄不應有精確度統計，實際 %+v", batch.Precision)
+	}
+}
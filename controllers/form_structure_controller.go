@@ -0,0 +1,126 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"google-form-submitter/models"
+)
+
+// formStructureTimeout 讀取表單結構的時間上限
+const formStructureTimeout = 30 * time.Second
+
+// FormStructureController 表單結構指紋與欄位重新對應控制器
+type FormStructureController struct {
+	monitor *models.FormMonitor
+}
+
+// NewFormStructureController 建立新的 FormStructureController
+func NewFormStructureController(monitor *models.FormMonitor) *FormStructureController {
+	return &FormStructureController{
+		monitor: monitor,
+	}
+}
+
+// FormStructureResponse 表單結構回應
+type FormStructureResponse struct {
+	Success   bool                       `json:"success"`
+	Baseline  *models.FormFingerprint    `json:"baseline,omitempty"`
+	LastCheck *models.FormStructureCheck `json:"last_check,omitempty"`
+	EntryMap  map[string]string          `json:"entry_map,omitempty"`
+	Message   string                     `json:"message,omitempty"`
+}
+
+// RemapRequest 重新對應表單欄位請求
+type RemapRequest struct {
+	EntryMap map[string]string `json:"entry_map" binding:"required"` // 欄位名稱 → 新的 entry ID
+}
+
+// ShowFormStructure 顯示表單結構頁面
+// GET /form
+func (fc *FormStructureController) ShowFormStructure(ctx *gin.Context) {
+	ctx.HTML(http.StatusOK, "form.html", nil)
+}
+
+// GetFormStructure 取得儲存的指紋、最近一次檢查結果與目前的 entry_map
+// GET /api/form/structure
+func (fc *FormStructureController) GetFormStructure(ctx *gin.Context) {
+	fc.respond(ctx, fc.monitor.Last(), "")
+}
+
+// CheckFormStructure 立即讀取表單並比對指紋與 entry_map
+// POST /api/form/structure/check
+func (fc *FormStructureController) CheckFormStructure(ctx *gin.Context) {
+	c, cancel := context.WithTimeout(ctx.Request.Context(), formStructureTimeout)
+	defer cancel()
+
+	check := fc.monitor.Check(c)
+	fc.respond(ctx, check, check.Message)
+}
+
+// AcceptFormStructure 以目前的表單結構取代指紋
+// POST /api/form/structure/baseline
+func (fc *FormStructureController) AcceptFormStructure(ctx *gin.Context) {
+	c, cancel := context.WithTimeout(ctx.Request.Context(), formStructureTimeout)
+	defer cancel()
+
+	check, err := fc.monitor.AcceptCurrent(c)
+	if err != nil {
+		ctx.JSON(formStructureErrorStatus(err), FormStructureResponse{Success: false, Message: err.Error()})
+		return
+	}
+	fc.respond(ctx, check, "已更新表單結構指紋")
+}
+
+// RemapEntryMap 套用新的欄位對應並更新指紋
+// PUT /api/form/entry-map
+func (fc *FormStructureController) RemapEntryMap(ctx *gin.Context) {
+	var req RemapRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, FormStructureResponse{Success: false, Message: "請求格式錯誤: " + err.Error()})
+		return
+	}
+
+	c, cancel := context.WithTimeout(ctx.Request.Context(), formStructureTimeout)
+	defer cancel()
+
+	check, err := fc.monitor.Remap(c, req.EntryMap)
+	if err != nil {
+		ctx.JSON(formStructureErrorStatus(err), FormStructureResponse{Success: false, Message: err.Error()})
+		return
+	}
+	fc.respond(ctx, check, "已重新對應表單欄位")
+}
+
+// respond 回傳指紋、檢查結果與目前的 entry_map
+func (fc *FormStructureController) respond(ctx *gin.Context, check *models.FormStructureCheck, message string) {
+	baseline, err := fc.monitor.Baseline()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, FormStructureResponse{Success: false, Message: err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusOK, FormStructureResponse{
+		Success:   true,
+		Baseline:  baseline,
+		LastCheck: check,
+		EntryMap:  fc.monitor.EntryMap(),
+		Message:   message,
+	})
+}
+
+// formStructureErrorStatus 對應錯誤的 HTTP 狀態碼
+func formStructureErrorStatus(err error) int {
+	var validationErr *models.ValidationError
+	switch {
+	case errors.As(err, &validationErr):
+		return http.StatusBadRequest
+	case errors.Is(err, models.ErrFormClosed):
+		return http.StatusConflict
+	default:
+		return http.StatusBadGateway
+	}
+}
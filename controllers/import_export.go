@@ -0,0 +1,111 @@
+package controllers
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"google-form-submitter/models"
+)
+
+// maxImportSize 匯入檔案大小上限
+const maxImportSize = 10 << 20
+
+// ImportSavedFormsResponse 匯入回應結構
+type ImportSavedFormsResponse struct {
+	Success bool                 `json:"success"`
+	Message string               `json:"message"`
+	Report  *models.ImportReport `json:"report,omitempty"`
+}
+
+// ImportSavedForms 匯入儲存資料（預設僅試跑驗證，dry_run=false 才會寫入）
+// POST /api/saved/import?format=csv|jsonl&dry_run=false
+func (c *FormController) ImportSavedForms(ctx *gin.Context) {
+	format := ctx.Query("format")
+	dryRun := ctx.Query("dry_run") != "false" && ctx.Query("dry_run") != "0"
+
+	var body io.Reader
+	if file, header, err := ctx.Request.FormFile("file"); err == nil {
+		// multipart 上傳
+		defer file.Close()
+		body = io.LimitReader(file, maxImportSize)
+		if format == "" {
+			format, _ = models.DetectFormat(header.Filename)
+		}
+	} else {
+		data, err := io.ReadAll(io.LimitReader(ctx.Request.Body, maxImportSize))
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, ImportSavedFormsResponse{
+				Success: false,
+				Message: "讀取上傳內容失敗",
+			})
+			return
+		}
+		body = bytes.NewReader(data)
+	}
+
+	if format != models.FormatCSV && format != models.FormatJSONL {
+		ctx.JSON(http.StatusBadRequest, ImportSavedFormsResponse{
+			Success: false,
+			Message: "format 必須為 csv 或 jsonl",
+		})
+		return
+	}
+
+	report, err := c.storage.ImportSavedForms(body, format, dryRun)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, ImportSavedFormsResponse{
+			Success: false,
+			Message: err.Error(),
+			Report:  report,
+		})
+		return
+	}
+
+	message := fmt.Sprintf("已匯入 %d 筆資料", report.Imported)
+	if dryRun {
+		message = fmt.Sprintf("試跑完成：%d 筆有效、%d 筆錯誤，確認後以 dry_run=false 匯入", report.Valid, report.Invalid)
+	}
+
+	ctx.JSON(http.StatusOK, ImportSavedFormsResponse{
+		Success: report.Invalid == 0,
+		Message: message,
+		Report:  report,
+	})
+}
+
+// ExportSavedForms 匯出儲存資料（API 一律不包含密碼）
+// GET /api/saved/export?format=csv|jsonl
+func (c *FormController) ExportSavedForms(ctx *gin.Context) {
+	format := ctx.DefaultQuery("format", models.FormatCSV)
+
+	contentType := "text/csv; charset=utf-8"
+	switch format {
+	case models.FormatCSV:
+	case models.FormatJSONL:
+		contentType = "application/x-ndjson; charset=utf-8"
+	default:
+		ctx.JSON(http.StatusBadRequest, ImportSavedFormsResponse{
+			Success: false,
+			Message: "format 必須為 csv 或 jsonl",
+		})
+		return
+	}
+
+	var buf bytes.Buffer
+	if _, err := c.storage.ExportSavedForms(&buf, format, false); err != nil {
+		ctx.JSON(http.StatusInternalServerError, ImportSavedFormsResponse{
+			Success: false,
+			Message: "匯出失敗: " + err.Error(),
+		})
+		return
+	}
+
+	filename := fmt.Sprintf("saved_forms_%s.%s", time.Now().Format("20060102"), format)
+	ctx.Header("Content-Disposition", `attachment; filename="`+filename+`"`)
+	ctx.Data(http.StatusOK, contentType, buf.Bytes())
+}
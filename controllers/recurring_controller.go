@@ -0,0 +1,381 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"google-form-submitter/models"
+)
+
+// RecurringController 週期排程控制器
+type RecurringController struct {
+	recurring *models.RecurringScheduler
+	storage   *models.Storage
+}
+
+// NewRecurringController 建立新的 RecurringController
+func NewRecurringController(recurring *models.RecurringScheduler, storage *models.Storage) *RecurringController {
+	return &RecurringController{
+		recurring: recurring,
+		storage:   storage,
+	}
+}
+
+// RecurringScheduleRequest 新增或更新週期排程請求
+type RecurringScheduleRequest struct {
+	Label          string               `json:"label" binding:"required"`
+	Kind           string               `json:"kind" binding:"required"`
+	Spec           string               `json:"spec" binding:"required"`
+	Timezone       string               `json:"timezone"`
+	SavedFormID    int64                `json:"saved_form_id"`
+	EmployeeRef    int64                `json:"employee_ref"`
+	LeaveType      string               `json:"leave_type"`
+	DateTemplate   *models.DateTemplate `json:"date_template"`
+	PrepareSeconds int                  `json:"prepare_seconds"`
+	RetryCount     int                  `json:"retry_count"`
+	RetryInterval  int                  `json:"retry_interval"`
+	MissedPolicy   string               `json:"missed_policy"`
+	GraceSeconds   int                  `json:"grace_seconds"`
+	EndCount       int                  `json:"end_count"`
+	EndUntil       string               `json:"end_until"`
+	Enabled        *bool                `json:"enabled"` // 省略時為 true
+
+	Strategy            string `json:"strategy"`
+	PollLeadSeconds     int    `json:"poll_lead_seconds"`
+	PollIntervalMs      int    `json:"poll_interval_ms"`
+	PollDeadlineSeconds int    `json:"poll_deadline_seconds"`
+}
+
+// RecurringScheduleResponse 週期排程回應
+type RecurringScheduleResponse struct {
+	Success bool                        `json:"success"`
+	ID      int64                       `json:"id,omitempty"`
+	Data    *models.RecurringSchedule   `json:"data,omitempty"`
+	List    []*models.RecurringSchedule `json:"schedules,omitempty"`
+	History []*models.SubmissionRecord  `json:"history,omitempty"`
+	Message string                      `json:"message,omitempty"`
+
+	// Precision 觸發記錄的送出精確度
+	Precision *models.FirePrecisionStats `json:"precision,omitempty"`
+}
+
+// PreviewRecurrenceRequest 預覽週期規則請求
+type PreviewRecurrenceRequest struct {
+	Kind     string `json:"kind" binding:"required"`
+	Spec     string `json:"spec" binding:"required"`
+	Timezone string `json:"timezone"`
+	Count    int    `json:"count"` // 預覽次數，預設 5，最多 20
+}
+
+// PreviewRecurrenceResponse 預覽週期規則回應
+type PreviewRecurrenceResponse struct {
+	Success bool        `json:"success"`
+	Times   []time.Time `json:"times,omitempty"`
+	Message string      `json:"message,omitempty"`
+}
+
+// parseRecurringID 解析路徑中的週期排程 ID，失敗時直接回應 400
+func parseRecurringID(ctx *gin.Context) (int64, bool) {
+	id, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, RecurringScheduleResponse{
+			Success: false,
+			Message: "無效的 ID 格式",
+		})
+		return 0, false
+	}
+	return id, true
+}
+
+// bindRecurringSchedule 解析請求並套用預設值與驗證，失敗時直接回應 400
+func (c *RecurringController) bindRecurringSchedule(ctx *gin.Context) (*models.RecurringSchedule, bool) {
+	var req RecurringScheduleRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, RecurringScheduleResponse{
+			Success: false,
+			Message: "請求格式錯誤或缺少必填欄位",
+		})
+		return nil, false
+	}
+
+	rs := &models.RecurringSchedule{
+		Label:          req.Label,
+		Kind:           req.Kind,
+		Spec:           req.Spec,
+		Timezone:       req.Timezone,
+		SavedFormID:    req.SavedFormID,
+		EmployeeRef:    req.EmployeeRef,
+		LeaveType:      req.LeaveType,
+		DateTemplate:   req.DateTemplate,
+		PrepareSeconds: req.PrepareSeconds,
+		RetryCount:     req.RetryCount,
+		RetryInterval:  req.RetryInterval,
+		MissedPolicy:   req.MissedPolicy,
+		GraceSeconds:   req.GraceSeconds,
+		EndCount:       req.EndCount,
+		EndUntil:       req.EndUntil,
+		Enabled:        req.Enabled == nil || *req.Enabled,
+
+		Strategy:            req.Strategy,
+		PollLeadSeconds:     req.PollLeadSeconds,
+		PollIntervalMs:      req.PollIntervalMs,
+		PollDeadlineSeconds: req.PollDeadlineSeconds,
+	}
+
+	// 設定預設值（與單次排程相同）
+	if rs.PrepareSeconds <= 0 {
+		rs.PrepareSeconds = 5
+	}
+	if rs.RetryCount <= 0 {
+		rs.RetryCount = 3
+	}
+	if rs.RetryInterval <= 0 {
+		rs.RetryInterval = 100
+	}
+
+	if err := models.ValidateRecurringSchedule(rs); err != nil {
+		ctx.JSON(http.StatusBadRequest, RecurringScheduleResponse{
+			Success: false,
+			Message: err.Error(),
+		})
+		return nil, false
+	}
+
+	if rs.SavedFormID > 0 {
+		if _, err := c.storage.GetByID(rs.SavedFormID); err != nil {
+			ctx.JSON(http.StatusBadRequest, RecurringScheduleResponse{
+				Success: false,
+				Message: "找不到指定的儲存資料",
+			})
+			return nil, false
+		}
+	} else if _, err := c.storage.GetEmployee(rs.EmployeeRef); err != nil {
+		ctx.JSON(http.StatusBadRequest, RecurringScheduleResponse{
+			Success: false,
+			Message: err.Error(),
+		})
+		return nil, false
+	}
+
+	return rs, true
+}
+
+// ListRecurringSchedules 列出所有週期排程與下一次觸發時間
+// GET /api/schedule/recurring
+func (c *RecurringController) ListRecurringSchedules(ctx *gin.Context) {
+	list, err := c.recurring.List()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, RecurringScheduleResponse{
+			Success: false,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, RecurringScheduleResponse{
+		Success: true,
+		List:    list,
+	})
+}
+
+// GetRecurringSchedule 取得單一週期排程
+// GET /api/schedule/recurring/:id
+func (c *RecurringController) GetRecurringSchedule(ctx *gin.Context) {
+	id, ok := parseRecurringID(ctx)
+	if !ok {
+		return
+	}
+
+	rs, err := c.recurring.Get(id)
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, RecurringScheduleResponse{
+			Success: false,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, RecurringScheduleResponse{
+		Success: true,
+		Data:    rs,
+	})
+}
+
+// CreateRecurringSchedule 新增週期排程並排定下一次觸發
+// POST /api/schedule/recurring
+func (c *RecurringController) CreateRecurringSchedule(ctx *gin.Context) {
+	rs, ok := c.bindRecurringSchedule(ctx)
+	if !ok {
+		return
+	}
+
+	id, err := c.storage.CreateRecurringSchedule(rs)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, RecurringScheduleResponse{
+			Success: false,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.respondArmed(ctx, id, "週期排程已建立")
+}
+
+// UpdateRecurringSchedule 更新週期排程並重新排定
+// PUT /api/schedule/recurring/:id
+func (c *RecurringController) UpdateRecurringSchedule(ctx *gin.Context) {
+	id, ok := parseRecurringID(ctx)
+	if !ok {
+		return
+	}
+
+	existing, err := c.storage.GetRecurringSchedule(id)
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, RecurringScheduleResponse{
+			Success: false,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	rs, ok := c.bindRecurringSchedule(ctx)
+	if !ok {
+		return
+	}
+	rs.ID = id
+	rs.CreatedAt = existing.CreatedAt
+
+	if err := c.storage.UpdateRecurringSchedule(rs); err != nil {
+		ctx.JSON(http.StatusInternalServerError, RecurringScheduleResponse{
+			Success: false,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.respondArmed(ctx, id, "週期排程已更新")
+}
+
+// respondArmed 排定週期排程後回應目前狀態；無法排定時仍回應成功並附上原因
+func (c *RecurringController) respondArmed(ctx *gin.Context, id int64, message string) {
+	if err := c.recurring.Arm(id); err != nil {
+		message += "，但無法排定下一次觸發: " + err.Error()
+	}
+
+	rs, err := c.recurring.Get(id)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, RecurringScheduleResponse{
+			Success: false,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, RecurringScheduleResponse{
+		Success: true,
+		ID:      id,
+		Data:    rs,
+		Message: message,
+	})
+}
+
+// DeleteRecurringSchedule 停止並刪除週期排程（提交記錄保留）
+// DELETE /api/schedule/recurring/:id
+func (c *RecurringController) DeleteRecurringSchedule(ctx *gin.Context) {
+	id, ok := parseRecurringID(ctx)
+	if !ok {
+		return
+	}
+
+	c.recurring.Disarm(id)
+	if err := c.storage.DeleteRecurringSchedule(id); err != nil {
+		ctx.JSON(http.StatusNotFound, RecurringScheduleResponse{
+			Success: false,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, RecurringScheduleResponse{
+		Success: true,
+		Message: "週期排程已刪除",
+	})
+}
+
+// GetRecurringHistory 列出週期排程每次觸發的提交記錄（新到舊）
+// GET /api/schedule/recurring/:id/history
+func (c *RecurringController) GetRecurringHistory(ctx *gin.Context) {
+	id, ok := parseRecurringID(ctx)
+	if !ok {
+		return
+	}
+
+	rs, err := c.storage.GetRecurringSchedule(id)
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, RecurringScheduleResponse{
+			Success: false,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	limit, _ := strconv.Atoi(ctx.DefaultQuery("limit", "50"))
+	records, err := c.storage.ListHistory(models.HistoryQuery{
+		Source:  models.HistorySourceRecurring,
+		BatchID: rs.BatchID(),
+		Limit:   limit,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, RecurringScheduleResponse{
+			Success: false,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, RecurringScheduleResponse{
+		Success:   true,
+		ID:        id,
+		History:   records,
+		Precision: models.SummarizeFirePrecision(records),
+	})
+}
+
+// PreviewRecurrence 預覽週期規則接下來的觸發時間
+// POST /api/schedule/recurring/preview
+func (c *RecurringController) PreviewRecurrence(ctx *gin.Context) {
+	var req PreviewRecurrenceRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, PreviewRecurrenceResponse{
+			Success: false,
+			Message: "請求格式錯誤或缺少必填欄位",
+		})
+		return
+	}
+
+	now := time.Now()
+	rec, err := models.ParseRecurrence(req.Kind, req.Spec, req.Timezone, now)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, PreviewRecurrenceResponse{
+			Success: false,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	count := req.Count
+	if count <= 0 {
+		count = 5
+	}
+	if count > 20 {
+		count = 20
+	}
+
+	ctx.JSON(http.StatusOK, PreviewRecurrenceResponse{
+		Success: true,
+		Times:   rec.Upcoming(now, count),
+	})
+}
@@ -1,7 +1,11 @@
 package controllers
 
 import (
+	"fmt"
+	"io"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 
@@ -10,15 +14,17 @@ import (
 
 // ScheduleController 排程控制器
 type ScheduleController struct {
-	scheduler *models.Scheduler
-	storage   *models.Storage
+	scheduler     *models.Scheduler
+	storage       *models.Storage
+	bookingWindow *models.BookingWindow // 未設定預約窗口規則時為 nil
 }
 
-// NewScheduleController 建立新的 ScheduleController
-func NewScheduleController(scheduler *models.Scheduler, storage *models.Storage) *ScheduleController {
+// NewScheduleController 建立新的 ScheduleController（bookingWindow 可為 nil）
+func NewScheduleController(scheduler *models.Scheduler, storage *models.Storage, bookingWindow *models.BookingWindow) *ScheduleController {
 	return &ScheduleController{
-		scheduler: scheduler,
-		storage:   storage,
+		scheduler:     scheduler,
+		storage:       storage,
+		bookingWindow: bookingWindow,
 	}
 }
 
@@ -28,7 +34,59 @@ type ScheduleStatusResponse struct {
 	Running bool                   `json:"running"`
 	Config  *models.ScheduleConfig `json:"config,omitempty"`
 	NextRun string                 `json:"next_run,omitempty"`
-	Message string                 `json:"message,omitempty"`
+	// NextRunAt 目標時間（含時區），供頁面顯示倒數
+	NextRunAt *time.Time `json:"next_run_at,omitempty"`
+	// State 目前狀態；States 為本輪每個狀態的進入時間
+	State      models.JobState          `json:"state,omitempty"`
+	StateSince *time.Time               `json:"state_since,omitempty"`
+	States     []models.StateTransition `json:"states,omitempty"`
+	LastResult *models.SubmissionRecord `json:"last_result,omitempty"`
+	// Preflight 本輪最近一次排程前檢查報告（啟動時與準備階段）
+	Preflight *models.PreflightReport `json:"preflight,omitempty"`
+	Message   string                  `json:"message,omitempty"`
+
+	// Precision 最近 precisionHistoryLimit 筆排程提交記錄的送出精確度，沒有送出記錄時省略
+	Precision *models.FirePrecisionStats `json:"precision,omitempty"`
+}
+
+// precisionHistoryLimit 排程狀態統計送出精確度時取的提交記錄筆數
+const precisionHistoryLimit = 50
+
+// PreflightRequest 手動排程前檢查請求
+type PreflightRequest struct {
+	Date        string `json:"date"` // 排程日期；未指定時使用預約窗口開放時間
+	SavedFormID int64  `json:"saved_form_id" binding:"required"`
+}
+
+// PreflightResponse 手動排程前檢查回應
+type PreflightResponse struct {
+	Success bool                    `json:"success"`
+	Report  *models.PreflightReport `json:"report,omitempty"`
+	Message string                  `json:"message,omitempty"`
+}
+
+// BookingScheduleRequest 依預約窗口建立排程請求
+type BookingScheduleRequest struct {
+	SavedFormID    int64  `json:"saved_form_id" binding:"required"`
+	PrepareSeconds int    `json:"prepare_seconds"`
+	RetryCount     int    `json:"retry_count"`
+	RetryInterval  int    `json:"retry_interval"`
+	MissedPolicy   string `json:"missed_policy"`
+	GraceSeconds   int    `json:"grace_seconds"`
+
+	Strategy            string `json:"strategy"` // 送出策略：fixed（預設）/ poll
+	PollLeadSeconds     int    `json:"poll_lead_seconds"`
+	PollIntervalMs      int    `json:"poll_interval_ms"`
+	PollDeadlineSeconds int    `json:"poll_deadline_seconds"`
+}
+
+// BookingWindowResponse 預約窗口回應
+type BookingWindowResponse struct {
+	Success bool                      `json:"success"`
+	Rule    string                    `json:"rule,omitempty"`
+	Window  *models.BookingWindowInfo `json:"window,omitempty"`
+	NextRun string                    `json:"next_run,omitempty"`
+	Message string                    `json:"message,omitempty"`
 }
 
 // CreateScheduleRequest 建立排程請求
@@ -38,6 +96,14 @@ type CreateScheduleRequest struct {
 	PrepareSeconds int    `json:"prepare_seconds"`
 	RetryCount     int    `json:"retry_count"`
 	RetryInterval  int    `json:"retry_interval"`
+	MissedPolicy   string `json:"missed_policy"` // 錯過排程時間的處理策略：skip（預設）/ grace / notify
+	GraceSeconds   int    `json:"grace_seconds"` // grace 策略的寬限秒數，預設 60
+
+	// 送出策略：fixed（預設）於目標時間送出；poll 於目標時間前開始探測，表單開放即送出
+	Strategy            string `json:"strategy"`
+	PollLeadSeconds     int    `json:"poll_lead_seconds"`     // 目標時間前幾秒開始探測，預設 10
+	PollIntervalMs      int    `json:"poll_interval_ms"`      // 探測間隔毫秒，預設 250
+	PollDeadlineSeconds int    `json:"poll_deadline_seconds"` // 目標時間後最多再探測幾秒，預設 60
 }
 
 // ShowSchedule 顯示排程管理頁面
@@ -59,20 +125,82 @@ func (sc *ScheduleController) GetScheduleStatus(ctx *gin.Context) {
 	}
 
 	running := sc.scheduler.IsRunning()
+	state, states := sc.scheduler.State()
 	resp := ScheduleStatusResponse{
-		Success: true,
-		Running: running,
-		Config:  sc.scheduler.GetConfig(),
+		Success:    true,
+		Running:    running,
+		Config:     sc.scheduler.GetConfig(),
+		State:      state,
+		States:     states,
+		LastResult: sc.scheduler.LastResult(),
+		Preflight:  sc.scheduler.Preflight(),
+	}
+	if len(states) > 0 {
+		resp.StateSince = &states[len(states)-1].At
+	}
+	if sc.storage != nil {
+		records, err := sc.storage.ListHistory(models.HistoryQuery{Source: models.HistorySourceSchedule, Limit: precisionHistoryLimit})
+		if err == nil {
+			resp.Precision = models.SummarizeFirePrecision(records)
+		}
 	}
 
 	if running {
 		nextRun := sc.scheduler.GetNextRunTime()
 		resp.NextRun = nextRun.Format("2006-01-02 15:04:05")
+		resp.NextRunAt = &nextRun
 	}
 
 	ctx.JSON(http.StatusOK, resp)
 }
 
+// scheduleEventKeepAlive SSE 連線閒置時送出註解行的間隔，避免被代理伺服器中斷
+const scheduleEventKeepAlive = 15 * time.Second
+
+// StreamScheduleEvents 以 Server-Sent Events 串流排程事件；連線時先送出最近的事件
+// GET /api/schedule/events
+func (sc *ScheduleController) StreamScheduleEvents(ctx *gin.Context) {
+	if sc.scheduler == nil {
+		ctx.JSON(http.StatusServiceUnavailable, ScheduleStatusResponse{
+			Success: false,
+			Message: "排程器未初始化",
+		})
+		return
+	}
+
+	recent, events, cancel := sc.scheduler.Events().Subscribe()
+	defer cancel()
+
+	ctx.Header("Content-Type", "text/event-stream")
+	ctx.Header("Cache-Control", "no-cache")
+	ctx.Header("Connection", "keep-alive")
+	ctx.Header("X-Accel-Buffering", "no")
+
+	for _, e := range recent {
+		ctx.SSEvent("schedule", e)
+	}
+	ctx.Writer.Flush()
+
+	keepAlive := time.NewTicker(scheduleEventKeepAlive)
+	defer keepAlive.Stop()
+
+	ctx.Stream(func(w io.Writer) bool {
+		select {
+		case e, ok := <-events:
+			if !ok {
+				return false
+			}
+			ctx.SSEvent("schedule", e)
+			return true
+		case <-keepAlive.C:
+			fmt.Fprint(w, ": keep-alive\n\n")
+			return true
+		case <-ctx.Request.Context().Done():
+			return false
+		}
+	})
+}
+
 // CreateSchedule 建立並啟動排程
 // POST /api/schedule
 func (sc *ScheduleController) CreateSchedule(ctx *gin.Context) {
@@ -118,6 +246,13 @@ func (sc *ScheduleController) CreateSchedule(ctx *gin.Context) {
 		PrepareSeconds: prepareSeconds,
 		RetryCount:     retryCount,
 		RetryInterval:  retryInterval,
+		MissedPolicy:   req.MissedPolicy,
+		GraceSeconds:   req.GraceSeconds,
+
+		Strategy:            req.Strategy,
+		PollLeadSeconds:     req.PollLeadSeconds,
+		PollIntervalMs:      req.PollIntervalMs,
+		PollDeadlineSeconds: req.PollDeadlineSeconds,
 	}
 
 	// 確保 scheduler 已初始化
@@ -138,19 +273,75 @@ func (sc *ScheduleController) CreateSchedule(ctx *gin.Context) {
 		return
 	}
 
+	state, _ := sc.scheduler.State()
 	resp := ScheduleStatusResponse{
 		Success: true,
-		Running: true,
+		Running: sc.scheduler.IsRunning(),
 		Config:  cfg,
+		State:   state,
 		Message: "排程已啟動",
 	}
 
+	// 目標時間已過且依錯過策略未送出
+	if state == models.JobMissed {
+		resp.LastResult = sc.scheduler.LastResult()
+		if resp.LastResult != nil {
+			resp.Message = resp.LastResult.Message
+		}
+		ctx.JSON(http.StatusOK, resp)
+		return
+	}
+
 	nextRun := sc.scheduler.GetNextRunTime()
 	resp.NextRun = nextRun.Format("2006-01-02 15:04:05")
+	resp.NextRunAt = &nextRun
 
 	ctx.JSON(http.StatusOK, resp)
 }
 
+// CheckPreflight 不啟動排程，對儲存資料執行一次排程前檢查
+// POST /api/schedule/preflight
+func (sc *ScheduleController) CheckPreflight(ctx *gin.Context) {
+	var req PreflightRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, PreflightResponse{
+			Success: false,
+			Message: "請求格式錯誤或缺少必填欄位",
+		})
+		return
+	}
+
+	cfg := &models.ScheduleConfig{Date: req.Date, SavedFormID: req.SavedFormID}
+	if req.Date == "" {
+		info, ok := sc.checkBookingWindow(ctx, req.SavedFormID)
+		if !ok {
+			return
+		}
+		cfg.At = info.OpensAt
+	} else if _, err := sc.storage.GetByID(req.SavedFormID); err != nil {
+		ctx.JSON(http.StatusNotFound, PreflightResponse{
+			Success: false,
+			Message: "找不到指定的儲存資料",
+		})
+		return
+	}
+
+	report, err := sc.scheduler.CheckPreflight(ctx.Request.Context(), cfg)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, PreflightResponse{
+			Success: false,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, PreflightResponse{
+		Success: true,
+		Report:  report,
+		Message: report.Summary(),
+	})
+}
+
 // StopSchedule 停止排程
 // DELETE /api/schedule
 func (sc *ScheduleController) StopSchedule(ctx *gin.Context) {
@@ -171,3 +362,150 @@ func (sc *ScheduleController) StopSchedule(ctx *gin.Context) {
 		Message: "排程已停止",
 	})
 }
+
+// checkBookingWindow 計算儲存資料的預約窗口，失敗時直接回應錯誤
+func (sc *ScheduleController) checkBookingWindow(ctx *gin.Context, savedFormID int64) (*models.BookingWindowInfo, bool) {
+	if sc.bookingWindow == nil {
+		ctx.JSON(http.StatusBadRequest, BookingWindowResponse{
+			Success: false,
+			Message: "未設定預約窗口規則，請在 config.json 設定 booking_window",
+		})
+		return nil, false
+	}
+
+	form, err := sc.storage.GetByID(savedFormID)
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, BookingWindowResponse{
+			Success: false,
+			Message: "找不到指定的儲存資料",
+		})
+		return nil, false
+	}
+	if form.DateTemplate != nil {
+		ctx.JSON(http.StatusBadRequest, BookingWindowResponse{
+			Success: false,
+			Message: "使用日期範本的儲存資料沒有固定的請假日期，請改用週期排程",
+		})
+		return nil, false
+	}
+
+	info, err := sc.bookingWindow.Check(form.StartDate, sc.storage.Clock().Now())
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, BookingWindowResponse{
+			Success: false,
+			Message: err.Error(),
+		})
+		return nil, false
+	}
+
+	return info, true
+}
+
+// GetBookingWindow 查詢儲存資料的預約窗口與倒數
+// GET /api/schedule/booking?saved_form_id=
+func (sc *ScheduleController) GetBookingWindow(ctx *gin.Context) {
+	id, err := strconv.ParseInt(ctx.Query("saved_form_id"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, BookingWindowResponse{
+			Success: false,
+			Message: "無效的 saved_form_id",
+		})
+		return
+	}
+
+	info, ok := sc.checkBookingWindow(ctx, id)
+	if !ok {
+		return
+	}
+
+	ctx.JSON(http.StatusOK, BookingWindowResponse{
+		Success: true,
+		Rule:    sc.bookingWindow.String(),
+		Window:  info,
+	})
+}
+
+// CreateBookingSchedule 依請假起點日期計算預約窗口開放時間並啟動排程
+// POST /api/schedule/booking
+func (sc *ScheduleController) CreateBookingSchedule(ctx *gin.Context) {
+	var req BookingScheduleRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, BookingWindowResponse{
+			Success: false,
+			Message: "請求格式錯誤或缺少必填欄位",
+		})
+		return
+	}
+
+	info, ok := sc.checkBookingWindow(ctx, req.SavedFormID)
+	if !ok {
+		return
+	}
+
+	const layout = "2006-01-02 15:04 MST"
+	switch info.Status {
+	case models.BookingWindowClosed:
+		ctx.JSON(http.StatusUnprocessableEntity, BookingWindowResponse{
+			Success: false,
+			Rule:    sc.bookingWindow.String(),
+			Window:  info,
+			Message: fmt.Sprintf("請假起點 %s 的預約窗口已於 %s 截止", info.StartDate, info.ClosesAt.Format(layout)),
+		})
+		return
+	case models.BookingWindowOpen:
+		ctx.JSON(http.StatusConflict, BookingWindowResponse{
+			Success: false,
+			Rule:    sc.bookingWindow.String(),
+			Window:  info,
+			Message: fmt.Sprintf("預約窗口已於 %s 開放，請直接提交", info.OpensAt.Format(layout)),
+		})
+		return
+	}
+
+	// 設定預設值
+	prepareSeconds := req.PrepareSeconds
+	if prepareSeconds <= 0 {
+		prepareSeconds = 5
+	}
+	retryCount := req.RetryCount
+	if retryCount <= 0 {
+		retryCount = 3
+	}
+	retryInterval := req.RetryInterval
+	if retryInterval <= 0 {
+		retryInterval = 100
+	}
+
+	cfg := &models.ScheduleConfig{
+		Enabled:        true,
+		Date:           info.OpensAt.Format("2006-01-02"),
+		At:             info.OpensAt,
+		SavedFormID:    req.SavedFormID,
+		PrepareSeconds: prepareSeconds,
+		RetryCount:     retryCount,
+		RetryInterval:  retryInterval,
+		MissedPolicy:   req.MissedPolicy,
+		GraceSeconds:   req.GraceSeconds,
+
+		Strategy:            req.Strategy,
+		PollLeadSeconds:     req.PollLeadSeconds,
+		PollIntervalMs:      req.PollIntervalMs,
+		PollDeadlineSeconds: req.PollDeadlineSeconds,
+	}
+
+	if err := sc.scheduler.StartWithConfig(cfg); err != nil {
+		ctx.JSON(http.StatusBadRequest, BookingWindowResponse{
+			Success: false,
+			Message: "排程啟動失敗: " + err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, BookingWindowResponse{
+		Success: true,
+		Rule:    sc.bookingWindow.String(),
+		Window:  info,
+		NextRun: info.OpensAt.Format(layout),
+		Message: "已排程於預約窗口開放時提交",
+	})
+}
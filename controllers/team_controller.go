@@ -0,0 +1,283 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"google-form-submitter/models"
+)
+
+// TeamController 團隊名單與團隊排程控制器
+type TeamController struct {
+	teamScheduler *models.TeamScheduler
+	storage       *models.Storage
+}
+
+// NewTeamController 建立新的 TeamController
+func NewTeamController(teamScheduler *models.TeamScheduler, storage *models.Storage) *TeamController {
+	return &TeamController{
+		teamScheduler: teamScheduler,
+		storage:       storage,
+	}
+}
+
+// RosterRequest 新增或更新團隊名單請求結構，members 的順序即優先順序
+type RosterRequest struct {
+	Name    string  `json:"name" binding:"required"`
+	Members []int64 `json:"members"`
+}
+
+// RosterResponse 團隊名單回應結構
+type RosterResponse struct {
+	Success bool             `json:"success"`
+	ID      int64            `json:"id,omitempty"`
+	Data    *models.Roster   `json:"data,omitempty"`
+	List    []*models.Roster `json:"rosters,omitempty"`
+	Message string           `json:"message,omitempty"`
+}
+
+// CreateTeamScheduleRequest 建立團隊排程請求
+type CreateTeamScheduleRequest struct {
+	RosterID       int64  `json:"roster_id" binding:"required"`
+	Date           string `json:"date" binding:"required"`
+	StartDate      string `json:"start_date" binding:"required"`
+	EndDate        string `json:"end_date" binding:"required"`
+	LeaveType      string `json:"leave_type" binding:"required"`
+	StaggerMs      int    `json:"stagger_ms"`
+	PrepareSeconds int    `json:"prepare_seconds"`
+	RetryCount     int    `json:"retry_count"`
+	RetryInterval  int    `json:"retry_interval"`
+	MissedPolicy   string `json:"missed_policy"`
+	GraceSeconds   int    `json:"grace_seconds"`
+
+	Strategy            string `json:"strategy"` // 送出策略：fixed（預設）/ poll
+	PollLeadSeconds     int    `json:"poll_lead_seconds"`
+	PollIntervalMs      int    `json:"poll_interval_ms"`
+	PollDeadlineSeconds int    `json:"poll_deadline_seconds"`
+}
+
+// TeamScheduleResponse 團隊排程回應
+type TeamScheduleResponse struct {
+	Success bool                       `json:"success"`
+	Status  *models.TeamScheduleStatus `json:"status,omitempty"`
+	Message string                     `json:"message,omitempty"`
+}
+
+// parseRosterID 解析路徑中的名單 ID，失敗時直接回應 400
+func parseRosterID(ctx *gin.Context) (int64, bool) {
+	id, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, RosterResponse{
+			Success: false,
+			Message: "無效的 ID 格式",
+		})
+		return 0, false
+	}
+	return id, true
+}
+
+// bindRoster 解析並驗證名單請求，失敗時直接回應 400
+func bindRoster(ctx *gin.Context) (*RosterRequest, bool) {
+	var req RosterRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, RosterResponse{
+			Success: false,
+			Message: "請求格式錯誤或缺少必填欄位",
+		})
+		return nil, false
+	}
+
+	if err := models.ValidateRoster(req.Name, req.Members); err != nil {
+		ctx.JSON(http.StatusBadRequest, RosterResponse{
+			Success: false,
+			Message: err.Error(),
+		})
+		return nil, false
+	}
+
+	return &req, true
+}
+
+// ListRosters 列出所有團隊名單
+// GET /api/rosters
+func (c *TeamController) ListRosters(ctx *gin.Context) {
+	rosters, err := c.storage.ListRosters()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, RosterResponse{
+			Success: false,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, RosterResponse{
+		Success: true,
+		List:    rosters,
+	})
+}
+
+// GetRoster 取得單一團隊名單
+// GET /api/rosters/:id
+func (c *TeamController) GetRoster(ctx *gin.Context) {
+	id, ok := parseRosterID(ctx)
+	if !ok {
+		return
+	}
+
+	roster, err := c.storage.GetRoster(id)
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, RosterResponse{
+			Success: false,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, RosterResponse{
+		Success: true,
+		Data:    roster,
+	})
+}
+
+// CreateRoster 新增團隊名單
+// POST /api/rosters
+func (c *TeamController) CreateRoster(ctx *gin.Context) {
+	req, ok := bindRoster(ctx)
+	if !ok {
+		return
+	}
+
+	id, err := c.storage.CreateRoster(req.Name, req.Members)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, RosterResponse{
+			Success: false,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, RosterResponse{
+		Success: true,
+		ID:      id,
+		Message: "名單已建立",
+	})
+}
+
+// UpdateRoster 更新團隊名單名稱與成員順序
+// PUT /api/rosters/:id
+func (c *TeamController) UpdateRoster(ctx *gin.Context) {
+	id, ok := parseRosterID(ctx)
+	if !ok {
+		return
+	}
+
+	req, ok := bindRoster(ctx)
+	if !ok {
+		return
+	}
+
+	if err := c.storage.UpdateRoster(id, req.Name, req.Members); err != nil {
+		ctx.JSON(http.StatusBadRequest, RosterResponse{
+			Success: false,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, RosterResponse{
+		Success: true,
+		ID:      id,
+		Message: "名單已更新",
+	})
+}
+
+// DeleteRoster 刪除團隊名單（已啟動的團隊排程不受影響）
+// DELETE /api/rosters/:id
+func (c *TeamController) DeleteRoster(ctx *gin.Context) {
+	id, ok := parseRosterID(ctx)
+	if !ok {
+		return
+	}
+
+	if err := c.storage.DeleteRoster(id); err != nil {
+		ctx.JSON(http.StatusNotFound, RosterResponse{
+			Success: false,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, RosterResponse{
+		Success: true,
+		Message: "名單已刪除",
+	})
+}
+
+// GetTeamSchedule 取得團隊排程總覽
+// GET /api/schedule/team
+func (c *TeamController) GetTeamSchedule(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, TeamScheduleResponse{
+		Success: true,
+		Status:  c.teamScheduler.Status(),
+	})
+}
+
+// CreateTeamSchedule 為名單每位成員建立同一目標時間的排程
+// POST /api/schedule/team
+func (c *TeamController) CreateTeamSchedule(ctx *gin.Context) {
+	var req CreateTeamScheduleRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, TeamScheduleResponse{
+			Success: false,
+			Message: "請求格式錯誤或缺少必填欄位",
+		})
+		return
+	}
+
+	cfg := &models.TeamScheduleConfig{
+		RosterID:       req.RosterID,
+		Date:           req.Date,
+		StartDate:      req.StartDate,
+		EndDate:        req.EndDate,
+		LeaveType:      req.LeaveType,
+		StaggerMs:      req.StaggerMs,
+		PrepareSeconds: req.PrepareSeconds,
+		RetryCount:     req.RetryCount,
+		RetryInterval:  req.RetryInterval,
+		MissedPolicy:   req.MissedPolicy,
+		GraceSeconds:   req.GraceSeconds,
+
+		Strategy:            req.Strategy,
+		PollLeadSeconds:     req.PollLeadSeconds,
+		PollIntervalMs:      req.PollIntervalMs,
+		PollDeadlineSeconds: req.PollDeadlineSeconds,
+	}
+
+	if err := c.teamScheduler.Start(cfg); err != nil {
+		ctx.JSON(http.StatusBadRequest, TeamScheduleResponse{
+			Success: false,
+			Message: "團隊排程啟動失敗: " + err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, TeamScheduleResponse{
+		Success: true,
+		Status:  c.teamScheduler.Status(),
+		Message: "團隊排程已啟動",
+	})
+}
+
+// StopTeamSchedule 停止團隊排程
+// DELETE /api/schedule/team
+func (c *TeamController) StopTeamSchedule(ctx *gin.Context) {
+	c.teamScheduler.Stop()
+
+	ctx.JSON(http.StatusOK, TeamScheduleResponse{
+		Success: true,
+		Status:  c.teamScheduler.Status(),
+		Message: "團隊排程已停止",
+	})
+}
@@ -1,6 +1,10 @@
 package main
 
 import (
+	"bufio"
+	"crypto/rand"
+	"encoding/base64"
+	"flag"
 	"fmt"
 	"log"
 	"os"
@@ -8,6 +12,7 @@ import (
 	"os/signal"
 	"path/filepath"
 	"runtime"
+	"strings"
 	"syscall"
 
 	"github.com/gin-gonic/gin"
@@ -36,6 +41,52 @@ func openBrowser(url string) {
 	}
 }
 
+// loadSecretKey 依序從環境變數、金鑰檔或啟動時輸入的通關密語取得密碼加密金鑰
+func loadSecretKey(cfg *config.Config) (*models.SecretKey, error) {
+	if cfg.Encryption.Key != "" {
+		key, err := models.ParseEncryptionKey(cfg.Encryption.Key)
+		if err != nil {
+			return nil, fmt.Errorf("ENCRYPTION_KEY 無效: %w", err)
+		}
+		return &models.SecretKey{Key: key}, nil
+	}
+
+	if cfg.Encryption.KeyFile != "" {
+		data, err := os.ReadFile(cfg.Encryption.KeyFile)
+		if os.IsNotExist(err) {
+			// 金鑰檔不存在時產生新金鑰
+			key := make([]byte, models.EncryptionKeySize)
+			if _, err := rand.Read(key); err != nil {
+				return nil, fmt.Errorf("產生金鑰失敗: %w", err)
+			}
+			if err := os.WriteFile(cfg.Encryption.KeyFile, []byte(base64.StdEncoding.EncodeToString(key)+"\n"), 0600); err != nil {
+				return nil, fmt.Errorf("寫入金鑰檔失敗: %w", err)
+			}
+			fmt.Printf("已產生新的金鑰檔: %s（請妥善備份）\n", cfg.Encryption.KeyFile)
+			return &models.SecretKey{Key: key}, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("讀取金鑰檔失敗: %w", err)
+		}
+		key, err := models.ParseEncryptionKey(string(data))
+		if err != nil {
+			return nil, fmt.Errorf("金鑰檔無效: %w", err)
+		}
+		return &models.SecretKey{Key: key}, nil
+	}
+
+	fmt.Print("請輸入密碼加密通關密語: ")
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && line == "" {
+		return nil, fmt.Errorf("讀取通關密語失敗: %w", err)
+	}
+	passphrase := strings.TrimRight(line, "\r\n")
+	if passphrase == "" {
+		return nil, fmt.Errorf("通關密語不可為空")
+	}
+	return &models.SecretKey{Passphrase: passphrase}, nil
+}
+
 func main() {
 	// 切換工作目錄到執行檔所在目錄，確保直接點擊執行檔時能找到 config.json 等檔案
 	exePath, err := os.Executable()
@@ -55,14 +106,70 @@ func main() {
 		os.Exit(1)
 	}
 
+	// 執行子指令（不啟動 Server）
+	if len(os.Args) > 1 && !strings.HasPrefix(os.Args[1], "-") {
+		cmd, ok := findCommand(os.Args[1])
+		if !ok {
+			runHelp(cfg, nil)
+			log.Fatalf("未知的子指令: %s", os.Args[1])
+		}
+		if err := cmd.run(cfg, os.Args[2:]); err != nil {
+			log.Fatalf("%s 執行失敗: %v", cmd.name, err)
+		}
+		return
+	}
+
+	// Server 參數
+	serverFlags := flag.NewFlagSet("server", flag.ExitOnError)
+	useMock := serverFlags.Bool("mock", false, "啟動內嵌的模擬表單並將 form_url 指向它（演練與離線測試用）")
+	serverFlags.Parse(os.Args[1:])
+
+	// 模擬表單模式：所有提交都送到本機的模擬表單
+	if *useMock {
+		// 模擬表單使用 entry_map 的複本，表單結構頁面重新對應時不會連帶改變模擬表單
+		entryIDs := make(map[string]string, len(cfg.EntryMap))
+		for field, entryID := range cfg.EntryMap {
+			entryIDs[field] = entryID
+		}
+		mock, err := models.StartMockFormServer(models.NewMockForm(models.MockFormOptions{EntryIDs: entryIDs}), "127.0.0.1:0")
+		if err != nil {
+			log.Fatalf("%v", err)
+		}
+		defer mock.Close()
+		cfg.FormURL = mock.FormURL()
+		fmt.Printf("模擬表單模式: 提交將送到 %s\n", cfg.FormURL)
+		fmt.Printf("收到的送出: %s/_mock/submissions，行為設定: %s/_mock/options\n", mock.BaseURL, mock.BaseURL)
+	}
+
+	// 取得密碼加密金鑰
+	secret, err := loadSecretKey(cfg)
+	if err != nil {
+		log.Fatalf("取得加密金鑰失敗: %v", err)
+	}
+
 	// 初始化 SQLite Storage
-	storage, err := models.NewStorage(cfg.DBPath)
+	storage, err := models.NewStorage(cfg.DBPath, secret)
 	if err != nil {
 		log.Fatalf("初始化資料庫失敗: %v", err)
 		os.Exit(1)
 	}
 	defer storage.Close()
 
+	// 載入匯入的假日行事曆（驗證與日期範本計算工作日使用）
+	if err := storage.ActivateCalendar(); err != nil {
+		log.Printf("警告: 載入假日行事曆失敗: %v", err)
+	}
+
+	// 表單重新對應過欄位時，以儲存的 entry_map 覆蓋 config.json
+	if fp, err := storage.GetFormFingerprint(cfg.FormURL); err != nil {
+		log.Printf("警告: %v", err)
+	} else if fp != nil && len(fp.EntryMap) > 0 {
+		for field, entryID := range fp.EntryMap {
+			cfg.EntryMap[field] = entryID
+		}
+		fmt.Println("使用表單結構頁面重新對應的 entry_map（覆蓋 config.json）")
+	}
+
 	// 初始化 GoogleFormSubmitter
 	submitter := models.NewGoogleFormSubmitter(cfg.FormURL, cfg.EntryMap)
 
@@ -74,14 +181,57 @@ func main() {
 		PrepareSeconds: cfg.Schedule.PrepareSeconds,
 		RetryCount:     cfg.Schedule.RetryCount,
 		RetryInterval:  cfg.Schedule.RetryInterval,
+		MissedPolicy:   cfg.Schedule.MissedPolicy,
+		GraceSeconds:   cfg.Schedule.GraceSeconds,
+
+		Strategy:            cfg.Schedule.Strategy,
+		PollLeadSeconds:     cfg.Schedule.PollLeadSeconds,
+		PollIntervalMs:      cfg.Schedule.PollIntervalMs,
+		PollDeadlineSeconds: cfg.Schedule.PollDeadlineSeconds,
+	}
+	// 預約窗口規則（未設定時依請假日期排程的功能停用）
+	var bookingWindow *models.BookingWindow
+	if w := cfg.BookingWindow; w.OpensDaysBefore > 0 {
+		bookingWindow, err = models.NewBookingWindow(w.OpensDaysBefore, w.OpenTime, w.ClosesDaysBefore, w.Timezone)
+		if err != nil {
+			log.Fatalf("配置錯誤: booking_window %v", err)
+		}
 	}
+
 	scheduler := models.NewScheduler(scheduleConfig, submitter, storage)
+	scheduler.SetBookingWindow(bookingWindow)
 	if cfg.Schedule.Enabled {
 		if err := scheduler.Start(); err != nil {
 			log.Printf("警告: 排程器啟動失敗: %v", err)
 		}
 	}
 
+	// 初始化團隊排程器
+	teamScheduler := models.NewTeamScheduler(submitter, storage)
+
+	// 初始化週期排程器並排定所有啟用的週期排程
+	recurringScheduler := models.NewRecurringScheduler(submitter, storage)
+	if err := recurringScheduler.LoadAll(); err != nil {
+		log.Printf("警告: 載入週期排程失敗: %v", err)
+	}
+
+	// 啟動自動備份
+	autoBackup := models.NewAutoBackup(storage, cfg.Backup.Dir, cfg.Backup.IntervalHours, cfg.Backup.Keep)
+	if err := autoBackup.Start(); err != nil {
+		log.Printf("警告: %v", err)
+	}
+	defer autoBackup.Stop()
+
+	// 定期檢查表單結構，變更時推送排程通知
+	formMonitor := models.NewFormMonitor(submitter, storage, cfg.FormWatch.IntervalMinutes)
+	formMonitor.OnDrift = func(check *models.FormStructureCheck) {
+		scheduler.Notify("表單結構已變更: " + check.Message + "，請至表單結構頁面重新對應")
+	}
+	if err := formMonitor.Start(); err != nil {
+		log.Printf("警告: %v", err)
+	}
+	defer formMonitor.Stop()
+
 	// 初始化 Gin router
 	router := gin.Default()
 
@@ -89,18 +239,27 @@ func main() {
 	router.LoadHTMLGlob("views/*.html")
 
 	// 建立 Controller
-	formController := controllers.NewFormController(cfg, storage)
+	formController := controllers.NewFormController(cfg, submitter, storage, scheduler)
 
 	// 註冊路由
 	// GET / - 顯示表單頁面
 	router.GET("/", formController.ShowForm)
 
+	// GET /saved - 儲存資料管理頁面
+	router.GET("/saved", formController.ShowSavedForms)
+
 	// POST /submit - 網頁表單提交
 	router.POST("/submit", formController.SubmitForm)
 
 	// POST /api/submit - API JSON 提交
 	router.POST("/api/submit", formController.SubmitAPI)
 
+	// POST /api/submit/batch - 批次提交（JSONL / JSON 陣列）
+	router.POST("/api/submit/batch", formController.SubmitBatch)
+
+	// GET /api/history - 提交記錄
+	router.GET("/api/history", formController.ListHistory)
+
 	// Storage API 路由
 	// GET /api/saved - 列出已儲存的表單
 	router.GET("/api/saved", formController.ListSavedForms)
@@ -108,18 +267,90 @@ func main() {
 	// POST /api/saved - 儲存表單資料
 	router.POST("/api/saved", formController.SaveForm)
 
+	// POST /api/saved/import - 匯入儲存資料（CSV / JSONL）
+	router.POST("/api/saved/import", formController.ImportSavedForms)
+
+	// GET /api/saved/export - 匯出儲存資料（不含密碼）
+	router.GET("/api/saved/export", formController.ExportSavedForms)
+
 	// GET /api/saved/:id - 取得單筆儲存的表單
 	router.GET("/api/saved/:id", formController.GetSavedForm)
 
 	// DELETE /api/saved/:id - 刪除已儲存的表單
 	router.DELETE("/api/saved/:id", formController.DeleteSavedForm)
 
+	// PUT/PATCH /api/saved/:id - 更新已儲存的表單
+	router.PUT("/api/saved/:id", formController.UpdateSavedForm)
+	router.PATCH("/api/saved/:id", formController.PatchSavedForm)
+
+	// 日期範本預覽
+	router.POST("/api/saved/preview", formController.PreviewDateTemplate)
+	router.GET("/api/saved/:id/preview", formController.PreviewSavedForm)
+
+	// POST /api/saved/:id/clone - 複製已儲存的表單
+	router.POST("/api/saved/:id/clone", formController.CloneSavedForm)
+
+	// PUT /api/saved/:id/password - 更換已儲存表單的密碼
+	router.PUT("/api/saved/:id/password", formController.UpdateSavedFormPassword)
+
+	// 員工資料路由
+	employeeController := controllers.NewEmployeeController(storage)
+	router.GET("/employees", employeeController.ShowEmployees)
+	router.GET("/api/employees", employeeController.ListEmployees)
+	router.POST("/api/employees", employeeController.CreateEmployee)
+	router.GET("/api/employees/:id", employeeController.GetEmployee)
+	router.PUT("/api/employees/:id", employeeController.UpdateEmployee)
+	router.DELETE("/api/employees/:id", employeeController.DeleteEmployee)
+	router.PUT("/api/employees/:id/password", employeeController.UpdateEmployeePassword)
+
 	// 排程管理路由
-	scheduleController := controllers.NewScheduleController(scheduler, storage)
+	scheduleController := controllers.NewScheduleController(scheduler, storage, bookingWindow)
 	router.GET("/schedule", scheduleController.ShowSchedule)
 	router.GET("/api/schedule", scheduleController.GetScheduleStatus)
 	router.POST("/api/schedule", scheduleController.CreateSchedule)
 	router.DELETE("/api/schedule", scheduleController.StopSchedule)
+	router.GET("/api/schedule/events", scheduleController.StreamScheduleEvents)
+	router.POST("/api/schedule/preflight", scheduleController.CheckPreflight)
+	router.GET("/api/schedule/booking", scheduleController.GetBookingWindow)
+	router.POST("/api/schedule/booking", scheduleController.CreateBookingSchedule)
+
+	// 團隊名單與團隊排程路由
+	teamController := controllers.NewTeamController(teamScheduler, storage)
+	router.GET("/api/rosters", teamController.ListRosters)
+	router.POST("/api/rosters", teamController.CreateRoster)
+	router.GET("/api/rosters/:id", teamController.GetRoster)
+	router.PUT("/api/rosters/:id", teamController.UpdateRoster)
+	router.DELETE("/api/rosters/:id", teamController.DeleteRoster)
+	router.GET("/api/schedule/team", teamController.GetTeamSchedule)
+	router.POST("/api/schedule/team", teamController.CreateTeamSchedule)
+	router.DELETE("/api/schedule/team", teamController.StopTeamSchedule)
+
+	// 週期排程路由
+	recurringController := controllers.NewRecurringController(recurringScheduler, storage)
+	router.GET("/api/schedule/recurring", recurringController.ListRecurringSchedules)
+	router.POST("/api/schedule/recurring", recurringController.CreateRecurringSchedule)
+	router.POST("/api/schedule/recurring/preview", recurringController.PreviewRecurrence)
+	router.GET("/api/schedule/recurring/:id", recurringController.GetRecurringSchedule)
+	router.PUT("/api/schedule/recurring/:id", recurringController.UpdateRecurringSchedule)
+	router.DELETE("/api/schedule/recurring/:id", recurringController.DeleteRecurringSchedule)
+	router.GET("/api/schedule/recurring/:id/history", recurringController.GetRecurringHistory)
+
+	// 假日行事曆路由
+	calendarController := controllers.NewCalendarController(storage)
+	router.GET("/api/calendar", calendarController.GetCalendar)
+
+	// 表單結構路由
+	formStructureController := controllers.NewFormStructureController(formMonitor)
+	router.GET("/form", formStructureController.ShowFormStructure)
+	router.GET("/api/form/structure", formStructureController.GetFormStructure)
+	router.POST("/api/form/structure/check", formStructureController.CheckFormStructure)
+	router.POST("/api/form/structure/baseline", formStructureController.AcceptFormStructure)
+	router.PUT("/api/form/entry-map", formStructureController.RemapEntryMap)
+
+	// 資料庫備份路由
+	backupController := controllers.NewBackupController(cfg, storage)
+	router.GET("/api/backup", backupController.ListBackups)
+	router.POST("/api/backup", backupController.CreateBackup)
 
 	// 顯示啟動訊息
 	addr := fmt.Sprintf(":%s", cfg.Port)
@@ -169,10 +400,12 @@ func main() {
 	<-quit
 	fmt.Println("\n正在關閉 Server...")
 
-	// 停止排程器
+	// 停止排程器並等待執行中的提交結束
 	if scheduler != nil {
-		scheduler.Stop()
+		scheduler.Shutdown()
 	}
+	teamScheduler.Shutdown()
+	recurringScheduler.Stop()
 
 	fmt.Println("Server 已關閉")
 }
@@ -0,0 +1,274 @@
+package models
+
+import (
+	"database/sql"
+	"fmt"
+	"io"
+	"log"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+// backupTimeFormat 備份檔名中的時間格式，精確到微秒，避免同一秒內的備份互相衝突。
+// 解析時沿用秒的格式：time.Parse 會接受秒之後的小數，舊版只到秒的檔名也能解析
+const (
+	backupTimeFormat      = "20060102-150405.000000"
+	backupTimeParseFormat = "20060102-150405"
+)
+
+// BackupInfo 備份檔資訊
+type BackupInfo struct {
+	Name      string    `json:"name"`
+	Path      string    `json:"path"`
+	Size      int64     `json:"size"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// backupPrefix 備份檔名前綴（資料庫檔名去掉副檔名）
+func backupPrefix(dbPath string) string {
+	base := filepath.Base(dbPath)
+	return strings.TrimSuffix(base, filepath.Ext(base)) + "-"
+}
+
+// Backup 以 VACUUM INTO 在 dir 建立一致的線上備份，服務運行中也可執行
+func (s *Storage) Backup(dir string) (*BackupInfo, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("建立備份目錄失敗: %w", err)
+	}
+
+	// VACUUM INTO 不會覆寫已存在的檔案，萬一檔名仍相同也只會失敗而不會蓋掉舊備份
+	now := time.Now()
+	path := filepath.Join(dir, backupPrefix(s.path)+now.Format(backupTimeFormat)+".db")
+	if err := s.vacuumInto(path); err != nil {
+		return nil, fmt.Errorf("備份失敗: %w", err)
+	}
+
+	stat, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("讀取備份檔失敗: %w", err)
+	}
+
+	return &BackupInfo{
+		Name:      filepath.Base(path),
+		Path:      path,
+		Size:      stat.Size(),
+		CreatedAt: now,
+	}, nil
+}
+
+// ListBackups 列出 dir 中 dbPath 的備份檔（新到舊）
+func ListBackups(dir, dbPath string) ([]BackupInfo, error) {
+	matches, err := filepath.Glob(filepath.Join(dir, backupPrefix(dbPath)+"*.db"))
+	if err != nil {
+		return nil, fmt.Errorf("讀取備份目錄失敗: %w", err)
+	}
+
+	backups := []BackupInfo{}
+	for _, path := range matches {
+		name := filepath.Base(path)
+		stamp := strings.TrimSuffix(strings.TrimPrefix(name, backupPrefix(dbPath)), ".db")
+		createdAt, err := time.ParseInLocation(backupTimeParseFormat, stamp, time.Local)
+		if err != nil {
+			// 不是本程式產生的備份檔
+			continue
+		}
+		stat, err := os.Stat(path)
+		if err != nil {
+			continue
+		}
+		backups = append(backups, BackupInfo{Name: name, Path: path, Size: stat.Size(), CreatedAt: createdAt})
+	}
+
+	sort.Slice(backups, func(i, j int) bool {
+		return backups[i].CreatedAt.After(backups[j].CreatedAt)
+	})
+
+	return backups, nil
+}
+
+// PruneBackups 只保留最新的 keep 份備份，回傳被刪除的檔名（keep <= 0 時不刪除）
+func PruneBackups(dir, dbPath string, keep int) ([]string, error) {
+	if keep <= 0 {
+		return nil, nil
+	}
+
+	backups, err := ListBackups(dir, dbPath)
+	if err != nil {
+		return nil, err
+	}
+	if len(backups) <= keep {
+		return nil, nil
+	}
+
+	var removed []string
+	for _, b := range backups[keep:] {
+		if err := os.Remove(b.Path); err != nil {
+			return removed, fmt.Errorf("刪除舊備份失敗: %w", err)
+		}
+		removed = append(removed, b.Name)
+	}
+
+	return removed, nil
+}
+
+// VerifyBackup 檢查備份檔的完整性與結構版本，回傳其版本號
+func VerifyBackup(path string) (int, error) {
+	version, _, err := ReadSchemaVersion(path)
+	if err != nil {
+		return 0, err
+	}
+	if version == 0 {
+		return 0, fmt.Errorf("備份檔沒有結構版本記錄，不是有效的資料庫備份")
+	}
+	if version > LatestSchemaVersion() {
+		return version, fmt.Errorf("備份檔版本 %d 高於程式支援的版本 %d，請更新程式", version, LatestSchemaVersion())
+	}
+
+	db, err := sql.Open("sqlite3", readOnlyDSN(path))
+	if err != nil {
+		return version, fmt.Errorf("無法連線到備份檔: %w", err)
+	}
+	defer db.Close()
+
+	var result string
+	if err := db.QueryRow("PRAGMA integrity_check").Scan(&result); err != nil {
+		return version, fmt.Errorf("完整性檢查失敗: %w", err)
+	}
+	if result != "ok" {
+		return version, fmt.Errorf("備份檔已損毀: %s", result)
+	}
+
+	return version, nil
+}
+
+// RestoreDatabase 驗證備份後以備份取代 dbPath，原資料庫改名保留並回傳其路徑。
+// 呼叫前必須先停止服務，確保沒有其他連線開啟 dbPath。
+func RestoreDatabase(backupPath, dbPath string) (string, error) {
+	if _, err := VerifyBackup(backupPath); err != nil {
+		return "", err
+	}
+
+	// 先複製到同目錄的暫存檔，確保最後的 rename 是原子操作
+	tmpPath := dbPath + ".restoring"
+	if err := copyFile(backupPath, tmpPath); err != nil {
+		os.Remove(tmpPath)
+		return "", fmt.Errorf("複製備份檔失敗: %w", err)
+	}
+
+	previousPath := ""
+	if _, err := os.Stat(dbPath); err == nil {
+		previousPath = fmt.Sprintf("%s.pre-restore-%s.bak", dbPath, time.Now().Format(backupTimeFormat))
+		if err := os.Rename(dbPath, previousPath); err != nil {
+			os.Remove(tmpPath)
+			return "", fmt.Errorf("保留原資料庫失敗: %w", err)
+		}
+	}
+
+	if err := os.Rename(tmpPath, dbPath); err != nil {
+		if previousPath != "" {
+			os.Rename(previousPath, dbPath)
+		}
+		return "", fmt.Errorf("還原資料庫失敗: %w", err)
+	}
+
+	return previousPath, nil
+}
+
+// readOnlyDSN 以 SQLite URI 唯讀開啟 path 的連線字串；路徑中的 ?、#、% 等字元會被跳脫，
+// 不會被當成 URI 參數
+func readOnlyDSN(path string) string {
+	u := url.URL{Scheme: "file", OmitHost: true, Path: filepath.ToSlash(path), RawQuery: "mode=ro"}
+	return u.String()
+}
+
+// copyFile 複製檔案並同步到磁碟
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Sync(); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// AutoBackup 定期自動備份並清除過舊的備份
+type AutoBackup struct {
+	storage       *Storage
+	dir           string
+	intervalHours int
+	keep          int
+	cron          *cron.Cron
+	logger        *log.Logger
+}
+
+// NewAutoBackup 建立自動備份排程
+func NewAutoBackup(storage *Storage, dir string, intervalHours, keep int) *AutoBackup {
+	return &AutoBackup{
+		storage:       storage,
+		dir:           dir,
+		intervalHours: intervalHours,
+		keep:          keep,
+		logger:        log.New(os.Stdout, "[Backup] ", log.LstdFlags),
+	}
+}
+
+// Start 啟動自動備份（intervalHours <= 0 時不啟動）
+func (a *AutoBackup) Start() error {
+	if a.intervalHours <= 0 {
+		return nil
+	}
+
+	a.cron = cron.New()
+	if _, err := a.cron.AddFunc(fmt.Sprintf("@every %dh", a.intervalHours), a.RunOnce); err != nil {
+		return fmt.Errorf("設定自動備份失敗: %w", err)
+	}
+	a.cron.Start()
+	a.logger.Printf("自動備份已啟動：每 %d 小時備份至 %s，保留 %d 份", a.intervalHours, a.dir, a.keep)
+
+	return nil
+}
+
+// RunOnce 執行一次備份與清除
+func (a *AutoBackup) RunOnce() {
+	info, err := a.storage.Backup(a.dir)
+	if err != nil {
+		a.logger.Printf("自動備份失敗: %v", err)
+		return
+	}
+	a.logger.Printf("已備份至: %s", info.Path)
+
+	removed, err := PruneBackups(a.dir, a.storage.path, a.keep)
+	if err != nil {
+		a.logger.Printf("清除舊備份失敗: %v", err)
+	}
+	for _, name := range removed {
+		a.logger.Printf("已刪除舊備份: %s", name)
+	}
+}
+
+// Stop 停止自動備份
+func (a *AutoBackup) Stop() {
+	if a.cron != nil {
+		a.cron.Stop()
+	}
+}
@@ -0,0 +1,371 @@
+package models
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// 批次提交預設值與上限
+const (
+	defaultBatchConcurrency = 2
+	MaxBatchConcurrency     = 10
+	defaultBatchRate        = 2.0 // 每秒最多開始送出的筆數
+	MinBatchRate            = 0.1
+	MaxBatchRate            = 20.0
+	MaxBatchItems           = 500
+)
+
+// 批次項目狀態
+const (
+	BatchStatusInvalid   = "invalid"   // 驗證失敗
+	BatchStatusValidated = "validated" // 試跑驗證通過，未送出
+	BatchStatusSucceeded = "succeeded"
+	BatchStatusFailed    = "failed"
+	BatchStatusCanceled  = "canceled" // 批次中止（例如用戶端中斷連線），未送出
+)
+
+// BatchOptions 批次提交設定
+type BatchOptions struct {
+	Concurrency int         // 同時送出的數量，<= 0 時使用預設值 2
+	Rate        float64     // 每秒最多開始送出的筆數，<= 0 時使用預設值 2，限制在 0.1 ~ 20
+	Retry       RetryPolicy // 單筆失敗的重試設定
+	DryRun      bool        // 只驗證不送出
+}
+
+// withDefaults 套用預設值並限制上限
+func (o BatchOptions) withDefaults() BatchOptions {
+	if o.Concurrency <= 0 {
+		o.Concurrency = defaultBatchConcurrency
+	}
+	if o.Concurrency > MaxBatchConcurrency {
+		o.Concurrency = MaxBatchConcurrency
+	}
+	if o.Rate <= 0 {
+		o.Rate = defaultBatchRate
+	}
+	o.Rate = min(max(o.Rate, MinBatchRate), MaxBatchRate)
+	o.Retry = o.Retry.withDefaults()
+	return o
+}
+
+// BatchItem 批次中的單筆請假資料
+type BatchItem struct {
+	Line    int // JSONL 為行號，JSON 陣列為第幾筆（從 1 起算）
+	Request *LeaveRequest
+	err     error // 解析錯誤
+}
+
+// BatchItemResult 單筆提交結果（不含密碼）
+type BatchItemResult struct {
+	Line       int       `json:"line"`
+	Name       string    `json:"name"`
+	EmployeeID string    `json:"employee_id"`
+	StartDate  string    `json:"start_date"`
+	EndDate    string    `json:"end_date"`
+	LeaveType  string    `json:"leave_type"`
+	Status     string    `json:"status"`
+	Attempts   int       `json:"attempts"`
+	Error      string    `json:"error,omitempty"`
+	StartedAt  time.Time `json:"started_at,omitzero"`
+	DurationMs int64     `json:"duration_ms"`
+}
+
+// BatchReport 批次提交報告
+type BatchReport struct {
+	BatchID    string            `json:"batch_id"`
+	DryRun     bool              `json:"dry_run"`
+	Total      int               `json:"total"`
+	Invalid    int               `json:"invalid"`
+	Succeeded  int               `json:"succeeded"`
+	Failed     int               `json:"failed"`
+	StartedAt  time.Time         `json:"started_at"`
+	FinishedAt time.Time         `json:"finished_at"`
+	Items      []BatchItemResult `json:"items"`
+}
+
+// ParseBatchRequests 解析 JSON 陣列或 JSONL（依第一個非空白字元判斷），格式錯誤的項目記錄在該項而不中斷解析
+func ParseBatchRequests(r io.Reader) ([]BatchItem, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("讀取批次資料失敗: %w", err)
+	}
+	data = bytes.TrimPrefix(bytes.TrimSpace(data), []byte("\ufeff"))
+
+	if len(data) > 0 && data[0] == '[' {
+		var raws []json.RawMessage
+		if err := json.Unmarshal(data, &raws); err != nil {
+			return nil, fmt.Errorf("JSON 陣列格式錯誤: %w", err)
+		}
+		items := make([]BatchItem, 0, len(raws))
+		for i, raw := range raws {
+			req := &LeaveRequest{}
+			item := BatchItem{Line: i + 1, Request: req}
+			if err := json.Unmarshal(raw, req); err != nil {
+				item.err = fmt.Errorf("JSON 格式錯誤: %v", err)
+			}
+			items = append(items, item)
+		}
+		return items, nil
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	var items []BatchItem
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := bytes.TrimSpace(scanner.Bytes())
+		if len(text) == 0 {
+			continue
+		}
+		req := &LeaveRequest{}
+		item := BatchItem{Line: line, Request: req}
+		if err := json.Unmarshal(text, req); err != nil {
+			item.err = fmt.Errorf("JSON 格式錯誤: %v", err)
+		}
+		items = append(items, item)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("讀取 JSONL 失敗: %w", err)
+	}
+
+	return items, nil
+}
+
+// rateLimiter 以固定間隔發放送出時段
+type rateLimiter struct {
+	mu       sync.Mutex
+	interval time.Duration
+	next     time.Time
+}
+
+// newRateLimiter 建立每秒 rate 筆的限速器
+func newRateLimiter(rate float64) *rateLimiter {
+	return &rateLimiter{interval: time.Duration(float64(time.Second) / rate)}
+}
+
+// Wait 等待到下一個可送出的時段，ctx 結束時回傳 false
+func (l *rateLimiter) Wait(ctx context.Context) bool {
+	l.mu.Lock()
+	now := time.Now()
+	if l.next.Before(now) {
+		l.next = now
+	}
+	slot := l.next
+	l.next = l.next.Add(l.interval)
+	l.mu.Unlock()
+
+	timer := time.NewTimer(time.Until(slot))
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// BatchSubmitter 批次提交器
+type BatchSubmitter struct {
+	submitter *GoogleFormSubmitter
+	storage   *Storage
+	logger    *log.Logger
+}
+
+// NewBatchSubmitter 建立批次提交器（storage 可為 nil，則不寫入提交記錄）
+func NewBatchSubmitter(submitter *GoogleFormSubmitter, storage *Storage) *BatchSubmitter {
+	return &BatchSubmitter{
+		submitter: submitter,
+		storage:   storage,
+		logger:    log.New(os.Stdout, "[Batch] ", log.LstdFlags|log.Lmicroseconds),
+	}
+}
+
+// Run 驗證全部項目後以有限的並行數與速率送出；任一項驗證失敗時不送出任何資料。
+// ctx 結束時（例如用戶端中斷連線）停止送出，尚未送出的項目標記為 canceled
+func (b *BatchSubmitter) Run(ctx context.Context, items []BatchItem, opts BatchOptions) (*BatchReport, error) {
+	opts = opts.withDefaults()
+
+	report := &BatchReport{
+		BatchID:   "batch-" + time.Now().Format("20060102-150405.000"),
+		DryRun:    opts.DryRun,
+		Total:     len(items),
+		StartedAt: time.Now(),
+		Items:     make([]BatchItemResult, len(items)),
+	}
+
+	if len(items) == 0 {
+		return report, &ValidationError{Field: "items", Message: "沒有可提交的資料"}
+	}
+	if len(items) > MaxBatchItems {
+		return report, &ValidationError{Field: "items", Message: fmt.Sprintf("單次批次最多 %d 筆，實際 %d 筆", MaxBatchItems, len(items))}
+	}
+
+	// 1. 全部驗證
+	for i, item := range items {
+		result := &report.Items[i]
+		result.Line = item.Line
+		result.Name = item.Request.Name
+		result.EmployeeID = item.Request.EmployeeID
+		result.StartDate = item.Request.StartDate
+		result.EndDate = item.Request.EndDate
+		result.LeaveType = item.Request.LeaveType
+
+		err := item.err
+		if err == nil {
+			err = Validate(item.Request, b.storage.Calendar())
+		}
+		if err != nil {
+			result.Status = BatchStatusInvalid
+			result.Error = err.Error()
+			report.Invalid++
+			continue
+		}
+		result.Status = BatchStatusValidated
+	}
+
+	if report.Invalid > 0 {
+		report.FinishedAt = time.Now()
+		return report, &ValidationError{Field: "items", Message: fmt.Sprintf("有 %d 筆資料驗證失敗，未送出任何資料", report.Invalid)}
+	}
+	if opts.DryRun {
+		report.FinishedAt = time.Now()
+		return report, nil
+	}
+
+	// 2. 以 worker pool 送出
+	b.logger.Printf("開始批次提交 %s：共 %d 筆，並行 %d，每秒 %.1f 筆", report.BatchID, len(items), opts.Concurrency, opts.Rate)
+
+	limiter := newRateLimiter(opts.Rate)
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+
+	for w := 0; w < opts.Concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				if ctx.Err() != nil || !limiter.Wait(ctx) {
+					report.Items[i].Status = BatchStatusCanceled
+					report.Items[i].Error = "批次已中止，未送出"
+					continue
+				}
+				b.submitItem(ctx, report.BatchID, items[i].Request, &report.Items[i], opts.Retry)
+			}
+		}()
+	}
+
+	for i := range items {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	for _, result := range report.Items {
+		if result.Status == BatchStatusSucceeded {
+			report.Succeeded++
+		} else {
+			report.Failed++
+		}
+	}
+	report.FinishedAt = time.Now()
+	if err := ctx.Err(); err != nil {
+		b.logger.Printf("批次提交 %s 已中止：成功 %d 筆，失敗或未送出 %d 筆", report.BatchID, report.Succeeded, report.Failed)
+		return report, fmt.Errorf("批次提交已中止: %w", err)
+	}
+	b.logger.Printf("批次提交 %s 完成：成功 %d 筆，失敗 %d 筆", report.BatchID, report.Succeeded, report.Failed)
+
+	return report, nil
+}
+
+// submitItem 送出單筆資料並寫入提交記錄
+func (b *BatchSubmitter) submitItem(ctx context.Context, batchID string, req *LeaveRequest, result *BatchItemResult, policy RetryPolicy) {
+	result.StartedAt = time.Now()
+
+	var attempts []SubmissionAttempt
+	prepared, err := newPreparedRequest(b.submitter, req)
+	if err == nil {
+		attempts, err = sendWithRetry(ctx, prepared, policy, b.logger, nil)
+		result.Attempts = len(attempts)
+	}
+
+	finishedAt := time.Now()
+	result.DurationMs = finishedAt.Sub(result.StartedAt).Milliseconds()
+	result.Status = BatchStatusSucceeded
+	if err != nil {
+		result.Status = BatchStatusFailed
+		result.Error = err.Error()
+	}
+
+	if b.storage == nil {
+		return
+	}
+	rec := newSubmissionRecord(HistorySourceBatch, req)
+	rec.BatchID = batchID
+	rec.Attempts = result.Attempts
+	rec.AttemptDetails = attempts
+	rec.Success = err == nil
+	rec.Message = "提交成功"
+	if err != nil {
+		rec.Message = err.Error()
+	}
+	rec.StartedAt = result.StartedAt
+	rec.FinishedAt = finishedAt
+	if _, err := b.storage.RecordSubmission(rec); err != nil {
+		b.logger.Printf("警告: %v", err)
+	}
+}
+
+// WriteJSON 以 JSON 輸出報告
+func (r *BatchReport) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(r); err != nil {
+		return fmt.Errorf("寫入報告失敗: %w", err)
+	}
+	return nil
+}
+
+// WriteCSV 以 CSV 輸出逐筆結果
+func (r *BatchReport) WriteCSV(w io.Writer) error {
+	writer := csv.NewWriter(w)
+
+	header := []string{"line", "status", "name", "employee_id", "start_date", "end_date", "leave_type", "attempts", "duration_ms", "error"}
+	if err := writer.Write(header); err != nil {
+		return fmt.Errorf("寫入報告失敗: %w", err)
+	}
+
+	for _, item := range r.Items {
+		fields := []string{
+			strconv.Itoa(item.Line),
+			item.Status,
+			item.Name,
+			item.EmployeeID,
+			item.StartDate,
+			item.EndDate,
+			item.LeaveType,
+			strconv.Itoa(item.Attempts),
+			strconv.FormatInt(item.DurationMs, 10),
+			item.Error,
+		}
+		if err := writer.Write(fields); err != nil {
+			return fmt.Errorf("寫入報告失敗: %w", err)
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
@@ -0,0 +1,188 @@
+package models
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// batchTestSubmitter 建立指向測試伺服器的提交器
+func batchTestSubmitter(url string) *GoogleFormSubmitter {
+	return NewGoogleFormSubmitter(url, map[string]string{
+		"name":        "entry.1",
+		"employee_id": "entry.2",
+		"start_date":  "entry.3",
+		"end_date":    "entry.4",
+		"leave_type":  "entry.5",
+		"password":    "entry.6",
+	})
+}
+
+// TestParseBatchRequests 測試 JSONL 與 JSON 陣列解析
+func TestParseBatchRequests(t *testing.T) {
+	jsonl := `{"name":"甲","employee_id":"A1","start_date":"2026-02-01","end_date":"2026-02-02","leave_type":"近假","password":"p"}
+
+not json
+`
+	items, err := ParseBatchRequests(strings.NewReader(jsonl))
+	if err != nil {
+		t.Fatalf("解析 JSONL 失敗: %v", err)
+	}
+	if len(items) != 2 || items[0].Line != 1 || items[1].Line != 3 || items[1].err == nil {
+		t.Errorf("JSONL 解析結果不正確: %+v", items)
+	}
+
+	array := `[{"name":"甲"},{"name":"乙"}]`
+	items, err = ParseBatchRequests(strings.NewReader(array))
+	if err != nil {
+		t.Fatalf("解析 JSON 陣列失敗: %v", err)
+	}
+	if len(items) != 2 || items[1].Request.Name != "乙" || items[1].Line != 2 {
+		t.Errorf("JSON 陣列解析結果不正確: %+v", items)
+	}
+}
+
+// TestBatchSubmitterRun 測試批次提交的並行上限、重試與提交記錄
+func TestBatchSubmitterRun(t *testing.T) {
+	storage, cleanup := setupTestStorage(t)
+	defer cleanup()
+
+	var inFlight, maxInFlight int32
+	var mu sync.Mutex
+	calls := map[string]int{}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&inFlight, 1)
+		defer atomic.AddInt32(&inFlight, -1)
+		for {
+			m := atomic.LoadInt32(&maxInFlight)
+			if n <= m || atomic.CompareAndSwapInt32(&maxInFlight, m, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+
+		r.ParseForm()
+		name := r.PostForm.Get("entry.1")
+		mu.Lock()
+		calls[name]++
+		count := calls[name]
+		mu.Unlock()
+
+		// 「丙」第一次失敗，重試後成功；「丁」永遠失敗
+		if (name == "丙" && count == 1) || name == "丁" {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	var items []BatchItem
+	for i, name := range []string{"甲", "乙", "丙", "丁", "戊"} {
+		items = append(items, BatchItem{Line: i + 1, Request: &LeaveRequest{
+			Name: name, EmployeeID: "A1", StartDate: "2026-02-01", EndDate: "2026-02-02", LeaveType: "近假", Password: "p",
+		}})
+	}
+
+	report, err := NewBatchSubmitter(batchTestSubmitter(server.URL), storage).Run(context.Background(), items, BatchOptions{
+		Concurrency: 2,
+		Rate:        100,
+		Retry:       RetryPolicy{Count: 2, Interval: 1},
+	})
+	if err != nil {
+		t.Fatalf("批次提交不應回傳錯誤: %v", err)
+	}
+
+	if report.Succeeded != 4 || report.Failed != 1 {
+		t.Errorf("成功 4 筆、失敗 1 筆，實際成功 %d、失敗 %d", report.Succeeded, report.Failed)
+	}
+	if report.Items[2].Attempts != 2 || report.Items[3].Status != BatchStatusFailed {
+		t.Errorf("重試結果不正確: %+v", report.Items)
+	}
+	if maxInFlight > 2 {
+		t.Errorf("同時送出數量不應超過 2，實際 %d", maxInFlight)
+	}
+
+	records, err := storage.ListHistory(HistoryQuery{BatchID: report.BatchID})
+	if err != nil {
+		t.Fatalf("讀取提交記錄失敗: %v", err)
+	}
+	if len(records) != 5 {
+		t.Errorf("應有 5 筆提交記錄，實際 %d 筆", len(records))
+	}
+
+	var buf bytes.Buffer
+	if err := report.WriteCSV(&buf); err != nil {
+		t.Fatalf("輸出 CSV 失敗: %v", err)
+	}
+	if lines := strings.Count(buf.String(), "\n"); lines != 6 {
+		t.Errorf("CSV 應有 6 行，實際 %d 行", lines)
+	}
+}
+
+// TestBatchSubmitterCanceled 測試 ctx 結束後停止送出，未送出的項目標記為 canceled
+func TestBatchSubmitterCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		cancel() // 模擬用戶端在第一筆送出後中斷連線
+	}))
+	defer server.Close()
+
+	var items []BatchItem
+	for i, name := range []string{"甲", "乙", "丙"} {
+		items = append(items, BatchItem{Line: i + 1, Request: &LeaveRequest{
+			Name: name, EmployeeID: "A1", StartDate: "2026-02-01", EndDate: "2026-02-02", LeaveType: "近假", Password: "p",
+		}})
+	}
+
+	start := time.Now()
+	report, err := NewBatchSubmitter(batchTestSubmitter(server.URL), nil).Run(ctx, items, BatchOptions{
+		Concurrency: 1,
+		Rate:        0.001, // 限制在最低速率，第二筆需等待 10 秒
+	})
+	if err == nil {
+		t.Fatal("中止的批次應回傳錯誤")
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("中止後應立即結束，實際 %v", elapsed)
+	}
+	if calls != 1 || report.Items[1].Status != BatchStatusCanceled || report.Items[2].Status != BatchStatusCanceled {
+		t.Errorf("中止後不應再送出，實際 %d 次: %+v", calls, report.Items)
+	}
+}
+
+// TestBatchSubmitterValidatesAll 測試任一筆驗證失敗時不送出任何資料
+func TestBatchSubmitterValidatesAll(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+	}))
+	defer server.Close()
+
+	items := []BatchItem{
+		{Line: 1, Request: &LeaveRequest{Name: "甲", EmployeeID: "A1", StartDate: "2026-02-01", EndDate: "2026-02-02", LeaveType: "近假", Password: "p"}},
+		{Line: 2, Request: &LeaveRequest{Name: "乙"}},
+	}
+
+	report, err := NewBatchSubmitter(batchTestSubmitter(server.URL), nil).Run(context.Background(), items, BatchOptions{})
+	if err == nil {
+		t.Fatal("有驗證失敗的資料時應回傳錯誤")
+	}
+	if report.Invalid != 1 || report.Items[1].Status != BatchStatusInvalid {
+		t.Errorf("驗證結果不正確: %+v", report.Items)
+	}
+	if calls != 0 {
+		t.Errorf("驗證失敗時不應送出任何請求，實際 %d 次", calls)
+	}
+}
@@ -0,0 +1,99 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+// 預約窗口狀態
+const (
+	BookingWindowPending = "pending" // 尚未開放，可排程在開放時間提交
+	BookingWindowOpen    = "open"    // 已開放，應直接提交
+	BookingWindowClosed  = "closed"  // 已截止
+)
+
+// BookingWindow 預約窗口規則：請假起點日期前 OpensDaysBefore 天的 OpenTime 開放，
+// 起點日期前 ClosesDaysBefore 天的 00:00 截止
+type BookingWindow struct {
+	OpensDaysBefore  int
+	ClosesDaysBefore int
+	Location         *time.Location
+	openHour         int
+	openMinute       int
+}
+
+// BookingWindowInfo 單一請假起點日期的預約窗口
+type BookingWindowInfo struct {
+	StartDate        string    `json:"start_date"`
+	OpensAt          time.Time `json:"opens_at"`
+	ClosesAt         time.Time `json:"closes_at"`
+	Status           string    `json:"status"`            // pending / open / closed
+	CountdownSeconds int64     `json:"countdown_seconds"` // 距離開放的秒數，已開放或截止時為 0
+}
+
+// NewBookingWindow 建立預約窗口規則；openTime 為 HH:MM，timezone 空白時使用 Asia/Taipei
+func NewBookingWindow(opensDaysBefore int, openTime string, closesDaysBefore int, timezone string) (*BookingWindow, error) {
+	if opensDaysBefore <= 0 {
+		return nil, fmt.Errorf("opens_days_before 必須大於 0")
+	}
+	if closesDaysBefore < 0 || closesDaysBefore >= opensDaysBefore {
+		return nil, fmt.Errorf("closes_days_before 必須介於 0 與 opens_days_before 之間")
+	}
+
+	if openTime == "" {
+		openTime = "00:00"
+	}
+	t, err := time.Parse("15:04", openTime)
+	if err != nil {
+		return nil, fmt.Errorf("open_time 格式錯誤，請使用 HH:MM 格式")
+	}
+
+	if timezone == "" {
+		timezone = "Asia/Taipei"
+	}
+	loc, err := time.LoadLocation(timezone)
+	if err != nil {
+		return nil, fmt.Errorf("無法辨識的時區: %s", timezone)
+	}
+
+	return &BookingWindow{
+		OpensDaysBefore:  opensDaysBefore,
+		ClosesDaysBefore: closesDaysBefore,
+		Location:         loc,
+		openHour:         t.Hour(),
+		openMinute:       t.Minute(),
+	}, nil
+}
+
+// String 以易讀文字描述規則
+func (w *BookingWindow) String() string {
+	return fmt.Sprintf("請假起點日期前 %d 天 %02d:%02d（%s）開放，前 %d 天截止",
+		w.OpensDaysBefore, w.openHour, w.openMinute, w.Location, w.ClosesDaysBefore)
+}
+
+// Check 計算請假起點日期的開放與截止時間，以及相對於 now 的狀態
+func (w *BookingWindow) Check(startDate string, now time.Time) (*BookingWindowInfo, error) {
+	start, err := time.ParseInLocation("2006-01-02", startDate, w.Location)
+	if err != nil {
+		return nil, fmt.Errorf("請假起點日期格式錯誤: %w", err)
+	}
+
+	opensDay := start.AddDate(0, 0, -w.OpensDaysBefore)
+	info := &BookingWindowInfo{
+		StartDate: startDate,
+		OpensAt:   time.Date(opensDay.Year(), opensDay.Month(), opensDay.Day(), w.openHour, w.openMinute, 0, 0, w.Location),
+		ClosesAt:  start.AddDate(0, 0, -w.ClosesDaysBefore),
+	}
+
+	switch {
+	case !now.Before(info.ClosesAt):
+		info.Status = BookingWindowClosed
+	case !now.Before(info.OpensAt):
+		info.Status = BookingWindowOpen
+	default:
+		info.Status = BookingWindowPending
+		info.CountdownSeconds = int64(info.OpensAt.Sub(now).Seconds())
+	}
+
+	return info, nil
+}
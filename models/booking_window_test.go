@@ -0,0 +1,63 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+// TestBookingWindowCheck 測試依請假起點日期計算預約窗口與狀態
+func TestBookingWindowCheck(t *testing.T) {
+	w, err := NewBookingWindow(30, "08:30", 1, "Asia/Taipei")
+	if err != nil {
+		t.Fatalf("建立規則失敗: %v", err)
+	}
+	taipei := w.Location
+
+	tests := []struct {
+		name      string
+		now       time.Time
+		status    string
+		countdown int64
+	}{
+		{"開放前一小時", time.Date(2025, 2, 28, 7, 30, 0, 0, taipei), BookingWindowPending, 3600},
+		{"開放當下", time.Date(2025, 2, 28, 8, 30, 0, 0, taipei), BookingWindowOpen, 0},
+		{"截止前", time.Date(2025, 3, 28, 23, 59, 0, 0, taipei), BookingWindowOpen, 0},
+		{"截止", time.Date(2025, 3, 29, 0, 0, 0, 0, taipei), BookingWindowClosed, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info, err := w.Check("2025-03-30", tt.now)
+			if err != nil {
+				t.Fatalf("計算失敗: %v", err)
+			}
+			if info.Status != tt.status || info.CountdownSeconds != tt.countdown {
+				t.Errorf("應為 %s（倒數 %d 秒），實際 %+v", tt.status, tt.countdown, info)
+			}
+			if got := info.OpensAt.Format("2006-01-02 15:04 MST"); got != "2025-02-28 08:30 CST" {
+				t.Errorf("開放時間不正確: %s", got)
+			}
+		})
+	}
+
+	// 以台北時間計算：UTC 前一天 16:00 即為台北 00:00
+	w, _ = NewBookingWindow(7, "", 0, "")
+	info, _ := w.Check("2025-01-20", time.Now())
+	if got := info.OpensAt.UTC().Format("2006-01-02 15:04"); got != "2025-01-12 16:00" {
+		t.Errorf("應以 Asia/Taipei 00:00 開放，實際 %s", got)
+	}
+
+	for _, bad := range []struct {
+		opens, closes int
+		openTime, tz  string
+	}{
+		{0, 0, "", ""},
+		{10, 10, "", ""},
+		{10, 0, "25:00", ""},
+		{10, 0, "", "Nowhere/City"},
+	} {
+		if _, err := NewBookingWindow(bad.opens, bad.openTime, bad.closes, bad.tz); err == nil {
+			t.Errorf("規則 %+v 應建立失敗", bad)
+		}
+	}
+}
@@ -0,0 +1,436 @@
+package models
+
+import (
+	"bufio"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// 行事曆日期類型
+const (
+	CalendarHoliday = "holiday" // 國定假日、彈性放假等平日放假
+	CalendarWorkday = "workday" // 補班日等週末上班
+)
+
+// 行事曆檔案格式
+const (
+	CalendarFormatICS = "ics"
+	CalendarFormatCSV = "csv"
+)
+
+// calendarSearchDays 計算工作日時往後搜尋的最大天數
+const calendarSearchDays = 366 * 2
+
+// CalendarDay 與平日 / 週末預設不同的日期
+type CalendarDay struct {
+	Date string `json:"date"` // YYYY-MM-DD
+	Kind string `json:"kind"` // holiday / workday
+	Name string `json:"name,omitempty"`
+}
+
+// LeaveDaysSummary 請假期間的日數統計
+type LeaveDaysSummary struct {
+	CalendarDays int            `json:"calendar_days"` // 日曆天數（含起訖）
+	WorkingDays  int            `json:"working_days"`  // 需請假的工作日數
+	Holidays     []*CalendarDay `json:"holidays"`      // 期間內匯入的假日與補班日
+}
+
+// Calendar 工作日行事曆：週一至週五為工作日、週末休息，再以匯入的假日與補班日覆蓋。
+// nil 或未匯入任何資料時只依星期判斷。
+type Calendar struct {
+	days map[string]*CalendarDay
+}
+
+// NewCalendar 以匯入的日期建立行事曆
+func NewCalendar(days []*CalendarDay) *Calendar {
+	c := &Calendar{days: make(map[string]*CalendarDay, len(days))}
+	for _, d := range days {
+		c.days[d.Date] = d
+	}
+	return c
+}
+
+// HasData 是否有匯入的假日資料
+func (c *Calendar) HasData() bool {
+	return c != nil && len(c.days) > 0
+}
+
+// Day 取得匯入的日期資料，沒有時為 nil
+func (c *Calendar) Day(t time.Time) *CalendarDay {
+	if c == nil {
+		return nil
+	}
+	return c.days[t.Format("2006-01-02")]
+}
+
+// IsWorkingDay 判斷是否為工作日
+func (c *Calendar) IsWorkingDay(t time.Time) bool {
+	if d := c.Day(t); d != nil {
+		return d.Kind == CalendarWorkday
+	}
+	return t.Weekday() != time.Saturday && t.Weekday() != time.Sunday
+}
+
+// Summarize 統計 start ~ end（含）的日曆天數、工作日數與期間內的假日
+func (c *Calendar) Summarize(start, end time.Time) *LeaveDaysSummary {
+	summary := &LeaveDaysSummary{Holidays: []*CalendarDay{}}
+	for day := start; !day.After(end); day = day.AddDate(0, 0, 1) {
+		summary.CalendarDays++
+		if c.IsWorkingDay(day) {
+			summary.WorkingDays++
+		}
+		if d := c.Day(day); d != nil {
+			summary.Holidays = append(summary.Holidays, d)
+		}
+	}
+	return summary
+}
+
+// NextWorkingDay 回傳 t 當天或之後的第一個工作日
+func (c *Calendar) NextWorkingDay(t time.Time) time.Time {
+	for i := 0; i < calendarSearchDays && !c.IsWorkingDay(t); i++ {
+		t = t.AddDate(0, 0, 1)
+	}
+	return t
+}
+
+// AddWorkingDays 回傳從 start 起算（含）第 n 個工作日，作為請假終點
+func (c *Calendar) AddWorkingDays(start time.Time, n int) (time.Time, error) {
+	day := start
+	for i := 0; i < calendarSearchDays; i++ {
+		if c.IsWorkingDay(day) {
+			n--
+			if n <= 0 {
+				return day, nil
+			}
+		}
+		day = day.AddDate(0, 0, 1)
+	}
+	return time.Time{}, fmt.Errorf("無法在 %d 天內湊足工作日", calendarSearchDays)
+}
+
+// DetectCalendarFormat 依副檔名判斷行事曆格式
+func DetectCalendarFormat(path string) (string, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".ics", ".ical":
+		return CalendarFormatICS, nil
+	case ".csv":
+		return CalendarFormatCSV, nil
+	default:
+		return "", fmt.Errorf("無法由副檔名判斷格式，請指定 ics 或 csv")
+	}
+}
+
+// ParseCalendar 解析 ICS 或 CSV 行事曆
+func ParseCalendar(r io.Reader, format string) ([]*CalendarDay, error) {
+	switch format {
+	case CalendarFormatICS:
+		return ParseCalendarICS(r)
+	case CalendarFormatCSV:
+		return ParseCalendarCSV(r)
+	default:
+		return nil, fmt.Errorf("不支援的格式: %s", format)
+	}
+}
+
+// isWorkdayName 依名稱判斷是否為補班日
+func isWorkdayName(name string) bool {
+	lower := strings.ToLower(name)
+	return strings.Contains(name, "補班") || strings.Contains(name, "補行上班") || strings.Contains(lower, "workday")
+}
+
+// ParseCalendarICS 解析 iCalendar 檔案中的全天 VEVENT；名稱含「補班」的事件視為補班日，其餘為假日
+func ParseCalendarICS(r io.Reader) ([]*CalendarDay, error) {
+	// 展開折行（以空白或 tab 開頭的行接續上一行）
+	var lines []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		lines = append(lines, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("讀取 ICS 失敗: %w", err)
+	}
+
+	var days []*CalendarDay
+	var inEvent bool
+	var start, end, summary, categories string
+	for i, line := range lines {
+		name, value, _ := strings.Cut(line, ":")
+		prop, _, _ := strings.Cut(strings.ToUpper(name), ";")
+		switch {
+		case prop == "BEGIN" && strings.EqualFold(value, "VEVENT"):
+			inEvent = true
+			start, end, summary, categories = "", "", "", ""
+		case prop == "END" && strings.EqualFold(value, "VEVENT"):
+			inEvent = false
+			expanded, err := expandICSEvent(start, end, summary, categories)
+			if err != nil {
+				return nil, fmt.Errorf("第 %d 行: %w", i+1, err)
+			}
+			days = append(days, expanded...)
+		case !inEvent:
+		case prop == "DTSTART":
+			start = value
+		case prop == "DTEND":
+			end = value
+		case prop == "SUMMARY":
+			summary = strings.NewReplacer(`\,`, ",", `\;`, ";", `\n`, " ").Replace(value)
+		case prop == "CATEGORIES":
+			categories = value
+		}
+	}
+
+	return dedupeCalendarDays(days), nil
+}
+
+// expandICSEvent 將事件展開為逐日資料（DTEND 為不含的結束日）
+func expandICSEvent(start, end, summary, categories string) ([]*CalendarDay, error) {
+	if start == "" {
+		return nil, fmt.Errorf("事件缺少 DTSTART")
+	}
+	from, err := parseCalendarDate(start)
+	if err != nil {
+		return nil, fmt.Errorf("DTSTART %w", err)
+	}
+	to := from.AddDate(0, 0, 1)
+	if end != "" {
+		if to, err = parseCalendarDate(end); err != nil {
+			return nil, fmt.Errorf("DTEND %w", err)
+		}
+		if !to.After(from) {
+			to = from.AddDate(0, 0, 1)
+		}
+	}
+
+	kind := CalendarHoliday
+	if isWorkdayName(summary) || strings.Contains(strings.ToUpper(categories), "WORKDAY") {
+		kind = CalendarWorkday
+	}
+
+	var days []*CalendarDay
+	for day := from; day.Before(to); day = day.AddDate(0, 0, 1) {
+		days = append(days, &CalendarDay{Date: day.Format("2006-01-02"), Kind: kind, Name: summary})
+	}
+	return days, nil
+}
+
+// ParseCalendarCSV 解析 CSV 行事曆，支援兩種欄位：
+//   - date, name, kind（kind 為 holiday / workday，或「假日」/「補班」，省略時依名稱判斷）
+//   - 政府行政機關辦公日曆表：西元日期, 是否放假（2 放假、0 上班）, 備註
+func ParseCalendarCSV(r io.Reader) ([]*CalendarDay, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("讀取 CSV 標題列失敗: %w", err)
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))] = i
+	}
+
+	dateCol, government := columns["date"], false
+	if _, ok := columns["date"]; !ok {
+		if dateCol, government = columns["西元日期"]; !government {
+			return nil, fmt.Errorf("CSV 缺少 date 或 西元日期 欄位")
+		}
+	}
+	field := func(record []string, name string) string {
+		if i, ok := columns[name]; ok && i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+
+	var days []*CalendarDay
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("第 %d 行: %w", line, err)
+		}
+		if dateCol >= len(record) || strings.TrimSpace(record[dateCol]) == "" {
+			continue
+		}
+
+		date, err := parseCalendarDate(strings.TrimSpace(record[dateCol]))
+		if err != nil {
+			return nil, fmt.Errorf("第 %d 行: %w", line, err)
+		}
+		day := &CalendarDay{Date: date.Format("2006-01-02")}
+
+		if government {
+			day.Name = field(record, "備註")
+			weekend := date.Weekday() == time.Saturday || date.Weekday() == time.Sunday
+			switch off := field(record, "是否放假"); {
+			case off == "2" && !weekend:
+				day.Kind = CalendarHoliday
+			case off == "0" && weekend:
+				day.Kind = CalendarWorkday
+			default:
+				continue // 與星期預設相同，不需記錄
+			}
+		} else {
+			day.Name = field(record, "name")
+			switch kind := strings.ToLower(field(record, "kind")); kind {
+			case CalendarHoliday, "假日", "放假":
+				day.Kind = CalendarHoliday
+			case CalendarWorkday, "補班", "上班":
+				day.Kind = CalendarWorkday
+			case "":
+				day.Kind = CalendarHoliday
+				if isWorkdayName(day.Name) {
+					day.Kind = CalendarWorkday
+				}
+			default:
+				return nil, fmt.Errorf("第 %d 行: 無法辨識的類型 %q", line, kind)
+			}
+		}
+		days = append(days, day)
+	}
+
+	return dedupeCalendarDays(days), nil
+}
+
+// parseCalendarDate 解析 YYYY-MM-DD、YYYYMMDD、YYYY/M/D 或 ICS 的 YYYYMMDDTHHMMSS[Z]
+func parseCalendarDate(value string) (time.Time, error) {
+	if len(value) > 8 && value[8] == 'T' {
+		value = value[:8]
+	}
+	for _, layout := range []string{"2006-01-02", "20060102", "2006/1/2"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("日期格式錯誤: %q", value)
+}
+
+// dedupeCalendarDays 依日期去除重複（後者覆蓋前者）並排序
+func dedupeCalendarDays(days []*CalendarDay) []*CalendarDay {
+	byDate := make(map[string]*CalendarDay, len(days))
+	for _, d := range days {
+		byDate[d.Date] = d
+	}
+	result := make([]*CalendarDay, 0, len(byDate))
+	for _, d := range byDate {
+		result = append(result, d)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Date < result[j].Date })
+	return result
+}
+
+// ImportCalendar 匯入行事曆日期（同日期覆蓋），並更新目前使用的行事曆；
+// replace 為 true 時先清除匯入檔涵蓋年份的既有資料
+func (s *Storage) ImportCalendar(days []*CalendarDay, source string, replace bool) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("開始交易失敗: %w", err)
+	}
+	defer tx.Rollback()
+
+	if replace {
+		years := make(map[string]bool)
+		for _, d := range days {
+			years[d.Date[:4]] = true
+		}
+		for year := range years {
+			if _, err := tx.Exec("DELETE FROM calendar_days WHERE date LIKE ?", year+"-%"); err != nil {
+				return fmt.Errorf("清除行事曆失敗: %w", err)
+			}
+		}
+	}
+
+	now := time.Now()
+	for _, d := range days {
+		_, err := tx.Exec(`
+			INSERT INTO calendar_days (date, kind, name, source, imported_at) VALUES (?, ?, ?, ?, ?)
+			ON CONFLICT(date) DO UPDATE SET kind = excluded.kind, name = excluded.name, source = excluded.source, imported_at = excluded.imported_at
+		`, d.Date, d.Kind, d.Name, source, now)
+		if err != nil {
+			return fmt.Errorf("匯入行事曆失敗: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("匯入行事曆失敗: %w", err)
+	}
+
+	return s.ActivateCalendar()
+}
+
+// ListCalendarDays 列出 from ~ to（含，YYYY-MM-DD，空白表示不限）的匯入日期
+func (s *Storage) ListCalendarDays(from, to string) ([]*CalendarDay, error) {
+	query := "SELECT date, kind, name FROM calendar_days WHERE 1 = 1"
+	var args []interface{}
+	if from != "" {
+		query += " AND date >= ?"
+		args = append(args, from)
+	}
+	if to != "" {
+		query += " AND date <= ?"
+		args = append(args, to)
+	}
+	query += " ORDER BY date"
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("查詢行事曆失敗: %w", err)
+	}
+	defer rows.Close()
+
+	days := []*CalendarDay{}
+	for rows.Next() {
+		d := &CalendarDay{}
+		if err := rows.Scan(&d.Date, &d.Kind, &d.Name); err != nil {
+			return nil, fmt.Errorf("讀取行事曆失敗: %w", err)
+		}
+		days = append(days, d)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("讀取行事曆失敗: %w", err)
+	}
+
+	return days, nil
+}
+
+// ActivateCalendar 從資料庫載入行事曆並設為目前使用的行事曆
+func (s *Storage) ActivateCalendar() error {
+	days, err := s.ListCalendarDays("", "")
+	if err != nil {
+		return err
+	}
+	s.setCalendar(NewCalendar(days))
+	return nil
+}
+
+// setCalendar 設定 Validate 與日期範本使用的行事曆，nil 表示不使用匯入的資料
+func (s *Storage) setCalendar(c *Calendar) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.calendar = c
+}
+
+// Calendar 取得目前使用的行事曆（未載入或 s 為 nil 時回傳 nil，Calendar 的方法皆可處理 nil）
+func (s *Storage) Calendar() *Calendar {
+	if s == nil {
+		return nil
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.calendar
+}
@@ -0,0 +1,111 @@
+package models
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestParseCalendar 測試 ICS 與兩種 CSV 欄位格式的解析
+func TestParseCalendar(t *testing.T) {
+	ics := "BEGIN:VCALENDAR\r\n" +
+		"BEGIN:VEVENT\r\n" +
+		"DTSTART;VALUE=DATE:20250127\r\n" +
+		"DTEND;VALUE=DATE:20250201\r\n" +
+		"SUMMARY:春節\r\n" +
+		" 連假\r\n" +
+		"END:VEVENT\r\n" +
+		"BEGIN:VEVENT\r\n" +
+		"DTSTART;VALUE=DATE:20250208\r\n" +
+		"SUMMARY:補班\r\n" +
+		"END:VEVENT\r\n" +
+		"END:VCALENDAR\r\n"
+	days, err := ParseCalendar(strings.NewReader(ics), CalendarFormatICS)
+	if err != nil {
+		t.Fatalf("解析 ICS 失敗: %v", err)
+	}
+	if len(days) != 6 || days[0].Date != "2025-01-27" || days[4].Date != "2025-01-31" || days[0].Name != "春節連假" {
+		t.Fatalf("DTEND 不含當天，應展開為 5 天假日加 1 天補班，實際 %+v", days)
+	}
+	if days[5].Date != "2025-02-08" || days[5].Kind != CalendarWorkday {
+		t.Errorf("名稱含補班應為補班日，實際 %+v", days[5])
+	}
+
+	csvText := "\ufeffdate,name,kind\n2025-02-28,和平紀念日,\n2025/3/1,補行上班,\n2025-04-04,兒童節,假日\n"
+	days, err = ParseCalendar(strings.NewReader(csvText), CalendarFormatCSV)
+	if err != nil {
+		t.Fatalf("解析 CSV 失敗: %v", err)
+	}
+	if len(days) != 3 || days[0].Kind != CalendarHoliday || days[1].Date != "2025-03-01" || days[1].Kind != CalendarWorkday {
+		t.Errorf("CSV 解析結果錯誤: %+v", days)
+	}
+
+	// 政府辦公日曆表只記錄與星期預設不同的日期
+	government := "西元日期,星期,是否放假,備註\n20250225,二,0,\n20250228,五,2,和平紀念日\n20250301,六,2,\n20250208,六,0,補行上班\n"
+	days, err = ParseCalendar(strings.NewReader(government), CalendarFormatCSV)
+	if err != nil {
+		t.Fatalf("解析政府格式失敗: %v", err)
+	}
+	if len(days) != 2 || days[0].Date != "2025-02-08" || days[0].Kind != CalendarWorkday || days[1].Name != "和平紀念日" {
+		t.Errorf("政府格式解析結果錯誤: %+v", days)
+	}
+
+	if _, err := ParseCalendar(strings.NewReader("date,kind\n2025-01-01,節日\n"), CalendarFormatCSV); err == nil {
+		t.Error("無法辨識的類型應解析失敗")
+	}
+}
+
+// TestCalendarWorkingDays 測試工作日計算、全假日請假驗證與依工作日數計算的日期範本
+func TestCalendarWorkingDays(t *testing.T) {
+	storage, cleanup := setupTestStorage(t)
+	defer cleanup()
+
+	err := storage.ImportCalendar([]*CalendarDay{
+		{Date: "2025-01-27", Kind: CalendarHoliday, Name: "彈性放假"},
+		{Date: "2025-01-28", Kind: CalendarHoliday, Name: "農曆除夕"},
+		{Date: "2025-02-08", Kind: CalendarWorkday, Name: "補班"},
+	}, "test.ics", false)
+	if err != nil {
+		t.Fatalf("匯入行事曆失敗: %v", err)
+	}
+
+	cal := storage.Calendar()
+	day := func(s string) time.Time {
+		d, _ := time.Parse("2006-01-02", s)
+		return d
+	}
+	if cal.IsWorkingDay(day("2025-01-27")) || !cal.IsWorkingDay(day("2025-02-08")) || cal.IsWorkingDay(day("2025-02-09")) {
+		t.Error("匯入的假日與補班日應覆蓋星期預設")
+	}
+	if end, _ := cal.AddWorkingDays(day("2025-01-24"), 3); end.Format("2006-01-02") != "2025-01-30" {
+		t.Errorf("1/24 起 3 個工作日應到 1/30，實際 %v", end)
+	}
+
+	req := &LeaveRequest{Name: "王小明", EmployeeID: "A1", StartDate: "2025-01-25", EndDate: "2025-01-28", LeaveType: "近假", Password: "p"}
+	if err := Validate(req, cal); err == nil || !strings.Contains(err.Error(), "皆為假日") {
+		t.Errorf("整段期間都是假日應驗證失敗，實際 %v", err)
+	}
+	req.EndDate = "2025-01-29"
+	if err := Validate(req, cal); err != nil {
+		t.Errorf("期間含工作日應通過驗證，實際 %v", err)
+	}
+
+	// 觸發日 1/15 + 10 天 = 1/25（星期六），順延到 1/29，再請 3 個工作日
+	tmpl := &DateTemplate{OffsetDays: 10, WorkingDays: 3, StartOnWorkday: true}
+	start, end, err := tmpl.Resolve(day("2025-01-15"), cal)
+	if err != nil || start != "2025-01-29" || end != "2025-01-31" {
+		t.Errorf("應為 2025-01-29 ~ 2025-01-31，實際 %s ~ %s (%v)", start, end, err)
+	}
+	if err := (&DateTemplate{OffsetDays: 1, LengthDays: 2, WorkingDays: 2}).Validate(); err == nil {
+		t.Error("length_days 與 working_days 同時指定應驗證失敗")
+	}
+
+	other, cleanupOther := setupTestStorage(t)
+	defer cleanupOther()
+	if other.Calendar().HasData() {
+		t.Error("行事曆應只屬於載入它的 Storage")
+	}
+	if err := Validate(&LeaveRequest{Name: "王小明", EmployeeID: "A1", StartDate: "2025-01-25", EndDate: "2025-01-26", LeaveType: "近假", Password: "p"}, other.Calendar()); err != nil {
+		t.Errorf("未匯入行事曆時不應檢查假日，實際 %v", err)
+	}
+}
@@ -0,0 +1,169 @@
+package models
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Clock 時間來源：排程器與日期規則透過 Clock 取得現在時間、建立計時器與等待，
+// 測試可改用 FakeClock，演練可改用 ShiftedClock
+type Clock interface {
+	Now() time.Time
+	NewTimer(d time.Duration) Timer
+	Sleep(d time.Duration)
+}
+
+// Timer Clock 建立的計時器
+type Timer interface {
+	C() <-chan time.Time
+	Stop() bool
+}
+
+// SystemClock 系統時鐘
+var SystemClock Clock = systemClock{}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time                 { return time.Now() }
+func (systemClock) NewTimer(d time.Duration) Timer { return systemTimer{time.NewTimer(d)} }
+func (systemClock) Sleep(d time.Duration)          { time.Sleep(d) }
+
+type systemTimer struct{ t *time.Timer }
+
+func (t systemTimer) C() <-chan time.Time { return t.t.C }
+func (t systemTimer) Stop() bool          { return t.t.Stop() }
+
+// FakeClock 測試用時鐘：時間只在呼叫 Advance / Set 時前進，到期的計時器隨之觸發
+type FakeClock struct {
+	mu     sync.Mutex
+	now    time.Time
+	timers []*fakeTimer
+}
+
+// NewFakeClock 建立停在 now 的測試時鐘
+func NewFakeClock(now time.Time) *FakeClock {
+	return &FakeClock{now: now}
+}
+
+type fakeTimer struct {
+	clock    *FakeClock
+	deadline time.Time
+	ch       chan time.Time
+}
+
+func (t *fakeTimer) C() <-chan time.Time { return t.ch }
+
+func (t *fakeTimer) Stop() bool {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+	for i, pending := range t.clock.timers {
+		if pending == t {
+			t.clock.timers = append(t.clock.timers[:i], t.clock.timers[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// Now 取得目前的模擬時間
+func (c *FakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// NewTimer 建立在模擬時間經過 d 後觸發的計時器
+func (c *FakeClock) NewTimer(d time.Duration) Timer {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	t := &fakeTimer{clock: c, deadline: c.now.Add(d), ch: make(chan time.Time, 1)}
+	if d <= 0 {
+		t.ch <- c.now
+		return t
+	}
+	c.timers = append(c.timers, t)
+	return t
+}
+
+// Sleep 阻塞到模擬時間經過 d
+func (c *FakeClock) Sleep(d time.Duration) {
+	<-c.NewTimer(d).C()
+}
+
+// Advance 將模擬時間往後推 d，並依到期順序觸發計時器
+func (c *FakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.now = c.now.Add(d)
+	sort.SliceStable(c.timers, func(i, j int) bool { return c.timers[i].deadline.Before(c.timers[j].deadline) })
+	remaining := c.timers[:0]
+	for _, t := range c.timers {
+		if t.deadline.After(c.now) {
+			remaining = append(remaining, t)
+			continue
+		}
+		t.ch <- c.now
+	}
+	c.timers = remaining
+}
+
+// Set 將模擬時間設為 t（不可倒退）
+func (c *FakeClock) Set(t time.Time) {
+	if d := t.Sub(c.Now()); d > 0 {
+		c.Advance(d)
+	}
+}
+
+// BlockUntil 等到至少有 n 個計時器在等待，或超過 timeout（實際時間）；回傳是否達到
+func (c *FakeClock) BlockUntil(n int, timeout time.Duration) bool {
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		c.mu.Lock()
+		waiting := len(c.timers)
+		c.mu.Unlock()
+		if waiting >= n {
+			return true
+		}
+		time.Sleep(time.Millisecond)
+	}
+	return false
+}
+
+// ShiftedClock 演練用時鐘：從指定的模擬時間開始，以 speed 倍速前進；
+// 計時器與等待依倍速縮短實際等待時間
+type ShiftedClock struct {
+	origin    time.Time
+	realStart time.Time
+	speed     float64
+}
+
+// NewShiftedClock 建立從 virtualNow 開始、以 speed 倍速（<= 0 視為 1）前進的時鐘
+func NewShiftedClock(virtualNow time.Time, speed float64) *ShiftedClock {
+	if speed <= 0 {
+		speed = 1
+	}
+	return &ShiftedClock{origin: virtualNow, realStart: time.Now(), speed: speed}
+}
+
+// Now 取得目前的模擬時間
+func (c *ShiftedClock) Now() time.Time {
+	return c.origin.Add(time.Duration(float64(time.Since(c.realStart)) * c.speed))
+}
+
+// NewTimer 建立在模擬時間經過 d 後觸發的計時器
+func (c *ShiftedClock) NewTimer(d time.Duration) Timer {
+	return systemTimer{time.NewTimer(c.real(d))}
+}
+
+// Sleep 等待模擬時間經過 d
+func (c *ShiftedClock) Sleep(d time.Duration) {
+	time.Sleep(c.real(d))
+}
+
+// real 將模擬時間長度換算為實際等待時間
+func (c *ShiftedClock) real(d time.Duration) time.Duration {
+	return time.Duration(float64(d) / c.speed)
+}
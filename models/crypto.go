@@ -0,0 +1,114 @@
+package models
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"strings"
+)
+
+const (
+	// encryptedPasswordPrefix 加密後密碼的前綴，用來區分舊版明文資料
+	encryptedPasswordPrefix = "enc:v1:"
+
+	// EncryptionKeySize AES-256 金鑰長度（位元組）
+	EncryptionKeySize = 32
+
+	// pbkdf2Iterations 由通關密語推導金鑰的迭代次數
+	pbkdf2Iterations = 600000
+)
+
+// SecretKey 密碼加密金鑰來源，Key 與 Passphrase 擇一提供
+type SecretKey struct {
+	Key        []byte // 32 bytes 原始金鑰（環境變數或金鑰檔）
+	Passphrase string // 啟動時輸入的通關密語，會搭配資料庫內的 salt 推導金鑰
+}
+
+// PasswordCipher 以 AES-256-GCM 加解密儲存的請假密碼
+type PasswordCipher struct {
+	aead cipher.AEAD
+}
+
+// NewPasswordCipher 建立 PasswordCipher
+func NewPasswordCipher(key []byte) (*PasswordCipher, error) {
+	if len(key) != EncryptionKeySize {
+		return nil, fmt.Errorf("加密金鑰長度必須為 %d bytes，實際 %d", EncryptionKeySize, len(key))
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("建立加密器失敗: %w", err)
+	}
+
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("建立加密器失敗: %w", err)
+	}
+
+	return &PasswordCipher{aead: aead}, nil
+}
+
+// Encrypt 加密密碼，輸出格式為 enc:v1:<base64(nonce||ciphertext)>
+func (c *PasswordCipher) Encrypt(plaintext string) (string, error) {
+	nonce := make([]byte, c.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("產生 nonce 失敗: %w", err)
+	}
+
+	sealed := c.aead.Seal(nonce, nonce, []byte(plaintext), nil)
+	return encryptedPasswordPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt 解密密碼
+func (c *PasswordCipher) Decrypt(value string) (string, error) {
+	if !IsEncryptedPassword(value) {
+		return "", fmt.Errorf("密碼尚未加密")
+	}
+
+	sealed, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(value, encryptedPasswordPrefix))
+	if err != nil {
+		return "", fmt.Errorf("密碼格式錯誤: %w", err)
+	}
+
+	nonceSize := c.aead.NonceSize()
+	if len(sealed) < nonceSize {
+		return "", fmt.Errorf("密碼格式錯誤: 長度不足")
+	}
+
+	plaintext, err := c.aead.Open(nil, sealed[:nonceSize], sealed[nonceSize:], nil)
+	if err != nil {
+		return "", fmt.Errorf("密碼解密失敗，金鑰可能不正確")
+	}
+
+	return string(plaintext), nil
+}
+
+// IsEncryptedPassword 判斷值是否具有加密格式的前綴；明文也可能以此開頭，
+// 判斷是否確實已加密需以金鑰解密（見 Storage.isCiphertext）
+func IsEncryptedPassword(value string) bool {
+	return strings.HasPrefix(value, encryptedPasswordPrefix)
+}
+
+// DeriveKey 由通關密語與 salt 推導 AES-256 金鑰
+func DeriveKey(passphrase string, salt []byte) ([]byte, error) {
+	if passphrase == "" {
+		return nil, fmt.Errorf("通關密語不可為空")
+	}
+	return pbkdf2.Key(sha256.New, passphrase, salt, pbkdf2Iterations, EncryptionKeySize)
+}
+
+// ParseEncryptionKey 解析 base64 編碼的 32 bytes 金鑰
+func ParseEncryptionKey(encoded string) ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil {
+		return nil, fmt.Errorf("金鑰必須為 base64 編碼: %w", err)
+	}
+	if len(key) != EncryptionKeySize {
+		return nil, fmt.Errorf("金鑰長度必須為 %d bytes，實際 %d", EncryptionKeySize, len(key))
+	}
+	return key, nil
+}
@@ -0,0 +1,140 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// weekdayNames align_weekday 可用的星期名稱
+var weekdayNames = map[string]time.Weekday{
+	"sunday":    time.Sunday,
+	"monday":    time.Monday,
+	"tuesday":   time.Tuesday,
+	"wednesday": time.Wednesday,
+	"thursday":  time.Thursday,
+	"friday":    time.Friday,
+	"saturday":  time.Saturday,
+}
+
+// DateTemplate 相對於排程觸發日的請假日期範本，
+// 例如「觸發日 + 30 天、請 3 天、起點順延到下一個星期一」。
+// WorkingDays 與 StartOnWorkday 依 Storage 載入的行事曆（見 Storage.Calendar）計算工作日。
+type DateTemplate struct {
+	OffsetDays     int    `json:"offset_days"`                // 起點 = 觸發日 + N 天
+	LengthDays     int    `json:"length_days"`                // 請假天數（含起點與終點），與 working_days 擇一
+	WorkingDays    int    `json:"working_days,omitempty"`     // 請假工作日數，終點為起點起算第 N 個工作日
+	AlignWeekday   string `json:"align_weekday,omitempty"`    // 起點不是此星期時順延到下一個（monday … sunday）
+	StartOnWorkday bool   `json:"start_on_workday,omitempty"` // 起點遇假日時順延到下一個工作日
+}
+
+// Validate 檢查範本設定
+func (t *DateTemplate) Validate() error {
+	if t.OffsetDays < 0 {
+		return &ValidationError{Field: "date_template", Message: "offset_days 不可為負數"}
+	}
+	if t.LengthDays < 0 || t.WorkingDays < 0 {
+		return &ValidationError{Field: "date_template", Message: "length_days 與 working_days 不可為負數"}
+	}
+	if (t.LengthDays > 0) == (t.WorkingDays > 0) {
+		return &ValidationError{Field: "date_template", Message: "length_days 與 working_days 必須擇一指定，且至少為 1"}
+	}
+	if t.AlignWeekday != "" {
+		if _, ok := weekdayNames[strings.ToLower(t.AlignWeekday)]; !ok {
+			return &ValidationError{Field: "date_template", Message: "align_weekday 必須為 monday 到 sunday"}
+		}
+	}
+	return nil
+}
+
+// Resolve 以觸發日計算實際的請假起訖日期（YYYY-MM-DD）；cal 為 nil 時只略過週末
+func (t *DateTemplate) Resolve(fireDate time.Time, cal *Calendar) (startDate, endDate string, err error) {
+	if err := t.Validate(); err != nil {
+		return "", "", err
+	}
+
+	y, m, d := fireDate.Date()
+	start := time.Date(y, m, d, 0, 0, 0, 0, fireDate.Location()).AddDate(0, 0, t.OffsetDays)
+
+	if t.AlignWeekday != "" {
+		weekday := weekdayNames[strings.ToLower(t.AlignWeekday)]
+		for start.Weekday() != weekday {
+			start = start.AddDate(0, 0, 1)
+		}
+	}
+
+	if t.StartOnWorkday {
+		start = cal.NextWorkingDay(start)
+	}
+
+	end := start.AddDate(0, 0, t.LengthDays-1)
+	if t.WorkingDays > 0 {
+		if end, err = cal.AddWorkingDays(start, t.WorkingDays); err != nil {
+			return "", "", err
+		}
+	}
+	return start.Format("2006-01-02"), end.Format("2006-01-02"), nil
+}
+
+// String 以易讀文字描述範本
+func (t *DateTemplate) String() string {
+	s := fmt.Sprintf("觸發日 +%d 天、請 %d 天", t.OffsetDays, t.LengthDays)
+	if t.WorkingDays > 0 {
+		s = fmt.Sprintf("觸發日 +%d 天、請 %d 個工作日", t.OffsetDays, t.WorkingDays)
+	}
+	if t.AlignWeekday != "" {
+		s += "、順延到 " + strings.ToLower(t.AlignWeekday)
+	}
+	if t.StartOnWorkday {
+		s += "、遇假日順延"
+	}
+	return s
+}
+
+// encodeDateTemplate 轉為資料庫儲存的 JSON 文字，無範本時為空字串
+func encodeDateTemplate(t *DateTemplate) (string, error) {
+	if t == nil {
+		return "", nil
+	}
+	data, err := json.Marshal(t)
+	if err != nil {
+		return "", fmt.Errorf("日期範本格式錯誤: %w", err)
+	}
+	return string(data), nil
+}
+
+// decodeDateTemplate 解析資料庫儲存的 JSON 文字
+func decodeDateTemplate(text string) (*DateTemplate, error) {
+	if text == "" {
+		return nil, nil
+	}
+	t := &DateTemplate{}
+	if err := json.Unmarshal([]byte(text), t); err != nil {
+		return nil, fmt.Errorf("日期範本格式錯誤: %w", err)
+	}
+	return t, nil
+}
+
+// LeaveRequestFor 以觸發日計算請假資料；使用日期範本時會驗證計算出的實際日期。
+// Password 仍為加密值，提交前需以 Storage.DecryptPassword 解密。
+func (sf *SavedForm) LeaveRequestFor(fireDate time.Time, cal *Calendar) (*LeaveRequest, error) {
+	req := sf.ToLeaveRequest()
+	if sf.DateTemplate == nil {
+		return req, nil
+	}
+
+	var err error
+	req.StartDate, req.EndDate, err = sf.DateTemplate.Resolve(fireDate, cal)
+	if err != nil {
+		return nil, err
+	}
+
+	check := *req
+	check.Password = "-"
+	if err := Validate(&check, cal); err != nil {
+		return nil, fmt.Errorf("日期範本計算結果無效（%s ~ %s）: %w", req.StartDate, req.EndDate, err)
+	}
+
+	return req, nil
+}
@@ -0,0 +1,75 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+// TestDateTemplateResolve 測試日期範本計算
+func TestDateTemplateResolve(t *testing.T) {
+	// 2026-03-04 為星期三
+	fireDate := time.Date(2026, 3, 4, 0, 0, 0, 0, time.Local)
+
+	tests := []struct {
+		name      string
+		template  DateTemplate
+		wantStart string
+		wantEnd   string
+		wantErr   bool
+	}{
+		{"觸發日當天", DateTemplate{OffsetDays: 0, LengthDays: 1}, "2026-03-04", "2026-03-04", false},
+		{"跨月", DateTemplate{OffsetDays: 30, LengthDays: 3}, "2026-04-03", "2026-04-05", false},
+		{"順延到星期一", DateTemplate{OffsetDays: 30, LengthDays: 3, AlignWeekday: "monday"}, "2026-04-06", "2026-04-08", false},
+		{"已是指定星期不順延", DateTemplate{OffsetDays: 5, LengthDays: 2, AlignWeekday: "Monday"}, "2026-03-09", "2026-03-10", false},
+		{"天數為 0", DateTemplate{OffsetDays: 1, LengthDays: 0}, "", "", true},
+		{"負的位移", DateTemplate{OffsetDays: -1, LengthDays: 1}, "", "", true},
+		{"無效的星期", DateTemplate{OffsetDays: 1, LengthDays: 1, AlignWeekday: "funday"}, "", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start, end, err := tt.template.Resolve(fireDate, nil)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("錯誤不符預期: %v", err)
+			}
+			if start != tt.wantStart || end != tt.wantEnd {
+				t.Errorf("預期 %s ~ %s，實際 %s ~ %s", tt.wantStart, tt.wantEnd, start, end)
+			}
+		})
+	}
+}
+
+// TestSchedulerResolvesDateTemplate 測試排程在準備階段以觸發日計算日期範本
+func TestSchedulerResolvesDateTemplate(t *testing.T) {
+	storage, cleanup := setupTestStorage(t)
+	defer cleanup()
+
+	id, err := storage.Save(&SavedForm{
+		Label:        "每期長假",
+		Name:         "測試員工",
+		EmployeeID:   "A12345",
+		LeaveType:    "長假",
+		DateTemplate: &DateTemplate{OffsetDays: 30, LengthDays: 3, AlignWeekday: "monday"},
+		Password:     "secret",
+	})
+	if err != nil {
+		t.Fatalf("儲存失敗: %v", err)
+	}
+
+	form, err := storage.GetByID(id)
+	if err != nil {
+		t.Fatalf("讀取失敗: %v", err)
+	}
+	if form.DateTemplate == nil || form.StartDate != "" {
+		t.Fatalf("應保存日期範本而非固定日期: %+v", form)
+	}
+
+	scheduler := NewScheduler(&ScheduleConfig{SavedFormID: id}, batchTestSubmitter("http://127.0.0.1:1"), storage)
+	req, err := scheduler.loadLeaveRequest(&ScheduleConfig{SavedFormID: id}, time.Date(2026, 3, 4, 0, 0, 0, 0, time.Local))
+	if err != nil {
+		t.Fatalf("準備失敗: %v", err)
+	}
+	if req.StartDate != "2026-04-06" || req.EndDate != "2026-04-08" || req.Password != "secret" {
+		t.Errorf("計算結果不正確: %+v", req)
+	}
+}
@@ -0,0 +1,374 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrEmployeeInUse 員工仍被儲存資料參照，不可刪除
+var ErrEmployeeInUse = errors.New("此員工仍有儲存的請假資料或週期排程，請先刪除相關資料")
+
+// ErrEmployeeMismatch 依員工代號儲存時姓名或密碼與既有員工不同（舊版 API 不可修改共用的員工資料）
+var ErrEmployeeMismatch = errors.New("此員工代號已存在，但姓名或密碼與員工資料不同；請改以 employee_ref 參照員工，密碼請至員工資料更換")
+
+// Employee 員工資料（姓名、員工代號與請假密碼），可被多筆儲存資料共用
+type Employee struct {
+	ID          int64     `json:"id"`
+	Name        string    `json:"name"`
+	EmployeeID  string    `json:"employee_id"`
+	Password    string    `json:"-"`            // 已加密的密碼，僅在提交時解密，永不輸出
+	HasPassword bool      `json:"has_password"` // 是否已設定密碼
+	FormCount   int       `json:"form_count"`   // 參照此員工的儲存資料筆數
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// ValidateEmployee 驗證員工資料必填欄位
+func ValidateEmployee(e *Employee) error {
+	if e.Name == "" {
+		return &ValidationError{Field: "name", Message: "姓名為必填欄位"}
+	}
+	if e.EmployeeID == "" {
+		return &ValidationError{Field: "employee_id", Message: "員工代號為必填欄位"}
+	}
+	return nil
+}
+
+// employeeColumns employees 查詢欄位（順序須與 scanEmployee 一致）
+const employeeColumns = `e.id, e.name, e.employee_id, e.password, e.created_at, e.updated_at,
+	(SELECT COUNT(*) FROM saved_forms f WHERE f.employee_ref = e.id)`
+
+// scanEmployee 讀取一筆 employees 記錄
+func scanEmployee(row rowScanner) (*Employee, error) {
+	e := &Employee{}
+	err := row.Scan(&e.ID, &e.Name, &e.EmployeeID, &e.Password, &e.CreatedAt, &e.UpdatedAt, &e.FormCount)
+	if err != nil {
+		return nil, err
+	}
+	e.HasPassword = e.Password != ""
+	return e, nil
+}
+
+// dbExecer 抽象 *sql.DB 與 *sql.Tx，讓員工寫入可在交易中進行
+type dbExecer interface {
+	Exec(query string, args ...any) (sql.Result, error)
+	QueryRow(query string, args ...any) *sql.Row
+}
+
+// CreateEmployee 新增員工
+func (s *Storage) CreateEmployee(e *Employee) (int64, error) {
+	password, err := s.encryptPassword(e.Password)
+	if err != nil {
+		return 0, fmt.Errorf("員工儲存失敗: %w", err)
+	}
+
+	now := time.Now()
+	result, err := s.db.Exec(`
+		INSERT INTO employees (name, employee_id, password, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?)
+	`, e.Name, e.EmployeeID, password, now, now)
+	if err != nil {
+		if _, lookupErr := s.GetEmployeeByEmployeeID(e.EmployeeID); lookupErr == nil {
+			return 0, fmt.Errorf("員工代號 %s 已存在", e.EmployeeID)
+		}
+		return 0, fmt.Errorf("員工儲存失敗: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("取得 ID 失敗: %w", err)
+	}
+
+	return id, nil
+}
+
+// GetEmployee 根據 ID 取得員工
+func (s *Storage) GetEmployee(id int64) (*Employee, error) {
+	row := s.db.QueryRow("SELECT "+employeeColumns+" FROM employees e WHERE e.id = ?", id)
+
+	e, err := scanEmployee(row)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("找不到指定的員工")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("查詢員工失敗: %w", err)
+	}
+
+	return e, nil
+}
+
+// GetEmployeeByEmployeeID 根據員工代號取得員工
+func (s *Storage) GetEmployeeByEmployeeID(employeeID string) (*Employee, error) {
+	row := s.db.QueryRow("SELECT "+employeeColumns+" FROM employees e WHERE e.employee_id = ?", employeeID)
+
+	e, err := scanEmployee(row)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("找不到指定的員工")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("查詢員工失敗: %w", err)
+	}
+
+	return e, nil
+}
+
+// employeeIDExists 員工代號是否已有員工
+func (s *Storage) employeeIDExists(employeeID string) (bool, error) {
+	var count int
+	if err := s.db.QueryRow("SELECT COUNT(*) FROM employees WHERE employee_id = ?", employeeID).Scan(&count); err != nil {
+		return false, fmt.Errorf("查詢員工失敗: %w", err)
+	}
+	return count > 0, nil
+}
+
+// ListEmployees 依姓名列出所有員工
+func (s *Storage) ListEmployees() ([]*Employee, error) {
+	rows, err := s.db.Query("SELECT " + employeeColumns + " FROM employees e ORDER BY e.name, e.id")
+	if err != nil {
+		return nil, fmt.Errorf("查詢員工失敗: %w", err)
+	}
+	defer rows.Close()
+
+	employees := []*Employee{}
+	for rows.Next() {
+		e, err := scanEmployee(rows)
+		if err != nil {
+			return nil, fmt.Errorf("讀取員工失敗: %w", err)
+		}
+		employees = append(employees, e)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("讀取員工失敗: %w", err)
+	}
+
+	return employees, nil
+}
+
+// UpdateEmployee 更新員工姓名與代號；Password 非空時一併更換密碼
+func (s *Storage) UpdateEmployee(e *Employee) error {
+	password, err := s.encryptPassword(e.Password)
+	if err != nil {
+		return fmt.Errorf("更新員工失敗: %w", err)
+	}
+
+	result, err := s.db.Exec(`
+		UPDATE employees
+		SET name = ?, employee_id = ?, password = CASE WHEN ? = '' THEN password ELSE ? END, updated_at = ?
+		WHERE id = ?
+	`, e.Name, e.EmployeeID, password, password, time.Now(), e.ID)
+	if err != nil {
+		if other, lookupErr := s.GetEmployeeByEmployeeID(e.EmployeeID); lookupErr == nil && other.ID != e.ID {
+			return fmt.Errorf("員工代號 %s 已存在", e.EmployeeID)
+		}
+		return fmt.Errorf("更新員工失敗: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("確認更新結果失敗: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("找不到指定的員工")
+	}
+
+	return nil
+}
+
+// UpdateEmployeePassword 更換員工密碼，所有參照此員工的儲存資料一併生效
+func (s *Storage) UpdateEmployeePassword(id int64, password string) error {
+	encrypted, err := s.cipher.Encrypt(password)
+	if err != nil {
+		return fmt.Errorf("更新密碼失敗: %w", err)
+	}
+
+	result, err := s.db.Exec("UPDATE employees SET password = ?, updated_at = ? WHERE id = ?", encrypted, time.Now(), id)
+	if err != nil {
+		return fmt.Errorf("更新密碼失敗: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("確認更新結果失敗: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("找不到指定的員工")
+	}
+
+	return nil
+}
+
+// DeleteEmployee 刪除員工並移出所有團隊名單；仍被儲存資料參照時回傳 ErrEmployeeInUse
+func (s *Storage) DeleteEmployee(id int64) error {
+	var count int
+	err := s.db.QueryRow(`
+		SELECT (SELECT COUNT(*) FROM saved_forms WHERE employee_ref = ?) +
+			(SELECT COUNT(*) FROM recurring_schedules WHERE employee_ref = ?)
+	`, id, id).Scan(&count)
+	if err != nil {
+		return fmt.Errorf("刪除員工失敗: %w", err)
+	}
+	if count > 0 {
+		return ErrEmployeeInUse
+	}
+
+	// 一併自團隊名單移除
+	if _, err := s.db.Exec("DELETE FROM roster_members WHERE employee_ref = ?", id); err != nil {
+		return fmt.Errorf("刪除員工失敗: %w", err)
+	}
+
+	result, err := s.db.Exec("DELETE FROM employees WHERE id = ?", id)
+	if err != nil {
+		return fmt.Errorf("刪除員工失敗: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("確認刪除結果失敗: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("找不到指定的員工")
+	}
+
+	return nil
+}
+
+// resolveEmployee 取得儲存資料要參照的員工 ID。
+// 已指定 EmployeeRef 時確認員工存在；否則依員工代號尋找或建立員工（相容直接填寫個人資料的舊版 API）。
+// 員工已存在時不會修改共用的姓名與密碼，有提供但不同時回傳 ErrEmployeeMismatch；
+// 密碼只能透過 UpdateEmployeePassword 更換。
+func (s *Storage) resolveEmployee(db dbExecer, form *SavedForm) (int64, error) {
+	if form.EmployeeRef > 0 {
+		var exists int
+		err := db.QueryRow("SELECT COUNT(*) FROM employees WHERE id = ?", form.EmployeeRef).Scan(&exists)
+		if err != nil {
+			return 0, fmt.Errorf("查詢員工失敗: %w", err)
+		}
+		if exists == 0 {
+			return 0, fmt.Errorf("找不到指定的員工")
+		}
+		return form.EmployeeRef, nil
+	}
+
+	if form.EmployeeID == "" {
+		return 0, fmt.Errorf("未指定員工")
+	}
+
+	var id int64
+	var name, stored string
+	err := db.QueryRow("SELECT id, name, password FROM employees WHERE employee_id = ?", form.EmployeeID).Scan(&id, &name, &stored)
+	if err == sql.ErrNoRows {
+		password, err := s.encryptPassword(form.Password)
+		if err != nil {
+			return 0, err
+		}
+		now := time.Now()
+		result, err := db.Exec(`
+			INSERT INTO employees (name, employee_id, password, created_at, updated_at)
+			VALUES (?, ?, ?, ?, ?)
+		`, form.Name, form.EmployeeID, password, now, now)
+		if err != nil {
+			return 0, fmt.Errorf("建立員工失敗: %w", err)
+		}
+		return result.LastInsertId()
+	}
+	if err != nil {
+		return 0, fmt.Errorf("查詢員工失敗: %w", err)
+	}
+
+	if form.Name != "" && form.Name != name {
+		return 0, ErrEmployeeMismatch
+	}
+	if form.Password != "" && !s.samePassword(form.Password, stored) {
+		return 0, ErrEmployeeMismatch
+	}
+
+	return id, nil
+}
+
+// samePassword 比對輸入的密碼（明文，或由既有資料帶入的密文）與員工儲存的密文是否相同
+func (s *Storage) samePassword(input, stored string) bool {
+	if stored == "" {
+		return false
+	}
+	if input == stored {
+		return true
+	}
+	if plain, err := s.cipher.Decrypt(input); err == nil {
+		input = plain
+	}
+	plain, err := s.cipher.Decrypt(stored)
+	return err == nil && plain == input
+}
+
+// FillEmployee 依 EmployeeRef 帶入員工的姓名、代號與密碼
+func (s *Storage) FillEmployee(form *SavedForm) error {
+	if form.EmployeeRef <= 0 {
+		return nil
+	}
+
+	e, err := s.GetEmployee(form.EmployeeRef)
+	if err != nil {
+		return err
+	}
+
+	form.Name = e.Name
+	form.EmployeeID = e.EmployeeID
+	form.Password = e.Password
+	form.HasPassword = e.HasPassword
+	return nil
+}
+
+// ValidateSavedForm 驗證儲存資料，日期範本以今天試算。
+// 參照員工時密碼可稍後於員工資料設定；依員工代號填寫時只有會建立新員工才必須提供密碼
+func (s *Storage) ValidateSavedForm(form *SavedForm) error {
+	if form.Label == "" {
+		return &ValidationError{Field: "label", Message: "識別標籤為必填欄位"}
+	}
+
+	req := form.ToLeaveRequest()
+	if form.EmployeeRef > 0 && req.Password == "" {
+		req.Password = "-"
+	}
+	if form.EmployeeRef == 0 && req.Password == "" && form.EmployeeID != "" {
+		exists, err := s.employeeIDExists(form.EmployeeID)
+		if err != nil {
+			return err
+		}
+		if exists {
+			req.Password = "-"
+		}
+	}
+
+	// 日期範本以今天為觸發日試算，確認範本本身可產生有效日期
+	if form.DateTemplate != nil {
+		var err error
+		if req.StartDate, req.EndDate, err = form.DateTemplate.Resolve(s.Clock().Now(), s.Calendar()); err != nil {
+			return err
+		}
+	}
+	return Validate(req, s.Calendar())
+}
+
+// FillLeaveRequest 以員工資料帶入請假申請的姓名、代號與解密後的密碼，僅供提交流程使用
+func (s *Storage) FillLeaveRequest(req *LeaveRequest, employeeRef int64) error {
+	e, err := s.GetEmployee(employeeRef)
+	if err != nil {
+		return err
+	}
+
+	password, err := s.DecryptPassword(e.Password)
+	if err != nil {
+		return fmt.Errorf("員工 %s %w", e.Name, err)
+	}
+
+	req.Name = e.Name
+	req.EmployeeID = e.EmployeeID
+	req.Password = password
+	return nil
+}
@@ -0,0 +1,119 @@
+package models
+
+import (
+	"runtime"
+	"sort"
+	"time"
+)
+
+// 送出前的最後逼近：計時器在 fireApproachWindow 前醒來後改以短暫休眠逼近，
+// 剩下 fireSpinWindow 時忙等（單一計時器在負載下可能晚數毫秒才喚醒）
+const (
+	fireApproachWindow = 20 * time.Millisecond
+	fireSpinWindow     = time.Millisecond
+)
+
+// fireOnTimeThreshold 送出誤差在此範圍內視為準時
+const fireOnTimeThreshold = time.Millisecond
+
+// fireTiming 一次送出的預計與實際時間
+type fireTiming struct {
+	intended time.Time
+	actual   time.Time
+}
+
+// delay 實際送出時間與預計的差距，正值表示晚於預計
+func (f *fireTiming) delay() time.Duration {
+	return f.actual.Sub(f.intended)
+}
+
+// apply 將送出時間寫入提交記錄
+func (f *fireTiming) apply(rec *SubmissionRecord) {
+	intended, actual := f.intended, f.actual
+	delay := f.delay().Microseconds()
+	rec.IntendedAt, rec.SentAt, rec.FireDelayUs = &intended, &actual, &delay
+}
+
+// waitToFire 等到送出時間，回傳 false 表示 job 已被取消。
+// 先以 waitUntil 的計時器等到 fireApproachWindow 前，再以每次休眠剩餘時間一半的方式逼近，
+// 最後 fireSpinWindow 忙等；使用測試或演練時鐘時只以計時器等待
+func (s *Scheduler) waitToFire(job *scheduleJob, sendTime time.Time) bool {
+	if s.clock != SystemClock {
+		return s.waitUntil(job, sendTime)
+	}
+	if !s.waitUntil(job, sendTime.Add(-fireApproachWindow)) {
+		return false
+	}
+
+	for {
+		if job.ctx.Err() != nil {
+			return false
+		}
+		remaining := sendTime.Sub(time.Now().Round(0))
+		switch {
+		case remaining <= 0:
+			return true
+		case remaining > fireSpinWindow:
+			time.Sleep(remaining / 2)
+		default:
+			runtime.Gosched()
+		}
+	}
+}
+
+// FirePrecisionStats 排程送出精確度統計（誤差以微秒表示，正值表示晚於預計）
+type FirePrecisionStats struct {
+	Count      int   `json:"count"`       // 有送出時間的記錄數
+	SampleSize int   `json:"sample_size"` // 統計範圍內的記錄數（含未實際送出的記錄）
+	OnTime     int   `json:"on_time"`     // 誤差在 ±1 毫秒內的次數
+	LastUs     int64 `json:"last_us"`     // 最近一次送出的誤差
+	MeanUs     int64 `json:"mean_us"`
+	MinUs      int64 `json:"min_us"`
+	MaxUs      int64 `json:"max_us"`
+	MeanAbsUs  int64 `json:"mean_abs_us"` // 以下為絕對誤差
+	P50AbsUs   int64 `json:"p50_abs_us"`
+	P95AbsUs   int64 `json:"p95_abs_us"`
+	MaxAbsUs   int64 `json:"max_abs_us"`
+}
+
+// SummarizeFirePrecision 統計提交記錄（新到舊）中有送出時間的記錄，沒有任何一筆時回傳 nil
+func SummarizeFirePrecision(records []*SubmissionRecord) *FirePrecisionStats {
+	var delays, abs []int64
+	for _, rec := range records {
+		if rec.FireDelayUs == nil {
+			continue
+		}
+		d := *rec.FireDelayUs
+		delays = append(delays, d)
+		abs = append(abs, max(d, -d))
+	}
+	if len(delays) == 0 {
+		return nil
+	}
+
+	stats := &FirePrecisionStats{Count: len(delays), SampleSize: len(records), LastUs: delays[0], MinUs: delays[0], MaxUs: delays[0]}
+	var sum, sumAbs int64
+	for i, d := range delays {
+		sum += d
+		sumAbs += abs[i]
+		stats.MinUs = min(stats.MinUs, d)
+		stats.MaxUs = max(stats.MaxUs, d)
+		if abs[i] <= fireOnTimeThreshold.Microseconds() {
+			stats.OnTime++
+		}
+	}
+	stats.MeanUs = sum / int64(len(delays))
+	stats.MeanAbsUs = sumAbs / int64(len(delays))
+
+	sort.Slice(abs, func(i, j int) bool { return abs[i] < abs[j] })
+	stats.P50AbsUs = percentile(abs, 50)
+	stats.P95AbsUs = percentile(abs, 95)
+	stats.MaxAbsUs = abs[len(abs)-1]
+	return stats
+}
+
+// percentile 已排序資料的第 p 百分位數（最近秩法）
+func percentile(sorted []int64, p int) int64 {
+	rank := (p*len(sorted) + 99) / 100
+	return sorted[max(rank, 1)-1]
+}
@@ -0,0 +1,194 @@
+package models
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+// formCheckTimeout 單次表單結構檢查的時間上限
+const formCheckTimeout = 30 * time.Second
+
+// FormMonitor 定期讀取表單結構，與儲存的指紋或 entry_map 不符時通知
+type FormMonitor struct {
+	submitter       *GoogleFormSubmitter
+	storage         *Storage
+	intervalMinutes int
+	cron            *cron.Cron
+	logger          *log.Logger
+
+	mu        sync.Mutex
+	last      *FormStructureCheck
+	lastAlert string // 上次通知的結構指紋與訊息，相同的變更只通知一次
+
+	// OnDrift 偵測到變更時呼叫（例如推送排程通知）
+	OnDrift func(check *FormStructureCheck)
+}
+
+// NewFormMonitor 建立表單結構監看
+func NewFormMonitor(submitter *GoogleFormSubmitter, storage *Storage, intervalMinutes int) *FormMonitor {
+	return &FormMonitor{
+		submitter:       submitter,
+		storage:         storage,
+		intervalMinutes: intervalMinutes,
+		logger:          log.New(os.Stdout, "[FormWatch] ", log.LstdFlags),
+	}
+}
+
+// Start 啟動定期檢查並立即檢查一次（intervalMinutes <= 0 時不啟動）
+func (m *FormMonitor) Start() error {
+	if m.intervalMinutes <= 0 {
+		return nil
+	}
+
+	m.cron = cron.New()
+	if _, err := m.cron.AddFunc(fmt.Sprintf("@every %dm", m.intervalMinutes), m.RunOnce); err != nil {
+		return fmt.Errorf("設定表單結構檢查失敗: %w", err)
+	}
+	m.cron.Start()
+	m.logger.Printf("表單結構檢查已啟動：每 %d 分鐘檢查一次", m.intervalMinutes)
+	go m.RunOnce()
+
+	return nil
+}
+
+// Stop 停止定期檢查
+func (m *FormMonitor) Stop() {
+	if m.cron != nil {
+		m.cron.Stop()
+	}
+}
+
+// RunOnce 執行一次檢查並記錄結果
+func (m *FormMonitor) RunOnce() {
+	ctx, cancel := context.WithTimeout(context.Background(), formCheckTimeout)
+	defer cancel()
+
+	check := m.Check(ctx)
+	if check.Status == FormCheckDrift {
+		m.logger.Printf("警告: 表單結構已變更: %s", check.Message)
+	} else {
+		m.logger.Printf("表單結構檢查: %s", check.Message)
+	}
+}
+
+// Check 讀取目前的表單結構並比對指紋與 entry_map；尚未建立指紋時回報 no_baseline，
+// 指紋只由 AcceptCurrent 與 Remap 建立
+func (m *FormMonitor) Check(ctx context.Context) *FormStructureCheck {
+	check := m.check(ctx)
+
+	m.mu.Lock()
+	m.last = check
+	alert := ""
+	if check.Status == FormCheckDrift {
+		alert = check.Current.Fingerprint + "|" + check.Message
+	}
+	notify := alert != "" && alert != m.lastAlert
+	m.lastAlert = alert
+	m.mu.Unlock()
+
+	if notify && m.OnDrift != nil {
+		m.OnDrift(check)
+	}
+	return check
+}
+
+func (m *FormMonitor) check(ctx context.Context) *FormStructureCheck {
+	current, timing, err := FetchFormStructure(ctx, m.submitter.HTTPClient, m.submitter.FormURL)
+	if err != nil {
+		return &FormStructureCheck{CheckedAt: time.Now(), Status: FormCheckUnavailable, Message: err.Error(), Timing: timing}
+	}
+	baseline, err := m.storage.GetFormFingerprint(m.submitter.FormURL)
+	if err != nil {
+		return &FormStructureCheck{CheckedAt: time.Now(), Status: FormCheckUnavailable, Message: err.Error(), Current: current, Timing: timing}
+	}
+
+	check := CompareFormStructure(current, baseline, m.submitter.EntryMapSnapshot())
+	check.Timing = timing
+	return check
+}
+
+// Last 最近一次檢查的結果，尚未檢查時為 nil
+func (m *FormMonitor) Last() *FormStructureCheck {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.last
+}
+
+// Baseline 取得目前表單網址儲存的指紋，尚未建立時為 nil
+func (m *FormMonitor) Baseline() (*FormFingerprint, error) {
+	return m.storage.GetFormFingerprint(m.submitter.FormURL)
+}
+
+// EntryMap 取得目前使用的 entry_map
+func (m *FormMonitor) EntryMap() map[string]string {
+	return m.submitter.EntryMapSnapshot()
+}
+
+// AcceptCurrent 以目前的表單結構取代指紋（保留重新對應的 entry_map）
+func (m *FormMonitor) AcceptCurrent(ctx context.Context) (*FormStructureCheck, error) {
+	current, _, err := FetchFormStructure(ctx, m.submitter.HTTPClient, m.submitter.FormURL)
+	if err != nil {
+		return nil, err
+	}
+	fp, err := m.storage.GetFormFingerprint(m.submitter.FormURL)
+	if err != nil {
+		return nil, err
+	}
+	if fp == nil {
+		fp = &FormFingerprint{FormURL: m.submitter.FormURL}
+	}
+	fp.Structure, fp.CapturedAt = current, time.Time{}
+	if err := m.storage.SaveFormFingerprint(fp); err != nil {
+		return nil, err
+	}
+	m.logger.Printf("已更新表單結構指紋: %s", current.Fingerprint)
+	return m.Check(ctx), nil
+}
+
+// Remap 以新的 entry_map 重新對應表單欄位：每個欄位的 entry ID 都必須在目前的表單上，
+// 套用後以目前的結構更新指紋，並儲存 entry_map 供下次啟動時覆蓋 config.json
+func (m *FormMonitor) Remap(ctx context.Context, entryMap map[string]string) (*FormStructureCheck, error) {
+	current, _, err := FetchFormStructure(ctx, m.submitter.HTTPClient, m.submitter.FormURL)
+	if err != nil {
+		return nil, err
+	}
+
+	merged := m.submitter.EntryMapSnapshot()
+	for field, entryID := range entryMap {
+		if _, ok := merged[field]; !ok {
+			return nil, &ValidationError{Field: field, Message: fmt.Sprintf("entry_map 沒有 %s 欄位", field)}
+		}
+		merged[field] = entryID
+	}
+	fields := make([]string, 0, len(merged))
+	for field := range merged {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	used := make(map[string]string)
+	for _, field := range fields {
+		entryID := merged[field]
+		if current.Question(entryID) == nil {
+			return nil, &ValidationError{Field: field, Message: fmt.Sprintf("表單上找不到 %s（%s）", entryID, field)}
+		}
+		if other, ok := used[entryID]; ok {
+			return nil, &ValidationError{Field: field, Message: fmt.Sprintf("%s 已對應到 %s", entryID, other)}
+		}
+		used[entryID] = field
+	}
+
+	fp := &FormFingerprint{FormURL: m.submitter.FormURL, Structure: current, EntryMap: merged}
+	if err := m.storage.SaveFormFingerprint(fp); err != nil {
+		return nil, err
+	}
+	m.submitter.UpdateEntryMap(merged)
+	m.logger.Printf("已重新對應表單欄位並更新指紋: %s", current.Fingerprint)
+	return m.Check(ctx), nil
+}
@@ -0,0 +1,489 @@
+package models
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// 表單結構與指紋的差異種類
+const (
+	FormChangeRemoved        = "removed"         // 題目被刪除
+	FormChangeAdded          = "added"           // 新增題目
+	FormChangeEntryChanged   = "entry_changed"   // 同一題目的 entry ID 改變
+	FormChangeTypeChanged    = "type_changed"    // 題目類型改變
+	FormChangeChoicesChanged = "choices_changed" // 選項改變
+)
+
+// 表單結構檢查的結果
+const (
+	FormCheckOK          = "ok"          // 與指紋相符，entry_map 的欄位皆在表單上
+	FormCheckDrift       = "drift"       // 與指紋不符或 entry_map 的欄位不在表單上
+	FormCheckNoBaseline  = "no_baseline" // 尚未建立指紋
+	FormCheckUnavailable = "unavailable" // 表單未開放或無法讀取
+)
+
+// ErrFormClosed 表單目前未開放，viewform 頁面沒有題目
+var ErrFormClosed = errors.New("表單目前未開放，無法讀取題目")
+
+// formQuestionTypes FB_PUBLIC_LOAD_DATA_ 中的題目類型代碼
+var formQuestionTypes = map[int]string{
+	0:  "short_answer",
+	1:  "paragraph",
+	2:  "multiple_choice",
+	3:  "dropdown",
+	4:  "checkboxes",
+	5:  "linear_scale",
+	7:  "grid",
+	9:  "date",
+	10: "time",
+	13: "file_upload",
+}
+
+// FormQuestion 表單上的一個題目；方格題等有多個 entry 的題目每列各為一筆
+type FormQuestion struct {
+	ItemID   string   `json:"item_id"`
+	Title    string   `json:"title"`
+	Type     string   `json:"type"`
+	EntryID  string   `json:"entry_id"` // entry.N
+	Choices  []string `json:"choices,omitempty"`
+	Required bool     `json:"required"`
+}
+
+// FormStructure 表單結構，Fingerprint 由題目 ID、entry ID、類型與選項計算（不含題目文字與順序）
+type FormStructure struct {
+	Title       string         `json:"title"`
+	Questions   []FormQuestion `json:"questions"`
+	Fingerprint string         `json:"fingerprint"`
+}
+
+// Question 依 entry ID 取得題目，不存在時為 nil
+func (f *FormStructure) Question(entryID string) *FormQuestion {
+	for i := range f.Questions {
+		if f.Questions[i].EntryID == entryID {
+			return &f.Questions[i]
+		}
+	}
+	return nil
+}
+
+// computeFingerprint 計算結構指紋
+func (f *FormStructure) computeFingerprint() string {
+	lines := make([]string, 0, len(f.Questions))
+	for _, q := range f.Questions {
+		lines = append(lines, strings.Join([]string{q.ItemID, q.EntryID, q.Type, strings.Join(q.Choices, "\x1f")}, "|"))
+	}
+	sort.Strings(lines)
+	sum := sha256.Sum256([]byte(strings.Join(lines, "\n")))
+	return hex.EncodeToString(sum[:8])
+}
+
+// ParseFormStructure 從 viewform 頁面的 FB_PUBLIC_LOAD_DATA_ 解析題目與選項
+func ParseFormStructure(page string) (*FormStructure, error) {
+	const marker = "FB_PUBLIC_LOAD_DATA_"
+	i := strings.Index(page, marker)
+	if i < 0 {
+		return nil, fmt.Errorf("頁面沒有表單結構資料（%s）", marker)
+	}
+	rest := page[i+len(marker):]
+	j := strings.IndexByte(rest, '[')
+	if j < 0 {
+		return nil, fmt.Errorf("頁面沒有表單結構資料（%s）", marker)
+	}
+
+	dec := json.NewDecoder(strings.NewReader(rest[j:]))
+	dec.UseNumber()
+	var data []any
+	if err := dec.Decode(&data); err != nil {
+		return nil, fmt.Errorf("解析表單結構失敗: %w", err)
+	}
+	info, _ := jsonIndex(data, 1).([]any)
+	items, _ := jsonIndex(info, 1).([]any)
+	if items == nil {
+		return nil, fmt.Errorf("表單結構資料沒有題目")
+	}
+
+	structure := &FormStructure{Questions: []FormQuestion{}}
+	structure.Title, _ = jsonIndex(data, 3).(string)
+	if structure.Title == "" {
+		structure.Title, _ = jsonIndex(info, 8).(string)
+	}
+	for _, raw := range items {
+		item, _ := raw.([]any)
+		entries, _ := jsonIndex(item, 4).([]any)
+		if len(entries) == 0 {
+			// 標題、段落、圖片等沒有回答欄位
+			continue
+		}
+		title, _ := jsonIndex(item, 1).(string)
+		code, _ := jsonIndex(item, 3).(json.Number)
+		typeCode, _ := code.Int64()
+		typ, ok := formQuestionTypes[int(typeCode)]
+		if !ok {
+			typ = fmt.Sprintf("type_%d", typeCode)
+		}
+
+		for _, rawEntry := range entries {
+			entry, _ := rawEntry.([]any)
+			if jsonIndex(entry, 0) == nil {
+				continue
+			}
+			q := FormQuestion{
+				ItemID:   fmt.Sprint(jsonIndex(item, 0)),
+				Title:    title,
+				Type:     typ,
+				EntryID:  fmt.Sprintf("entry.%v", jsonIndex(entry, 0)),
+				Required: fmt.Sprint(jsonIndex(entry, 2)) == "1",
+			}
+			choices, _ := jsonIndex(entry, 1).([]any)
+			for _, rawChoice := range choices {
+				choice, _ := rawChoice.([]any)
+				if label, ok := jsonIndex(choice, 0).(string); ok {
+					q.Choices = append(q.Choices, label)
+				}
+			}
+			// 方格題每列一個 entry，列名在第 4 個元素
+			if row, ok := jsonIndex(entry, 3).([]any); ok && len(entries) > 1 {
+				if label, ok := jsonIndex(row, 0).(string); ok {
+					q.Title += " - " + label
+				}
+			}
+			structure.Questions = append(structure.Questions, q)
+		}
+	}
+	structure.Fingerprint = structure.computeFingerprint()
+	return structure, nil
+}
+
+// jsonIndex 安全地取得 JSON 陣列的元素，超出範圍時為 nil
+func jsonIndex(a []any, i int) any {
+	if i < len(a) {
+		return a[i]
+	}
+	return nil
+}
+
+// FetchFormStructure 讀取 formURL 對應的 viewform 頁面並解析表單結構，一併回傳讀取頁面的網路各階段耗時
+// （未送出請求時為 nil）；表單未開放時回傳 ErrFormClosed
+func FetchFormStructure(ctx context.Context, client *http.Client, formURL string) (*FormStructure, *RequestTiming, error) {
+	viewURL, ok := viewFormURL(formURL)
+	if !ok {
+		return nil, nil, fmt.Errorf("表單網址不是 formResponse，無法推得 viewform 頁面")
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, viewURL, nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("表單網址格式錯誤: %w", err)
+	}
+	req, trace := traceRequest(req)
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, trace.Timing(), fmt.Errorf("讀取表單頁面失敗: %w", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 4<<20))
+	timing := trace.Timing()
+	if err != nil {
+		return nil, timing, fmt.Errorf("讀取表單頁面失敗: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, timing, fmt.Errorf("表單頁面回應 HTTP %d", resp.StatusCode)
+	}
+	if !formAccepting(resp, string(body)) {
+		return nil, timing, ErrFormClosed
+	}
+	structure, err := ParseFormStructure(string(body))
+	return structure, timing, err
+}
+
+// FormChange 目前表單與指紋的一項差異
+type FormChange struct {
+	Kind    string `json:"kind"`
+	EntryID string `json:"entry_id"`
+	Title   string `json:"title"`
+	Detail  string `json:"detail,omitempty"`
+}
+
+// DiffFormStructure 以 entry ID 比對指紋與目前的表單結構；entry ID 不同但題目 ID 相同時視為 entry 改變
+func DiffFormStructure(baseline, current *FormStructure) []FormChange {
+	changes := []FormChange{}
+	matched := make(map[string]bool)
+	for _, old := range baseline.Questions {
+		now := current.Question(old.EntryID)
+		if now == nil {
+			if moved := current.questionByItem(old.ItemID, baseline); moved != nil {
+				matched[moved.EntryID] = true
+				changes = append(changes, FormChange{
+					Kind: FormChangeEntryChanged, EntryID: old.EntryID, Title: old.Title,
+					Detail: fmt.Sprintf("%s → %s", old.EntryID, moved.EntryID),
+				})
+				continue
+			}
+			changes = append(changes, FormChange{Kind: FormChangeRemoved, EntryID: old.EntryID, Title: old.Title})
+			continue
+		}
+		matched[now.EntryID] = true
+		if now.Type != old.Type {
+			changes = append(changes, FormChange{
+				Kind: FormChangeTypeChanged, EntryID: old.EntryID, Title: now.Title,
+				Detail: fmt.Sprintf("%s → %s", old.Type, now.Type),
+			})
+		}
+		if detail := diffChoices(old.Choices, now.Choices); detail != "" {
+			changes = append(changes, FormChange{Kind: FormChangeChoicesChanged, EntryID: old.EntryID, Title: now.Title, Detail: detail})
+		}
+	}
+	for _, q := range current.Questions {
+		if !matched[q.EntryID] {
+			changes = append(changes, FormChange{Kind: FormChangeAdded, EntryID: q.EntryID, Title: q.Title})
+		}
+	}
+	return changes
+}
+
+// questionByItem 找出題目 ID 相同、且 entry ID 不在指紋中的題目
+func (f *FormStructure) questionByItem(itemID string, baseline *FormStructure) *FormQuestion {
+	for i := range f.Questions {
+		q := &f.Questions[i]
+		if q.ItemID == itemID && baseline.Question(q.EntryID) == nil {
+			return q
+		}
+	}
+	return nil
+}
+
+// diffChoices 描述選項的增減，相同時回傳空字串
+func diffChoices(old, now []string) string {
+	var removed, added []string
+	for _, c := range old {
+		if !containsString(now, c) {
+			removed = append(removed, c)
+		}
+	}
+	for _, c := range now {
+		if !containsString(old, c) {
+			added = append(added, c)
+		}
+	}
+	var parts []string
+	if len(removed) > 0 {
+		parts = append(parts, "少了 "+strings.Join(removed, "、"))
+	}
+	if len(added) > 0 {
+		parts = append(parts, "多了 "+strings.Join(added, "、"))
+	}
+	return strings.Join(parts, "；")
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// fieldTitleHints 沒有指紋可參考時，以題目文字猜測 entry_map 欄位對應的題目
+var fieldTitleHints = map[string][]string{
+	"name":        {"姓名", "名字", "name"},
+	"employee_id": {"員工編號", "工號", "編號", "employee"},
+	"start_date":  {"起始", "起點", "開始", "start"},
+	"end_date":    {"結束", "終點", "end"},
+	"leave_type":  {"假別", "leave type"},
+	"password":    {"密碼", "password"},
+}
+
+// FieldMapping entry_map 的一個欄位在目前表單上的對應與建議
+type FieldMapping struct {
+	Field     string `json:"field"`
+	EntryID   string `json:"entry_id"`            // entry_map 目前的 entry ID
+	Found     bool   `json:"found"`               // 目前表單上是否有此 entry ID
+	Title     string `json:"title,omitempty"`     // 找到時為對應的題目
+	Suggested string `json:"suggested,omitempty"` // 找不到時建議的 entry ID
+	Reason    string `json:"reason,omitempty"`    // 建議的依據
+}
+
+// MapEntryFields 比對 entry_map 與目前的表單結構；找不到的欄位依序以指紋中同一題目 ID、
+// 相同題目文字或題目文字的關鍵字建議新的 entry ID（baseline 可為 nil）
+func MapEntryFields(entryMap map[string]string, baseline, current *FormStructure) []FieldMapping {
+	fields := make([]string, 0, len(entryMap))
+	for field := range entryMap {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	used := make(map[string]bool)
+	mappings := make([]FieldMapping, 0, len(fields))
+	for _, field := range fields {
+		m := FieldMapping{Field: field, EntryID: entryMap[field]}
+		if q := current.Question(m.EntryID); q != nil {
+			m.Found, m.Title = true, q.Title
+			used[q.EntryID] = true
+		}
+		mappings = append(mappings, m)
+	}
+
+	for i := range mappings {
+		m := &mappings[i]
+		if m.Found {
+			continue
+		}
+		pick := func(q *FormQuestion, reason string) bool {
+			if q == nil || used[q.EntryID] {
+				return false
+			}
+			m.Suggested, m.Reason = q.EntryID, reason
+			used[q.EntryID] = true
+			return true
+		}
+		if baseline != nil {
+			if old := baseline.Question(m.EntryID); old != nil {
+				if pick(current.findQuestion(func(q *FormQuestion) bool { return q.ItemID == old.ItemID }), "同一題目") ||
+					pick(current.findQuestion(func(q *FormQuestion) bool { return q.Title == old.Title }), "題目文字相同：「"+old.Title+"」") {
+					continue
+				}
+			}
+		}
+		for _, hint := range fieldTitleHints[m.Field] {
+			if pick(current.findQuestion(func(q *FormQuestion) bool {
+				return !used[q.EntryID] && strings.Contains(strings.ToLower(q.Title), hint)
+			}), "題目包含「"+hint+"」") {
+				break
+			}
+		}
+	}
+	return mappings
+}
+
+// findQuestion 回傳第一個符合條件的題目
+func (f *FormStructure) findQuestion(match func(*FormQuestion) bool) *FormQuestion {
+	for i := range f.Questions {
+		if match(&f.Questions[i]) {
+			return &f.Questions[i]
+		}
+	}
+	return nil
+}
+
+// FormStructureCheck 一次表單結構檢查的結果
+type FormStructureCheck struct {
+	CheckedAt time.Time      `json:"checked_at"`
+	Status    string         `json:"status"`
+	Message   string         `json:"message"`
+	Current   *FormStructure `json:"current,omitempty"`
+	Changes   []FormChange   `json:"changes,omitempty"`
+	Mappings  []FieldMapping `json:"mappings,omitempty"`
+
+	Timing *RequestTiming `json:"timing,omitempty"` // 讀取表單頁面的網路各階段耗時
+}
+
+// CompareFormStructure 比對目前的表單結構與儲存的指紋（可為 nil）及 entry_map
+func CompareFormStructure(current *FormStructure, baseline *FormFingerprint, entryMap map[string]string) *FormStructureCheck {
+	check := &FormStructureCheck{CheckedAt: time.Now(), Current: current}
+	var base *FormStructure
+	if baseline != nil {
+		base = baseline.Structure
+	}
+	check.Mappings = MapEntryFields(entryMap, base, current)
+
+	var missing []string
+	for _, m := range check.Mappings {
+		if m.EntryID != "" && !m.Found {
+			missing = append(missing, fmt.Sprintf("%s（%s）", m.Field, m.EntryID))
+		}
+	}
+	if base != nil && base.Fingerprint != current.Fingerprint {
+		check.Changes = DiffFormStructure(base, current)
+	}
+
+	var problems []string
+	if len(missing) > 0 {
+		problems = append(problems, "表單上找不到 "+strings.Join(missing, "、"))
+	}
+	if len(check.Changes) > 0 {
+		problems = append(problems, fmt.Sprintf("與 %s 建立的指紋相比有 %d 項變更", baseline.CapturedAt.Format("2006-01-02 15:04"), len(check.Changes)))
+	}
+	switch {
+	case len(problems) > 0:
+		check.Status, check.Message = FormCheckDrift, strings.Join(problems, "；")
+	case base == nil:
+		check.Status, check.Message = FormCheckNoBaseline, fmt.Sprintf("尚未建立表單結構指紋（目前 %d 題）", len(current.Questions))
+	default:
+		check.Status, check.Message = FormCheckOK, fmt.Sprintf("與指紋相符（%d 題），entry_map 的欄位皆在表單上", len(current.Questions))
+	}
+	return check
+}
+
+// FormFingerprint 儲存的表單結構指紋，每個表單網址一筆
+type FormFingerprint struct {
+	FormURL   string         `json:"form_url"`
+	Structure *FormStructure `json:"structure"`
+	// EntryMap 重新對應後的 entry_map，啟動時覆蓋 config.json；空表示使用 config.json
+	EntryMap   map[string]string `json:"entry_map,omitempty"`
+	CapturedAt time.Time         `json:"captured_at"`
+	UpdatedAt  time.Time         `json:"updated_at"`
+}
+
+// GetFormFingerprint 取得表單網址的指紋，尚未建立時回傳 nil
+func (s *Storage) GetFormFingerprint(formURL string) (*FormFingerprint, error) {
+	fp := &FormFingerprint{FormURL: formURL}
+	var structure, entryMap string
+	err := s.db.QueryRow("SELECT structure, entry_map, captured_at, updated_at FROM form_fingerprints WHERE form_url = ?", formURL).
+		Scan(&structure, &entryMap, &fp.CapturedAt, &fp.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("查詢表單指紋失敗: %w", err)
+	}
+	if err := json.Unmarshal([]byte(structure), &fp.Structure); err != nil {
+		return nil, fmt.Errorf("讀取表單指紋失敗: %w", err)
+	}
+	if entryMap != "" {
+		if err := json.Unmarshal([]byte(entryMap), &fp.EntryMap); err != nil {
+			return nil, fmt.Errorf("讀取重新對應的 entry_map 失敗: %w", err)
+		}
+	}
+	return fp, nil
+}
+
+// SaveFormFingerprint 新增或更新表單網址的指紋與重新對應的 entry_map
+func (s *Storage) SaveFormFingerprint(fp *FormFingerprint) error {
+	structure, err := json.Marshal(fp.Structure)
+	if err != nil {
+		return fmt.Errorf("儲存表單指紋失敗: %w", err)
+	}
+	entryMap := ""
+	if len(fp.EntryMap) > 0 {
+		data, err := json.Marshal(fp.EntryMap)
+		if err != nil {
+			return fmt.Errorf("儲存表單指紋失敗: %w", err)
+		}
+		entryMap = string(data)
+	}
+	now := time.Now()
+	if fp.CapturedAt.IsZero() {
+		fp.CapturedAt = now
+	}
+	fp.UpdatedAt = now
+	_, err = s.db.Exec(`
+		INSERT INTO form_fingerprints (form_url, fingerprint, structure, entry_map, captured_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT(form_url) DO UPDATE SET
+			fingerprint = excluded.fingerprint, structure = excluded.structure, entry_map = excluded.entry_map,
+			captured_at = excluded.captured_at, updated_at = excluded.updated_at
+	`, fp.FormURL, fp.Structure.Fingerprint, string(structure), entryMap, fp.CapturedAt, fp.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("儲存表單指紋失敗: %w", err)
+	}
+	return nil
+}
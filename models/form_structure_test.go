@@ -0,0 +1,216 @@
+package models
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// googleFormPage 仿照 Google Form viewform 頁面的 FB_PUBLIC_LOAD_DATA_（含段落標題、下拉選單與方格題）
+const googleFormPage = `<html><body><script type="text/javascript" nonce="x">var FB_PUBLIC_LOAD_DATA_ = [null,["說明",` +
+	`[[111,"姓名",null,0,[[1001,null,1]]],` +
+	`[112,"請假資訊",null,8,null],` +
+	`[113,"假別",null,3,[[1005,[["近假",null,null,null,0],["長假",null,null,null,0]],1]]],` +
+	`[114,"起始日期",null,9,[[1003,null,1,null,null,null,null,[0,1]]]],` +
+	`[115,"滿意度",null,7,[[2001,[["好"],["普通"]],0,["上午"]],[2002,[["好"],["普通"]],0,["下午"]]]]` +
+	`],null,null,null,null,null,null,"請假申請"],"/forms","請假申請",null,null,null,"",null,0,0,null,"",0,[0,0,0,0,0]];` +
+	`</script></body></html>`
+
+func TestParseFormStructure(t *testing.T) {
+	structure, err := ParseFormStructure(googleFormPage)
+	if err != nil {
+		t.Fatalf("解析失敗: %v", err)
+	}
+	if structure.Title != "請假申請" || len(structure.Questions) != 5 {
+		t.Fatalf("應解析出 5 個 entry，實際 %+v", structure)
+	}
+
+	leave := structure.Question("entry.1005")
+	if leave == nil || leave.ItemID != "113" || leave.Type != "dropdown" || strings.Join(leave.Choices, ",") != "近假,長假" || !leave.Required {
+		t.Errorf("假別題目解析錯誤: %+v", leave)
+	}
+	if q := structure.Question("entry.1003"); q == nil || q.Type != "date" {
+		t.Errorf("日期題目解析錯誤: %+v", q)
+	}
+	if q := structure.Question("entry.2002"); q == nil || q.Title != "滿意度 - 下午" || q.Required {
+		t.Errorf("方格題每列應各為一筆: %+v", q)
+	}
+
+	// 題目文字改變不影響指紋，選項改變會
+	renamed, _ := ParseFormStructure(strings.Replace(googleFormPage, `"姓名"`, `"您的姓名"`, 1))
+	if renamed.Fingerprint != structure.Fingerprint {
+		t.Error("題目文字改變不應影響指紋")
+	}
+	changed, _ := ParseFormStructure(strings.Replace(googleFormPage, `["長假",null,null,null,0]`, `["特休",null,null,null,0]`, 1))
+	if changed.Fingerprint == structure.Fingerprint {
+		t.Error("選項改變應影響指紋")
+	}
+
+	if _, err := ParseFormStructure("<html><body>已停止接受回應</body></html>"); err == nil {
+		t.Error("沒有 FB_PUBLIC_LOAD_DATA_ 時應回傳錯誤")
+	}
+}
+
+func TestDiffFormStructure(t *testing.T) {
+	baseline, _ := ParseFormStructure(googleFormPage)
+	page := strings.Replace(googleFormPage, `[[1001,null,1]]`, `[[3001,null,1]]`, 1)                 // 姓名的 entry 改變
+	page = strings.Replace(page, `"起始日期",null,9`, `"起始日期",null,0`, 1)                                // 日期改為簡答
+	page = strings.Replace(page, `["長假",null,null,null,0]`, `["特休",null,null,null,0]`, 1)            // 選項改變
+	page = strings.Replace(page, `[112,"請假資訊",null,8,null]`, `[116,"備註",null,1,[[4001,null,0]]]`, 1) // 新增題目
+	current, err := ParseFormStructure(page)
+	if err != nil {
+		t.Fatalf("解析失敗: %v", err)
+	}
+
+	kinds := make(map[string]FormChange)
+	for _, c := range DiffFormStructure(baseline, current) {
+		kinds[c.Kind] = c
+	}
+	if c := kinds[FormChangeEntryChanged]; c.Detail != "entry.1001 → entry.3001" {
+		t.Errorf("應偵測到 entry 改變，實際 %+v", c)
+	}
+	if c := kinds[FormChangeTypeChanged]; c.EntryID != "entry.1003" || c.Detail != "date → short_answer" {
+		t.Errorf("應偵測到類型改變，實際 %+v", c)
+	}
+	if c := kinds[FormChangeChoicesChanged]; c.Detail != "少了 長假；多了 特休" {
+		t.Errorf("應偵測到選項改變，實際 %+v", c)
+	}
+	if c := kinds[FormChangeAdded]; c.EntryID != "entry.4001" {
+		t.Errorf("應偵測到新增題目，實際 %+v", c)
+	}
+	if _, ok := kinds[FormChangeRemoved]; ok {
+		t.Error("entry 改變的題目不應視為刪除")
+	}
+
+	entryMap := map[string]string{"name": "entry.1001", "leave_type": "entry.1005", "start_date": "entry.9999"}
+	mappings := make(map[string]FieldMapping)
+	for _, m := range MapEntryFields(entryMap, baseline, current) {
+		mappings[m.Field] = m
+	}
+	if m := mappings["name"]; m.Found || m.Suggested != "entry.3001" || m.Reason != "同一題目" {
+		t.Errorf("應依題目 ID 建議新的 entry，實際 %+v", m)
+	}
+	if m := mappings["leave_type"]; !m.Found || m.Title != "假別" {
+		t.Errorf("仍在表單上的欄位應標示找到，實際 %+v", m)
+	}
+	if m := mappings["start_date"]; m.Suggested != "entry.1003" || !strings.Contains(m.Reason, "起始") {
+		t.Errorf("沒有指紋可參考時應依題目關鍵字建議，實際 %+v", m)
+	}
+
+	check := CompareFormStructure(current, &FormFingerprint{Structure: baseline}, entryMap)
+	if check.Status != FormCheckDrift || !strings.Contains(check.Message, "name（entry.1001）") {
+		t.Errorf("應判定為變更並列出找不到的欄位，實際 %+v", check)
+	}
+}
+
+func TestFormMonitor(t *testing.T) {
+	storage, cleanup := setupTestStorage(t)
+	defer cleanup()
+
+	entries := map[string]string{"name": "entry.1", "employee_id": "entry.2", "start_date": "entry.3", "end_date": "entry.4", "leave_type": "entry.5", "password": "entry.6"}
+	form := NewMockForm(MockFormOptions{EntryIDs: entries})
+	mock, err := StartMockFormServer(form, "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("啟動模擬表單失敗: %v", err)
+	}
+	defer mock.Close()
+
+	submitter := batchTestSubmitter(mock.FormURL())
+	monitor := NewFormMonitor(submitter, storage, 0)
+	var alerts []*FormStructureCheck
+	monitor.OnDrift = func(check *FormStructureCheck) { alerts = append(alerts, check) }
+
+	// 檢查不會自動建立指紋，需明確接受目前的結構
+	if check := monitor.Check(t.Context()); check.Status != FormCheckNoBaseline {
+		t.Fatalf("尚未建立指紋時應回報 no_baseline，實際 %+v", check)
+	}
+	if baseline, _ := monitor.Baseline(); baseline != nil {
+		t.Fatalf("檢查不應建立指紋，實際 %+v", baseline)
+	}
+	if check, err := monitor.AcceptCurrent(t.Context()); err != nil || check.Status != FormCheckOK {
+		t.Fatalf("接受目前結構後應相符，實際 %+v: %v", check, err)
+	}
+	baseline, err := monitor.Baseline()
+	if err != nil || baseline == nil || len(baseline.Structure.Questions) != 6 {
+		t.Fatalf("應儲存 6 題的指紋，實際 %+v: %v", baseline, err)
+	}
+	if q := baseline.Structure.Question("entry.5"); q == nil || q.Type != "dropdown" {
+		t.Errorf("模擬表單的假別應為下拉選單，實際 %+v", q)
+	}
+
+	// 表單擁有者重建了密碼題目
+	changed := map[string]string{"name": "entry.1", "employee_id": "entry.2", "start_date": "entry.3", "end_date": "entry.4", "leave_type": "entry.5", "password": "entry.60"}
+	form.SetOptions(MockFormOptions{EntryIDs: changed})
+	check := monitor.Check(t.Context())
+	if check.Status != FormCheckDrift || len(check.Changes) != 1 || check.Changes[0].Kind != FormChangeEntryChanged {
+		t.Fatalf("應偵測到 entry 改變，實際 %+v", check)
+	}
+	monitor.Check(t.Context())
+	if len(alerts) != 1 {
+		t.Errorf("相同的變更只應通知一次，實際 %d 次", len(alerts))
+	}
+	var suggested string
+	for _, m := range check.Mappings {
+		if m.Field == "password" {
+			suggested = m.Suggested
+		}
+	}
+	if suggested != "entry.60" {
+		t.Errorf("應建議對應到 entry.60，實際 %q", suggested)
+	}
+
+	// 排程前檢查也會發現
+	scheduler := NewScheduler(&ScheduleConfig{}, submitter, storage)
+	id, err := storage.Save(&SavedForm{Label: "結構", Name: "王小明", EmployeeID: "A12345", StartDate: "2030-01-02", EndDate: "2030-01-03", LeaveType: "近假", Password: "p"})
+	if err != nil {
+		t.Fatalf("儲存失敗: %v", err)
+	}
+	report, err := scheduler.CheckPreflight(t.Context(), &ScheduleConfig{SavedFormID: id, At: time.Now().Add(time.Hour)})
+	if err != nil {
+		t.Fatalf("檢查失敗: %v", err)
+	}
+	if c := report.Check(PreflightCheckFormStructure); c == nil || c.Status != PreflightFail {
+		t.Errorf("表單結構變更時排程前檢查應失敗，實際 %+v", c)
+	}
+
+	// 對應到不存在或重複的 entry 時拒絕
+	if _, err := monitor.Remap(t.Context(), map[string]string{"password": "entry.7"}); err == nil {
+		t.Error("對應到表單上沒有的 entry 應失敗")
+	}
+	if _, err := monitor.Remap(t.Context(), map[string]string{"password": "entry.1"}); err == nil {
+		t.Error("兩個欄位對應到同一個 entry 應失敗")
+	}
+
+	check, err = monitor.Remap(t.Context(), map[string]string{"password": "entry.60"})
+	if err != nil || check.Status != FormCheckOK {
+		t.Fatalf("重新對應後應相符，實際 %+v: %v", check, err)
+	}
+	if submitter.EntryMapSnapshot()["password"] != "entry.60" {
+		t.Error("重新對應應立即套用到提交器")
+	}
+	if baseline, _ := monitor.Baseline(); baseline.EntryMap["password"] != "entry.60" {
+		t.Errorf("應儲存重新對應的 entry_map，實際 %+v", baseline.EntryMap)
+	}
+	report, _ = scheduler.CheckPreflight(t.Context(), &ScheduleConfig{SavedFormID: id, At: time.Now().Add(time.Hour)})
+	if c := report.Check(PreflightCheckFormStructure); c == nil || c.Status != PreflightOK {
+		t.Errorf("重新對應後排程前檢查應通過，實際 %+v", c)
+	}
+
+	// 選項改變不影響 entry_map，確認後接受目前結構
+	form.SetOptions(MockFormOptions{EntryIDs: changed, Choices: map[string][]string{"leave_type": {"近假", "長假", "特休"}}})
+	if check := monitor.Check(t.Context()); check.Status != FormCheckDrift || check.Changes[0].Kind != FormChangeChoicesChanged {
+		t.Fatalf("應偵測到選項改變，實際 %+v", check)
+	}
+	if check, err := monitor.AcceptCurrent(t.Context()); err != nil || check.Status != FormCheckOK {
+		t.Errorf("接受目前結構後應相符，實際 %+v: %v", check, err)
+	}
+
+	// 表單未開放時無法檢查
+	form.SetOptions(MockFormOptions{EntryIDs: changed, Closed: true})
+	if check := monitor.Check(t.Context()); check.Status != FormCheckUnavailable {
+		t.Errorf("表單未開放時應為 unavailable，實際 %+v", check)
+	}
+	if _, err := monitor.AcceptCurrent(t.Context()); err != ErrFormClosed {
+		t.Errorf("表單未開放時不應更新指紋，實際 %v", err)
+	}
+}
@@ -0,0 +1,174 @@
+package models
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// 提交記錄來源
+const (
+	HistorySourceSchedule  = "schedule"
+	HistorySourceBatch     = "batch"
+	HistorySourceTeam      = "team"
+	HistorySourceRecurring = "recurring"
+)
+
+// SubmissionRecord 單次提交記錄（不含密碼）
+type SubmissionRecord struct {
+	ID          int64  `json:"id"`
+	Source      string `json:"source"`                  // schedule / batch / team / recurring
+	BatchID     string `json:"batch_id,omitempty"`      // 批次或團隊排程編號
+	SavedFormID int64  `json:"saved_form_id,omitempty"` // 排程使用的儲存資料 ID
+	Name        string `json:"name"`
+	EmployeeID  string `json:"employee_id"`
+	StartDate   string `json:"start_date"`
+	EndDate     string `json:"end_date"`
+	LeaveType   string `json:"leave_type"`
+	Success     bool   `json:"success"`
+	Attempts    int    `json:"attempts"`
+	Message     string `json:"message"`
+	// MissedDecision 錯過排程時間的處理結果（skipped / notified / grace_submitted / grace_expired），準時執行時為空
+	MissedDecision string    `json:"missed_decision,omitempty"`
+	StartedAt      time.Time `json:"started_at"`
+	FinishedAt     time.Time `json:"finished_at"`
+
+	// 排程送出的精確度：預計送出時間、實際送出時間與兩者的差距（微秒，正值表示晚於預計）；
+	// 未實際送出或非排程送出時為空
+	IntendedAt  *time.Time `json:"intended_at,omitempty"`
+	SentAt      *time.Time `json:"sent_at,omitempty"`
+	FireDelayUs *int64     `json:"fire_delay_us,omitempty"`
+
+	// AttemptDetails 每次送出嘗試的結果與網路各階段耗時
+	AttemptDetails []SubmissionAttempt `json:"attempt_details,omitempty"`
+}
+
+// SubmissionAttempt 單次送出嘗試
+type SubmissionAttempt struct {
+	Attempt    int            `json:"attempt"`
+	StatusCode int            `json:"status_code,omitempty"` // 連線失敗時為 0
+	Error      string         `json:"error,omitempty"`
+	SentAt     time.Time      `json:"sent_at"`
+	Timing     *RequestTiming `json:"timing,omitempty"`
+}
+
+// newSubmissionRecord 由請假資料建立提交記錄
+func newSubmissionRecord(source string, req *LeaveRequest) *SubmissionRecord {
+	return &SubmissionRecord{
+		Source:     source,
+		Name:       req.Name,
+		EmployeeID: req.EmployeeID,
+		StartDate:  req.StartDate,
+		EndDate:    req.EndDate,
+		LeaveType:  req.LeaveType,
+	}
+}
+
+// HistoryQuery 提交記錄查詢條件
+type HistoryQuery struct {
+	Source  string
+	BatchID string
+	Limit   int
+}
+
+const submissionHistoryColumns = "id, source, batch_id, saved_form_id, name, employee_id, start_date, end_date, leave_type, success, attempts, message, missed_decision, started_at, finished_at, intended_at, sent_at, fire_delay_us, attempt_details"
+
+// RecordSubmission 寫入一筆提交記錄
+func (s *Storage) RecordSubmission(rec *SubmissionRecord) (int64, error) {
+	attemptDetails := ""
+	if len(rec.AttemptDetails) > 0 {
+		data, err := json.Marshal(rec.AttemptDetails)
+		if err != nil {
+			return 0, fmt.Errorf("編碼送出嘗試記錄失敗: %w", err)
+		}
+		attemptDetails = string(data)
+	}
+
+	result, err := s.db.Exec(`
+		INSERT INTO submission_history (source, batch_id, saved_form_id, name, employee_id, start_date, end_date, leave_type, success, attempts, message, missed_decision, started_at, finished_at, intended_at, sent_at, fire_delay_us, attempt_details)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, rec.Source, rec.BatchID, rec.SavedFormID, rec.Name, rec.EmployeeID, rec.StartDate, rec.EndDate, rec.LeaveType,
+		rec.Success, rec.Attempts, rec.Message, rec.MissedDecision, rec.StartedAt, rec.FinishedAt, rec.IntendedAt, rec.SentAt, rec.FireDelayUs, attemptDetails)
+	if err != nil {
+		return 0, fmt.Errorf("寫入提交記錄失敗: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("取得 ID 失敗: %w", err)
+	}
+	rec.ID = id
+
+	return id, nil
+}
+
+// ListHistory 依條件列出提交記錄（新到舊）
+func (s *Storage) ListHistory(q HistoryQuery) ([]*SubmissionRecord, error) {
+	query := "SELECT " + submissionHistoryColumns + " FROM submission_history WHERE 1 = 1"
+	var args []interface{}
+	if q.Source != "" {
+		query += " AND source = ?"
+		args = append(args, q.Source)
+	}
+	if q.BatchID != "" {
+		query += " AND batch_id = ?"
+		args = append(args, q.BatchID)
+	}
+	query += " ORDER BY started_at DESC, id DESC"
+	if q.Limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, q.Limit)
+	}
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("查詢提交記錄失敗: %w", err)
+	}
+	defer rows.Close()
+
+	records := []*SubmissionRecord{}
+	for rows.Next() {
+		rec, err := scanSubmissionRecord(rows)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, rec)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("查詢提交記錄失敗: %w", err)
+	}
+
+	return records, nil
+}
+
+// scanSubmissionRecord 讀取單筆提交記錄
+func scanSubmissionRecord(row rowScanner) (*SubmissionRecord, error) {
+	rec := &SubmissionRecord{}
+	var savedFormID, fireDelay sql.NullInt64
+	var intendedAt, sentAt sql.NullTime
+	var attemptDetails string
+	err := row.Scan(&rec.ID, &rec.Source, &rec.BatchID, &savedFormID, &rec.Name, &rec.EmployeeID,
+		&rec.StartDate, &rec.EndDate, &rec.LeaveType, &rec.Success, &rec.Attempts, &rec.Message, &rec.MissedDecision,
+		&rec.StartedAt, &rec.FinishedAt, &intendedAt, &sentAt, &fireDelay, &attemptDetails)
+	if err != nil {
+		return nil, fmt.Errorf("讀取提交記錄失敗: %w", err)
+	}
+	rec.SavedFormID = savedFormID.Int64
+	if intendedAt.Valid {
+		rec.IntendedAt = &intendedAt.Time
+	}
+	if sentAt.Valid {
+		rec.SentAt = &sentAt.Time
+	}
+	if fireDelay.Valid {
+		rec.FireDelayUs = &fireDelay.Int64
+	}
+	if attemptDetails != "" {
+		if err := json.Unmarshal([]byte(attemptDetails), &rec.AttemptDetails); err != nil {
+			return nil, fmt.Errorf("解析送出嘗試記錄失敗: %w", err)
+		}
+	}
+	return rec, nil
+}
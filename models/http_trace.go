@@ -0,0 +1,122 @@
+package models
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"net/http/httptrace"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RequestTiming 單次 HTTP 請求各階段的耗時（微秒）；重用連線時 DNS、連線與 TLS 為 0
+type RequestTiming struct {
+	DNSUs     int64 `json:"dns_us"`     // DNS 解析
+	ConnectUs int64 `json:"connect_us"` // TCP 連線
+	TLSUs     int64 `json:"tls_us"`     // TLS 交握
+	WaitUs    int64 `json:"wait_us"`    // 寫完請求到收到第一個回應位元組（伺服器處理時間）
+	ReadUs    int64 `json:"read_us"`    // 讀取回應內容
+	TotalUs   int64 `json:"total_us"`   // 從送出請求到讀完回應
+
+	Reused     bool   `json:"reused"`                // 是否重用 keep-alive 連線
+	IdleUs     int64  `json:"idle_us,omitempty"`     // 重用的連線閒置時間
+	RemoteAddr string `json:"remote_addr,omitempty"` // 連線的伺服器位址
+}
+
+// String 以一行文字摘要各階段耗時（供日誌與事件訊息）
+func (t *RequestTiming) String() string {
+	us := func(v int64) time.Duration { return (time.Duration(v) * time.Microsecond).Round(10 * time.Microsecond) }
+	var parts []string
+	if t.Reused {
+		parts = append(parts, fmt.Sprintf("重用連線（閒置 %v）", us(t.IdleUs)))
+	} else {
+		parts = append(parts, fmt.Sprintf("DNS %v", us(t.DNSUs)), fmt.Sprintf("連線 %v", us(t.ConnectUs)))
+		if t.TLSUs > 0 {
+			parts = append(parts, fmt.Sprintf("TLS %v", us(t.TLSUs)))
+		}
+	}
+	parts = append(parts, fmt.Sprintf("伺服器 %v", us(t.WaitUs)), fmt.Sprintf("讀取 %v", us(t.ReadUs)), fmt.Sprintf("共 %v", us(t.TotalUs)))
+	return strings.Join(parts, "、")
+}
+
+// requestTrace 以 httptrace 記錄一次請求各階段的時間點
+type requestTrace struct {
+	mu                        sync.Mutex
+	start                     time.Time
+	dnsStart, dnsDone         time.Time
+	connectStart, connectDone time.Time
+	tlsStart, tlsDone         time.Time
+	wroteRequest, firstByte   time.Time
+	reused                    bool
+	idle                      time.Duration
+	remoteAddr                string
+}
+
+// traceRequest 複製請求並掛上 httptrace，從呼叫時開始計時；讀完回應後以 Timing 取得各階段耗時
+func traceRequest(req *http.Request) (*http.Request, *requestTrace) {
+	t := &requestTrace{start: time.Now()}
+	mark := func(field *time.Time) {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		// 同時嘗試多個位址時只記錄第一次
+		if field.IsZero() {
+			*field = time.Now()
+		}
+	}
+	trace := &httptrace.ClientTrace{
+		DNSStart:     func(httptrace.DNSStartInfo) { mark(&t.dnsStart) },
+		DNSDone:      func(httptrace.DNSDoneInfo) { mark(&t.dnsDone) },
+		ConnectStart: func(string, string) { mark(&t.connectStart) },
+		ConnectDone: func(_, _ string, err error) {
+			if err == nil {
+				mark(&t.connectDone)
+			}
+		},
+		TLSHandshakeStart: func() { mark(&t.tlsStart) },
+		TLSHandshakeDone: func(_ tls.ConnectionState, err error) {
+			if err == nil {
+				mark(&t.tlsDone)
+			}
+		},
+		GotConn: func(info httptrace.GotConnInfo) {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			t.reused, t.idle = info.Reused, info.IdleTime
+			if info.Conn != nil {
+				t.remoteAddr = info.Conn.RemoteAddr().String()
+			}
+		},
+		WroteRequest:         func(httptrace.WroteRequestInfo) { mark(&t.wroteRequest) },
+		GotFirstResponseByte: func() { mark(&t.firstByte) },
+	}
+	return req.WithContext(httptrace.WithClientTrace(req.Context(), trace)), t
+}
+
+// Timing 以目前時間為結束計算各階段耗時；未到達的階段為 0
+func (t *requestTrace) Timing() *RequestTiming {
+	end := time.Now()
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	span := func(from, to time.Time) int64 {
+		if from.IsZero() || to.IsZero() {
+			return 0
+		}
+		return to.Sub(from).Microseconds()
+	}
+	timing := &RequestTiming{
+		DNSUs:      span(t.dnsStart, t.dnsDone),
+		ConnectUs:  span(t.connectStart, t.connectDone),
+		TLSUs:      span(t.tlsStart, t.tlsDone),
+		WaitUs:     span(t.wroteRequest, t.firstByte),
+		ReadUs:     span(t.firstByte, end),
+		TotalUs:    end.Sub(t.start).Microseconds(),
+		Reused:     t.reused,
+		RemoteAddr: t.remoteAddr,
+	}
+	if t.reused {
+		timing.IdleUs = t.idle.Microseconds()
+	}
+	return timing
+}
@@ -14,4 +14,6 @@ type LeaveRequest struct {
 type SubmitResult struct {
 	Success bool   `json:"success"`
 	Message string `json:"message"`
+
+	Timing *RequestTiming `json:"timing,omitempty"` // 送出請求的網路各階段耗時
 }
@@ -0,0 +1,506 @@
+package models
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// migration 資料庫結構遷移，版本號需嚴格遞增且發布後不可修改
+type migration struct {
+	version     int
+	description string
+	up          func(tx *sql.Tx) error
+
+	// check 套用前檢查既有資料，回傳錯誤時中止遷移（可為 nil）
+	check func(tx *sql.Tx, s *Storage) error
+}
+
+// execSQL 建立只執行 SQL 的遷移步驟
+func execSQL(statements string) func(tx *sql.Tx) error {
+	return func(tx *sql.Tx) error {
+		_, err := tx.Exec(statements)
+		return err
+	}
+}
+
+// migrations 內建於執行檔的遷移清單（依版本排序）
+var migrations = []migration{
+	{
+		version:     1,
+		description: "建立 saved_forms 資料表",
+		up: execSQL(`
+			CREATE TABLE IF NOT EXISTS saved_forms (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				label TEXT NOT NULL,
+				name TEXT NOT NULL,
+				employee_id TEXT NOT NULL,
+				start_date TEXT NOT NULL,
+				end_date TEXT NOT NULL,
+				leave_type TEXT NOT NULL,
+				password TEXT NOT NULL,
+				created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+				updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+			);
+			CREATE INDEX IF NOT EXISTS idx_saved_forms_label ON saved_forms(label);
+		`),
+	},
+	{
+		version:     2,
+		description: "建立 storage_meta 資料表（加密金鑰檢查值與 salt）",
+		up: execSQL(`
+			CREATE TABLE IF NOT EXISTS storage_meta (
+				key TEXT PRIMARY KEY,
+				value TEXT NOT NULL
+			);
+		`),
+	},
+	{
+		version:     3,
+		description: "建立儲存資料搜尋與排序用索引",
+		up: execSQL(`
+			CREATE INDEX IF NOT EXISTS idx_saved_forms_employee_id ON saved_forms(employee_id);
+			CREATE INDEX IF NOT EXISTS idx_saved_forms_leave_type ON saved_forms(leave_type);
+			CREATE INDEX IF NOT EXISTS idx_saved_forms_dates ON saved_forms(start_date, end_date);
+			CREATE INDEX IF NOT EXISTS idx_saved_forms_created_at ON saved_forms(created_at);
+		`),
+	},
+	{
+		version:     4,
+		description: "建立 submission_history 資料表",
+		up: execSQL(`
+			CREATE TABLE IF NOT EXISTS submission_history (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				source TEXT NOT NULL,
+				batch_id TEXT NOT NULL DEFAULT '',
+				saved_form_id INTEGER,
+				name TEXT NOT NULL,
+				employee_id TEXT NOT NULL,
+				start_date TEXT NOT NULL,
+				end_date TEXT NOT NULL,
+				leave_type TEXT NOT NULL,
+				success BOOLEAN NOT NULL,
+				attempts INTEGER NOT NULL,
+				message TEXT NOT NULL DEFAULT '',
+				started_at DATETIME NOT NULL,
+				finished_at DATETIME NOT NULL
+			);
+			CREATE INDEX IF NOT EXISTS idx_submission_history_started_at ON submission_history(started_at);
+			CREATE INDEX IF NOT EXISTS idx_submission_history_batch_id ON submission_history(batch_id);
+		`),
+	},
+	{
+		version:     5,
+		description: "拆分 employees 資料表，saved_forms 改為參照員工",
+		check:       checkEmployeeConflicts,
+		up: execSQL(`
+			CREATE TABLE employees (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				name TEXT NOT NULL,
+				employee_id TEXT NOT NULL UNIQUE,
+				password TEXT NOT NULL DEFAULT '',
+				created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+				updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+			);
+
+			-- check 已確認同一員工代號的姓名與密碼一致，取有密碼且最後更新的一筆
+			INSERT INTO employees (name, employee_id, password, created_at, updated_at)
+			SELECT f.name, f.employee_id, f.password, f.created_at, f.updated_at
+			FROM saved_forms f
+			WHERE f.id = (
+				SELECT g.id FROM saved_forms g
+				WHERE g.employee_id = f.employee_id
+				ORDER BY g.password != '' DESC, g.updated_at DESC, g.id DESC
+				LIMIT 1
+			);
+
+			CREATE TABLE saved_forms_new (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				label TEXT NOT NULL,
+				employee_ref INTEGER NOT NULL REFERENCES employees(id),
+				start_date TEXT NOT NULL,
+				end_date TEXT NOT NULL,
+				leave_type TEXT NOT NULL,
+				created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+				updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+			);
+
+			INSERT INTO saved_forms_new (id, label, employee_ref, start_date, end_date, leave_type, created_at, updated_at)
+			SELECT f.id, f.label, e.id, f.start_date, f.end_date, f.leave_type, f.created_at, f.updated_at
+			FROM saved_forms f
+			JOIN employees e ON e.employee_id = f.employee_id;
+
+			DROP TABLE saved_forms;
+			ALTER TABLE saved_forms_new RENAME TO saved_forms;
+
+			CREATE INDEX idx_saved_forms_label ON saved_forms(label);
+			CREATE INDEX idx_saved_forms_employee_ref ON saved_forms(employee_ref);
+			CREATE INDEX idx_saved_forms_leave_type ON saved_forms(leave_type);
+			CREATE INDEX idx_saved_forms_dates ON saved_forms(start_date, end_date);
+			CREATE INDEX idx_saved_forms_created_at ON saved_forms(created_at);
+		`),
+	},
+	{
+		version:     6,
+		description: "建立 rosters 與 roster_members 資料表（團隊名單）",
+		up: execSQL(`
+			CREATE TABLE rosters (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				name TEXT NOT NULL UNIQUE,
+				created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+				updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+			);
+
+			CREATE TABLE roster_members (
+				roster_id INTEGER NOT NULL REFERENCES rosters(id),
+				employee_ref INTEGER NOT NULL REFERENCES employees(id),
+				position INTEGER NOT NULL,
+				PRIMARY KEY (roster_id, employee_ref)
+			);
+			CREATE INDEX idx_roster_members_employee_ref ON roster_members(employee_ref);
+		`),
+	},
+	{
+		version:     7,
+		description: "saved_forms 新增 date_template 欄位（相對日期範本）",
+		up: execSQL(`
+			ALTER TABLE saved_forms ADD COLUMN date_template TEXT NOT NULL DEFAULT '';
+		`),
+	},
+	{
+		version:     8,
+		description: "建立 recurring_schedules 資料表（週期排程）",
+		up: execSQL(`
+			CREATE TABLE recurring_schedules (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				label TEXT NOT NULL,
+				kind TEXT NOT NULL,
+				spec TEXT NOT NULL,
+				timezone TEXT NOT NULL DEFAULT '',
+				saved_form_id INTEGER NOT NULL DEFAULT 0,
+				employee_ref INTEGER NOT NULL DEFAULT 0,
+				leave_type TEXT NOT NULL DEFAULT '',
+				date_template TEXT NOT NULL DEFAULT '',
+				prepare_seconds INTEGER NOT NULL DEFAULT 0,
+				retry_count INTEGER NOT NULL DEFAULT 0,
+				retry_interval INTEGER NOT NULL DEFAULT 0,
+				end_count INTEGER NOT NULL DEFAULT 0,
+				end_until TEXT NOT NULL DEFAULT '',
+				enabled INTEGER NOT NULL DEFAULT 1,
+				occurrences INTEGER NOT NULL DEFAULT 0,
+				last_run_at DATETIME,
+				created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+				updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+			);
+			CREATE INDEX idx_recurring_schedules_saved_form_id ON recurring_schedules(saved_form_id);
+			CREATE INDEX idx_recurring_schedules_employee_ref ON recurring_schedules(employee_ref);
+		`),
+	},
+	{
+		version:     9,
+		description: "建立 calendar_days 資料表（國定假日與補班日）",
+		up: execSQL(`
+			CREATE TABLE calendar_days (
+				date TEXT PRIMARY KEY,
+				kind TEXT NOT NULL,
+				name TEXT NOT NULL DEFAULT '',
+				source TEXT NOT NULL DEFAULT '',
+				imported_at DATETIME DEFAULT CURRENT_TIMESTAMP
+			);
+		`),
+	},
+	{
+		version:     10,
+		description: "新增錯過排程時間的處理策略與提交記錄的處理結果",
+		up: execSQL(`
+			ALTER TABLE submission_history ADD COLUMN missed_decision TEXT NOT NULL DEFAULT '';
+			ALTER TABLE recurring_schedules ADD COLUMN missed_policy TEXT NOT NULL DEFAULT '';
+			ALTER TABLE recurring_schedules ADD COLUMN grace_seconds INTEGER NOT NULL DEFAULT 0;
+		`),
+	},
+	{
+		version:     11,
+		description: "週期排程新增送出策略與探測參數",
+		up: execSQL(`
+			ALTER TABLE recurring_schedules ADD COLUMN strategy TEXT NOT NULL DEFAULT '';
+			ALTER TABLE recurring_schedules ADD COLUMN poll_lead_seconds INTEGER NOT NULL DEFAULT 0;
+			ALTER TABLE recurring_schedules ADD COLUMN poll_interval_ms INTEGER NOT NULL DEFAULT 0;
+			ALTER TABLE recurring_schedules ADD COLUMN poll_deadline_seconds INTEGER NOT NULL DEFAULT 0;
+		`),
+	},
+	{
+		version:     12,
+		description: "建立 form_fingerprints 資料表（表單結構指紋與重新對應的 entry_map）",
+		up: execSQL(`
+			CREATE TABLE form_fingerprints (
+				form_url TEXT PRIMARY KEY,
+				fingerprint TEXT NOT NULL,
+				structure TEXT NOT NULL,
+				entry_map TEXT NOT NULL DEFAULT '',
+				captured_at DATETIME NOT NULL,
+				updated_at DATETIME NOT NULL
+			);
+		`),
+	},
+	{
+		version:     13,
+		description: "提交記錄新增預計與實際送出時間（送出精確度）",
+		up: execSQL(`
+			ALTER TABLE submission_history ADD COLUMN intended_at DATETIME;
+			ALTER TABLE submission_history ADD COLUMN sent_at DATETIME;
+			ALTER TABLE submission_history ADD COLUMN fire_delay_us INTEGER;
+		`),
+	},
+	{
+		version:     14,
+		description: "提交記錄新增每次送出嘗試的網路各階段耗時",
+		up: execSQL(`
+			ALTER TABLE submission_history ADD COLUMN attempt_details TEXT NOT NULL DEFAULT '';
+		`),
+	},
+}
+
+// LatestSchemaVersion 回傳執行檔支援的最新資料庫版本
+func LatestSchemaVersion() int {
+	return migrations[len(migrations)-1].version
+}
+
+// AppliedMigration 已套用的遷移記錄
+type AppliedMigration struct {
+	Version     int       `json:"version"`
+	Description string    `json:"description"`
+	AppliedAt   time.Time `json:"applied_at"`
+}
+
+// ensureMigrationTable 建立 schema_migrations 資料表
+func ensureMigrationTable(db *sql.DB) error {
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version INTEGER PRIMARY KEY,
+			description TEXT NOT NULL,
+			applied_at DATETIME NOT NULL
+		)
+	`)
+	if err != nil {
+		return fmt.Errorf("建立 schema_migrations 失敗: %w", err)
+	}
+	return nil
+}
+
+// schemaVersion 讀取目前資料庫版本（未套用任何遷移時為 0）
+func schemaVersion(db *sql.DB) (int, error) {
+	var version sql.NullInt64
+	if err := db.QueryRow("SELECT MAX(version) FROM schema_migrations").Scan(&version); err != nil {
+		return 0, fmt.Errorf("讀取資料庫版本失敗: %w", err)
+	}
+	return int(version.Int64), nil
+}
+
+// hasUserTables 判斷資料庫是否已有資料表（用來略過全新資料庫的備份）
+func hasUserTables(db *sql.DB) (bool, error) {
+	var count int
+	err := db.QueryRow(`
+		SELECT COUNT(*) FROM sqlite_master
+		WHERE type = 'table' AND name NOT LIKE 'sqlite_%' AND name != 'schema_migrations'
+	`).Scan(&count)
+	if err != nil {
+		return false, fmt.Errorf("讀取資料表清單失敗: %w", err)
+	}
+	return count > 0, nil
+}
+
+// hasTable 判斷資料表是否存在
+func hasTable(db *sql.DB, name string) (bool, error) {
+	var count int
+	if err := db.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?", name).Scan(&count); err != nil {
+		return false, fmt.Errorf("讀取資料表清單失敗: %w", err)
+	}
+	return count > 0, nil
+}
+
+// checkEmployeeConflicts 檢查同一員工代號的舊版資料姓名與密碼是否一致（v5 拆分 employees 前）。
+// 不一致時中止遷移並列出員工代號，避免拆分時默默捨棄其中一組姓名或密碼；
+// 加密的密碼每次加密結果不同，解密後再比對，無法解密的值以原值比對
+func checkEmployeeConflicts(tx *sql.Tx, s *Storage) error {
+	rows, err := tx.Query("SELECT employee_id, name, password FROM saved_forms ORDER BY employee_id, id")
+	if err != nil {
+		return fmt.Errorf("查詢儲存資料失敗: %w", err)
+	}
+	defer rows.Close()
+
+	type identity struct {
+		names     map[string]bool
+		passwords map[string]bool
+	}
+	var ids []string
+	seen := map[string]*identity{}
+	for rows.Next() {
+		var employeeID, name, password string
+		if err := rows.Scan(&employeeID, &name, &password); err != nil {
+			return fmt.Errorf("讀取資料失敗: %w", err)
+		}
+		if IsEncryptedPassword(password) && s.cipher != nil {
+			if plain, err := s.cipher.Decrypt(password); err == nil {
+				password = plain
+			}
+		}
+
+		e := seen[employeeID]
+		if e == nil {
+			e = &identity{names: map[string]bool{}, passwords: map[string]bool{}}
+			seen[employeeID] = e
+			ids = append(ids, employeeID)
+		}
+		e.names[name] = true
+		if password != "" {
+			e.passwords[password] = true
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("讀取資料失敗: %w", err)
+	}
+
+	var conflicts []string
+	for _, id := range ids {
+		if len(seen[id].names) > 1 || len(seen[id].passwords) > 1 {
+			conflicts = append(conflicts, id)
+		}
+	}
+	if len(conflicts) > 0 {
+		return fmt.Errorf("員工代號 %s 在多筆儲存資料中的姓名或密碼不一致，請先以舊版程式統一後再升級", strings.Join(conflicts, "、"))
+	}
+	return nil
+}
+
+// migrate 將資料庫升級到最新版本，升級前先備份既有資料庫
+func (s *Storage) migrate() error {
+	if err := ensureMigrationTable(s.db); err != nil {
+		return err
+	}
+
+	current, err := schemaVersion(s.db)
+	if err != nil {
+		return err
+	}
+
+	latest := LatestSchemaVersion()
+	if current > latest {
+		return fmt.Errorf("資料庫版本 %d 高於程式支援的版本 %d，請更新程式", current, latest)
+	}
+	if current == latest {
+		return nil
+	}
+
+	hasTables, err := hasUserTables(s.db)
+	if err != nil {
+		return err
+	}
+	if hasTables {
+		backupPath := fmt.Sprintf("%s.v%d-%s.bak", s.path, current, time.Now().Format(backupTimeFormat))
+		if err := s.vacuumInto(backupPath); err != nil {
+			return fmt.Errorf("遷移前備份失敗: %w", err)
+		}
+		fmt.Printf("資料庫升級前已備份至: %s\n", backupPath)
+	}
+
+	for _, m := range migrations {
+		if m.version <= current {
+			continue
+		}
+		if err := s.applyMigration(m); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// applyMigration 在單一交易中套用遷移並記錄版本
+func (s *Storage) applyMigration(m migration) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("開始交易失敗: %w", err)
+	}
+	defer tx.Rollback()
+
+	if m.check != nil {
+		if err := m.check(tx, s); err != nil {
+			return fmt.Errorf("資料庫遷移 v%d（%s）失敗: %w", m.version, m.description, err)
+		}
+	}
+
+	if err := m.up(tx); err != nil {
+		return fmt.Errorf("資料庫遷移 v%d（%s）失敗: %w", m.version, m.description, err)
+	}
+
+	if _, err := tx.Exec(
+		"INSERT INTO schema_migrations (version, description, applied_at) VALUES (?, ?, ?)",
+		m.version, m.description, time.Now(),
+	); err != nil {
+		return fmt.Errorf("記錄遷移版本失敗: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("資料庫遷移 v%d 失敗: %w", m.version, err)
+	}
+
+	return nil
+}
+
+// vacuumInto 以 VACUUM INTO 產生一致的資料庫副本
+func (s *Storage) vacuumInto(destPath string) error {
+	if _, err := os.Stat(destPath); err == nil {
+		return fmt.Errorf("目標檔案已存在: %s", destPath)
+	}
+	if _, err := s.db.Exec("VACUUM INTO ?", destPath); err != nil {
+		return err
+	}
+	return nil
+}
+
+// ReadSchemaVersion 以唯讀方式讀取資料庫版本與遷移記錄，不會觸發升級
+func ReadSchemaVersion(dbPath string) (int, []AppliedMigration, error) {
+	if _, err := os.Stat(dbPath); err != nil {
+		return 0, nil, fmt.Errorf("無法讀取資料庫: %w", err)
+	}
+
+	db, err := sql.Open("sqlite3", readOnlyDSN(dbPath))
+	if err != nil {
+		return 0, nil, fmt.Errorf("無法連線到資料庫: %w", err)
+	}
+	defer db.Close()
+
+	var exists int
+	err = db.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'schema_migrations'").Scan(&exists)
+	if err != nil {
+		return 0, nil, fmt.Errorf("讀取資料庫失敗: %w", err)
+	}
+	if exists == 0 {
+		return 0, nil, nil
+	}
+
+	rows, err := db.Query("SELECT version, description, applied_at FROM schema_migrations ORDER BY version")
+	if err != nil {
+		return 0, nil, fmt.Errorf("讀取遷移記錄失敗: %w", err)
+	}
+	defer rows.Close()
+
+	var applied []AppliedMigration
+	version := 0
+	for rows.Next() {
+		var m AppliedMigration
+		if err := rows.Scan(&m.Version, &m.Description, &m.AppliedAt); err != nil {
+			return 0, nil, fmt.Errorf("讀取遷移記錄失敗: %w", err)
+		}
+		applied = append(applied, m)
+		version = m.Version
+	}
+
+	if err := rows.Err(); err != nil {
+		return 0, nil, fmt.Errorf("讀取遷移記錄失敗: %w", err)
+	}
+
+	return version, applied, nil
+}
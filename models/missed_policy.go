@@ -0,0 +1,73 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+// 錯過目標時間（啟動時已過、休眠喚醒或系統時間跳動）時的處理策略
+const (
+	MissedPolicySkip   = "skip"   // 略過，不送出（預設）
+	MissedPolicyGrace  = "grace"  // 錯過不超過寬限秒數時立即送出，否則略過
+	MissedPolicyNotify = "notify" // 不送出，發出通知提醒手動處理
+)
+
+// 錯過目標時間的處理結果，寫入提交記錄的 missed_decision
+const (
+	MissedSkipped        = "skipped"         // 依 skip 策略略過
+	MissedNotified       = "notified"        // 依 notify 策略通知，未送出
+	MissedGraceSubmitted = "grace_submitted" // 於寬限時間內補送
+	MissedGraceExpired   = "grace_expired"   // 超過寬限時間，未送出
+)
+
+// defaultGraceSeconds grace 策略未指定寬限秒數時的預設值
+const defaultGraceSeconds = 60
+
+// missedTolerance 等待結束時晚於預計時間超過此值才視為錯過（一般計時器只會晚幾毫秒）
+const missedTolerance = 2 * time.Second
+
+// validateMissedPolicy 檢查錯過策略，空字串視為 skip
+func validateMissedPolicy(policy string) error {
+	switch policy {
+	case "", MissedPolicySkip, MissedPolicyGrace, MissedPolicyNotify:
+		return nil
+	}
+	return fmt.Errorf("missed_policy 必須為 %s、%s 或 %s", MissedPolicySkip, MissedPolicyGrace, MissedPolicyNotify)
+}
+
+// graceWindow grace 策略的寬限時間
+func (c *ScheduleConfig) graceWindow() time.Duration {
+	seconds := c.GraceSeconds
+	if seconds <= 0 {
+		seconds = defaultGraceSeconds
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// missedDecision 依策略決定錯過 late 時間後的處理結果
+func (c *ScheduleConfig) missedDecision(late time.Duration) string {
+	switch c.MissedPolicy {
+	case MissedPolicyGrace:
+		if late <= c.graceWindow() {
+			return MissedGraceSubmitted
+		}
+		return MissedGraceExpired
+	case MissedPolicyNotify:
+		return MissedNotified
+	}
+	return MissedSkipped
+}
+
+// missedMessage 錯過目標時間的說明，用於事件、通知與提交記錄
+func missedMessage(decision string, target time.Time, late time.Duration, grace time.Duration) string {
+	when := fmt.Sprintf("排程時間 %s 已過 %v", target.Format("2006-01-02 15:04:05"), late.Round(time.Second))
+	switch decision {
+	case MissedGraceSubmitted:
+		return fmt.Sprintf("%s，於寬限 %v 內立即送出", when, grace)
+	case MissedGraceExpired:
+		return fmt.Sprintf("%s，超過寬限 %v，未送出", when, grace)
+	case MissedNotified:
+		return when + "，未送出，請手動處理"
+	}
+	return when + "，已略過"
+}
@@ -0,0 +1,421 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"html/template"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MockFormPath 模擬表單的路徑前綴，與 Google Form 的網址結構相同
+const MockFormPath = "/forms/d/e/mock/"
+
+// mockFormFieldTitles 模擬表單各欄位的題目名稱
+var mockFormFieldTitles = map[string]string{
+	"name":        "姓名",
+	"employee_id": "員工編號",
+	"start_date":  "請假起始日期",
+	"end_date":    "請假結束日期",
+	"leave_type":  "假別",
+	"password":    "密碼",
+}
+
+// mockFormDefaultChoices 未設定 Choices 時有選項的欄位（以下拉選單呈現）
+var mockFormDefaultChoices = map[string][]string{
+	"leave_type": {"近假", "長假"},
+}
+
+// MockFormOptions 模擬表單的行為設定
+type MockFormOptions struct {
+	Title       string            `json:"title"`
+	EntryIDs    map[string]string `json:"entry_ids"`          // 欄位名稱 → entry ID
+	OpensAt     time.Time         `json:"opens_at,omitempty"` // 開放時間，之前顯示已停止接受回應
+	Closed      bool              `json:"closed"`             // 強制顯示已停止接受回應
+	LatencyMs   int               `json:"latency_ms"`         // 每次送出的回應延遲
+	ErrorRate   float64           `json:"error_rate"`         // 隨機回應錯誤的機率（0 ~ 1）
+	FailFirst   int               `json:"fail_first"`         // 前 N 次送出回應錯誤
+	ErrorStatus int               `json:"error_status"`       // 注入錯誤的 HTTP 狀態碼，預設 500
+
+	// Choices 欄位名稱 → 選項，有選項的欄位為下拉選單；未設定時假別為近假、長假
+	Choices map[string][]string `json:"choices,omitempty"`
+}
+
+// MockSubmission 模擬表單收到的送出
+type MockSubmission struct {
+	Seq        int        `json:"seq"`
+	At         time.Time  `json:"at"`
+	StatusCode int        `json:"status_code"`
+	Accepted   bool       `json:"accepted"`
+	Reason     string     `json:"reason,omitempty"`  // 未受理的原因
+	Missing    []string   `json:"missing,omitempty"` // 缺少的 entry ID
+	Form       url.Values `json:"form"`
+}
+
+// MockForm 模擬的 Google Form：提供 viewform 頁面與 formResponse 端點，
+// 可設定開放時間、延遲與錯誤，並記錄每次送出供檢查
+type MockForm struct {
+	mu          sync.Mutex
+	clock       Clock
+	opts        MockFormOptions
+	posts       int
+	submissions []MockSubmission
+	rand        *rand.Rand
+}
+
+// NewMockForm 建立模擬表單
+func NewMockForm(opts MockFormOptions) *MockForm {
+	return &MockForm{
+		clock: SystemClock,
+		opts:  opts.withDefaults(),
+		rand:  rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+// withDefaults 套用預設值
+func (o MockFormOptions) withDefaults() MockFormOptions {
+	if o.Title == "" {
+		o.Title = "請假申請（模擬表單）"
+	}
+	if o.ErrorStatus == 0 {
+		o.ErrorStatus = http.StatusInternalServerError
+	}
+	return o
+}
+
+// SetClock 設定判斷開放時間、注入延遲與記錄送出時間使用的時鐘
+func (m *MockForm) SetClock(c Clock) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.clock = c
+}
+
+// Options 取得目前的行為設定
+func (m *MockForm) Options() MockFormOptions {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.opts
+}
+
+// SetOptions 更換行為設定（已收到的送出保留，FailFirst 重新計算）
+func (m *MockForm) SetOptions(opts MockFormOptions) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.opts = opts.withDefaults()
+	m.posts = 0
+}
+
+// IsOpen 是否正在接受回應
+func (m *MockForm) IsOpen() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.isOpenLocked()
+}
+
+func (m *MockForm) isOpenLocked() bool {
+	if m.opts.Closed {
+		return false
+	}
+	return m.opts.OpensAt.IsZero() || !m.clock.Now().Before(m.opts.OpensAt)
+}
+
+// Submissions 取得收到的送出
+func (m *MockForm) Submissions() []MockSubmission {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]MockSubmission(nil), m.submissions...)
+}
+
+// Reset 清除收到的送出
+func (m *MockForm) Reset() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.submissions = nil
+	m.posts = 0
+}
+
+// ServeHTTP 依路徑提供 viewform、formResponse 與控制端點（/_mock/submissions、/_mock/options）
+func (m *MockForm) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case r.URL.Path == "/_mock/submissions":
+		m.serveSubmissions(w, r)
+	case r.URL.Path == "/_mock/options":
+		m.serveOptions(w, r)
+	case r.URL.Path == MockFormPath+"viewform":
+		m.serveViewForm(w)
+	case r.URL.Path == MockFormPath+"formResponse" && r.Method == http.MethodPost:
+		m.serveFormResponse(w, r)
+	case r.URL.Path == MockFormPath+"formResponse":
+		// 預熱連線的 HEAD 與直接開啟網址的 GET
+		w.WriteHeader(http.StatusOK)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// serveFormResponse 受理送出：依設定注入延遲、錯誤與停止接受回應，並記錄內容
+func (m *MockForm) serveFormResponse(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	form, _ := url.ParseQuery(string(body))
+
+	m.mu.Lock()
+	opts := m.opts
+	clock := m.clock
+	m.mu.Unlock()
+
+	if opts.LatencyMs > 0 {
+		clock.Sleep(time.Duration(opts.LatencyMs) * time.Millisecond)
+	}
+
+	m.mu.Lock()
+	m.posts++
+	sub := MockSubmission{Seq: len(m.submissions) + 1, At: m.clock.Now(), StatusCode: http.StatusOK, Form: form}
+	switch {
+	case !m.isOpenLocked():
+		sub.StatusCode = http.StatusForbidden
+		sub.Reason = "表單已停止接受回應"
+	case m.posts <= opts.FailFirst:
+		sub.StatusCode = opts.ErrorStatus
+		sub.Reason = fmt.Sprintf("注入錯誤（前 %d 次）", opts.FailFirst)
+	case opts.ErrorRate > 0 && m.rand.Float64() < opts.ErrorRate:
+		sub.StatusCode = opts.ErrorStatus
+		sub.Reason = "注入隨機錯誤"
+	}
+	if sub.StatusCode == http.StatusOK {
+		for _, entryID := range opts.EntryIDs {
+			if entryID != "" && form.Get(entryID) == "" {
+				sub.Missing = append(sub.Missing, entryID)
+			}
+		}
+		sort.Strings(sub.Missing)
+		if len(sub.Missing) > 0 {
+			sub.StatusCode = http.StatusBadRequest
+			sub.Reason = "缺少必填欄位"
+		}
+	}
+	sub.Accepted = sub.StatusCode == http.StatusOK
+	m.submissions = append(m.submissions, sub)
+	m.mu.Unlock()
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(sub.StatusCode)
+	switch {
+	case sub.StatusCode == http.StatusForbidden:
+		mockClosedPage.Execute(w, opts)
+	case sub.Accepted:
+		mockResponsePage.Execute(w, opts)
+	default:
+		fmt.Fprintf(w, "<html><body>%s</body></html>", template.HTMLEscapeString(sub.Reason))
+	}
+}
+
+// mockQuestion viewform 頁面上的一個題目
+type mockQuestion struct {
+	Field   string
+	Title   string
+	EntryID string
+	Choices []string
+}
+
+// mockFormData 仿照 Google Form 的 FB_PUBLIC_LOAD_DATA_：每題為 [題目 ID, 標題, 說明, 類型, [[entry, 選項, 必填]]]，
+// 題目 ID 由欄位名稱產生，更換 entry ID 時維持不變
+func mockFormData(title string, questions []mockQuestion) template.JS {
+	items := make([]any, 0, len(questions))
+	for _, q := range questions {
+		h := fnv.New32a()
+		h.Write([]byte(q.Field))
+		// Google 的 entry 為數字；無法表示為 JSON 數字的（如 012）保留字串
+		var entry any = strings.TrimPrefix(q.EntryID, "entry.")
+		if n, err := strconv.ParseInt(entry.(string), 10, 64); err == nil && strconv.FormatInt(n, 10) == entry {
+			entry = n
+		}
+		typeCode, choices := 0, any(nil)
+		if len(q.Choices) > 0 {
+			typeCode = 3
+			list := make([]any, 0, len(q.Choices))
+			for _, c := range q.Choices {
+				list = append(list, []any{c, nil, nil, nil, 0})
+			}
+			choices = list
+		}
+		items = append(items, []any{h.Sum32() % 1000000000, q.Title, nil, typeCode, []any{[]any{entry, choices, 1}}})
+	}
+	data, _ := json.Marshal([]any{nil, []any{nil, items, nil, nil, nil, nil, nil, nil, title}, "/forms", title})
+	return template.JS(data)
+}
+
+// serveViewForm 開放時顯示表單題目，否則顯示已停止接受回應
+func (m *MockForm) serveViewForm(w http.ResponseWriter) {
+	m.mu.Lock()
+	opts := m.opts
+	open := m.isOpenLocked()
+	m.mu.Unlock()
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if !open {
+		mockClosedPage.Execute(w, opts)
+		return
+	}
+
+	fields := make([]string, 0, len(opts.EntryIDs))
+	for field, entryID := range opts.EntryIDs {
+		if entryID != "" {
+			fields = append(fields, field)
+		}
+	}
+	sort.Strings(fields)
+
+	choices := opts.Choices
+	if choices == nil {
+		choices = mockFormDefaultChoices
+	}
+	questions := make([]mockQuestion, 0, len(fields))
+	for _, field := range fields {
+		title := mockFormFieldTitles[field]
+		if title == "" {
+			title = field
+		}
+		questions = append(questions, mockQuestion{Field: field, Title: title, EntryID: opts.EntryIDs[field], Choices: choices[field]})
+	}
+	mockViewPage.Execute(w, struct {
+		Title     string
+		Questions []mockQuestion
+		Data      template.JS
+	}{opts.Title, questions, mockFormData(opts.Title, questions)})
+}
+
+// serveSubmissions GET 列出收到的送出，DELETE 清除
+func (m *MockForm) serveSubmissions(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		writeMockJSON(w, m.Submissions())
+	case http.MethodDelete:
+		m.Reset()
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// serveOptions GET 取得行為設定，PUT 更換
+func (m *MockForm) serveOptions(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		writeMockJSON(w, m.Options())
+	case http.MethodPut:
+		var opts MockFormOptions
+		if err := json.NewDecoder(r.Body).Decode(&opts); err != nil {
+			http.Error(w, "設定格式錯誤: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		m.SetOptions(opts)
+		writeMockJSON(w, m.Options())
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func writeMockJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	json.NewEncoder(w).Encode(v)
+}
+
+var mockViewPage = template.Must(template.New("viewform").Parse(`<!DOCTYPE html>
+<html><head><meta charset="utf-8"><title>{{.Title}}</title></head>
+<body>
+<h1>{{.Title}}</h1>
+<form action="formResponse" method="POST">
+{{range .Questions}}<div class="question" data-field="{{.Field}}">
+<label>{{.Title}}</label>
+{{if .Choices}}<select name="{{.EntryID}}">{{range .Choices}}<option>{{.}}</option>{{end}}</select>
+{{else}}<input type="text" name="{{.EntryID}}">
+{{end}}</div>
+{{end}}<button type="submit">提交</button>
+</form>
+<script>var FB_PUBLIC_LOAD_DATA_ = {{.Data}};</script>
+</body></html>
+`))
+
+var mockClosedPage = template.Must(template.New("closedform").Parse(`<!DOCTYPE html>
+<html><head><meta charset="utf-8"><title>{{.Title}}</title></head>
+<body class="closedform">
+<h1>{{.Title}}</h1>
+<p>「{{.Title}}」表單已停止接受回應。</p>
+{{if not .OpensAt.IsZero}}<p>開放時間: {{.OpensAt.Format "2006-01-02 15:04:05"}}</p>{{end}}
+</body></html>
+`))
+
+var mockResponsePage = template.Must(template.New("formResponse").Parse(`<!DOCTYPE html>
+<html><head><meta charset="utf-8"><title>{{.Title}}</title></head>
+<body>
+<h1>{{.Title}}</h1>
+<p>我們已經收到您回覆的表單。</p>
+</body></html>
+`))
+
+// MockFormServer 在本機埠上執行的模擬表單
+type MockFormServer struct {
+	*MockForm
+	server  *http.Server
+	BaseURL string
+}
+
+// StartMockFormServer 在 addr（例如 127.0.0.1:0）啟動模擬表單
+func StartMockFormServer(form *MockForm, addr string) (*MockFormServer, error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("啟動模擬表單失敗: %w", err)
+	}
+
+	s := &MockFormServer{
+		MockForm: form,
+		server:   &http.Server{Handler: form},
+		BaseURL:  "http://" + listener.Addr().String(),
+	}
+	go s.server.Serve(listener)
+	return s, nil
+}
+
+// FormURL 送出網址（設定為 form_url）
+func (s *MockFormServer) FormURL() string {
+	return s.BaseURL + MockFormPath + "formResponse"
+}
+
+// ViewFormURL 表單頁面網址
+func (s *MockFormServer) ViewFormURL() string {
+	return s.BaseURL + MockFormPath + "viewform"
+}
+
+// Close 關閉模擬表單
+func (s *MockFormServer) Close() error {
+	return s.server.Close()
+}
+
+// MockEntryIDs 將以逗號分隔的 field=entry.N 設定覆寫到 base 的複本上
+func MockEntryIDs(base map[string]string, overrides string) (map[string]string, error) {
+	ids := make(map[string]string, len(base))
+	for field, entryID := range base {
+		ids[field] = entryID
+	}
+	for _, pair := range strings.Split(overrides, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		field, entryID, ok := strings.Cut(pair, "=")
+		if !ok || field == "" {
+			return nil, fmt.Errorf("entry 設定格式錯誤（應為 欄位=entry.N）: %s", pair)
+		}
+		ids[field] = entryID
+	}
+	return ids, nil
+}
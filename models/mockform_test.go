@@ -0,0 +1,168 @@
+package models
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestMockFormOpening 測試模擬表單依開放時間切換頁面與受理送出
+func TestMockFormOpening(t *testing.T) {
+	opensAt := time.Date(2026, 3, 1, 0, 0, 0, 0, time.Local)
+	clock := NewFakeClock(opensAt.Add(-time.Minute))
+	form := NewMockForm(MockFormOptions{
+		EntryIDs: map[string]string{"name": "entry.1", "employee_id": "entry.2"},
+		OpensAt:  opensAt,
+	})
+	form.SetClock(clock)
+	server := httptest.NewServer(form)
+	defer server.Close()
+
+	viewform := func() string {
+		resp, err := http.Get(server.URL + MockFormPath + "viewform")
+		if err != nil {
+			t.Fatalf("讀取表單頁面失敗: %v", err)
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return string(body)
+	}
+	submit := func(values url.Values) int {
+		resp, err := http.PostForm(server.URL+MockFormPath+"formResponse", values)
+		if err != nil {
+			t.Fatalf("送出失敗: %v", err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	full := url.Values{"entry.1": {"王小明"}, "entry.2": {"A1"}}
+
+	// 開放前
+	if page := viewform(); !strings.Contains(page, "已停止接受回應") {
+		t.Errorf("開放前應顯示已停止接受回應，實際 %s", page)
+	}
+	if status := submit(full); status != http.StatusForbidden {
+		t.Errorf("開放前送出應回應 403，實際 %d", status)
+	}
+
+	// 開放後
+	clock.Set(opensAt)
+	page := viewform()
+	if !strings.Contains(page, `name="entry.1"`) || !strings.Contains(page, `name="entry.2"`) {
+		t.Errorf("開放後應顯示設定的 entry ID，實際 %s", page)
+	}
+	if status := submit(url.Values{"entry.1": {"王小明"}}); status != http.StatusBadRequest {
+		t.Errorf("缺少欄位應回應 400，實際 %d", status)
+	}
+	if status := submit(full); status != http.StatusOK {
+		t.Errorf("完整送出應回應 200，實際 %d", status)
+	}
+
+	subs := form.Submissions()
+	if len(subs) != 3 {
+		t.Fatalf("應記錄 3 次送出，實際 %d", len(subs))
+	}
+	if subs[0].Accepted || subs[1].Accepted || !subs[2].Accepted {
+		t.Errorf("只有最後一次應被受理，實際 %+v", subs)
+	}
+	if len(subs[1].Missing) != 1 || subs[1].Missing[0] != "entry.2" {
+		t.Errorf("應記錄缺少 entry.2，實際 %v", subs[1].Missing)
+	}
+	if !subs[2].At.Equal(opensAt) || subs[2].Form.Get("entry.2") != "A1" {
+		t.Errorf("送出記錄應包含模擬時間與內容，實際 %+v", subs[2])
+	}
+}
+
+// TestMockFormControl 測試注入錯誤與控制端點
+func TestMockFormControl(t *testing.T) {
+	form := NewMockForm(MockFormOptions{EntryIDs: map[string]string{"name": "entry.1"}, FailFirst: 2, ErrorStatus: http.StatusServiceUnavailable})
+	server := httptest.NewServer(form)
+	defer server.Close()
+
+	prepared, err := newPreparedRequest(NewGoogleFormSubmitter(server.URL+MockFormPath+"formResponse", map[string]string{"name": "entry.1"}), &LeaveRequest{Name: "王小明"})
+	if err != nil {
+		t.Fatalf("建立請求失敗: %v", err)
+	}
+	attempts, err := sendWithRetry(t.Context(), prepared, RetryPolicy{Count: 3, Interval: 1}, log.New(io.Discard, "", 0), nil)
+	if err != nil || len(attempts) != 3 {
+		t.Fatalf("前 2 次注入錯誤後應在第 3 次成功，實際 %d 次: %v", len(attempts), err)
+	}
+	// 每次嘗試都記錄網路各階段耗時：第一次建立連線，讀完錯誤回應後重用同一條連線
+	for i, a := range attempts {
+		wantStatus := http.StatusServiceUnavailable
+		if i == 2 {
+			wantStatus = http.StatusOK
+		}
+		if a.Attempt != i+1 || a.StatusCode != wantStatus || (a.Error != "") != (i < 2) || a.Timing == nil {
+			t.Fatalf("第 %d 次嘗試記錄不正確: %+v", i+1, a)
+		}
+		if a.Timing.Reused != (i > 0) || a.Timing.RemoteAddr != server.Listener.Addr().String() ||
+			a.Timing.TotalUs <= 0 || a.Timing.TotalUs < a.Timing.WaitUs+a.Timing.ConnectUs {
+			t.Errorf("第 %d 次嘗試的耗時不正確: %+v", i+1, a.Timing)
+		}
+	}
+	if attempts[0].Timing.TLSUs != 0 || attempts[0].Timing.IdleUs != 0 {
+		t.Errorf("第一次嘗試應建立新的 TCP 連線且沒有 TLS，實際 %+v", attempts[0].Timing)
+	}
+
+	// 控制端點
+	resp, err := http.Get(server.URL + "/_mock/submissions")
+	if err != nil {
+		t.Fatalf("讀取送出記錄失敗: %v", err)
+	}
+	var subs []MockSubmission
+	json.NewDecoder(resp.Body).Decode(&subs)
+	resp.Body.Close()
+	if len(subs) != 3 || subs[0].StatusCode != http.StatusServiceUnavailable || !subs[2].Accepted {
+		t.Errorf("送出記錄不正確: %+v", subs)
+	}
+
+	// 直接提交也回傳網路各階段耗時
+	result, err := NewGoogleFormSubmitter(server.URL+MockFormPath+"formResponse", map[string]string{"name": "entry.1"}).Submit(&LeaveRequest{
+		Name: "王小明", EmployeeID: "A1", StartDate: "2026-03-02", EndDate: "2026-03-03", LeaveType: "近假", Password: "p",
+	})
+	if err != nil || !result.Success || result.Timing == nil || result.Timing.TotalUs <= 0 {
+		t.Errorf("直接提交應回傳網路耗時，實際 %+v（%v）", result, err)
+	}
+
+	req, _ := http.NewRequest(http.MethodPut, server.URL+"/_mock/options", strings.NewReader(`{"closed": true}`))
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil || resp.StatusCode != http.StatusOK {
+		t.Fatalf("更新設定失敗: %v", err)
+	}
+	resp.Body.Close()
+	if form.IsOpen() {
+		t.Error("設定 closed 後應停止接受回應")
+	}
+
+	req, _ = http.NewRequest(http.MethodDelete, server.URL+"/_mock/submissions", nil)
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("清除送出記錄失敗: %v", err)
+	}
+	resp.Body.Close()
+	if len(form.Submissions()) != 0 {
+		t.Error("清除後不應有送出記錄")
+	}
+}
+
+// TestMockEntryIDs 測試 entry ID 覆寫
+func TestMockEntryIDs(t *testing.T) {
+	base := map[string]string{"name": "entry.1", "password": "entry.6"}
+	ids, err := MockEntryIDs(base, "name=entry.9, password=")
+	if err != nil {
+		t.Fatalf("解析失敗: %v", err)
+	}
+	if ids["name"] != "entry.9" || ids["password"] != "" || base["name"] != "entry.1" {
+		t.Errorf("覆寫結果不正確: %v（原設定 %v）", ids, base)
+	}
+	if _, err := MockEntryIDs(base, "entry.9"); err == nil {
+		t.Error("缺少欄位名稱應回傳錯誤")
+	}
+}
@@ -0,0 +1,188 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// 送出策略
+const (
+	StrategyFixed = "fixed" // 於目標時間送出（預設）
+	StrategyPoll  = "poll"  // 目標時間前開始探測 viewform 頁面，表單一開放就送出
+)
+
+// poll 策略未指定時的預設值
+const (
+	defaultPollLeadSeconds     = 10  // 目標時間前幾秒開始探測
+	defaultPollIntervalMs      = 250 // 探測間隔
+	defaultPollDeadlineSeconds = 60  // 目標時間後最多再探測幾秒
+	minPollIntervalMs          = 50
+)
+
+// pollProbeTimeout 單次探測的時間上限
+const pollProbeTimeout = 5 * time.Second
+
+// validateStrategy 檢查送出策略與 poll 參數，空字串視為 fixed
+func (c *ScheduleConfig) validateStrategy() error {
+	switch c.Strategy {
+	case "", StrategyFixed:
+		return nil
+	case StrategyPoll:
+	default:
+		return fmt.Errorf("strategy 必須為 %s 或 %s", StrategyFixed, StrategyPoll)
+	}
+	if c.PollLeadSeconds < 0 || c.PollLeadSeconds > 600 {
+		return fmt.Errorf("poll_lead_seconds 必須介於 0 到 600")
+	}
+	if c.PollIntervalMs != 0 && c.PollIntervalMs < minPollIntervalMs {
+		return fmt.Errorf("poll_interval_ms 不可小於 %d", minPollIntervalMs)
+	}
+	if c.PollDeadlineSeconds < 0 || c.PollDeadlineSeconds > 3600 {
+		return fmt.Errorf("poll_deadline_seconds 必須介於 0 到 3600")
+	}
+	return nil
+}
+
+// polling 是否使用 poll 策略
+func (c *ScheduleConfig) polling() bool {
+	return c.Strategy == StrategyPoll
+}
+
+// pollWindow poll 策略開始探測與停止探測的時間
+func (c *ScheduleConfig) pollWindow(target time.Time) (start, deadline time.Time) {
+	lead := c.PollLeadSeconds
+	if lead == 0 {
+		lead = defaultPollLeadSeconds
+	}
+	after := c.PollDeadlineSeconds
+	if after == 0 {
+		after = defaultPollDeadlineSeconds
+	}
+	return target.Add(-time.Duration(lead) * time.Second), target.Add(time.Duration(after) * time.Second)
+}
+
+// pollInterval poll 策略的探測間隔
+func (c *ScheduleConfig) pollInterval() time.Duration {
+	ms := c.PollIntervalMs
+	if ms == 0 {
+		ms = defaultPollIntervalMs
+	}
+	return time.Duration(ms) * time.Millisecond
+}
+
+// latestSendTime 最晚的送出時間：fixed 為目標時間，poll 為停止探測的時間
+func (c *ScheduleConfig) latestSendTime(target time.Time) time.Time {
+	if !c.polling() {
+		return target
+	}
+	_, deadline := c.pollWindow(target)
+	return deadline
+}
+
+// formAccepting 判斷 viewform 回應是否為開放中的表單
+func formAccepting(resp *http.Response, page string) bool {
+	if resp.StatusCode != http.StatusOK {
+		return false
+	}
+	for _, marker := range closedFormMarkers {
+		if strings.Contains(page, marker) || strings.Contains(resp.Request.URL.Path, marker) {
+			return false
+		}
+	}
+	return true
+}
+
+// probeForm 讀取一次 viewform 頁面，回傳表單是否開放與請求各階段的耗時
+func probeForm(ctx context.Context, client *http.Client, viewURL string) (bool, *RequestTiming, error) {
+	ctx, cancel := context.WithTimeout(ctx, pollProbeTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, viewURL, nil)
+	if err != nil {
+		return false, nil, err
+	}
+	req.Header.Set("Cache-Control", "no-cache")
+	req, trace := traceRequest(req)
+	resp, err := client.Do(req)
+	if err != nil {
+		return false, trace.Timing(), err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 4<<20))
+	if err != nil {
+		return false, trace.Timing(), err
+	}
+	return formAccepting(resp, string(body)), trace.Timing(), nil
+}
+
+// pollUntilOpen 從開始探測的時間起，每隔探測間隔讀取 viewform 頁面，表單開放時回傳偵測到的時間；
+// 到停止探測的時間仍未開放時回傳錯誤，job 被取消時 ok 為 false
+func (s *Scheduler) pollUntilOpen(job *scheduleJob) (openedAt time.Time, probes int, ok bool, err error) {
+	cfg := &job.config
+	start, deadline := cfg.pollWindow(job.targetTime)
+	interval := cfg.pollInterval()
+	viewURL, _ := viewFormURL(s.submitter.FormURL)
+
+	if !s.waitUntil(job, start) {
+		return time.Time{}, 0, false, nil
+	}
+	s.logger.Printf("開始探測表單，間隔 %v，最晚探測到 %s", interval, deadline.Format("15:04:05"))
+	s.publish(job.targetTime, ScheduleEvent{
+		Type:    ScheduleEventPolling,
+		Message: fmt.Sprintf("每 %v 探測一次，最晚到 %s", interval, deadline.Format("15:04:05")),
+	})
+
+	var lastErr error
+	for {
+		probes++
+		open, timing, err := probeForm(job.ctx, s.submitter.HTTPClient, viewURL)
+		now := s.clock.Now()
+		if job.ctx.Err() != nil {
+			return time.Time{}, probes, false, nil
+		}
+		if err != nil {
+			// 探測失敗時繼續，只記錄第一次與不同的錯誤
+			if lastErr == nil || lastErr.Error() != err.Error() {
+				s.logger.Printf("警告: 探測表單失敗: %v（%s）", err, timing)
+			}
+			lastErr = err
+		}
+		if open {
+			s.logger.Printf("偵測到表單開放的探測: %s", timing)
+			return now, probes, true, nil
+		}
+		if !now.Before(deadline) {
+			message := fmt.Sprintf("探測 %d 次，表單至 %s 仍未開放", probes, deadline.Format("15:04:05"))
+			if lastErr != nil {
+				message += fmt.Sprintf("（最後錯誤: %v）", lastErr)
+			}
+			return time.Time{}, probes, true, errors.New(message)
+		}
+
+		timer := s.clock.NewTimer(min(interval, deadline.Sub(now)))
+		select {
+		case <-timer.C():
+		case <-job.ctx.Done():
+			timer.Stop()
+			return time.Time{}, probes, false, nil
+		}
+	}
+}
+
+// describeOpening 描述表單開放時間與目標時間的差距
+func describeOpening(openedAt, target time.Time, probes int) string {
+	diff := openedAt.Sub(target).Round(time.Millisecond)
+	when := "準時"
+	switch {
+	case diff < 0:
+		when = fmt.Sprintf("比目標時間早 %v", -diff)
+	case diff > 0:
+		when = fmt.Sprintf("比目標時間晚 %v", diff)
+	}
+	return fmt.Sprintf("探測 %d 次後於 %s 偵測到表單開放（%s）", probes, openedAt.Format("15:04:05.000"), when)
+}
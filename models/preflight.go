@@ -0,0 +1,391 @@
+package models
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// 排程前檢查的執行時機
+const (
+	PreflightPhaseArm     = "arm"     // 排程啟動時
+	PreflightPhasePrepare = "prepare" // 準備階段
+	PreflightPhaseManual  = "manual"  // 手動檢查（不啟動排程）
+)
+
+// 單項檢查結果
+const (
+	PreflightOK      = "ok"
+	PreflightWarn    = "warn"
+	PreflightFail    = "fail"
+	PreflightSkipped = "skipped"
+)
+
+// 檢查項目
+const (
+	PreflightCheckData         = "data"         // 請假資料驗證（含密碼解密）
+	PreflightCheckDateWindow   = "date_window"  // 送出時間是否落在預約窗口內
+	PreflightCheckReachability = "reachability" // 表單主機的 DNS 與 TLS 連線
+	PreflightCheckEntryIDs     = "entry_ids"    // viewform 頁面的 entry ID 是否符合 entry_map
+	PreflightCheckClockSkew    = "clock_skew"   // 本機時間與表單伺服器時間的誤差
+
+	PreflightCheckFormStructure = "form_structure" // 表單結構是否與指紋相符
+)
+
+// preflightTimeout 單次檢查中網路部分的時間上限
+const preflightTimeout = 10 * time.Second
+
+// preflightMargin 準備階段的檢查至少在送出時間前此時間結束
+const preflightMargin = time.Second
+
+// 時間誤差超過 preflightSkewWarn 警告，超過 preflightSkewFail 視為失敗
+const (
+	preflightSkewWarn = time.Second
+	preflightSkewFail = 5 * time.Second
+)
+
+// PreflightCheck 單項檢查結果
+type PreflightCheck struct {
+	Name      string `json:"name"`
+	Status    string `json:"status"` // ok / warn / fail / skipped
+	Message   string `json:"message"`
+	ElapsedMs int64  `json:"elapsed_ms,omitempty"`
+
+	Timing *RequestTiming `json:"timing,omitempty"` // 需要連線的項目：網路各階段耗時
+}
+
+// PreflightReport 排程前檢查報告
+type PreflightReport struct {
+	Phase     string           `json:"phase"` // arm / prepare / manual
+	CheckedAt time.Time        `json:"checked_at"`
+	Passed    bool             `json:"passed"` // 沒有任何一項失敗
+	Checks    []PreflightCheck `json:"checks"`
+}
+
+// add 加入一項檢查結果
+func (r *PreflightReport) add(name, status, message string, elapsed time.Duration) {
+	r.Checks = append(r.Checks, PreflightCheck{Name: name, Status: status, Message: message, ElapsedMs: elapsed.Milliseconds()})
+	if status == PreflightFail {
+		r.Passed = false
+	}
+}
+
+// attachTiming 將網路各階段耗時附加到指定項目
+func (r *PreflightReport) attachTiming(name string, timing *RequestTiming) {
+	if check := r.Check(name); check != nil {
+		check.Timing = timing
+	}
+}
+
+// Check 取得指定項目的結果，不存在時為 nil
+func (r *PreflightReport) Check(name string) *PreflightCheck {
+	for i := range r.Checks {
+		if r.Checks[i].Name == name {
+			return &r.Checks[i]
+		}
+	}
+	return nil
+}
+
+// Summary 以一行文字摘要失敗與警告的項目
+func (r *PreflightReport) Summary() string {
+	var problems []string
+	for _, c := range r.Checks {
+		if c.Status == PreflightFail || c.Status == PreflightWarn {
+			problems = append(problems, fmt.Sprintf("[%s] %s", c.Name, c.Message))
+		}
+	}
+	if len(problems) == 0 {
+		return "全部通過"
+	}
+	return strings.Join(problems, "；")
+}
+
+// runPreflight 執行全部檢查：資料與預約窗口在本機檢查，其餘連線到表單主機
+func (s *Scheduler) runPreflight(ctx context.Context, cfg *ScheduleConfig, target time.Time, phase string) *PreflightReport {
+	report := &PreflightReport{Phase: phase, CheckedAt: s.clock.Now(), Passed: true}
+
+	req, err := s.loadLeaveRequest(cfg, target)
+	if err != nil {
+		report.add(PreflightCheckData, PreflightFail, err.Error(), 0)
+	} else {
+		report.add(PreflightCheckData, PreflightOK, fmt.Sprintf("%s %s ~ %s %s", req.Name, req.StartDate, req.EndDate, req.LeaveType), 0)
+	}
+	s.checkDateWindow(report, req, target)
+
+	ctx, cancel := context.WithTimeout(ctx, preflightTimeout)
+	defer cancel()
+	s.checkReachability(ctx, report)
+	s.checkViewForm(ctx, report)
+
+	return report
+}
+
+// checkDateWindow 檢查送出時間：有預約窗口時需落在窗口內，否則至少早於請假起點日期
+func (s *Scheduler) checkDateWindow(report *PreflightReport, req *LeaveRequest, target time.Time) {
+	if req == nil {
+		report.add(PreflightCheckDateWindow, PreflightSkipped, "請假資料無效，無法檢查", 0)
+		return
+	}
+
+	if s.bookingWindow == nil {
+		start, err := time.ParseInLocation("2006-01-02", req.StartDate, target.Location())
+		switch {
+		case err != nil:
+			report.add(PreflightCheckDateWindow, PreflightFail, "請假起點日期格式錯誤", 0)
+		case !target.Before(start):
+			report.add(PreflightCheckDateWindow, PreflightWarn, fmt.Sprintf("送出時間 %s 不早於請假起點日期 %s", target.Format("2006-01-02 15:04:05"), req.StartDate), 0)
+		default:
+			report.add(PreflightCheckDateWindow, PreflightOK, fmt.Sprintf("送出時間早於請假起點日期 %s", req.StartDate), 0)
+		}
+		return
+	}
+
+	info, err := s.bookingWindow.Check(req.StartDate, target)
+	switch {
+	case err != nil:
+		report.add(PreflightCheckDateWindow, PreflightFail, err.Error(), 0)
+	case info.Status == BookingWindowPending:
+		report.add(PreflightCheckDateWindow, PreflightFail, fmt.Sprintf("送出時間早於窗口開放時間 %s", info.OpensAt.Format("2006-01-02 15:04")), 0)
+	case info.Status == BookingWindowClosed:
+		report.add(PreflightCheckDateWindow, PreflightFail, fmt.Sprintf("送出時間晚於窗口截止時間 %s", info.ClosesAt.Format("2006-01-02 15:04")), 0)
+	default:
+		report.add(PreflightCheckDateWindow, PreflightOK, fmt.Sprintf("窗口 %s ~ %s", info.OpensAt.Format("2006-01-02 15:04"), info.ClosesAt.Format("2006-01-02 15:04")), 0)
+	}
+}
+
+// checkReachability 解析表單主機並完成 TLS 交握（非 HTTPS 時只建立 TCP 連線）
+func (s *Scheduler) checkReachability(ctx context.Context, report *PreflightReport) {
+	u, err := url.Parse(s.submitter.FormURL)
+	if err != nil || u.Hostname() == "" {
+		report.add(PreflightCheckReachability, PreflightFail, "表單網址格式錯誤", 0)
+		return
+	}
+	host, port := u.Hostname(), u.Port()
+	if port == "" {
+		port = "443"
+		if u.Scheme == "http" {
+			port = "80"
+		}
+	}
+
+	started := time.Now()
+	addrs, err := net.DefaultResolver.LookupHost(ctx, host)
+	dnsElapsed := time.Since(started)
+	if err != nil {
+		report.add(PreflightCheckReachability, PreflightFail, fmt.Sprintf("DNS 解析 %s 失敗: %v", host, err), dnsElapsed)
+		return
+	}
+
+	dialStarted := time.Now()
+	var conn net.Conn
+	if u.Scheme == "https" {
+		dialer := &tls.Dialer{Config: &tls.Config{ServerName: host}}
+		conn, err = dialer.DialContext(ctx, "tcp", net.JoinHostPort(host, port))
+	} else {
+		var dialer net.Dialer
+		conn, err = dialer.DialContext(ctx, "tcp", net.JoinHostPort(host, port))
+	}
+	dialElapsed := time.Since(dialStarted)
+	if err != nil {
+		report.add(PreflightCheckReachability, PreflightFail, fmt.Sprintf("連線 %s 失敗: %v", host, err), time.Since(started))
+		return
+	}
+	defer conn.Close()
+
+	message := fmt.Sprintf("DNS %v（%d 個位址）", dnsElapsed.Round(time.Millisecond), len(addrs))
+	if tlsConn, ok := conn.(*tls.Conn); ok {
+		message += fmt.Sprintf("、TLS %v（%s）", dialElapsed.Round(time.Millisecond), tls.VersionName(tlsConn.ConnectionState().Version))
+	} else {
+		message += fmt.Sprintf("、TCP %v（非 HTTPS）", dialElapsed.Round(time.Millisecond))
+	}
+	report.add(PreflightCheckReachability, PreflightOK, message, time.Since(started))
+}
+
+// viewFormURL 由 formResponse 網址推得 viewform 網址
+func viewFormURL(formURL string) (string, bool) {
+	base, ok := strings.CutSuffix(formURL, "/formResponse")
+	if !ok {
+		return "", false
+	}
+	return base + "/viewform", true
+}
+
+// entryIDPattern viewform 頁面上的 entry ID（input 名稱）；
+// formDataIDPattern 為 FB_PUBLIC_LOAD_DATA_ 中題目的 entry 數字
+var (
+	entryIDPattern    = regexp.MustCompile(`entry\.(\d+)`)
+	formDataIDPattern = regexp.MustCompile(`\[\[(\d+),`)
+)
+
+// 表單未開放時 viewform 頁面上的文字
+var closedFormMarkers = []string{"已停止接受回應", "no longer accepting responses", "closedform"}
+
+// checkViewForm 讀取 viewform 頁面比對 entry_map，並以回應的 Date 標頭估計本機時間誤差
+func (s *Scheduler) checkViewForm(ctx context.Context, report *PreflightReport) {
+	target, ok := viewFormURL(s.submitter.FormURL)
+	if !ok {
+		target = s.submitter.FormURL
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		report.add(PreflightCheckEntryIDs, PreflightFail, "表單網址格式錯誤", 0)
+		report.add(PreflightCheckClockSkew, PreflightSkipped, "無法連線到表單", 0)
+		report.add(PreflightCheckFormStructure, PreflightSkipped, "無法連線到表單", 0)
+		return
+	}
+	req, trace := traceRequest(req)
+	sent := time.Now()
+	resp, err := s.submitter.HTTPClient.Do(req)
+	if err != nil {
+		report.add(PreflightCheckEntryIDs, PreflightFail, fmt.Sprintf("讀取表單頁面失敗: %v", err), time.Since(sent))
+		report.attachTiming(PreflightCheckEntryIDs, trace.Timing())
+		report.add(PreflightCheckClockSkew, PreflightSkipped, "無法連線到表單", 0)
+		report.add(PreflightCheckFormStructure, PreflightSkipped, "無法連線到表單", 0)
+		return
+	}
+	received := time.Now()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 4<<20))
+	resp.Body.Close()
+	elapsed := time.Since(sent)
+	timing := trace.Timing()
+
+	switch {
+	case !ok:
+		report.add(PreflightCheckEntryIDs, PreflightSkipped, "表單網址不是 formResponse，無法推得 viewform 頁面", elapsed)
+		report.add(PreflightCheckFormStructure, PreflightSkipped, "無法推得 viewform 頁面", 0)
+	case err != nil:
+		report.add(PreflightCheckEntryIDs, PreflightFail, fmt.Sprintf("讀取表單頁面失敗: %v", err), elapsed)
+		report.add(PreflightCheckFormStructure, PreflightSkipped, "讀取表單頁面失敗", 0)
+	case !formAccepting(resp, string(body)):
+		s.compareEntryIDs(report, resp, string(body), elapsed)
+		report.add(PreflightCheckFormStructure, PreflightSkipped, "表單目前未開放，無法比對結構", 0)
+	default:
+		s.compareEntryIDs(report, resp, string(body), elapsed)
+		s.checkFormStructure(report, string(body))
+	}
+	report.attachTiming(PreflightCheckEntryIDs, timing)
+
+	s.checkClockSkew(report, resp.Header.Get("Date"), sent, received)
+}
+
+// compareEntryIDs 比對 viewform 頁面上的 entry ID 與 entry_map
+func (s *Scheduler) compareEntryIDs(report *PreflightReport, resp *http.Response, page string, elapsed time.Duration) {
+	for _, marker := range closedFormMarkers {
+		if strings.Contains(page, marker) || strings.Contains(resp.Request.URL.Path, marker) {
+			report.add(PreflightCheckEntryIDs, PreflightWarn, "表單目前未開放，無法比對 entry ID", elapsed)
+			return
+		}
+	}
+	if resp.StatusCode != http.StatusOK {
+		report.add(PreflightCheckEntryIDs, PreflightFail, fmt.Sprintf("表單頁面回應 HTTP %d", resp.StatusCode), elapsed)
+		return
+	}
+
+	onPage := make(map[string]bool)
+	for _, m := range entryIDPattern.FindAllStringSubmatch(page, -1) {
+		onPage[m[1]] = true
+	}
+	if i := strings.Index(page, "FB_PUBLIC_LOAD_DATA_"); i >= 0 {
+		for _, m := range formDataIDPattern.FindAllStringSubmatch(page[i:], -1) {
+			onPage[m[1]] = true
+		}
+	}
+
+	entryMap := s.submitter.EntryMapSnapshot()
+	fields := make([]string, 0, len(entryMap))
+	for field := range entryMap {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	var missing []string
+	mapped := 0
+	for _, field := range fields {
+		entryID := entryMap[field]
+		if entryID == "" {
+			continue
+		}
+		mapped++
+		if !onPage[strings.TrimPrefix(entryID, "entry.")] {
+			missing = append(missing, fmt.Sprintf("%s（%s）", field, entryID))
+		}
+	}
+
+	switch {
+	case mapped == 0:
+		report.add(PreflightCheckEntryIDs, PreflightFail, "entry_map 未設定任何 entry ID", elapsed)
+	case len(missing) > 0:
+		report.add(PreflightCheckEntryIDs, PreflightFail, "表單上找不到 "+strings.Join(missing, "、"), elapsed)
+	default:
+		report.add(PreflightCheckEntryIDs, PreflightOK, fmt.Sprintf("%d 個欄位皆在表單上", mapped), elapsed)
+	}
+}
+
+// checkFormStructure 比對頁面上的表單結構與儲存的指紋；沒有指紋時只警告，不建立指紋
+// （預檢為唯讀檢查，指紋只由 POST /api/form/structure/baseline 建立）
+func (s *Scheduler) checkFormStructure(report *PreflightReport, page string) {
+	start := time.Now()
+	current, err := ParseFormStructure(page)
+	if err != nil {
+		report.add(PreflightCheckFormStructure, PreflightWarn, err.Error(), time.Since(start))
+		return
+	}
+	baseline, err := s.storage.GetFormFingerprint(s.submitter.FormURL)
+	if err != nil {
+		report.add(PreflightCheckFormStructure, PreflightWarn, err.Error(), time.Since(start))
+		return
+	}
+	check := CompareFormStructure(current, baseline, s.submitter.EntryMapSnapshot())
+	status := PreflightOK
+	switch check.Status {
+	case FormCheckDrift:
+		status = PreflightFail
+	case FormCheckNoBaseline:
+		status = PreflightWarn
+		check.Message += "，請於表單結構頁面確認後接受目前結構為指紋"
+	}
+	report.add(PreflightCheckFormStructure, status, check.Message, time.Since(start))
+}
+
+// checkClockSkew 以回應的 Date 標頭（精確到秒）與請求往返的中點估計本機時間誤差；
+// 使用測試或演練時鐘時略過
+func (s *Scheduler) checkClockSkew(report *PreflightReport, date string, sent, received time.Time) {
+	if s.clock != SystemClock {
+		report.add(PreflightCheckClockSkew, PreflightSkipped, "使用模擬時鐘，略過", 0)
+		return
+	}
+	serverTime, err := http.ParseTime(date)
+	if err != nil {
+		report.add(PreflightCheckClockSkew, PreflightSkipped, "回應沒有 Date 標頭", 0)
+		return
+	}
+
+	// Date 只到秒，取該秒的中點；往返時間過長時估計不準，一併列出
+	rtt := received.Sub(sent)
+	local := sent.Add(rtt / 2)
+	skew := local.Sub(serverTime.Add(500 * time.Millisecond)).Round(time.Millisecond)
+	abs, direction := skew, "快"
+	if skew < 0 {
+		abs, direction = -skew, "慢"
+	}
+	message := fmt.Sprintf("本機比伺服器%s %v（往返 %v，Date 標頭精確到秒）", direction, abs, rtt.Round(time.Millisecond))
+
+	switch {
+	case abs > preflightSkewFail:
+		report.add(PreflightCheckClockSkew, PreflightFail, message, 0)
+	case abs > preflightSkewWarn+rtt/2:
+		report.add(PreflightCheckClockSkew, PreflightWarn, message, 0)
+	default:
+		report.add(PreflightCheckClockSkew, PreflightOK, message, 0)
+	}
+}
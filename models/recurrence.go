@@ -0,0 +1,417 @@
+package models
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	_ "time/tzdata" // 確保沒有系統時區資料時仍可載入 Asia/Taipei 等時區
+
+	"github.com/robfig/cron/v3"
+)
+
+// 週期規則格式
+const (
+	RecurrenceCron  = "cron"  // cron 表達式（5 欄，或含秒的 6 欄）
+	RecurrenceRRule = "rrule" // iCalendar RRULE（支援常用子集）
+)
+
+// rruleSearchDays RRULE 往後搜尋下一次觸發日的最大天數
+const rruleSearchDays = 366 * 30
+
+// cronParser 接受可選的秒欄位與 @monthly 等描述符
+var cronParser = cron.NewParser(cron.SecondOptional | cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor)
+
+// rruleWeekdays RRULE BYDAY 的星期代碼
+var rruleWeekdays = map[string]time.Weekday{
+	"SU": time.Sunday,
+	"MO": time.Monday,
+	"TU": time.Tuesday,
+	"WE": time.Wednesday,
+	"TH": time.Thursday,
+	"FR": time.Friday,
+	"SA": time.Saturday,
+}
+
+// Recurrence 解析後的週期規則，於指定時區計算下一次觸發時間
+type Recurrence struct {
+	Kind     string
+	Spec     string
+	Location *time.Location
+	Count    int       // RRULE COUNT，0 表示未設定
+	Until    time.Time // RRULE UNTIL，零值表示未設定
+
+	cron cron.Schedule
+	rule *rrule
+}
+
+// rruleDay BYDAY 項目，Nth 非 0 時表示當月第 N 個（負數從月底算起）
+type rruleDay struct {
+	Weekday time.Weekday
+	Nth     int
+}
+
+// rrule RRULE 支援的欄位
+type rrule struct {
+	freq       string
+	interval   int
+	dtstart    time.Time
+	byMonth    []int
+	byMonthDay []int
+	byDay      []rruleDay
+	byHour     []int
+	byMinute   []int
+	bySecond   []int
+}
+
+// ParseRecurrence 解析週期規則；anchor 為 RRULE 未指定 DTSTART 時的起算時間
+func ParseRecurrence(kind, spec, timezone string, anchor time.Time) (*Recurrence, error) {
+	spec = strings.TrimSpace(spec)
+	if spec == "" {
+		return nil, &ValidationError{Field: "spec", Message: "週期規則為必填欄位"}
+	}
+
+	loc := time.Local
+	if timezone != "" {
+		var err error
+		if loc, err = time.LoadLocation(timezone); err != nil {
+			return nil, &ValidationError{Field: "timezone", Message: fmt.Sprintf("無法辨識的時區: %s", timezone)}
+		}
+	}
+
+	r := &Recurrence{Kind: kind, Spec: spec, Location: loc}
+	switch kind {
+	case RecurrenceCron:
+		if strings.HasPrefix(spec, "TZ=") || strings.HasPrefix(spec, "CRON_TZ=") {
+			return nil, &ValidationError{Field: "spec", Message: "請以 timezone 欄位指定時區"}
+		}
+		schedule, err := cronParser.Parse(spec)
+		if err != nil {
+			return nil, &ValidationError{Field: "spec", Message: fmt.Sprintf("cron 表達式錯誤: %v", err)}
+		}
+		r.cron = schedule
+	case RecurrenceRRule:
+		rule, count, until, err := parseRRule(spec, loc, anchor.In(loc))
+		if err != nil {
+			return nil, &ValidationError{Field: "spec", Message: fmt.Sprintf("RRULE 格式錯誤: %v", err)}
+		}
+		r.rule, r.Count, r.Until = rule, count, until
+	default:
+		return nil, &ValidationError{Field: "kind", Message: "kind 必須為 cron 或 rrule"}
+	}
+
+	return r, nil
+}
+
+// Next 回傳 after 之後的下一次觸發時間（位於規則的時區），找不到時為零值
+func (r *Recurrence) Next(after time.Time) time.Time {
+	after = after.In(r.Location)
+	var next time.Time
+	if r.cron != nil {
+		next = r.cron.Next(after)
+	} else {
+		next = r.rule.next(after)
+	}
+	if !next.IsZero() && !r.Until.IsZero() && next.After(r.Until) {
+		return time.Time{}
+	}
+	return next
+}
+
+// Upcoming 列出 after 之後最多 n 次觸發時間，供預覽使用
+func (r *Recurrence) Upcoming(after time.Time, n int) []time.Time {
+	var times []time.Time
+	for len(times) < n {
+		next := r.Next(after)
+		if next.IsZero() {
+			break
+		}
+		times = append(times, next)
+		after = next
+	}
+	return times
+}
+
+// parseRRule 解析 RRULE；可包含 DTSTART 行（DTSTART:20250101T000000）與 RRULE: 前綴
+func parseRRule(spec string, loc *time.Location, anchor time.Time) (*rrule, int, time.Time, error) {
+	rule := &rrule{interval: 1, dtstart: anchor}
+	var count int
+	var until time.Time
+	var ruleText string
+
+	for _, line := range strings.FieldsFunc(spec, func(c rune) bool { return c == '\n' || c == '\r' }) {
+		line = strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(strings.ToUpper(line), "DTSTART"):
+			value := line[strings.LastIndex(line, ":")+1:]
+			t, err := parseRRuleTime(value, loc)
+			if err != nil {
+				return nil, 0, time.Time{}, fmt.Errorf("DTSTART %w", err)
+			}
+			rule.dtstart = t
+		case line != "":
+			ruleText = strings.TrimPrefix(strings.ToUpper(line), "RRULE:")
+		}
+	}
+
+	for _, part := range strings.Split(ruleText, ";") {
+		if part == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(part, "=")
+		if !ok {
+			return nil, 0, time.Time{}, fmt.Errorf("無法解析 %q", part)
+		}
+
+		var err error
+		switch key {
+		case "FREQ":
+			rule.freq = value
+		case "INTERVAL":
+			rule.interval, err = strconv.Atoi(value)
+			if err == nil && rule.interval < 1 {
+				err = fmt.Errorf("必須大於 0")
+			}
+		case "COUNT":
+			count, err = strconv.Atoi(value)
+			if err == nil && count < 1 {
+				err = fmt.Errorf("必須大於 0")
+			}
+		case "UNTIL":
+			until, err = parseRRuleTime(value, loc)
+			if err == nil && len(value) == 8 {
+				until = until.AddDate(0, 0, 1).Add(-time.Nanosecond) // 只有日期時包含當天
+			}
+		case "BYMONTH":
+			rule.byMonth, err = parseRRuleInts(value, 1, 12, false)
+		case "BYMONTHDAY":
+			rule.byMonthDay, err = parseRRuleInts(value, 1, 31, true)
+		case "BYHOUR":
+			rule.byHour, err = parseRRuleInts(value, 0, 23, false)
+		case "BYMINUTE":
+			rule.byMinute, err = parseRRuleInts(value, 0, 59, false)
+		case "BYSECOND":
+			rule.bySecond, err = parseRRuleInts(value, 0, 59, false)
+		case "BYDAY":
+			rule.byDay, err = parseRRuleDays(value)
+		case "WKST":
+			// 週起始日固定為星期一
+		default:
+			err = fmt.Errorf("不支援的欄位")
+		}
+		if err != nil {
+			return nil, 0, time.Time{}, fmt.Errorf("%s: %w", key, err)
+		}
+	}
+
+	switch rule.freq {
+	case "DAILY", "WEEKLY", "MONTHLY", "YEARLY":
+	case "":
+		return nil, 0, time.Time{}, fmt.Errorf("缺少 FREQ")
+	default:
+		return nil, 0, time.Time{}, fmt.Errorf("FREQ 僅支援 DAILY、WEEKLY、MONTHLY、YEARLY")
+	}
+
+	// 未指定的欄位沿用起算時間，與 RFC 5545 的預設行為一致
+	start := rule.dtstart
+	if rule.byHour == nil {
+		rule.byHour = []int{start.Hour()}
+	}
+	if rule.byMinute == nil {
+		rule.byMinute = []int{start.Minute()}
+	}
+	if rule.bySecond == nil {
+		rule.bySecond = []int{start.Second()}
+	}
+	switch {
+	case rule.freq == "WEEKLY" && rule.byDay == nil:
+		rule.byDay = []rruleDay{{Weekday: start.Weekday()}}
+	case rule.freq == "MONTHLY" && rule.byDay == nil && rule.byMonthDay == nil:
+		rule.byMonthDay = []int{start.Day()}
+	case rule.freq == "YEARLY" && rule.byDay == nil && rule.byMonthDay == nil:
+		rule.byMonthDay = []int{start.Day()}
+		if rule.byMonth == nil {
+			rule.byMonth = []int{int(start.Month())}
+		}
+	}
+
+	return rule, count, until, nil
+}
+
+// parseRRuleTime 解析 YYYYMMDD、YYYYMMDDTHHMMSS 或 UTC 的 YYYYMMDDTHHMMSSZ
+func parseRRuleTime(value string, loc *time.Location) (time.Time, error) {
+	switch {
+	case strings.HasSuffix(value, "Z"):
+		return time.Parse("20060102T150405Z", value)
+	case len(value) == 8:
+		return time.ParseInLocation("20060102", value, loc)
+	default:
+		return time.ParseInLocation("20060102T150405", value, loc)
+	}
+}
+
+// parseRRuleInts 解析逗號分隔的整數清單
+func parseRRuleInts(value string, min, max int, allowNegative bool) ([]int, error) {
+	var nums []int
+	for _, s := range strings.Split(value, ",") {
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			return nil, fmt.Errorf("%q 不是整數", s)
+		}
+		abs := n
+		if allowNegative && n < 0 {
+			abs = -n
+		}
+		if abs < min || abs > max {
+			return nil, fmt.Errorf("%d 超出範圍 %d-%d", n, min, max)
+		}
+		nums = append(nums, n)
+	}
+	sort.Ints(nums)
+	return nums, nil
+}
+
+// parseRRuleDays 解析 BYDAY，例如 MO,WE 或 1MO、-1FR
+func parseRRuleDays(value string) ([]rruleDay, error) {
+	var days []rruleDay
+	for _, s := range strings.Split(value, ",") {
+		if len(s) < 2 {
+			return nil, fmt.Errorf("%q 無法辨識", s)
+		}
+		weekday, ok := rruleWeekdays[s[len(s)-2:]]
+		if !ok {
+			return nil, fmt.Errorf("%q 無法辨識", s)
+		}
+		day := rruleDay{Weekday: weekday}
+		if prefix := s[:len(s)-2]; prefix != "" {
+			n, err := strconv.Atoi(prefix)
+			if err != nil || n == 0 || n < -5 || n > 5 {
+				return nil, fmt.Errorf("%q 的序數無效", s)
+			}
+			day.Nth = n
+		}
+		days = append(days, day)
+	}
+	return days, nil
+}
+
+// next 逐日搜尋 after 之後第一個符合規則的時間
+func (r *rrule) next(after time.Time) time.Time {
+	loc := r.dtstart.Location()
+	after = after.In(loc)
+	day := dateOnly(after)
+	if first := dateOnly(r.dtstart); day.Before(first) {
+		day = first
+	}
+
+	for i := 0; i < rruleSearchDays; i++ {
+		if r.matchDay(day) {
+			for _, h := range r.byHour {
+				for _, m := range r.byMinute {
+					for _, s := range r.bySecond {
+						t := time.Date(day.Year(), day.Month(), day.Day(), h, m, s, 0, loc)
+						if t.After(after) && !t.Before(r.dtstart) {
+							return t
+						}
+					}
+				}
+			}
+		}
+		day = day.AddDate(0, 0, 1)
+	}
+	return time.Time{}
+}
+
+// matchDay 檢查日期是否符合頻率間隔與 BY* 條件
+func (r *rrule) matchDay(day time.Time) bool {
+	start := dateOnly(r.dtstart)
+
+	switch r.freq {
+	case "DAILY":
+		if int(day.Sub(start).Hours()/24+0.5)%r.interval != 0 {
+			return false
+		}
+	case "WEEKLY":
+		weeks := int(weekStart(day).Sub(weekStart(start)).Hours()/24+0.5) / 7
+		if weeks%r.interval != 0 {
+			return false
+		}
+	case "MONTHLY":
+		months := (day.Year()-start.Year())*12 + int(day.Month()) - int(start.Month())
+		if months%r.interval != 0 {
+			return false
+		}
+	case "YEARLY":
+		if (day.Year()-start.Year())%r.interval != 0 {
+			return false
+		}
+	}
+
+	if r.byMonth != nil && !containsInt(r.byMonth, int(day.Month())) {
+		return false
+	}
+
+	if r.byMonthDay != nil {
+		lastDay := time.Date(day.Year(), day.Month()+1, 0, 0, 0, 0, 0, day.Location()).Day()
+		matched := false
+		for _, d := range r.byMonthDay {
+			if d == day.Day() || (d < 0 && lastDay+d+1 == day.Day()) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+
+	if r.byDay != nil {
+		matched := false
+		for _, d := range r.byDay {
+			if d.Weekday != day.Weekday() {
+				continue
+			}
+			if d.Nth == 0 || d.Nth == nthWeekdayOfMonth(day, d.Nth < 0) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+
+	return true
+}
+
+// dateOnly 取得當天 00:00
+func dateOnly(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, t.Location())
+}
+
+// weekStart 取得該週星期一 00:00
+func weekStart(t time.Time) time.Time {
+	offset := (int(t.Weekday()) + 6) % 7
+	return dateOnly(t).AddDate(0, 0, -offset)
+}
+
+// nthWeekdayOfMonth 回傳此日期是當月第幾個同星期（fromEnd 時為倒數第幾個，以負數表示）
+func nthWeekdayOfMonth(day time.Time, fromEnd bool) int {
+	if !fromEnd {
+		return (day.Day()-1)/7 + 1
+	}
+	lastDay := time.Date(day.Year(), day.Month()+1, 0, 0, 0, 0, 0, day.Location()).Day()
+	return -((lastDay-day.Day())/7 + 1)
+}
+
+// containsInt 檢查清單是否包含 n
+func containsInt(list []int, n int) bool {
+	for _, v := range list {
+		if v == n {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,317 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrSavedFormInUse 儲存資料仍被週期排程參照，不可刪除
+var ErrSavedFormInUse = errors.New("此資料仍被週期排程使用，請先刪除相關週期排程")
+
+// 週期排程狀態（依設定與排程器狀態計算，不寫入資料庫）
+const (
+	RecurringArmed    = "armed"    // 已排定下一次觸發
+	RecurringDisabled = "disabled" // 已停用
+	RecurringEnded    = "ended"    // 已達結束條件
+	RecurringError    = "error"    // 無法排定下一次觸發
+)
+
+// RecurringSchedule 週期排程：依 cron 或 RRULE 在指定時區重複觸發提交，
+// 提交內容為儲存資料，或員工加上假別與相對日期範本
+type RecurringSchedule struct {
+	ID       int64  `json:"id"`
+	Label    string `json:"label"`
+	Kind     string `json:"kind"`     // cron / rrule
+	Spec     string `json:"spec"`     // cron 表達式或 RRULE
+	Timezone string `json:"timezone"` // IANA 時區，空白時使用系統時區
+
+	SavedFormID  int64         `json:"saved_form_id,omitempty"`
+	EmployeeRef  int64         `json:"employee_ref,omitempty"`
+	LeaveType    string        `json:"leave_type,omitempty"`
+	DateTemplate *DateTemplate `json:"date_template,omitempty"`
+
+	PrepareSeconds int `json:"prepare_seconds"`
+	RetryCount     int `json:"retry_count"`
+	RetryInterval  int `json:"retry_interval"`
+
+	MissedPolicy string `json:"missed_policy,omitempty"` // 錯過觸發時間的處理策略（skip / grace / notify）
+	GraceSeconds int    `json:"grace_seconds,omitempty"` // grace 策略的寬限秒數
+
+	Strategy            string `json:"strategy,omitempty"` // 送出策略（fixed / poll）
+	PollLeadSeconds     int    `json:"poll_lead_seconds,omitempty"`
+	PollIntervalMs      int    `json:"poll_interval_ms,omitempty"`
+	PollDeadlineSeconds int    `json:"poll_deadline_seconds,omitempty"`
+
+	EndCount int    `json:"end_count,omitempty"` // 觸發幾次後結束，0 表示不限
+	EndUntil string `json:"end_until,omitempty"` // YYYY-MM-DD，此日之後不再觸發
+
+	Enabled     bool       `json:"enabled"`
+	Occurrences int        `json:"occurrences"` // 已觸發次數
+	LastRunAt   *time.Time `json:"last_run_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+
+	// 以下由排程器填入
+	Status    string     `json:"status,omitempty"`
+	NextRunAt *time.Time `json:"next_run_at,omitempty"`
+	LastError string     `json:"last_error,omitempty"`
+}
+
+// BatchID 此週期排程寫入提交記錄時使用的批次 ID，用於查詢每次觸發的結果
+func (rs *RecurringSchedule) BatchID() string {
+	return fmt.Sprintf("recurring-%d", rs.ID)
+}
+
+// Recurrence 解析週期規則
+func (rs *RecurringSchedule) Recurrence() (*Recurrence, error) {
+	anchor := rs.CreatedAt
+	if anchor.IsZero() {
+		anchor = time.Now()
+	}
+	return ParseRecurrence(rs.Kind, rs.Spec, rs.Timezone, anchor)
+}
+
+// untilTime 結束日期當天的最後一刻，未設定時為零值
+func (rs *RecurringSchedule) untilTime(loc *time.Location) (time.Time, error) {
+	if rs.EndUntil == "" {
+		return time.Time{}, nil
+	}
+	day, err := time.ParseInLocation("2006-01-02", rs.EndUntil, loc)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return day.AddDate(0, 0, 1).Add(-time.Nanosecond), nil
+}
+
+// NextOccurrence 計算 after 之後的下一次觸發時間；已達結束條件時回傳零值
+func (rs *RecurringSchedule) NextOccurrence(after time.Time) (time.Time, error) {
+	rec, err := rs.Recurrence()
+	if err != nil {
+		return time.Time{}, err
+	}
+	if rs.EndCount > 0 && rs.Occurrences >= rs.EndCount {
+		return time.Time{}, nil
+	}
+
+	next := rec.Next(after)
+	until, err := rs.untilTime(rec.Location)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if !next.IsZero() && !until.IsZero() && next.After(until) {
+		return time.Time{}, nil
+	}
+	return next, nil
+}
+
+// ValidateRecurringSchedule 驗證週期排程；RRULE 中的 COUNT / UNTIL 會併入結束條件
+func ValidateRecurringSchedule(rs *RecurringSchedule) error {
+	if rs.Label == "" {
+		return &ValidationError{Field: "label", Message: "識別標籤為必填欄位"}
+	}
+
+	rec, err := rs.Recurrence()
+	if err != nil {
+		return err
+	}
+	if rs.EndCount == 0 && rec.Count > 0 {
+		rs.EndCount = rec.Count
+	}
+	if rs.EndUntil == "" && !rec.Until.IsZero() {
+		rs.EndUntil = rec.Until.In(rec.Location).Format("2006-01-02")
+	}
+
+	if rs.EndCount < 0 {
+		return &ValidationError{Field: "end_count", Message: "end_count 不可為負數"}
+	}
+	if err := validateMissedPolicy(rs.MissedPolicy); err != nil {
+		return &ValidationError{Field: "missed_policy", Message: err.Error()}
+	}
+	strategy := ScheduleConfig{Strategy: rs.Strategy, PollLeadSeconds: rs.PollLeadSeconds, PollIntervalMs: rs.PollIntervalMs, PollDeadlineSeconds: rs.PollDeadlineSeconds}
+	if err := strategy.validateStrategy(); err != nil {
+		return &ValidationError{Field: "strategy", Message: err.Error()}
+	}
+	if _, err := rs.untilTime(rec.Location); err != nil {
+		return &ValidationError{Field: "end_until", Message: "end_until 格式錯誤，請使用 YYYY-MM-DD 格式"}
+	}
+
+	switch {
+	case rs.SavedFormID > 0:
+		if rs.EmployeeRef > 0 || rs.DateTemplate != nil {
+			return &ValidationError{Field: "saved_form_id", Message: "使用儲存資料時不可再指定員工或日期範本"}
+		}
+	case rs.EmployeeRef > 0:
+		if rs.LeaveType == "" {
+			return &ValidationError{Field: "leave_type", Message: "假別為必填欄位"}
+		}
+		if rs.DateTemplate == nil {
+			return &ValidationError{Field: "date_template", Message: "指定員工時需提供日期範本"}
+		}
+		if err := rs.DateTemplate.Validate(); err != nil {
+			return err
+		}
+	default:
+		return &ValidationError{Field: "saved_form_id", Message: "請指定儲存資料或員工"}
+	}
+
+	return nil
+}
+
+// recurringScheduleColumns recurring_schedules 查詢欄位（順序須與 scanRecurringSchedule 一致）
+const recurringScheduleColumns = `id, label, kind, spec, timezone, saved_form_id, employee_ref, leave_type, date_template,
+	prepare_seconds, retry_count, retry_interval, missed_policy, grace_seconds,
+	strategy, poll_lead_seconds, poll_interval_ms, poll_deadline_seconds, end_count, end_until, enabled, occurrences, last_run_at, created_at, updated_at`
+
+// scanRecurringSchedule 讀取一筆 recurring_schedules 記錄
+func scanRecurringSchedule(row rowScanner) (*RecurringSchedule, error) {
+	rs := &RecurringSchedule{}
+	var template string
+	var lastRun sql.NullTime
+	err := row.Scan(&rs.ID, &rs.Label, &rs.Kind, &rs.Spec, &rs.Timezone, &rs.SavedFormID, &rs.EmployeeRef, &rs.LeaveType, &template,
+		&rs.PrepareSeconds, &rs.RetryCount, &rs.RetryInterval, &rs.MissedPolicy, &rs.GraceSeconds,
+		&rs.Strategy, &rs.PollLeadSeconds, &rs.PollIntervalMs, &rs.PollDeadlineSeconds, &rs.EndCount, &rs.EndUntil, &rs.Enabled, &rs.Occurrences, &lastRun,
+		&rs.CreatedAt, &rs.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if lastRun.Valid {
+		rs.LastRunAt = &lastRun.Time
+	}
+	if rs.DateTemplate, err = decodeDateTemplate(template); err != nil {
+		return nil, err
+	}
+	return rs, nil
+}
+
+// CreateRecurringSchedule 新增週期排程
+func (s *Storage) CreateRecurringSchedule(rs *RecurringSchedule) (int64, error) {
+	template, err := encodeDateTemplate(rs.DateTemplate)
+	if err != nil {
+		return 0, err
+	}
+
+	now := time.Now()
+	result, err := s.db.Exec(`
+		INSERT INTO recurring_schedules (label, kind, spec, timezone, saved_form_id, employee_ref, leave_type, date_template,
+			prepare_seconds, retry_count, retry_interval, missed_policy, grace_seconds,
+			strategy, poll_lead_seconds, poll_interval_ms, poll_deadline_seconds, end_count, end_until, enabled, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, rs.Label, rs.Kind, rs.Spec, rs.Timezone, rs.SavedFormID, rs.EmployeeRef, rs.LeaveType, template,
+		rs.PrepareSeconds, rs.RetryCount, rs.RetryInterval, rs.MissedPolicy, rs.GraceSeconds,
+		rs.Strategy, rs.PollLeadSeconds, rs.PollIntervalMs, rs.PollDeadlineSeconds, rs.EndCount, rs.EndUntil, rs.Enabled, now, now)
+	if err != nil {
+		return 0, fmt.Errorf("週期排程儲存失敗: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("取得 ID 失敗: %w", err)
+	}
+
+	return id, nil
+}
+
+// GetRecurringSchedule 根據 ID 取得週期排程
+func (s *Storage) GetRecurringSchedule(id int64) (*RecurringSchedule, error) {
+	row := s.db.QueryRow("SELECT "+recurringScheduleColumns+" FROM recurring_schedules WHERE id = ?", id)
+
+	rs, err := scanRecurringSchedule(row)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("找不到指定的週期排程")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("查詢週期排程失敗: %w", err)
+	}
+
+	return rs, nil
+}
+
+// ListRecurringSchedules 列出所有週期排程
+func (s *Storage) ListRecurringSchedules() ([]*RecurringSchedule, error) {
+	rows, err := s.db.Query("SELECT " + recurringScheduleColumns + " FROM recurring_schedules ORDER BY id")
+	if err != nil {
+		return nil, fmt.Errorf("查詢週期排程失敗: %w", err)
+	}
+	defer rows.Close()
+
+	list := []*RecurringSchedule{}
+	for rows.Next() {
+		rs, err := scanRecurringSchedule(rows)
+		if err != nil {
+			return nil, fmt.Errorf("讀取週期排程失敗: %w", err)
+		}
+		list = append(list, rs)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("讀取週期排程失敗: %w", err)
+	}
+
+	return list, nil
+}
+
+// UpdateRecurringSchedule 更新週期排程設定（已觸發次數與最後執行時間保留不變）
+func (s *Storage) UpdateRecurringSchedule(rs *RecurringSchedule) error {
+	template, err := encodeDateTemplate(rs.DateTemplate)
+	if err != nil {
+		return err
+	}
+
+	result, err := s.db.Exec(`
+		UPDATE recurring_schedules
+		SET label = ?, kind = ?, spec = ?, timezone = ?, saved_form_id = ?, employee_ref = ?, leave_type = ?, date_template = ?,
+			prepare_seconds = ?, retry_count = ?, retry_interval = ?, missed_policy = ?, grace_seconds = ?,
+			strategy = ?, poll_lead_seconds = ?, poll_interval_ms = ?, poll_deadline_seconds = ?, end_count = ?, end_until = ?, enabled = ?, updated_at = ?
+		WHERE id = ?
+	`, rs.Label, rs.Kind, rs.Spec, rs.Timezone, rs.SavedFormID, rs.EmployeeRef, rs.LeaveType, template,
+		rs.PrepareSeconds, rs.RetryCount, rs.RetryInterval, rs.MissedPolicy, rs.GraceSeconds,
+		rs.Strategy, rs.PollLeadSeconds, rs.PollIntervalMs, rs.PollDeadlineSeconds, rs.EndCount, rs.EndUntil, rs.Enabled, time.Now(), rs.ID)
+	if err != nil {
+		return fmt.Errorf("更新週期排程失敗: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("確認更新結果失敗: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("找不到指定的週期排程")
+	}
+
+	return nil
+}
+
+// RecordRecurringOccurrence 記錄一次觸發：更新最後執行時間，counted 時觸發次數加一（計入 end_count）
+func (s *Storage) RecordRecurringOccurrence(id int64, at time.Time, counted bool) error {
+	increment := 0
+	if counted {
+		increment = 1
+	}
+	_, err := s.db.Exec("UPDATE recurring_schedules SET occurrences = occurrences + ?, last_run_at = ? WHERE id = ?", increment, at, id)
+	if err != nil {
+		return fmt.Errorf("更新週期排程失敗: %w", err)
+	}
+	return nil
+}
+
+// DeleteRecurringSchedule 刪除週期排程（提交記錄保留）
+func (s *Storage) DeleteRecurringSchedule(id int64) error {
+	result, err := s.db.Exec("DELETE FROM recurring_schedules WHERE id = ?", id)
+	if err != nil {
+		return fmt.Errorf("刪除週期排程失敗: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("確認刪除結果失敗: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("找不到指定的週期排程")
+	}
+
+	return nil
+}
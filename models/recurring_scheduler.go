@@ -0,0 +1,313 @@
+package models
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+// recurringJob 單一週期排程目前排定的觸發
+type recurringJob struct {
+	schedule  *RecurringSchedule
+	scheduler *Scheduler // 下一次觸發使用的一次性排程器，已結束或無法排定時為 nil
+	next      time.Time
+	err       error
+
+	failed *Scheduler         // 這次觸發無法啟動的排程器，觸發時間到時據以寫入失敗記錄
+	cancel context.CancelFunc // 停止等待失敗的觸發時間
+}
+
+// RecurringScheduler 週期排程器：每個週期排程同一時間只排定下一次觸發，
+// 觸發結束後寫入提交記錄與觸發次數，再依規則重新排定。
+// 某次觸發無法啟動（例如日期範本算出的期間皆為假日）時，於該次觸發時間寫入失敗記錄後排定下一次
+type RecurringScheduler struct {
+	submitter *GoogleFormSubmitter
+	storage   *Storage
+	logger    *log.Logger
+	clock     Clock // 計算下一次觸發與觸發排程器使用的時鐘
+	mu        sync.Mutex
+	jobs      map[int64]*recurringJob
+	closed    bool
+	waiting   sync.WaitGroup // 等待失敗觸發時間的 goroutine
+}
+
+// NewRecurringScheduler 建立週期排程器
+func NewRecurringScheduler(submitter *GoogleFormSubmitter, storage *Storage) *RecurringScheduler {
+	return &RecurringScheduler{
+		submitter: submitter,
+		storage:   storage,
+		logger:    log.New(os.Stdout, "[Recurring] ", log.LstdFlags|log.Lmicroseconds),
+		clock:     SystemClock,
+		jobs:      make(map[int64]*recurringJob),
+	}
+}
+
+// SetClock 設定週期排程使用的時鐘，需在 LoadAll / Arm 前呼叫
+func (r *RecurringScheduler) SetClock(c Clock) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if c == nil {
+		c = SystemClock
+	}
+	r.clock = c
+}
+
+// now 目前時間
+func (r *RecurringScheduler) now() time.Time {
+	r.mu.Lock()
+	clock := r.clock
+	r.mu.Unlock()
+	return clock.Now()
+}
+
+// LoadAll 排定資料庫中所有啟用的週期排程；個別排程失敗只記錄警告
+func (r *RecurringScheduler) LoadAll() error {
+	list, err := r.storage.ListRecurringSchedules()
+	if err != nil {
+		return err
+	}
+
+	for _, rs := range list {
+		if err := r.Arm(rs.ID); err != nil {
+			r.logger.Printf("警告: %v", err)
+		}
+	}
+	return nil
+}
+
+// Arm 重新讀取設定並排定下一次觸發（會先停止此週期排程目前的觸發）
+func (r *RecurringScheduler) Arm(id int64) error {
+	return r.arm(id, r.now())
+}
+
+// arm 排定 after 之後的下一次觸發
+func (r *RecurringScheduler) arm(id int64, after time.Time) error {
+	r.Disarm(id)
+
+	rs, err := r.storage.GetRecurringSchedule(id)
+	if err != nil {
+		return err
+	}
+
+	job := &recurringJob{schedule: rs}
+	defer func() {
+		r.mu.Lock()
+		closed := r.closed
+		if !closed {
+			r.jobs[id] = job
+			if job.failed != nil {
+				var ctx context.Context
+				ctx, job.cancel = context.WithCancel(context.Background())
+				r.waiting.Add(1)
+				go r.skipFailed(ctx, id, job)
+			}
+		}
+		r.mu.Unlock()
+
+		// 排定期間已關閉（例如上一次觸發結束時正好程式結束），不再保留新的排程
+		if closed && job.scheduler != nil {
+			job.scheduler.Shutdown()
+		}
+	}()
+
+	if !rs.Enabled {
+		return nil
+	}
+
+	next, err := rs.NextOccurrence(after)
+	if err != nil {
+		job.err = err
+		return fmt.Errorf("週期排程「%s」無法計算下一次觸發: %w", rs.Label, err)
+	}
+	if next.IsZero() {
+		r.logger.Printf("週期排程「%s」已達結束條件（已觸發 %d 次）", rs.Label, rs.Occurrences)
+		return nil
+	}
+
+	cfg := &ScheduleConfig{
+		Enabled:        true,
+		At:             next,
+		SavedFormID:    rs.SavedFormID,
+		PrepareSeconds: rs.PrepareSeconds,
+		RetryCount:     rs.RetryCount,
+		RetryInterval:  rs.RetryInterval,
+		MissedPolicy:   rs.MissedPolicy,
+		GraceSeconds:   rs.GraceSeconds,
+		EmployeeRef:    rs.EmployeeRef,
+		LeaveType:      rs.LeaveType,
+		DateTemplate:   rs.DateTemplate,
+
+		Strategy:            rs.Strategy,
+		PollLeadSeconds:     rs.PollLeadSeconds,
+		PollIntervalMs:      rs.PollIntervalMs,
+		PollDeadlineSeconds: rs.PollDeadlineSeconds,
+	}
+
+	scheduler := NewScheduler(cfg, r.submitter, r.storage)
+	scheduler.SetClock(r.clock)
+	scheduler.source = HistorySourceRecurring
+	scheduler.batchID = rs.BatchID()
+	scheduler.logger = log.New(os.Stdout, fmt.Sprintf("[Recurring #%d %s] ", rs.ID, rs.Label), log.LstdFlags|log.Lmicroseconds)
+	scheduler.onComplete = func(rec *SubmissionRecord) {
+		r.complete(id, scheduler, next, rec)
+	}
+
+	if err := scheduler.Start(); err != nil {
+		job.err, job.failed, job.next = err, scheduler, next
+		return fmt.Errorf("週期排程「%s」%s 的觸發無法啟動，將於該時間記錄失敗後排定下一次: %w", rs.Label, next.Format("2006-01-02 15:04:05"), err)
+	}
+
+	job.scheduler = scheduler
+	job.next = next
+	r.logger.Printf("週期排程「%s」下一次觸發: %s", rs.Label, next.Format("2006-01-02 15:04:05 MST"))
+	return nil
+}
+
+// skipFailed 等到無法啟動的觸發時間，寫入失敗記錄後排定下一次；
+// 等到觸發時間才處理，持續失敗時（例如儲存資料已刪除）也只會每次觸發記錄一筆
+func (r *RecurringScheduler) skipFailed(ctx context.Context, id int64, job *recurringJob) {
+	defer r.waiting.Done()
+
+	timer := r.clock.NewTimer(job.next.Sub(r.clock.Now()))
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return
+	case <-timer.C():
+	}
+
+	r.mu.Lock()
+	current := !r.closed && r.jobs[id] == job
+	r.mu.Unlock()
+	if !current {
+		return
+	}
+
+	now := r.clock.Now()
+	cfg := job.failed.GetConfig()
+	rec := job.failed.targetRecord(cfg, job.next)
+	rec.BatchID = job.failed.batchID
+	rec.SavedFormID = cfg.SavedFormID
+	rec.Message = fmt.Sprintf("觸發無法啟動，未送出: %v", job.err)
+	rec.StartedAt, rec.FinishedAt = now, now
+	if _, err := r.storage.RecordSubmission(rec); err != nil {
+		r.logger.Printf("警告: %v", err)
+	}
+	if err := r.storage.RecordRecurringOccurrence(id, now, false); err != nil {
+		r.logger.Printf("警告: %v", err)
+	}
+
+	if err := r.arm(id, job.next); err != nil {
+		r.logger.Printf("警告: %v", err)
+	}
+}
+
+// complete 一次觸發結束（提交記錄已由排程器寫入）：更新觸發次數並排定下一次。
+// 只有實際送出過（至少一次嘗試）的觸發計入 end_count；錯過後略過、通知或寬限過期，
+// 以及準備失敗而未送出的觸發只更新最後執行時間，不會提早用完次數
+func (r *RecurringScheduler) complete(id int64, scheduler *Scheduler, occurrence time.Time, rec *SubmissionRecord) {
+	if err := r.storage.RecordRecurringOccurrence(id, rec.StartedAt, rec.Attempts > 0); err != nil {
+		r.logger.Printf("警告: %v", err)
+	}
+
+	r.mu.Lock()
+	job := r.jobs[id]
+	current := !r.closed && job != nil && job.scheduler == scheduler
+	r.mu.Unlock()
+
+	scheduler.Stop()
+	if !current {
+		return // 期間已被停用、刪除或重新排定
+	}
+
+	after := occurrence
+	if now := r.now(); now.After(after) {
+		after = now
+	}
+	if err := r.arm(id, after); err != nil {
+		r.logger.Printf("警告: %v", err)
+	}
+}
+
+// Disarm 停止週期排程目前排定的觸發
+func (r *RecurringScheduler) Disarm(id int64) {
+	r.mu.Lock()
+	job := r.jobs[id]
+	delete(r.jobs, id)
+	r.mu.Unlock()
+
+	if job != nil && job.cancel != nil {
+		job.cancel()
+	}
+	if job != nil && job.scheduler != nil {
+		job.scheduler.Stop()
+	}
+}
+
+// Stop 停止所有週期排程並等待執行中的觸發結束（程式結束時使用）
+func (r *RecurringScheduler) Stop() {
+	r.mu.Lock()
+	r.closed = true
+	jobs := r.jobs
+	r.jobs = make(map[int64]*recurringJob)
+	r.mu.Unlock()
+
+	for _, job := range jobs {
+		if job.cancel != nil {
+			job.cancel()
+		}
+		if job.scheduler != nil {
+			job.scheduler.Shutdown()
+		}
+	}
+	r.waiting.Wait()
+}
+
+// fill 填入週期排程的狀態與下一次觸發時間
+func (r *RecurringScheduler) fill(rs *RecurringSchedule) {
+	r.mu.Lock()
+	job := r.jobs[rs.ID]
+	r.mu.Unlock()
+
+	switch {
+	case !rs.Enabled:
+		rs.Status = RecurringDisabled
+	case job == nil:
+		rs.Status = RecurringError
+		rs.LastError = "尚未排定"
+	case job.err != nil:
+		rs.Status = RecurringError
+		rs.LastError = job.err.Error()
+	case job.scheduler == nil:
+		rs.Status = RecurringEnded
+	default:
+		rs.Status = RecurringArmed
+		next := job.next
+		rs.NextRunAt = &next
+	}
+}
+
+// Get 取得週期排程與目前狀態
+func (r *RecurringScheduler) Get(id int64) (*RecurringSchedule, error) {
+	rs, err := r.storage.GetRecurringSchedule(id)
+	if err != nil {
+		return nil, err
+	}
+	r.fill(rs)
+	return rs, nil
+}
+
+// List 列出所有週期排程與目前狀態
+func (r *RecurringScheduler) List() ([]*RecurringSchedule, error) {
+	list, err := r.storage.ListRecurringSchedules()
+	if err != nil {
+		return nil, err
+	}
+	for _, rs := range list {
+		r.fill(rs)
+	}
+	return list, nil
+}
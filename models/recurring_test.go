@@ -0,0 +1,260 @@
+package models
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestRecurrenceNext 測試 cron 與 RRULE 在指定時區計算下一次觸發
+func TestRecurrenceNext(t *testing.T) {
+	taipei, err := time.LoadLocation("Asia/Taipei")
+	if err != nil {
+		t.Fatalf("載入時區失敗: %v", err)
+	}
+	after := time.Date(2025, 1, 15, 12, 0, 0, 0, taipei)
+	anchor := time.Date(2025, 1, 1, 0, 0, 0, 0, taipei)
+
+	tests := []struct {
+		name string
+		kind string
+		spec string
+		want []string
+	}{
+		{"每月 1 號午夜", RecurrenceCron, "0 0 1 * *", []string{"2025-02-01 00:00:00", "2025-03-01 00:00:00"}},
+		{"含秒欄位", RecurrenceCron, "30 0 9 * * MON", []string{"2025-01-20 09:00:30", "2025-01-27 09:00:30"}},
+		{"每月 1 號", RecurrenceRRule, "FREQ=MONTHLY;BYMONTHDAY=1", []string{"2025-02-01 00:00:00", "2025-03-01 00:00:00"}},
+		{"每月最後一個星期五 8 點", RecurrenceRRule, "RRULE:FREQ=MONTHLY;BYDAY=-1FR;BYHOUR=8", []string{"2025-01-31 08:00:00", "2025-02-28 08:00:00"}},
+		{"每月最後一天", RecurrenceRRule, "FREQ=MONTHLY;BYMONTHDAY=-1", []string{"2025-01-31 00:00:00", "2025-02-28 00:00:00"}},
+		{"隔週星期一", RecurrenceRRule, "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO", []string{"2025-01-27 00:00:00", "2025-02-10 00:00:00"}},
+		{"DTSTART 決定時間", RecurrenceRRule, "DTSTART:20250110T073000\nRRULE:FREQ=DAILY;INTERVAL=3", []string{"2025-01-16 07:30:00", "2025-01-19 07:30:00"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec, err := ParseRecurrence(tt.kind, tt.spec, "Asia/Taipei", anchor)
+			if err != nil {
+				t.Fatalf("解析失敗: %v", err)
+			}
+			got := rec.Upcoming(after, len(tt.want))
+			if len(got) != len(tt.want) {
+				t.Fatalf("應有 %d 次觸發，實際 %v", len(tt.want), got)
+			}
+			for i, want := range tt.want {
+				if s := got[i].In(taipei).Format("2006-01-02 15:04:05"); s != want {
+					t.Errorf("第 %d 次應為 %s，實際 %s", i+1, want, s)
+				}
+			}
+		})
+	}
+
+	// cron 依指定時區解讀：台北午夜為 UTC 前一天 16:00
+	rec, _ := ParseRecurrence(RecurrenceCron, "0 0 * * *", "Asia/Taipei", anchor)
+	if next := rec.Next(after).UTC(); next.Hour() != 16 {
+		t.Errorf("應以台北時間計算，實際 %v", next)
+	}
+
+	for _, bad := range []struct{ kind, spec, tz string }{
+		{RecurrenceCron, "61 * * * *", ""},
+		{RecurrenceRRule, "FREQ=HOURLY", ""},
+		{RecurrenceRRule, "FREQ=MONTHLY;BYDAY=9MO", ""},
+		{RecurrenceCron, "0 0 * * *", "Mars/Base"},
+		{"weekly", "0 0 * * *", ""},
+	} {
+		if _, err := ParseRecurrence(bad.kind, bad.spec, bad.tz, anchor); err == nil {
+			t.Errorf("%s %q（%s）應解析失敗", bad.kind, bad.spec, bad.tz)
+		}
+	}
+}
+
+// TestRecurringScheduleEndCondition 測試 RRULE 的 COUNT / UNTIL 併入結束條件
+func TestRecurringScheduleEndCondition(t *testing.T) {
+	rs := &RecurringSchedule{
+		Label:        "每月",
+		Kind:         RecurrenceRRule,
+		Spec:         "FREQ=MONTHLY;BYMONTHDAY=1;COUNT=3;UNTIL=20250401",
+		Timezone:     "Asia/Taipei",
+		EmployeeRef:  1,
+		LeaveType:    "近假",
+		DateTemplate: &DateTemplate{OffsetDays: 30, LengthDays: 2},
+		CreatedAt:    time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+	}
+	if err := ValidateRecurringSchedule(rs); err != nil {
+		t.Fatalf("驗證失敗: %v", err)
+	}
+	if rs.EndCount != 3 || rs.EndUntil != "2025-04-01" {
+		t.Fatalf("結束條件應取自 RRULE: count=%d until=%s", rs.EndCount, rs.EndUntil)
+	}
+
+	after := time.Date(2025, 3, 15, 0, 0, 0, 0, time.UTC)
+	if next, _ := rs.NextOccurrence(after); next.Format("2006-01-02") != "2025-04-01" {
+		t.Errorf("結束日當天仍應觸發，實際 %v", next)
+	}
+	if next, _ := rs.NextOccurrence(after.AddDate(0, 0, 20)); !next.IsZero() {
+		t.Errorf("超過結束日後不應再觸發，實際 %v", next)
+	}
+	rs.Occurrences = 3
+	if next, _ := rs.NextOccurrence(after); !next.IsZero() {
+		t.Errorf("達到次數後不應再觸發，實際 %v", next)
+	}
+
+	rs.DateTemplate = nil
+	if err := ValidateRecurringSchedule(rs); err == nil {
+		t.Error("指定員工但沒有日期範本應驗證失敗")
+	}
+}
+
+// TestRecurringSchedulerRearms 測試週期排程每次觸發後重新排定，並在達到次數後結束
+func TestRecurringSchedulerRearms(t *testing.T) {
+	storage, cleanup := setupTestStorage(t)
+	defer cleanup()
+
+	var posts int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "POST" {
+			atomic.AddInt32(&posts, 1)
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	employeeRef, err := storage.CreateEmployee(&Employee{Name: "王小明", EmployeeID: "A1", Password: "p"})
+	if err != nil {
+		t.Fatalf("建立員工失敗: %v", err)
+	}
+
+	rs := &RecurringSchedule{
+		Label:         "每秒",
+		Kind:          RecurrenceCron,
+		Spec:          "* * * * * *",
+		EmployeeRef:   employeeRef,
+		LeaveType:     "近假",
+		DateTemplate:  &DateTemplate{OffsetDays: 10, LengthDays: 1},
+		RetryCount:    1,
+		RetryInterval: 1,
+		EndCount:      2,
+		Enabled:       true,
+	}
+	if err := ValidateRecurringSchedule(rs); err != nil {
+		t.Fatalf("驗證失敗: %v", err)
+	}
+	id, err := storage.CreateRecurringSchedule(rs)
+	if err != nil {
+		t.Fatalf("建立週期排程失敗: %v", err)
+	}
+
+	recurring := NewRecurringScheduler(batchTestSubmitter(server.URL), storage)
+	defer recurring.Stop()
+	if err := recurring.LoadAll(); err != nil {
+		t.Fatalf("載入週期排程失敗: %v", err)
+	}
+
+	got, _ := recurring.Get(id)
+	if got.Status != RecurringArmed || got.NextRunAt == nil {
+		t.Fatalf("應已排定下一次觸發: %+v", got)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if got, _ = recurring.Get(id); got.Status == RecurringEnded {
+			break
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	if got.Status != RecurringEnded || got.Occurrences != 2 || got.LastRunAt == nil {
+		t.Fatalf("觸發兩次後應結束: %+v", got)
+	}
+	if n := atomic.LoadInt32(&posts); n != 2 {
+		t.Errorf("應提交 2 次，實際 %d", n)
+	}
+
+	records, err := storage.ListHistory(HistoryQuery{Source: HistorySourceRecurring, BatchID: got.BatchID()})
+	if err != nil || len(records) != 2 {
+		t.Fatalf("應有 2 筆觸發記錄，實際 %d (%v)", len(records), err)
+	}
+	if !records[0].StartedAt.After(records[1].StartedAt) || !records[0].Success {
+		t.Errorf("兩次觸發應各自成功並有不同時間: %+v %+v", records[0], records[1])
+	}
+
+	if err := storage.DeleteEmployee(employeeRef); err != ErrEmployeeInUse {
+		t.Errorf("被週期排程參照的員工應無法刪除，實際 %v", err)
+	}
+}
+
+// TestRecurringSchedulerSkipsFailedOccurrence 測試某次觸發無法啟動時記錄失敗、不計入次數並排定下一次
+func TestRecurringSchedulerSkipsFailedOccurrence(t *testing.T) {
+	storage, cleanup := setupTestStorage(t)
+	defer cleanup()
+
+	// 1/7 觸發時 +10 天為 1/17（假日），1/8 觸發時為 1/18（工作日）
+	if err := storage.ImportCalendar([]*CalendarDay{{Date: "2030-01-17", Kind: CalendarHoliday, Name: "假日"}}, "test.csv", false); err != nil {
+		t.Fatalf("匯入行事曆失敗: %v", err)
+	}
+	employeeRef, err := storage.CreateEmployee(&Employee{Name: "王小明", EmployeeID: "A1", Password: "p"})
+	if err != nil {
+		t.Fatalf("建立員工失敗: %v", err)
+	}
+	rs := &RecurringSchedule{
+		Label:        "每天",
+		Kind:         RecurrenceCron,
+		Spec:         "0 0 0 * * *",
+		Timezone:     "UTC",
+		EmployeeRef:  employeeRef,
+		LeaveType:    "近假",
+		DateTemplate: &DateTemplate{OffsetDays: 10, LengthDays: 1},
+		EndCount:     1,
+		Enabled:      true,
+	}
+	if err := ValidateRecurringSchedule(rs); err != nil {
+		t.Fatalf("驗證失敗: %v", err)
+	}
+	id, err := storage.CreateRecurringSchedule(rs)
+	if err != nil {
+		t.Fatalf("建立週期排程失敗: %v", err)
+	}
+
+	clock := NewFakeClock(time.Date(2030, 1, 6, 12, 0, 0, 0, time.UTC))
+	recurring := NewRecurringScheduler(batchTestSubmitter("http://127.0.0.1:1/formResponse"), storage)
+	recurring.SetClock(clock)
+	defer recurring.Stop()
+
+	if err := recurring.Arm(id); err == nil {
+		t.Fatal("期間皆為假日的觸發應無法啟動")
+	}
+	if got, _ := recurring.Get(id); got.Status != RecurringError {
+		t.Fatalf("無法啟動時應顯示錯誤，實際 %+v", got)
+	}
+
+	// 到了失敗的觸發時間：寫入失敗記錄並排定下一次
+	if !clock.BlockUntil(1, time.Second) {
+		t.Fatal("應等待失敗的觸發時間")
+	}
+	clock.Set(time.Date(2030, 1, 7, 0, 0, 0, 0, time.UTC))
+
+	var got *RecurringSchedule
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if got, _ = recurring.Get(id); got.Status == RecurringArmed {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if got.Status != RecurringArmed || got.NextRunAt == nil || !got.NextRunAt.Equal(time.Date(2030, 1, 8, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("應排定 1/8 的觸發，實際 %+v", got)
+	}
+	if got.Occurrences != 0 || got.LastRunAt == nil {
+		t.Errorf("未送出的觸發不應計入 end_count，但應更新最後執行時間: %+v", got)
+	}
+
+	records, err := storage.ListHistory(HistoryQuery{Source: HistorySourceRecurring, BatchID: got.BatchID()})
+	if err != nil || len(records) != 1 {
+		t.Fatalf("應有 1 筆失敗記錄，實際 %d (%v)", len(records), err)
+	}
+	if records[0].Success || records[0].StartDate != "2030-01-17" || !strings.Contains(records[0].Message, "皆為假日") {
+		t.Errorf("失敗記錄應包含日期與原因: %+v", records[0])
+	}
+}
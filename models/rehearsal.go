@@ -0,0 +1,94 @@
+package models
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// RehearsalOptions 排程演練設定：以加速或平移的時鐘跑完整排程流程，送到本機模擬端點
+type RehearsalOptions struct {
+	SavedFormID    int64
+	At             time.Time     // 模擬的目標時間
+	Lead           time.Duration // 演練開始時距目標時間的模擬時間，預設 10 秒
+	Speed          float64       // 時鐘倍速，預設 1
+	PrepareSeconds int
+	RetryCount     int
+	RetryInterval  int
+	Mock           MockFormOptions // 模擬表單的行為（開放時間、延遲、錯誤），未指定 EntryIDs 時使用 entryMap
+}
+
+// RehearsalResult 演練結果
+type RehearsalResult struct {
+	TargetTime  time.Time         `json:"target_time"`
+	State       JobState          `json:"state"`
+	Events      []ScheduleEvent   `json:"events"`
+	Submissions []MockSubmission  `json:"submissions"` // 模擬表單收到的送出（時間為模擬時間）
+	Record      *SubmissionRecord `json:"record,omitempty"`
+}
+
+// RunRehearsal 演練一次排程：時鐘從目標時間前 Lead 開始以 Speed 倍速前進，
+// 使用正式的欄位對應與儲存資料，但送到本機的模擬表單，且不寫入提交記錄。
+// ctx 取消時停止演練並回傳目前為止的結果。
+func RunRehearsal(ctx context.Context, storage *Storage, entryMap map[string]string, opts RehearsalOptions, logger *log.Logger) (*RehearsalResult, error) {
+	if opts.At.IsZero() {
+		return nil, fmt.Errorf("演練目標時間未設定")
+	}
+	lead := opts.Lead
+	if lead <= 0 {
+		lead = 10 * time.Second
+	}
+
+	clock := NewShiftedClock(opts.At.Add(-lead), opts.Speed)
+	mockOpts := opts.Mock
+	if mockOpts.EntryIDs == nil {
+		mockOpts.EntryIDs = entryMap
+	}
+	form := NewMockForm(mockOpts)
+	form.SetClock(clock)
+	mock, err := StartMockFormServer(form, "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	defer mock.Close()
+
+	cfg := &ScheduleConfig{
+		Enabled:        true,
+		At:             opts.At,
+		SavedFormID:    opts.SavedFormID,
+		PrepareSeconds: opts.PrepareSeconds,
+		RetryCount:     opts.RetryCount,
+		RetryInterval:  opts.RetryInterval,
+	}
+	scheduler := NewScheduler(cfg, NewGoogleFormSubmitter(mock.FormURL(), entryMap), storage)
+	scheduler.rehearsal = true
+	scheduler.clock = clock
+	if logger != nil {
+		scheduler.logger = logger
+	}
+
+	done := make(chan struct{})
+	var once sync.Once
+	scheduler.onComplete = func(*SubmissionRecord) { once.Do(func() { close(done) }) }
+
+	if err := scheduler.Start(); err != nil {
+		return nil, err
+	}
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+	}
+	scheduler.Shutdown()
+
+	state, _ := scheduler.State()
+	return &RehearsalResult{
+		TargetTime:  opts.At,
+		State:       state,
+		Events:      scheduler.Events().Recent(),
+		Submissions: mock.Submissions(),
+		Record:      scheduler.LastResult(),
+	}, nil
+}
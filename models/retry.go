@@ -0,0 +1,153 @@
+package models
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// 重試預設值
+const (
+	defaultRetryCount    = 3
+	defaultRetryInterval = 100 // 毫秒
+)
+
+// RetryPolicy 提交失敗時的重試設定
+type RetryPolicy struct {
+	Count    int // 總嘗試次數，<= 0 時使用預設值 3
+	Interval int // 重試間隔毫秒，<= 0 時使用預設值 100
+}
+
+// withDefaults 套用預設值
+func (p RetryPolicy) withDefaults() RetryPolicy {
+	if p.Count <= 0 {
+		p.Count = defaultRetryCount
+	}
+	if p.Interval <= 0 {
+		p.Interval = defaultRetryInterval
+	}
+	return p
+}
+
+// newPreparedRequest 依提交器設定建構可直接送出的請求
+func newPreparedRequest(submitter *GoogleFormSubmitter, req *LeaveRequest) (*preparedRequest, error) {
+	formData := submitter.BuildFormData(req)
+
+	httpReq, err := newFormRequest(submitter.FormURL, formData)
+	if err != nil {
+		return nil, fmt.Errorf("建立 HTTP 請求失敗: %w", err)
+	}
+
+	return &preparedRequest{
+		formData:   formData,
+		httpClient: submitter.HTTPClient,
+		targetURL:  submitter.FormURL,
+		request:    httpReq,
+		leave:      req,
+	}, nil
+}
+
+// warmUpConnection 以 HEAD 請求預先完成 DNS、TCP 與 TLS 交握，
+// 讓正式送出時可重用已建立的 keep-alive 連線；回傳預熱請求各階段的耗時
+func warmUpConnection(prepared *preparedRequest) (*RequestTiming, error) {
+	req, err := http.NewRequest("HEAD", prepared.targetURL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	req, trace := traceRequest(req)
+	resp, err := prepared.httpClient.Do(req)
+	if err != nil {
+		return trace.Timing(), err
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	return trace.Timing(), nil
+}
+
+// newFormRequest 建立 form-urlencoded POST 請求
+func newFormRequest(targetURL string, formData url.Values) (*http.Request, error) {
+	httpReq, err := http.NewRequest("POST", targetURL, strings.NewReader(formData.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return httpReq, nil
+}
+
+// attemptHook 每次送出結束後呼叫；err 為該次嘗試的錯誤
+type attemptHook func(attempt *SubmissionAttempt, err error)
+
+// sendWithRetry 送出預先準備的請求，失敗時依 policy 重試，回傳每次嘗試的結果與網路各階段耗時；
+// ctx 取消後不再重試（已送出的請求仍會完成），onAttempt 可為 nil
+func sendWithRetry(ctx context.Context, prepared *preparedRequest, policy RetryPolicy, logger *log.Logger, onAttempt attemptHook) ([]SubmissionAttempt, error) {
+	policy = policy.withDefaults()
+	if onAttempt == nil {
+		onAttempt = func(*SubmissionAttempt, error) {}
+	}
+
+	var lastErr error
+	var attempts []SubmissionAttempt
+	record := func(sentAt time.Time, statusCode int, timing *RequestTiming, err error) {
+		attempt := SubmissionAttempt{Attempt: len(attempts) + 1, StatusCode: statusCode, SentAt: sentAt, Timing: timing}
+		if err != nil {
+			attempt.Error = err.Error()
+		}
+		attempts = append(attempts, attempt)
+		onAttempt(&attempts[len(attempts)-1], err)
+	}
+
+	for i := 0; i < policy.Count; i++ {
+		if i > 0 {
+			logger.Printf("第 %d 次重試...", i)
+			timer := time.NewTimer(time.Duration(policy.Interval) * time.Millisecond)
+			select {
+			case <-timer.C:
+			case <-ctx.Done():
+				timer.Stop()
+				return attempts, fmt.Errorf("排程已停止，未再重試（已嘗試 %d 次）: %w", len(attempts), lastErr)
+			}
+
+			// 重新建立請求（因為 Body 已被讀取）
+			newReq, err := newFormRequest(prepared.targetURL, prepared.formData)
+			if err != nil {
+				lastErr = fmt.Errorf("重建請求失敗: %w", err)
+				continue
+			}
+			prepared.request = newReq
+		}
+
+		// 發送請求（讀完回應內容以取得完整的耗時並讓連線可重用）
+		sentAt := time.Now()
+		req, trace := traceRequest(prepared.request)
+		resp, err := prepared.httpClient.Do(req)
+		if err != nil {
+			timing := trace.Timing()
+			lastErr = fmt.Errorf("發送請求失敗: %w", err)
+			logger.Printf("請求失敗: %v（%s）", err, timing)
+			record(sentAt, 0, timing, lastErr)
+			continue
+		}
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+		timing := trace.Timing()
+
+		// 檢查回應狀態
+		if resp.StatusCode == http.StatusOK {
+			logger.Printf("Google Form 回應成功 (HTTP %d，%s)", resp.StatusCode, timing)
+			record(sentAt, resp.StatusCode, timing, nil)
+			return attempts, nil
+		}
+
+		lastErr = fmt.Errorf("Google Form 回應錯誤: HTTP %d", resp.StatusCode)
+		logger.Printf("回應錯誤: HTTP %d（%s）", resp.StatusCode, timing)
+		record(sentAt, resp.StatusCode, timing, lastErr)
+	}
+
+	return attempts, fmt.Errorf("提交失敗，已重試 %d 次: %w", policy.Count, lastErr)
+}
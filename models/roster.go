@@ -0,0 +1,243 @@
+package models
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// RosterMember 團隊名單成員，Priority 越小越優先（從 1 開始）
+type RosterMember struct {
+	EmployeeRef int64  `json:"employee_ref"`
+	Priority    int    `json:"priority"`
+	Name        string `json:"name"`
+	EmployeeID  string `json:"employee_id"`
+	HasPassword bool   `json:"has_password"`
+}
+
+// Roster 團隊名單：一組依優先順序排列的員工
+type Roster struct {
+	ID        int64           `json:"id"`
+	Name      string          `json:"name"`
+	Members   []*RosterMember `json:"members"`
+	CreatedAt time.Time       `json:"created_at"`
+	UpdatedAt time.Time       `json:"updated_at"`
+}
+
+// ValidateRoster 驗證團隊名單名稱與成員
+func ValidateRoster(name string, employeeRefs []int64) error {
+	if name == "" {
+		return &ValidationError{Field: "name", Message: "名單名稱為必填欄位"}
+	}
+	if len(employeeRefs) == 0 {
+		return &ValidationError{Field: "members", Message: "名單至少需要一位成員"}
+	}
+	seen := make(map[int64]bool, len(employeeRefs))
+	for _, ref := range employeeRefs {
+		if ref <= 0 {
+			return &ValidationError{Field: "members", Message: "成員的員工 ID 無效"}
+		}
+		if seen[ref] {
+			return &ValidationError{Field: "members", Message: "同一位員工不可重複加入名單"}
+		}
+		seen[ref] = true
+	}
+	return nil
+}
+
+// CreateRoster 新增團隊名單，employeeRefs 的順序即優先順序
+func (s *Storage) CreateRoster(name string, employeeRefs []int64) (int64, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("開始交易失敗: %w", err)
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+	result, err := tx.Exec("INSERT INTO rosters (name, created_at, updated_at) VALUES (?, ?, ?)", name, now, now)
+	if err != nil {
+		return 0, fmt.Errorf("名單儲存失敗，名稱可能已存在: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("取得 ID 失敗: %w", err)
+	}
+
+	if err := insertRosterMembers(tx, id, employeeRefs); err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("名單儲存失敗: %w", err)
+	}
+
+	return id, nil
+}
+
+// insertRosterMembers 依順序寫入名單成員並確認員工存在
+func insertRosterMembers(tx *sql.Tx, rosterID int64, employeeRefs []int64) error {
+	for i, ref := range employeeRefs {
+		var exists int
+		if err := tx.QueryRow("SELECT COUNT(*) FROM employees WHERE id = ?", ref).Scan(&exists); err != nil {
+			return fmt.Errorf("查詢員工失敗: %w", err)
+		}
+		if exists == 0 {
+			return fmt.Errorf("找不到 ID 為 %d 的員工", ref)
+		}
+
+		if _, err := tx.Exec(
+			"INSERT INTO roster_members (roster_id, employee_ref, position) VALUES (?, ?, ?)",
+			rosterID, ref, i+1,
+		); err != nil {
+			return fmt.Errorf("寫入名單成員失敗: %w", err)
+		}
+	}
+	return nil
+}
+
+// GetRoster 根據 ID 取得團隊名單與依優先順序排列的成員
+func (s *Storage) GetRoster(id int64) (*Roster, error) {
+	r := &Roster{}
+	err := s.db.QueryRow("SELECT id, name, created_at, updated_at FROM rosters WHERE id = ?", id).
+		Scan(&r.ID, &r.Name, &r.CreatedAt, &r.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("找不到指定的名單")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("查詢名單失敗: %w", err)
+	}
+
+	r.Members, err = s.rosterMembers(id)
+	if err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// rosterMembers 讀取名單成員（依優先順序）
+func (s *Storage) rosterMembers(rosterID int64) ([]*RosterMember, error) {
+	rows, err := s.db.Query(`
+		SELECT m.employee_ref, m.position, e.name, e.employee_id, e.password
+		FROM roster_members m JOIN employees e ON e.id = m.employee_ref
+		WHERE m.roster_id = ?
+		ORDER BY m.position
+	`, rosterID)
+	if err != nil {
+		return nil, fmt.Errorf("查詢名單成員失敗: %w", err)
+	}
+	defer rows.Close()
+
+	members := []*RosterMember{}
+	for rows.Next() {
+		m := &RosterMember{}
+		var password string
+		if err := rows.Scan(&m.EmployeeRef, &m.Priority, &m.Name, &m.EmployeeID, &password); err != nil {
+			return nil, fmt.Errorf("讀取名單成員失敗: %w", err)
+		}
+		m.HasPassword = password != ""
+		members = append(members, m)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("讀取名單成員失敗: %w", err)
+	}
+
+	return members, nil
+}
+
+// ListRosters 依名稱列出所有團隊名單
+func (s *Storage) ListRosters() ([]*Roster, error) {
+	rows, err := s.db.Query("SELECT id, name, created_at, updated_at FROM rosters ORDER BY name, id")
+	if err != nil {
+		return nil, fmt.Errorf("查詢名單失敗: %w", err)
+	}
+
+	rosters := []*Roster{}
+	for rows.Next() {
+		r := &Roster{}
+		if err := rows.Scan(&r.ID, &r.Name, &r.CreatedAt, &r.UpdatedAt); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("讀取名單失敗: %w", err)
+		}
+		rosters = append(rosters, r)
+	}
+	rows.Close()
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("讀取名單失敗: %w", err)
+	}
+
+	for _, r := range rosters {
+		if r.Members, err = s.rosterMembers(r.ID); err != nil {
+			return nil, err
+		}
+	}
+
+	return rosters, nil
+}
+
+// UpdateRoster 更新名單名稱並以新的順序取代所有成員
+func (s *Storage) UpdateRoster(id int64, name string, employeeRefs []int64) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("開始交易失敗: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec("UPDATE rosters SET name = ?, updated_at = ? WHERE id = ?", name, time.Now(), id)
+	if err != nil {
+		return fmt.Errorf("更新名單失敗，名稱可能已存在: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("確認更新結果失敗: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("找不到指定的名單")
+	}
+
+	if _, err := tx.Exec("DELETE FROM roster_members WHERE roster_id = ?", id); err != nil {
+		return fmt.Errorf("更新名單成員失敗: %w", err)
+	}
+
+	if err := insertRosterMembers(tx, id, employeeRefs); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("更新名單失敗: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteRoster 刪除團隊名單
+func (s *Storage) DeleteRoster(id int64) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("開始交易失敗: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM roster_members WHERE roster_id = ?", id); err != nil {
+		return fmt.Errorf("刪除名單失敗: %w", err)
+	}
+
+	result, err := tx.Exec("DELETE FROM rosters WHERE id = ?", id)
+	if err != nil {
+		return fmt.Errorf("刪除名單失敗: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("確認刪除結果失敗: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("找不到指定的名單")
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,169 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// savedFormSortColumns 允許排序的欄位
+var savedFormSortColumns = map[string]string{
+	"id":         "f.id",
+	"label":      "f.label",
+	"name":       "e.name",
+	"start_date": "f.start_date",
+	"end_date":   "f.end_date",
+	"created_at": "f.created_at",
+	"updated_at": "f.updated_at",
+}
+
+// SavedFormQuery 儲存資料的搜尋條件
+type SavedFormQuery struct {
+	Label        string // 標籤包含的文字
+	EmployeeRef  int64  // 員工 ID
+	EmployeeID   string // 員工代號（完全相符）
+	LeaveType    string // 假別（完全相符）
+	From         string // 與 [From, To] 期間重疊，YYYY-MM-DD
+	To           string
+	UpcomingOnly bool   // 僅列出終點日期不早於今天或使用日期範本的資料
+	Sort         string // 排序欄位，預設 created_at
+	Order        string // asc 或 desc，預設 desc
+	Limit        int    // 0 表示不限制
+	Offset       int
+}
+
+// SavedFormPage 搜尋結果
+type SavedFormPage struct {
+	Forms []*SavedForm
+	Total int // 符合條件的總筆數（不受分頁影響）
+}
+
+// Validate 檢查搜尋條件
+func (q *SavedFormQuery) Validate() error {
+	if q.Sort != "" {
+		if _, ok := savedFormSortColumns[q.Sort]; !ok {
+			return fmt.Errorf("不支援的排序欄位: %s", q.Sort)
+		}
+	}
+	if q.Order != "" && q.Order != "asc" && q.Order != "desc" {
+		return fmt.Errorf("排序方向必須為 asc 或 desc")
+	}
+	if q.From != "" {
+		if _, err := parseDate(q.From); err != nil {
+			return fmt.Errorf("from 日期格式錯誤，請使用 YYYY-MM-DD")
+		}
+	}
+	if q.To != "" {
+		if _, err := parseDate(q.To); err != nil {
+			return fmt.Errorf("to 日期格式錯誤，請使用 YYYY-MM-DD")
+		}
+	}
+	if q.Limit < 0 || q.Offset < 0 {
+		return fmt.Errorf("limit 與 offset 不可為負數")
+	}
+	return nil
+}
+
+// whereClause 組合 WHERE 條件與參數，now 為判斷是否到期的目前時間
+func (q *SavedFormQuery) whereClause(now time.Time) (string, []any) {
+	var conds []string
+	var args []any
+
+	if q.Label != "" {
+		conds = append(conds, `f.label LIKE ? ESCAPE '\'`)
+		args = append(args, "%"+escapeLike(q.Label)+"%")
+	}
+	if q.EmployeeRef > 0 {
+		conds = append(conds, "f.employee_ref = ?")
+		args = append(args, q.EmployeeRef)
+	}
+	if q.EmployeeID != "" {
+		conds = append(conds, "e.employee_id = ?")
+		args = append(args, q.EmployeeID)
+	}
+	if q.LeaveType != "" {
+		conds = append(conds, "f.leave_type = ?")
+		args = append(args, q.LeaveType)
+	}
+	// 日期以 YYYY-MM-DD 字串儲存，字典序即時間順序
+	if q.From != "" {
+		conds = append(conds, "f.end_date >= ?")
+		args = append(args, q.From)
+	}
+	if q.To != "" {
+		conds = append(conds, "f.start_date <= ?")
+		args = append(args, q.To)
+	}
+	// 日期範本於觸發時才計算，視為尚未到期
+	if q.UpcomingOnly {
+		conds = append(conds, "(f.end_date >= ? OR f.date_template != '')")
+		args = append(args, now.Format("2006-01-02"))
+	}
+
+	if len(conds) == 0 {
+		return "", nil
+	}
+	return " WHERE " + strings.Join(conds, " AND "), args
+}
+
+// orderClause 組合 ORDER BY，以 id 作為穩定的次要排序
+func (q *SavedFormQuery) orderClause() string {
+	column := "f.created_at"
+	if q.Sort != "" {
+		column = savedFormSortColumns[q.Sort]
+	}
+	order := "DESC"
+	if q.Order == "asc" {
+		order = "ASC"
+	}
+	return fmt.Sprintf(" ORDER BY %s %s, f.id %s", column, order, order)
+}
+
+// escapeLike 跳脫 LIKE 的萬用字元
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
+
+// Search 依條件搜尋、排序並分頁列出儲存資料
+func (s *Storage) Search(q *SavedFormQuery) (*SavedFormPage, error) {
+	if err := q.Validate(); err != nil {
+		return nil, err
+	}
+
+	where, args := q.whereClause(s.Clock().Now())
+
+	var total int
+	if err := s.db.QueryRow("SELECT COUNT(*) FROM "+savedFormTables+where, args...).Scan(&total); err != nil {
+		return nil, fmt.Errorf("查詢資料失敗: %w", err)
+	}
+
+	query := "SELECT " + savedFormColumns + " FROM " + savedFormTables + where + q.orderClause()
+	if q.Limit > 0 {
+		query += " LIMIT ? OFFSET ?"
+		args = append(args, q.Limit, q.Offset)
+	} else if q.Offset > 0 {
+		query += " LIMIT -1 OFFSET ?"
+		args = append(args, q.Offset)
+	}
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("查詢資料失敗: %w", err)
+	}
+	defer rows.Close()
+
+	page := &SavedFormPage{Forms: []*SavedForm{}, Total: total}
+	for rows.Next() {
+		form, err := scanSavedForm(rows)
+		if err != nil {
+			return nil, fmt.Errorf("讀取資料失敗: %w", err)
+		}
+		page.Forms = append(page.Forms, form)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("讀取資料失敗: %w", err)
+	}
+
+	return page, nil
+}
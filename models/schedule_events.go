@@ -0,0 +1,115 @@
+package models
+
+import (
+	"sync"
+	"time"
+)
+
+// 排程事件類型
+const (
+	ScheduleEventArmed         = "armed"          // 排程已啟動，等待準備時間
+	ScheduleEventStopped       = "stopped"        // 排程已停止
+	ScheduleEventPreparing     = "preparing"      // 進入準備狀態
+	ScheduleEventPrepared      = "prepared"       // 表單資料與連線已準備完成
+	ScheduleEventPrepareFailed = "prepare_failed" // 準備失敗，不會送出
+	ScheduleEventWaiting       = "waiting"        // 等待到送出時間
+	ScheduleEventPolling       = "polling"        // poll 策略開始探測表單
+	ScheduleEventFormOpen      = "form_open"      // poll 策略偵測到表單開放
+	ScheduleEventSending       = "sending"        // 開始送出
+	ScheduleEventAttempt       = "attempt"        // 單次送出的結果
+	ScheduleEventRetry         = "retry"          // 即將重試
+	ScheduleEventSucceeded     = "succeeded"      // 提交成功
+	ScheduleEventFailed        = "failed"         // 重試用盡仍失敗
+	ScheduleEventCancelled     = "cancelled"      // 等待中被取消
+	ScheduleEventMissed        = "missed"         // 錯過排程時間（啟動時已過、休眠喚醒或系統時間跳動），decision 為處理結果
+	ScheduleEventClockJump     = "clock_jump"     // 等待中偵測到系統時間跳動，已重新計算等待時間
+	ScheduleEventNotice        = "notice"         // 需要使用者注意的通知
+	ScheduleEventPreflight     = "preflight"      // 排程前檢查結果（啟動時與準備階段）
+)
+
+// scheduleEventHistory 事件匯流排保留的最近事件數，新訂閱者會先收到這些事件
+const scheduleEventHistory = 100
+
+// ScheduleEvent 排程執行過程中的事件
+type ScheduleEvent struct {
+	Seq        int64      `json:"seq"`
+	Type       string     `json:"type"`
+	Time       time.Time  `json:"time"`
+	TargetTime *time.Time `json:"target_time,omitempty"` // 排程目標時間
+	SendTime   *time.Time `json:"send_time,omitempty"`   // 預計送出時間（含錯開延遲）
+	Attempt    int        `json:"attempt,omitempty"`
+	StatusCode int        `json:"status_code,omitempty"`
+	ElapsedMs  int64      `json:"elapsed_ms,omitempty"` // 單次送出或整體提交的耗時
+	Decision   string     `json:"decision,omitempty"`   // 錯過排程時間的處理結果
+	Message    string     `json:"message,omitempty"`
+
+	Preflight *PreflightReport `json:"preflight,omitempty"` // 排程前檢查報告（preflight 事件）
+	Timing    *RequestTiming   `json:"timing,omitempty"`    // 請求各階段耗時（prepared 的預熱連線與每次 attempt）
+}
+
+// EventBus 排程事件匯流排：保留最近的事件並廣播給訂閱者。
+// 訂閱者的緩衝區已滿時捨棄該事件，不阻塞排程執行。
+type EventBus struct {
+	mu          sync.Mutex
+	seq         int64
+	recent      []ScheduleEvent
+	subscribers map[chan ScheduleEvent]struct{}
+}
+
+// NewEventBus 建立事件匯流排
+func NewEventBus() *EventBus {
+	return &EventBus{subscribers: make(map[chan ScheduleEvent]struct{})}
+}
+
+// Publish 發布事件，自動填入序號與時間（未指定時）
+func (b *EventBus) Publish(e ScheduleEvent) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.seq++
+	e.Seq = b.seq
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+
+	b.recent = append(b.recent, e)
+	if len(b.recent) > scheduleEventHistory {
+		b.recent = b.recent[len(b.recent)-scheduleEventHistory:]
+	}
+
+	for ch := range b.subscribers {
+		select {
+		case ch <- e:
+		default:
+		}
+	}
+}
+
+// Subscribe 訂閱事件，回傳目前保留的最近事件與後續事件的 channel；
+// 使用完畢需呼叫 cancel 取消訂閱
+func (b *EventBus) Subscribe() (recent []ScheduleEvent, events <-chan ScheduleEvent, cancel func()) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	ch := make(chan ScheduleEvent, 32)
+	b.subscribers[ch] = struct{}{}
+	recent = append([]ScheduleEvent(nil), b.recent...)
+
+	var once sync.Once
+	cancel = func() {
+		once.Do(func() {
+			b.mu.Lock()
+			delete(b.subscribers, ch)
+			b.mu.Unlock()
+			close(ch)
+		})
+	}
+	return recent, ch, cancel
+}
+
+// Recent 取得保留的最近事件
+func (b *EventBus) Recent() []ScheduleEvent {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]ScheduleEvent(nil), b.recent...)
+}
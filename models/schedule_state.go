@@ -0,0 +1,57 @@
+package models
+
+import "time"
+
+// JobState 排程工作的狀態
+type JobState string
+
+// 排程工作狀態
+const (
+	JobIdle       JobState = "idle"       // 尚未排程
+	JobArmed      JobState = "armed"      // 已排定，等待準備時間
+	JobPreparing  JobState = "preparing"  // 讀取資料、預熱連線
+	JobWaiting    JobState = "waiting"    // 準備完成，等待送出時間
+	JobSubmitting JobState = "submitting" // 送出中（含重試）
+	JobSucceeded  JobState = "succeeded"  // 提交成功
+	JobFailed     JobState = "failed"     // 準備失敗、重試用盡或表單未開放
+	JobCancelled  JobState = "cancelled"  // 執行前被停止
+	JobMissed     JobState = "missed"     // 錯過目標時間，依策略未執行
+)
+
+// jobTransitions 允許的狀態轉換；結束狀態（succeeded / failed / cancelled / missed）可重新排程
+var jobTransitions = map[JobState][]JobState{
+	JobIdle:       {JobArmed, JobMissed},
+	JobArmed:      {JobPreparing, JobCancelled, JobMissed},
+	JobPreparing:  {JobWaiting, JobFailed, JobCancelled},
+	JobWaiting:    {JobSubmitting, JobFailed, JobCancelled, JobMissed}, // poll 策略至停止探測仍未開放時失敗
+	JobSubmitting: {JobSucceeded, JobFailed},
+	JobSucceeded:  {JobArmed, JobMissed},
+	JobFailed:     {JobArmed, JobMissed},
+	JobCancelled:  {JobArmed, JobMissed},
+	JobMissed:     {JobArmed, JobMissed},
+}
+
+// CanTransition 檢查是否允許從 from 轉換到 to
+func (from JobState) CanTransition(to JobState) bool {
+	for _, next := range jobTransitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// IsActive 是否為排定後、尚未結束的狀態
+func (s JobState) IsActive() bool {
+	switch s {
+	case JobArmed, JobPreparing, JobWaiting, JobSubmitting:
+		return true
+	}
+	return false
+}
+
+// StateTransition 進入某個狀態的時間
+type StateTransition struct {
+	State JobState  `json:"state"`
+	At    time.Time `json:"at"`
+}
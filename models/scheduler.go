@@ -1,26 +1,50 @@
 package models
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
 	"net/url"
 	"os"
-	"strings"
 	"sync"
 	"time"
-
-	"github.com/robfig/cron/v3"
 )
 
+// ErrSavedFormScheduled 儲存資料正被啟用中的排程使用，不可刪除
+var ErrSavedFormScheduled = errors.New("此資料正被啟用中的排程使用，請先停止排程")
+
 // ScheduleConfig 排程配置（從 config 包複製以避免循環依賴）
 type ScheduleConfig struct {
-	Enabled        bool   `json:"enabled"`
-	Date           string `json:"date"`            // YYYY-MM-DD 格式
-	SavedFormID    int64  `json:"saved_form_id"`   // 要提交的儲存資料 ID
-	PrepareSeconds int    `json:"prepare_seconds"` // 提前準備秒數，預設 5
-	RetryCount     int    `json:"retry_count"`     // 失敗重試次數，預設 3
-	RetryInterval  int    `json:"retry_interval"`  // 重試間隔毫秒，預設 100
+	Enabled        bool      `json:"enabled"`
+	Date           string    `json:"date"`            // YYYY-MM-DD 格式
+	At             time.Time `json:"at,omitempty"`    // 精確目標時間，設定時取代 Date（週期排程使用）
+	SavedFormID    int64     `json:"saved_form_id"`   // 要提交的儲存資料 ID
+	PrepareSeconds int       `json:"prepare_seconds"` // 提前準備秒數，預設 5
+	RetryCount     int       `json:"retry_count"`     // 失敗重試次數，預設 3
+	RetryInterval  int       `json:"retry_interval"`  // 重試間隔毫秒，預設 100
+
+	// 錯過目標時間（啟動時已過、休眠喚醒或系統時間跳動）的處理策略：skip（預設）/ grace / notify
+	MissedPolicy string `json:"missed_policy,omitempty"`
+	GraceSeconds int    `json:"grace_seconds,omitempty"` // grace 策略的寬限秒數，預設 60
+
+	// 送出策略：fixed（預設）於目標時間送出；poll 於目標時間前 PollLeadSeconds 秒開始
+	// 每 PollIntervalMs 毫秒探測 viewform 頁面，表單開放即送出，目標時間後 PollDeadlineSeconds 秒仍未開放則放棄
+	Strategy            string `json:"strategy,omitempty"`
+	PollLeadSeconds     int    `json:"poll_lead_seconds,omitempty"`     // 預設 10
+	PollIntervalMs      int    `json:"poll_interval_ms,omitempty"`      // 預設 250，最小 50
+	PollDeadlineSeconds int    `json:"poll_deadline_seconds,omitempty"` // 預設 60
+
+	// 未指定 SavedFormID 時，改以員工與下列請假欄位組成提交內容（團隊排程使用）
+	EmployeeRef int64  `json:"employee_ref,omitempty"`
+	StartDate   string `json:"start_date,omitempty"`
+	EndDate     string `json:"end_date,omitempty"`
+	LeaveType   string `json:"leave_type,omitempty"`
+	StaggerMs   int    `json:"stagger_ms,omitempty"` // 目標時間後延遲送出的毫秒數
+
+	// 設定時以目標時間為觸發日計算 StartDate / EndDate
+	DateTemplate *DateTemplate `json:"date_template,omitempty"`
 }
 
 // preparedRequest 預先準備的 HTTP 請求
@@ -29,19 +53,53 @@ type preparedRequest struct {
 	httpClient *http.Client
 	targetURL  string
 	request    *http.Request
+	leave      *LeaveRequest // 提交內容，用於寫入提交記錄
+
+	warmUp *RequestTiming // 預熱連線的各階段耗時，未預熱時為 nil
 }
 
+// scheduleJob 一次排程執行：啟動時複製的設定快照與專屬的取消 context，
+// 執行中的 goroutine 只讀取自己的 job，不再碰觸 Scheduler 可被替換的欄位
+type scheduleJob struct {
+	ctx         context.Context
+	cancel      context.CancelFunc
+	config      ScheduleConfig
+	targetTime  time.Time
+	prepareTime time.Time
+	missed      string // 錯過目標時間但於寬限內補送時為 MissedGraceSubmitted
+}
+
+// wallCheckInterval 等待時至少每隔此時間以牆上時間重新計算剩餘時間：
+// 休眠期間計時器不會前進，喚醒或系統時間調整後可在此時間內重新排定
+const wallCheckInterval = 10 * time.Second
+
+// clockJumpThreshold 一段等待的牆上時間與計時器時間相差超過此值時視為系統時間跳動
+const clockJumpThreshold = 2 * time.Second
+
 // Scheduler 定時排程器
 type Scheduler struct {
 	config     *ScheduleConfig
 	submitter  *GoogleFormSubmitter
 	storage    *Storage
-	cron       *cron.Cron
 	logger     *log.Logger
-	stopChan   chan struct{}
 	mu         sync.Mutex
-	running    bool
+	wg         sync.WaitGroup // 執行中的 job goroutine，Shutdown 時等待結束
+	job        *scheduleJob   // 目前的 job，Stop 後仍保留以判斷舊 goroutine 是否過期
+	closed     bool           // 已 Shutdown，不再接受 Start
+	running    bool           // 排程是否仍在進行（armed 到 submitting），執行結束後為 false
+	state      JobState       // 目前狀態
+	states     []StateTransition
 	targetTime time.Time
+	source     string                      // 寫入提交記錄的來源
+	batchID    string                      // 寫入提交記錄的批次 ID（團隊排程用於彙整）
+	lastResult *SubmissionRecord           // 最近一次執行結果
+	onComplete func(rec *SubmissionRecord) // 每次執行結束（含準備失敗）後呼叫
+	events     *EventBus                   // 執行過程的事件，供頁面即時顯示
+	clock      Clock                       // 現在時間與等待使用的時鐘，測試與演練可替換
+	rehearsal  bool                        // 演練模式：不寫入提交記錄
+
+	bookingWindow *BookingWindow   // 預約窗口規則，排程前檢查用；nil 時只檢查送出時間早於請假起點
+	preflight     *PreflightReport // 本輪最近一次排程前檢查報告
 }
 
 // NewScheduler 建立排程器
@@ -51,129 +109,448 @@ func NewScheduler(cfg *ScheduleConfig, submitter *GoogleFormSubmitter, storage *
 		submitter: submitter,
 		storage:   storage,
 		logger:    log.New(os.Stdout, "[Scheduler] ", log.LstdFlags|log.Lmicroseconds),
-		stopChan:  make(chan struct{}),
+		source:    HistorySourceSchedule,
+		state:     JobIdle,
+		events:    NewEventBus(),
+		clock:     SystemClock,
+	}
+}
+
+// SetClock 設定排程使用的時鐘（測試用 FakeClock、演練用 ShiftedClock），需在 Start 前呼叫；
+// 重試間隔與 HTTP 請求仍以實際時間進行
+func (s *Scheduler) SetClock(c Clock) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if c == nil {
+		c = SystemClock
+	}
+	s.clock = c
+}
+
+// SetBookingWindow 設定排程前檢查使用的預約窗口規則，需在 Start 前呼叫
+func (s *Scheduler) SetBookingWindow(w *BookingWindow) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.bookingWindow = w
+}
+
+// setStateLocked 轉換狀態並記錄時間，不允許的轉換會被忽略並回傳 false；呼叫者需持有 s.mu
+func (s *Scheduler) setStateLocked(to JobState) bool {
+	if !s.state.CanTransition(to) {
+		s.logger.Printf("警告: 不允許的狀態轉換 %s → %s", s.state, to)
+		return false
+	}
+	// 重新排程時清除上一輪的狀態記錄
+	if to == JobArmed || to == JobMissed {
+		s.states = nil
+	}
+	s.state = to
+	s.states = append(s.states, StateTransition{State: to, At: s.clock.Now()})
+	return true
+}
+
+// setJobState 由 job goroutine 轉換狀態；job 已被停止或取代時不轉換並回傳 false
+func (s *Scheduler) setJobState(job *scheduleJob, to JobState) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.job != job || job.ctx.Err() != nil {
+		return false
+	}
+	return s.setStateLocked(to)
+}
+
+// State 取得目前狀態與本輪每個狀態的進入時間
+func (s *Scheduler) State() (JobState, []StateTransition) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.state, append([]StateTransition(nil), s.states...)
+}
+
+// Events 取得排程事件匯流排
+func (s *Scheduler) Events() *EventBus {
+	return s.events
+}
+
+// publish 發布附帶目標時間的排程事件
+func (s *Scheduler) publish(target time.Time, e ScheduleEvent) {
+	if e.TargetTime == nil && !target.IsZero() {
+		e.TargetTime = &target
+	}
+	if e.Time.IsZero() {
+		e.Time = s.clock.Now()
 	}
+	s.events.Publish(e)
+}
+
+// Notify 推送 notice 事件（排程頁面顯示提醒與桌面通知），供排程以外的檢查使用
+func (s *Scheduler) Notify(message string) {
+	s.publish(time.Time{}, ScheduleEvent{Type: ScheduleEventNotice, Message: message})
 }
 
 // Start 啟動排程器
 func (s *Scheduler) Start() error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	return s.startLocked()
+}
 
+// startLocked 以目前設定的快照建立新的 job 並啟動；呼叫者需持有 s.mu
+func (s *Scheduler) startLocked() error {
+	if s.closed {
+		return fmt.Errorf("排程器已關閉")
+	}
 	if s.running {
 		return fmt.Errorf("排程器已在運行中")
 	}
+	if s.state == JobSubmitting {
+		return fmt.Errorf("上一次排程仍在送出中，請稍後再試")
+	}
 
 	if !s.config.Enabled {
 		s.logger.Println("排程功能未啟用")
 		return nil
 	}
+	cfg := *s.config
+	if err := validateMissedPolicy(cfg.MissedPolicy); err != nil {
+		return fmt.Errorf("排程配置錯誤: %w", err)
+	}
+	if err := cfg.validateStrategy(); err != nil {
+		return fmt.Errorf("排程配置錯誤: %w", err)
+	}
+	if _, ok := viewFormURL(s.submitter.FormURL); cfg.polling() && !ok {
+		return fmt.Errorf("排程配置錯誤: poll 策略需要以 /formResponse 結尾的表單網址以推得 viewform 頁面")
+	}
 
-	// 解析排程日期
-	targetTime, err := ParseScheduleDate(s.config.Date)
-	if err != nil {
-		return fmt.Errorf("排程日期格式錯誤: %w", err)
+	// 解析排程日期（已指定精確目標時間時直接使用）
+	targetTime := cfg.At
+	if targetTime.IsZero() {
+		var err error
+		targetTime, err = ParseScheduleDate(cfg.Date)
+		if err != nil {
+			return fmt.Errorf("排程日期格式錯誤: %w", err)
+		}
 	}
 	s.targetTime = targetTime
 
-	// 檢查目標時間是否已過
-	now := time.Now()
-	if targetTime.Before(now) {
-		s.logger.Printf("警告: 排程時間 %s 已過，排程將不會執行", targetTime.Format("2006-01-02 15:04:05"))
-		return nil
+	// 檢查目標時間（poll 策略為停止探測的時間）是否已過，依錯過策略略過、通知或於寬限內立即送出
+	now := s.clock.Now()
+	missed := ""
+	if cfg.latestSendTime(targetTime).Before(now) {
+		late := now.Sub(targetTime)
+		missed = cfg.missedDecision(late)
+		message := missedMessage(missed, targetTime, late, cfg.graceWindow())
+		s.logger.Printf("警告: %s", message)
+		if missed != MissedGraceSubmitted {
+			if s.setStateLocked(JobMissed) {
+				s.lastResult = s.missedRecord(&cfg, targetTime, now, missed, message)
+				s.announceMissed(targetTime, missed, message)
+			}
+			return nil
+		}
 	}
 
-	// 驗證 SavedFormID 是否存在
-	if s.config.SavedFormID <= 0 {
+	// 驗證 SavedFormID（或 EmployeeRef）是否存在
+	switch {
+	case cfg.SavedFormID > 0:
+		if _, err := s.storage.GetByID(cfg.SavedFormID); errors.Is(err, ErrSavedFormNotFound) {
+			return fmt.Errorf("排程配置錯誤: 找不到 ID 為 %d 的儲存資料", cfg.SavedFormID)
+		} else if err != nil {
+			return fmt.Errorf("讀取儲存資料失敗: %w", err)
+		}
+	case cfg.EmployeeRef > 0:
+		if _, err := s.storage.GetEmployee(cfg.EmployeeRef); err != nil {
+			return fmt.Errorf("排程配置錯誤: 找不到 ID 為 %d 的員工", cfg.EmployeeRef)
+		}
+	default:
 		return fmt.Errorf("排程配置錯誤: saved_form_id 未設定")
 	}
 
-	_, err = s.storage.GetByID(s.config.SavedFormID)
-	if err != nil {
-		return fmt.Errorf("排程配置錯誤: 找不到 ID 為 %d 的儲存資料", s.config.SavedFormID)
+	// 以目標時間組出提交內容並驗證（含日期範本與密碼解密），資料無效時不啟動；
+	// 其餘排程前檢查需要連線，於 job goroutine 中執行
+	if _, err := s.loadLeaveRequest(&cfg, targetTime); err != nil {
+		return fmt.Errorf("排程前檢查未通過: %w", err)
 	}
 
-	// 建立 cron 排程器
-	s.cron = cron.New(cron.WithSeconds())
-
-	// 計算準備時間（目標時間前 N 秒）
-	prepareSeconds := s.config.PrepareSeconds
+	// 計算準備時間（目標時間前 N 秒，poll 策略為開始探測前 N 秒）；準備時間已過時 job 會立即進入準備狀態
+	prepareSeconds := cfg.PrepareSeconds
 	if prepareSeconds <= 0 {
 		prepareSeconds = 5
 	}
 	prepareTime := targetTime.Add(-time.Duration(prepareSeconds) * time.Second)
-
-	// 如果準備時間已過但目標時間未過，直接進入準備狀態
-	if prepareTime.Before(now) && targetTime.After(now) {
+	if cfg.polling() {
+		pollStart, _ := cfg.pollWindow(targetTime)
+		prepareTime = pollStart.Add(-time.Duration(prepareSeconds) * time.Second)
+	}
+	if prepareTime.Before(now) {
 		s.logger.Println("準備時間已過，立即進入準備狀態")
-		go s.executeWithPrecision()
-	} else {
-		// 設定在準備時間觸發
-		cronSpec := fmt.Sprintf("%d %d %d %d %d *",
-			prepareTime.Second(),
-			prepareTime.Minute(),
-			prepareTime.Hour(),
-			prepareTime.Day(),
-			int(prepareTime.Month()),
-		)
-
-		_, err = s.cron.AddFunc(cronSpec, func() {
-			s.executeWithPrecision()
-		})
-		if err != nil {
-			return fmt.Errorf("設定排程失敗: %w", err)
-		}
-
-		s.cron.Start()
 	}
 
+	ctx, cancel := context.WithCancel(context.Background())
+	job := &scheduleJob{
+		ctx:         ctx,
+		cancel:      cancel,
+		config:      cfg,
+		targetTime:  targetTime,
+		prepareTime: prepareTime,
+		missed:      missed,
+	}
+	s.job = job
 	s.running = true
+	s.lastResult = nil
+	s.preflight = nil
+	s.setStateLocked(JobArmed)
 	s.logger.Printf("排程器已啟動，目標時間: %s", targetTime.Format("2006-01-02 15:04:05.000"))
+	s.publish(targetTime, ScheduleEvent{
+		Type:    ScheduleEventArmed,
+		Message: fmt.Sprintf("準備時間 %s", prepareTime.Format("2006-01-02 15:04:05")),
+	})
+	if missed != "" {
+		s.announceMissed(targetTime, missed, missedMessage(missed, targetTime, now.Sub(targetTime), cfg.graceWindow()))
+	}
+
+	s.wg.Add(1)
+	go s.run(job)
 
 	return nil
 }
 
-// StartWithConfig 使用新的配置啟動排程器（會先停止舊的排程）
-func (s *Scheduler) StartWithConfig(cfg *ScheduleConfig) error {
-	// 先停止現有排程
-	s.Stop()
+// run job 的 goroutine：等到準備時間後執行，被取消時直接結束；
+// 等待期間休眠或系統時間跳動而錯過目標時間時依錯過策略處理
+func (s *Scheduler) run(job *scheduleJob) {
+	defer s.wg.Done()
+
+	// 準備時間已到時直接進入準備階段，由準備階段的檢查取代
+	if s.clock.Now().Before(job.prepareTime) {
+		s.preflightJob(job, PreflightPhaseArm, job.prepareTime)
+	}
+
+	if !s.waitUntil(job, job.prepareTime) {
+		return
+	}
+	if s.checkMissed(job, job.config.latestSendTime(job.targetTime)) {
+		return
+	}
+
+	s.executeWithPrecision(job)
+}
+
+// waitUntil 等到牆上時間到達 deadline，回傳 false 表示 job 已被取消。
+// 每段等待最長 wallCheckInterval，醒來後以牆上時間重新計算剩餘時間，並回報系統時間跳動
+func (s *Scheduler) waitUntil(job *scheduleJob, deadline time.Time) bool {
+	for {
+		// Round(0) 去除單調時鐘讀數，以牆上時間計算
+		before := s.clock.Now().Round(0)
+		remaining := deadline.Sub(before)
+		if remaining <= 0 {
+			return true
+		}
+		step := min(remaining, wallCheckInterval)
+
+		timer := s.clock.NewTimer(step)
+		select {
+		case <-timer.C():
+		case <-job.ctx.Done():
+			timer.Stop()
+			return false
+		}
+
+		if jump := s.clock.Now().Round(0).Sub(before) - step; jump > clockJumpThreshold || jump < -clockJumpThreshold {
+			s.logger.Printf("警告: 偵測到系統時間跳動 %v（休眠喚醒或時間調整），重新計算等待時間", jump.Round(time.Millisecond))
+			s.publish(job.targetTime, ScheduleEvent{
+				Type:    ScheduleEventClockJump,
+				Message: fmt.Sprintf("系統時間跳動 %v，重新計算等待時間", jump.Round(time.Millisecond)),
+			})
+		}
+	}
+}
 
-	// 重置 stopChan
+// checkMissed 等待結束後檢查是否已晚於 deadline 超過容許誤差（休眠喚醒或系統時間跳動），
+// 依錯過策略處理；回傳 true 表示這次執行已結束、不再送出
+func (s *Scheduler) checkMissed(job *scheduleJob, deadline time.Time) bool {
+	if job.missed != "" {
+		return false
+	}
+	now := s.clock.Now()
+	late := now.Sub(deadline)
+	if late <= missedTolerance {
+		return false
+	}
+
+	decision := job.config.missedDecision(late)
+	message := missedMessage(decision, deadline, late, job.config.graceWindow())
+	s.logger.Printf("警告: %s", message)
+	if decision == MissedGraceSubmitted {
+		job.missed = decision
+		s.announceMissed(job.targetTime, decision, message)
+		return false
+	}
+	if job.ctx.Err() != nil {
+		return true
+	}
+
+	s.announceMissed(job.targetTime, decision, message)
+	s.finish(job, s.missedRecord(&job.config, job.targetTime, now, decision, message), JobMissed)
+	return true
+}
+
+// preflightJob 執行 job 的排程前檢查，須在 deadline 前結束；結果存為本輪報告並發布事件，
+// 有項目失敗時另發出通知。檢查結果不會阻止送出（資料無效時準備階段本就會失敗）
+func (s *Scheduler) preflightJob(job *scheduleJob, phase string, deadline time.Time) {
+	remaining := deadline.Sub(s.clock.Now())
+	if remaining <= 0 {
+		s.logger.Printf("距離送出時間太近，略過排程前檢查（%s）", phase)
+		return
+	}
+	ctx, cancel := context.WithTimeout(job.ctx, remaining)
+	defer cancel()
+
+	target := job.targetTime
+	report := s.runPreflight(ctx, &job.config, target, phase)
 	s.mu.Lock()
-	s.stopChan = make(chan struct{})
+	current := s.job == job && job.ctx.Err() == nil
+	if current {
+		s.preflight = report
+	}
 	s.mu.Unlock()
+	if !current {
+		return
+	}
 
-	// 更新配置並啟動
+	summary := report.Summary()
+	s.logger.Printf("排程前檢查（%s）: %s", phase, summary)
+	s.publish(target, ScheduleEvent{Type: ScheduleEventPreflight, Preflight: report, Message: summary})
+	if !report.Passed {
+		s.publish(target, ScheduleEvent{Type: ScheduleEventNotice, Message: "排程前檢查未通過: " + summary})
+	}
+}
+
+// Preflight 取得本輪最近一次排程前檢查報告，尚未檢查時為 nil
+func (s *Scheduler) Preflight() *PreflightReport {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.preflight
+}
+
+// CheckPreflight 不啟動排程，以 cfg 的目標時間執行一次排程前檢查
+func (s *Scheduler) CheckPreflight(ctx context.Context, cfg *ScheduleConfig) (*PreflightReport, error) {
+	target := cfg.At
+	if target.IsZero() {
+		var err error
+		if target, err = ParseScheduleDate(cfg.Date); err != nil {
+			return nil, fmt.Errorf("排程日期格式錯誤: %w", err)
+		}
+	}
+	if cfg.SavedFormID <= 0 && cfg.EmployeeRef <= 0 {
+		return nil, fmt.Errorf("saved_form_id 未設定")
+	}
+	return s.runPreflight(ctx, cfg, target, PreflightPhaseManual), nil
+}
+
+// announceMissed 發布錯過排程時間的事件；notify 策略另發出通知
+func (s *Scheduler) announceMissed(target time.Time, decision, message string) {
+	s.publish(target, ScheduleEvent{Type: ScheduleEventMissed, Decision: decision, Message: message})
+	if decision == MissedNotified {
+		s.publish(target, ScheduleEvent{Type: ScheduleEventNotice, Decision: decision, Message: message})
+	}
+}
+
+// missedRecord 建立並寫入錯過排程時間、未送出的提交記錄（演練模式不寫入）
+func (s *Scheduler) missedRecord(cfg *ScheduleConfig, target, now time.Time, decision, message string) *SubmissionRecord {
+	rec := s.targetRecord(cfg, target)
+	rec.BatchID = s.batchID
+	rec.SavedFormID = cfg.SavedFormID
+	rec.Message = message
+	rec.MissedDecision = decision
+	rec.StartedAt = now
+	rec.FinishedAt = now
+
+	if !s.rehearsal {
+		if _, err := s.storage.RecordSubmission(rec); err != nil {
+			s.logger.Printf("警告: %v", err)
+		}
+	}
+	return rec
+}
+
+// targetRecord 建立未能載入請假資料時的提交記錄，盡量補上提交對象，讀取失敗不影響記錄
+func (s *Scheduler) targetRecord(cfg *ScheduleConfig, target time.Time) *SubmissionRecord {
+	rec := &SubmissionRecord{
+		Source:    s.source,
+		StartDate: cfg.StartDate,
+		EndDate:   cfg.EndDate,
+		LeaveType: cfg.LeaveType,
+	}
+
+	switch {
+	case cfg.SavedFormID > 0:
+		if form, err := s.storage.GetByID(cfg.SavedFormID); err == nil {
+			rec.Name, rec.EmployeeID, rec.LeaveType = form.Name, form.EmployeeID, form.LeaveType
+			if req, err := form.LeaveRequestFor(target, s.storage.Calendar()); err == nil {
+				rec.StartDate, rec.EndDate = req.StartDate, req.EndDate
+			}
+		}
+	case cfg.EmployeeRef > 0:
+		if employee, err := s.storage.GetEmployee(cfg.EmployeeRef); err == nil {
+			rec.Name, rec.EmployeeID = employee.Name, employee.EmployeeID
+		}
+		if cfg.DateTemplate != nil {
+			if start, end, err := cfg.DateTemplate.Resolve(target, s.storage.Calendar()); err == nil {
+				rec.StartDate, rec.EndDate = start, end
+			}
+		}
+	}
+	return rec
+}
+
+// StartWithConfig 使用新的配置啟動排程器（會先停止舊的排程）
+func (s *Scheduler) StartWithConfig(cfg *ScheduleConfig) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.stopLocked()
 	s.config = cfg
-	return s.Start()
+	return s.startLocked()
 }
 
-// GetConfig 取得當前排程配置
+// GetConfig 取得當前排程配置的複本
 func (s *Scheduler) GetConfig() *ScheduleConfig {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	return s.config
+	cfg := *s.config
+	return &cfg
 }
 
-// Stop 停止排程器
+// Stop 停止排程器：取消目前的 job，不等待執行中的 goroutine（需要等待時使用 Shutdown）。
+// 已開始送出的請求會完成當次嘗試，但不再重試。
 func (s *Scheduler) Stop() {
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	s.stopLocked()
+}
 
+// stopLocked 見 Stop；呼叫者需持有 s.mu
+func (s *Scheduler) stopLocked() {
 	if !s.running {
 		return
 	}
 
-	// 發送停止信號
-	close(s.stopChan)
-
-	// 停止 cron
-	if s.cron != nil {
-		s.cron.Stop()
-	}
-
+	s.job.cancel()
 	s.running = false
+	if s.state == JobArmed || s.state == JobPreparing || s.state == JobWaiting {
+		s.setStateLocked(JobCancelled)
+	}
 	s.logger.Println("排程器已停止")
+	s.publish(s.targetTime, ScheduleEvent{Type: ScheduleEventStopped})
+}
+
+// Shutdown 停止排程器並等待執行中的 job 結束，之後不再接受 Start（程式結束時使用）
+func (s *Scheduler) Shutdown() {
+	s.mu.Lock()
+	s.closed = true
+	s.stopLocked()
+	s.mu.Unlock()
+
+	s.wg.Wait()
 }
 
 // GetNextRunTime 取得下次執行時間
@@ -184,147 +561,268 @@ func (s *Scheduler) GetNextRunTime() time.Time {
 	return s.targetTime
 }
 
-// calculateTargetTime 計算目標時間（排程日期的 00:00:00）
-func (s *Scheduler) calculateTargetTime() time.Time {
-	return s.targetTime
+// LastResult 取得最近一次執行結果，尚未執行時為 nil
+func (s *Scheduler) LastResult() *SubmissionRecord {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.lastResult
 }
 
-// prepareSubmission 準備提交（預先建立連線、構建資料）
-func (s *Scheduler) prepareSubmission() (*preparedRequest, error) {
+// finish 記錄執行結果並進入結束狀態（排程不再視為進行中），再通知 onComplete；
+// job 已被新的排程取代時只通知 onComplete
+func (s *Scheduler) finish(job *scheduleJob, rec *SubmissionRecord, state JobState) {
+	s.mu.Lock()
+	if s.job == job {
+		s.lastResult = rec
+		s.setStateLocked(state)
+		s.running = false
+	}
+	onComplete := s.onComplete
+	s.mu.Unlock()
+
+	if onComplete != nil {
+		onComplete(rec)
+	}
+}
+
+// loadLeaveRequest 以目標時間讀取要提交的請假資料並驗證，並在此才解密密碼
+func (s *Scheduler) loadLeaveRequest(cfg *ScheduleConfig, target time.Time) (*LeaveRequest, error) {
+	if cfg.SavedFormID <= 0 {
+		req := &LeaveRequest{
+			StartDate: cfg.StartDate,
+			EndDate:   cfg.EndDate,
+			LeaveType: cfg.LeaveType,
+		}
+		if cfg.DateTemplate != nil {
+			var err error
+			if req.StartDate, req.EndDate, err = cfg.DateTemplate.Resolve(target, s.storage.Calendar()); err != nil {
+				return nil, err
+			}
+		}
+		if err := s.storage.FillLeaveRequest(req, cfg.EmployeeRef); err != nil {
+			return nil, fmt.Errorf("讀取員工資料失敗: %w", err)
+		}
+		if err := Validate(req, s.storage.Calendar()); err != nil {
+			return nil, fmt.Errorf("請假資料無效（%s ~ %s）: %w", req.StartDate, req.EndDate, err)
+		}
+		return req, nil
+	}
+
 	// 從 Storage 讀取表單資料
-	savedForm, err := s.storage.GetByID(s.config.SavedFormID)
+	savedForm, err := s.storage.GetByID(cfg.SavedFormID)
 	if err != nil {
 		return nil, fmt.Errorf("讀取儲存資料失敗: %w", err)
 	}
 
-	// 轉換為 LeaveRequest
-	req := savedForm.ToLeaveRequest()
+	// 轉換為 LeaveRequest（日期範本以觸發日計算並驗證），並在此才解密密碼
+	req, err := savedForm.LeaveRequestFor(target, s.storage.Calendar())
+	if err != nil {
+		return nil, err
+	}
+	req.Password, err = s.storage.DecryptPassword(savedForm.Password)
+	if err != nil {
+		return nil, fmt.Errorf("解密密碼失敗: %w", err)
+	}
+	if err := Validate(req, s.storage.Calendar()); err != nil {
+		return nil, fmt.Errorf("請假資料無效: %w", err)
+	}
 
-	// 建構表單資料
-	formData := s.submitter.BuildFormData(req)
+	return req, nil
+}
 
-	// 預先建立 HTTP 請求
-	httpReq, err := http.NewRequest(
-		"POST",
-		s.submitter.FormURL,
-		strings.NewReader(formData.Encode()),
-	)
+// prepareSubmission 準備提交（預先建立連線、構建資料）
+func (s *Scheduler) prepareSubmission(job *scheduleJob) (*preparedRequest, error) {
+	req, err := s.loadLeaveRequest(&job.config, job.targetTime)
 	if err != nil {
-		return nil, fmt.Errorf("建立 HTTP 請求失敗: %w", err)
+		return nil, err
 	}
-	httpReq.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 
-	return &preparedRequest{
-		formData:   formData,
-		httpClient: s.submitter.HTTPClient,
-		targetURL:  s.submitter.FormURL,
-		request:    httpReq,
-	}, nil
+	prepared, err := newPreparedRequest(s.submitter, req)
+	if err != nil {
+		return nil, err
+	}
+
+	// 預熱連線失敗不影響提交，送出時會重新建立連線
+	timing, err := warmUpConnection(prepared)
+	if err != nil {
+		s.logger.Printf("警告: 預熱連線失敗: %v", err)
+	} else {
+		prepared.warmUp = timing
+		s.logger.Printf("預熱連線完成: %s", timing)
+	}
+
+	return prepared, nil
 }
 
 // executeWithPrecision 精確時間執行提交
-func (s *Scheduler) executeWithPrecision() {
-	s.logger.Println("進入準備狀態...")
+func (s *Scheduler) executeWithPrecision(job *scheduleJob) {
+	// 1. 目標時間與設定皆取自 job 的快照
+	targetTime := job.targetTime
+	cfg := &job.config
 
-	// 1. 計算目標時間
-	targetTime := s.calculateTargetTime()
+	if !s.setJobState(job, JobPreparing) {
+		return
+	}
+	s.logger.Println("進入準備狀態...")
+	s.publish(targetTime, ScheduleEvent{Type: ScheduleEventPreparing})
 
 	// 2. 準備階段：預先建立連線、構建資料
-	prepared, err := s.prepareSubmission()
+	prepared, err := s.prepareSubmission(job)
 	if err != nil {
 		s.logger.Printf("準備失敗: %v", err)
+		s.publish(targetTime, ScheduleEvent{Type: ScheduleEventPrepareFailed, Message: err.Error()})
+		s.finishRecord(job, s.targetRecord(cfg, targetTime), s.clock.Now(), nil, nil, fmt.Errorf("準備失敗: %w", err), "")
 		return
 	}
-	s.logger.Println("表單資料已準備完成")
-
-	// 3. 計算等待時間
-	waitDuration := time.Until(targetTime)
-	if waitDuration < 0 {
-		s.logger.Println("目標時間已過，立即執行")
-		waitDuration = 0
+	// 3. 再次執行排程前檢查，保留 preflightMargin 給送出前的等待（poll 策略須在開始探測前結束）
+	stagger := time.Duration(cfg.StaggerMs) * time.Millisecond
+	sendTime := targetTime.Add(stagger)
+	checkBy := sendTime
+	if cfg.polling() {
+		checkBy, _ = cfg.pollWindow(targetTime)
 	}
+	s.preflightJob(job, PreflightPhasePrepare, checkBy.Add(-preflightMargin))
 
-	s.logger.Printf("等待 %v 後執行提交...", waitDuration)
+	if !s.setJobState(job, JobWaiting) {
+		return
+	}
+	s.logger.Println("表單資料已準備完成")
+	prepEvent := ScheduleEvent{Type: ScheduleEventPrepared, Timing: prepared.warmUp}
+	if prepared.warmUp != nil {
+		prepEvent.Message = "預熱連線：" + prepared.warmUp.String()
+	}
+	s.publish(targetTime, prepEvent)
 
-	// 4. 使用 time.NewTimer 精確等待到目標時間
-	if waitDuration > 0 {
-		timer := time.NewTimer(waitDuration)
-		select {
-		case <-timer.C:
-			// 時間到，繼續執行
-		case <-s.stopChan:
-			timer.Stop()
+	// 4. 計算等待時間（含錯開送出的延遲）；poll 策略探測到表單開放後才決定送出時間
+	opening := ""
+	if cfg.polling() {
+		openedAt, probes, ok, err := s.pollUntilOpen(job)
+		if !ok {
 			s.logger.Println("排程被取消")
+			s.publish(targetTime, ScheduleEvent{Type: ScheduleEventCancelled})
+			return
+		}
+		if err != nil {
+			s.logger.Printf("提交失敗: %v", err)
+			s.publish(targetTime, ScheduleEvent{Type: ScheduleEventFailed, Message: err.Error()})
+			s.finishRecord(job, newSubmissionRecord(s.source, prepared.leave), s.clock.Now(), nil, nil, err, "")
 			return
 		}
+		opening = describeOpening(openedAt, targetTime, probes)
+		s.logger.Println(opening)
+		s.publish(targetTime, ScheduleEvent{Type: ScheduleEventFormOpen, Message: opening})
+		sendTime = openedAt.Add(stagger)
+	} else {
+		waitDuration := sendTime.Sub(s.clock.Now())
+		if waitDuration < 0 {
+			s.logger.Println("目標時間已過，立即執行")
+			waitDuration = 0
+		}
+		s.logger.Printf("等待 %v 後執行提交...", waitDuration)
+		s.publish(targetTime, ScheduleEvent{Type: ScheduleEventWaiting, SendTime: &sendTime, Message: fmt.Sprintf("等待 %v", waitDuration)})
 	}
 
-	// 5. 記錄實際執行時間
-	actualTime := time.Now()
-	s.logger.Printf("開始執行提交，實際時間: %s", actualTime.Format("2006-01-02 15:04:05.000"))
+	// 5. 以計時器等到送出前，最後數毫秒改以短暫休眠與忙等逼近（等待中休眠或時間跳動而錯過時依錯過策略處理）
+	if !s.waitToFire(job, sendTime) {
+		s.logger.Println("排程被取消")
+		s.publish(targetTime, ScheduleEvent{Type: ScheduleEventCancelled})
+		return
+	}
+	if s.checkMissed(job, sendTime) {
+		return
+	}
 
-	// 6. 立即發送請求（帶重試）
-	err = s.submitWithRetry(prepared)
+	// 6. 記錄實際執行時間
+	if !s.setJobState(job, JobSubmitting) {
+		return
+	}
+	actualTime := s.clock.Now()
+	fire := &fireTiming{intended: sendTime, actual: actualTime}
+	s.logger.Printf("開始執行提交，實際時間: %s（誤差 %v）", actualTime.Format("2006-01-02 15:04:05.000000"), fire.delay())
+	s.publish(targetTime, ScheduleEvent{
+		Type:     ScheduleEventSending,
+		Time:     actualTime,
+		SendTime: &sendTime,
+		Message:  fmt.Sprintf("與預計送出時間相差 %v", fire.delay()),
+	})
+
+	// 7. 立即發送請求（帶重試）
+	attempts, err := s.submitWithRetry(job, prepared)
+	elapsed := s.clock.Now().Sub(actualTime)
 	if err != nil {
 		s.logger.Printf("提交失敗: %v", err)
+		s.publish(targetTime, ScheduleEvent{Type: ScheduleEventFailed, Attempt: len(attempts), ElapsedMs: elapsed.Milliseconds(), Message: err.Error()})
 	} else {
-		s.logger.Printf("提交成功，耗時: %v", time.Since(actualTime))
+		s.logger.Printf("提交成功，耗時: %v", elapsed)
+		s.publish(targetTime, ScheduleEvent{Type: ScheduleEventSucceeded, Attempt: len(attempts), ElapsedMs: elapsed.Milliseconds()})
 	}
+
+	// 8. 寫入提交記錄
+	s.finishRecord(job, newSubmissionRecord(s.source, prepared.leave), actualTime, fire, attempts, err, opening)
 }
 
-// submitWithRetry 帶重試的提交
-func (s *Scheduler) submitWithRetry(prepared *preparedRequest) error {
-	retryCount := s.config.RetryCount
-	if retryCount <= 0 {
-		retryCount = 3
+// finishRecord 補上執行結果後寫入提交記錄（演練模式不寫入）並結束 job；rec 為已填入提交對象的記錄，
+// fire 為實際送出的時間（未送出時為 nil），attempts 為每次送出嘗試的結果，note 附加於訊息後（如 poll 策略偵測到開放的時間）
+func (s *Scheduler) finishRecord(job *scheduleJob, rec *SubmissionRecord, startedAt time.Time, fire *fireTiming, attempts []SubmissionAttempt, err error, note string) {
+	rec.BatchID = s.batchID
+	rec.SavedFormID = job.config.SavedFormID
+	rec.MissedDecision = job.missed
+	rec.Attempts = len(attempts)
+	rec.AttemptDetails = attempts
+	rec.Success = err == nil
+	rec.Message = "提交成功"
+	if err != nil {
+		rec.Message = err.Error()
 	}
-
-	retryInterval := s.config.RetryInterval
-	if retryInterval <= 0 {
-		retryInterval = 100
+	if note != "" {
+		rec.Message += "；" + note
 	}
-
-	var lastErr error
-
-	for i := 0; i < retryCount; i++ {
-		if i > 0 {
-			s.logger.Printf("第 %d 次重試...", i)
-			time.Sleep(time.Duration(retryInterval) * time.Millisecond)
-
-			// 重新建立請求（因為 Body 已被讀取）
-			newReq, err := http.NewRequest(
-				"POST",
-				prepared.targetURL,
-				strings.NewReader(prepared.formData.Encode()),
-			)
-			if err != nil {
-				lastErr = fmt.Errorf("重建請求失敗: %w", err)
-				continue
-			}
-			newReq.Header.Set("Content-Type", "application/x-www-form-urlencoded")
-			prepared.request = newReq
+	rec.StartedAt = startedAt
+	rec.FinishedAt = s.clock.Now()
+	if fire != nil {
+		fire.apply(rec)
+	}
+	if !s.rehearsal {
+		if _, err := s.storage.RecordSubmission(rec); err != nil {
+			s.logger.Printf("警告: %v", err)
 		}
+	}
+	state := JobSucceeded
+	if !rec.Success {
+		state = JobFailed
+	}
+	s.finish(job, rec, state)
+}
 
-		// 發送請求
-		resp, err := prepared.httpClient.Do(prepared.request)
+// submitWithRetry 帶重試的提交，回傳每次嘗試的結果
+func (s *Scheduler) submitWithRetry(job *scheduleJob, prepared *preparedRequest) ([]SubmissionAttempt, error) {
+	policy := RetryPolicy{
+		Count:    job.config.RetryCount,
+		Interval: job.config.RetryInterval,
+	}.withDefaults()
+
+	return sendWithRetry(job.ctx, prepared, policy, s.logger, func(attempt *SubmissionAttempt, err error) {
+		e := ScheduleEvent{
+			Type:       ScheduleEventAttempt,
+			Attempt:    attempt.Attempt,
+			StatusCode: attempt.StatusCode,
+			ElapsedMs:  attempt.Timing.TotalUs / 1000,
+			Timing:     attempt.Timing,
+			Message:    "回應成功",
+		}
 		if err != nil {
-			lastErr = fmt.Errorf("發送請求失敗: %w", err)
-			s.logger.Printf("請求失敗: %v", err)
-			continue
+			e.Message = err.Error()
 		}
-		resp.Body.Close()
-
-		// 檢查回應狀態
-		if resp.StatusCode == http.StatusOK {
-			s.logger.Printf("Google Form 回應成功 (HTTP %d)", resp.StatusCode)
-			return nil
+		s.publish(job.targetTime, e)
+
+		if err != nil && attempt.Attempt < policy.Count {
+			s.publish(job.targetTime, ScheduleEvent{
+				Type:    ScheduleEventRetry,
+				Attempt: attempt.Attempt + 1,
+				Message: fmt.Sprintf("%d 毫秒後重試", policy.Interval),
+			})
 		}
-
-		lastErr = fmt.Errorf("Google Form 回應錯誤: HTTP %d", resp.StatusCode)
-		s.logger.Printf("回應錯誤: HTTP %d", resp.StatusCode)
-	}
-
-	return fmt.Errorf("提交失敗，已重試 %d 次: %w", retryCount, lastErr)
+	})
 }
 
 // ParseScheduleDate 解析排程日期（YYYY-MM-DD 格式，時間固定為 00:00:00）
@@ -342,6 +840,24 @@ func ParseScheduleDate(dateStr string) (time.Time, error) {
 	return t, nil
 }
 
+// ReferencesSavedForm 檢查啟用中的排程是否使用指定的儲存資料
+func (s *Scheduler) ReferencesSavedForm(id int64) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.running && s.config != nil && s.config.SavedFormID == id
+}
+
+// DeleteSavedForm 刪除未被啟用中排程使用的儲存資料；檢查與刪除在同一把鎖內完成，
+// 避免檢查後、刪除前排程以該資料啟動。使用中時回傳 ErrSavedFormScheduled
+func (s *Scheduler) DeleteSavedForm(id int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.running && s.config != nil && s.config.SavedFormID == id {
+		return ErrSavedFormScheduled
+	}
+	return s.storage.Delete(id)
+}
+
 // IsRunning 檢查排程器是否正在運行
 func (s *Scheduler) IsRunning() bool {
 	s.mu.Lock()
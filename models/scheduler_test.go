@@ -1,7 +1,15 @@
 package models
 
 import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 )
@@ -10,6 +18,11 @@ import (
 // 12.3 測試排程功能
 // ============================================
 
+// testSecretKey 測試用固定加密金鑰
+func testSecretKey() *SecretKey {
+	return &SecretKey{Key: bytes.Repeat([]byte{0x42}, EncryptionKeySize)}
+}
+
 // setupTestStorage 建立測試用 Storage
 func setupTestStorage(t *testing.T) (*Storage, func()) {
 	tmpFile, err := os.CreateTemp("", "test_scheduler_*.db")
@@ -18,7 +31,7 @@ func setupTestStorage(t *testing.T) (*Storage, func()) {
 	}
 	tmpFile.Close()
 
-	storage, err := NewStorage(tmpFile.Name())
+	storage, err := NewStorage(tmpFile.Name(), testSecretKey())
 	if err != nil {
 		os.Remove(tmpFile.Name())
 		t.Fatalf("無法初始化 Storage: %v", err)
@@ -191,6 +204,61 @@ func TestSchedulerStartWithInvalidSavedFormID(t *testing.T) {
 	}
 }
 
+// TestSchedulerDeleteSavedForm 測試刪除儲存資料與以該資料啟動排程同時進行時，
+// 不會出現排程已啟動但資料已刪除的情況
+func TestSchedulerDeleteSavedForm(t *testing.T) {
+	storage, cleanup := setupTestStorage(t)
+	defer cleanup()
+
+	submitter := NewGoogleFormSubmitter("https://docs.google.com/forms/d/e/test/formResponse", map[string]string{})
+	futureDate := time.Now().AddDate(1, 0, 0).Format("2006-01-02")
+
+	for i := 0; i < 20; i++ {
+		id, err := storage.Save(&SavedForm{
+			Label:      "測試資料",
+			Name:       "測試員工",
+			EmployeeID: "A12345",
+			StartDate:  "2026-02-01",
+			EndDate:    "2026-02-03",
+			LeaveType:  "近假",
+			Password:   "testpass",
+		})
+		if err != nil {
+			t.Fatalf("儲存資料失敗: %v", err)
+		}
+
+		scheduler := NewScheduler(&ScheduleConfig{Enabled: true, Date: futureDate, SavedFormID: id}, submitter, storage)
+		var startErr, deleteErr error
+		var wg sync.WaitGroup
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			startErr = scheduler.Start()
+		}()
+		go func() {
+			defer wg.Done()
+			deleteErr = scheduler.DeleteSavedForm(id)
+		}()
+		wg.Wait()
+
+		switch {
+		case startErr == nil && deleteErr == nil:
+			t.Fatal("排程已啟動時不應刪除其使用的資料")
+		case startErr == nil:
+			if !errors.Is(deleteErr, ErrSavedFormScheduled) {
+				t.Errorf("使用中的資料應回傳 ErrSavedFormScheduled，實際 %v", deleteErr)
+			}
+		case deleteErr == nil:
+			if !strings.Contains(startErr.Error(), "找不到 ID") {
+				t.Errorf("資料已刪除時應明確回報，實際 %v", startErr)
+			}
+		default:
+			t.Fatalf("啟動與刪除至少一個應成功: start=%v delete=%v", startErr, deleteErr)
+		}
+		scheduler.Shutdown()
+	}
+}
+
 // TestGetNextRunTime 測試取得下次執行時間
 // Requirements: 7.8
 func TestGetNextRunTime(t *testing.T) {
@@ -418,3 +486,984 @@ func TestIsRunning(t *testing.T) {
 		t.Error("初始狀態不應處於運行狀態")
 	}
 }
+
+// TestSchedulerEvents 測試排程執行過程依序發布事件，並記錄每次送出與重試
+func TestSchedulerEvents(t *testing.T) {
+	storage, cleanup := setupTestStorage(t)
+	defer cleanup()
+
+	// 第一次回應 500，第二次成功
+	var posts int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "POST" && atomic.AddInt32(&posts, 1) == 1 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	employeeRef, err := storage.CreateEmployee(&Employee{Name: "王小明", EmployeeID: "A1", Password: "p"})
+	if err != nil {
+		t.Fatalf("建立員工失敗: %v", err)
+	}
+
+	scheduler := NewScheduler(&ScheduleConfig{
+		Enabled:       true,
+		At:            time.Now().Add(300 * time.Millisecond),
+		EmployeeRef:   employeeRef,
+		StartDate:     "2026-02-02",
+		EndDate:       "2026-02-03",
+		LeaveType:     "近假",
+		RetryCount:    3,
+		RetryInterval: 1,
+	}, batchTestSubmitter(server.URL), storage)
+	_, events, cancel := scheduler.Events().Subscribe()
+	defer cancel()
+
+	if err := scheduler.Start(); err != nil {
+		t.Fatalf("啟動排程器失敗: %v", err)
+	}
+	defer scheduler.Stop()
+
+	var got []ScheduleEvent
+	timeout := time.After(5 * time.Second)
+	for len(got) == 0 || got[len(got)-1].Type != ScheduleEventSucceeded {
+		select {
+		case e := <-events:
+			got = append(got, e)
+		case <-timeout:
+			t.Fatalf("等待提交完成逾時，已收到 %+v", got)
+		}
+	}
+
+	want := []string{
+		ScheduleEventArmed, ScheduleEventPreparing, ScheduleEventPrepared, ScheduleEventWaiting,
+		ScheduleEventSending, ScheduleEventAttempt, ScheduleEventRetry, ScheduleEventAttempt, ScheduleEventSucceeded,
+	}
+	if len(got) != len(want) {
+		t.Fatalf("事件數量應為 %d，實際 %+v", len(want), got)
+	}
+	for i, e := range got {
+		if e.Type != want[i] {
+			t.Errorf("第 %d 個事件應為 %s，實際 %s", i+1, want[i], e.Type)
+		}
+		if e.Seq != int64(i+1) || e.TargetTime == nil {
+			t.Errorf("事件應有遞增序號與目標時間: %+v", e)
+		}
+	}
+	if got[5].StatusCode != http.StatusInternalServerError || got[7].StatusCode != http.StatusOK || got[8].Attempt != 2 {
+		t.Errorf("送出結果記錄錯誤: %+v %+v %+v", got[5], got[7], got[8])
+	}
+
+	if recent := scheduler.Events().Recent(); len(recent) != len(want) {
+		t.Errorf("應保留 %d 個最近事件，實際 %d", len(want), len(recent))
+	}
+}
+
+// TestSchedulerStates 測試狀態轉換規則與每個狀態的進入時間，執行結束後不再視為運行中
+func TestSchedulerStates(t *testing.T) {
+	for _, tt := range []struct {
+		from, to JobState
+		ok       bool
+	}{
+		{JobIdle, JobArmed, true},
+		{JobArmed, JobPreparing, true},
+		{JobWaiting, JobSubmitting, true},
+		{JobSubmitting, JobCancelled, false},
+		{JobIdle, JobSubmitting, false},
+		{JobSucceeded, JobArmed, true},
+		{JobFailed, JobWaiting, false},
+	} {
+		if got := tt.from.CanTransition(tt.to); got != tt.ok {
+			t.Errorf("%s → %s 應為 %v", tt.from, tt.to, tt.ok)
+		}
+	}
+
+	storage, cleanup := setupTestStorage(t)
+	defer cleanup()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	employeeRef, err := storage.CreateEmployee(&Employee{Name: "王小明", EmployeeID: "A1", Password: "p"})
+	if err != nil {
+		t.Fatalf("建立員工失敗: %v", err)
+	}
+	cfg := &ScheduleConfig{
+		Enabled:       true,
+		At:            time.Now().Add(-time.Minute),
+		EmployeeRef:   employeeRef,
+		StartDate:     "2026-02-02",
+		EndDate:       "2026-02-03",
+		LeaveType:     "近假",
+		RetryCount:    1,
+		RetryInterval: 1,
+	}
+	scheduler := NewScheduler(cfg, batchTestSubmitter(server.URL), storage)
+	if state, _ := scheduler.State(); state != JobIdle {
+		t.Errorf("初始狀態應為 idle，實際 %s", state)
+	}
+
+	// 目標時間已過
+	if err := scheduler.Start(); err != nil {
+		t.Fatalf("啟動排程器失敗: %v", err)
+	}
+	if state, _ := scheduler.State(); state != JobMissed || scheduler.IsRunning() {
+		t.Errorf("目標時間已過應為 missed，實際 %s", state)
+	}
+
+	// 執行前停止
+	cfg.At = time.Now().Add(time.Hour)
+	if err := scheduler.Start(); err != nil {
+		t.Fatalf("啟動排程器失敗: %v", err)
+	}
+	if state, _ := scheduler.State(); state != JobArmed || !scheduler.IsRunning() {
+		t.Errorf("啟動後應為 armed，實際 %s", state)
+	}
+	scheduler.Stop()
+	if state, _ := scheduler.State(); state != JobCancelled {
+		t.Errorf("停止後應為 cancelled，實際 %s", state)
+	}
+
+	// 完整執行
+	done := make(chan struct{})
+	scheduler.onComplete = func(*SubmissionRecord) { close(done) }
+	next := *cfg
+	next.At = time.Now().Add(200 * time.Millisecond)
+	if err := scheduler.StartWithConfig(&next); err != nil {
+		t.Fatalf("啟動排程器失敗: %v", err)
+	}
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("等待提交完成逾時")
+	}
+
+	state, states := scheduler.State()
+	if state != JobSucceeded || scheduler.IsRunning() {
+		t.Errorf("提交成功後應為 succeeded 且不再運行，實際 %s running=%v", state, scheduler.IsRunning())
+	}
+	want := []JobState{JobArmed, JobPreparing, JobWaiting, JobSubmitting, JobSucceeded}
+	if len(states) != len(want) {
+		t.Fatalf("狀態記錄應為 %v，實際 %+v", want, states)
+	}
+	for i, st := range states {
+		if st.State != want[i] || (i > 0 && st.At.Before(states[i-1].At)) {
+			t.Errorf("第 %d 個狀態應為 %s 且時間遞增，實際 %+v", i+1, want[i], st)
+		}
+	}
+}
+
+// TestSchedulerConcurrentLifecycle 同時啟動、停止、重新設定與查詢排程器（需搭配 go test -race）
+func TestSchedulerConcurrentLifecycle(t *testing.T) {
+	storage, cleanup := setupTestStorage(t)
+	defer cleanup()
+
+	var posts int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "POST" {
+			atomic.AddInt32(&posts, 1)
+			time.Sleep(5 * time.Millisecond)
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	employeeRef, err := storage.CreateEmployee(&Employee{Name: "王小明", EmployeeID: "A1", Password: "p"})
+	if err != nil {
+		t.Fatalf("建立員工失敗: %v", err)
+	}
+	newConfig := func(delay time.Duration) *ScheduleConfig {
+		return &ScheduleConfig{
+			Enabled:       true,
+			At:            time.Now().Add(delay),
+			EmployeeRef:   employeeRef,
+			StartDate:     "2026-02-02",
+			EndDate:       "2026-02-03",
+			LeaveType:     "近假",
+			RetryCount:    2,
+			RetryInterval: 1,
+		}
+	}
+
+	scheduler := NewScheduler(newConfig(time.Hour), batchTestSubmitter(server.URL), storage)
+	_, events, cancel := scheduler.Events().Subscribe()
+	defer cancel()
+
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 30; i++ {
+				switch (g + i) % 5 {
+				case 0:
+					scheduler.StartWithConfig(newConfig(time.Duration(i%4) * 10 * time.Millisecond))
+				case 1:
+					scheduler.Stop()
+				case 2:
+					scheduler.Start()
+				case 3:
+					scheduler.GetConfig()
+					scheduler.GetNextRunTime()
+					scheduler.LastResult()
+				case 4:
+					scheduler.State()
+					scheduler.IsRunning()
+					scheduler.ReferencesSavedForm(1)
+				}
+				time.Sleep(time.Millisecond)
+			}
+		}(g)
+	}
+
+	// 持續讀取事件，確認廣播不會阻塞
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for range events {
+		}
+	}()
+
+	wg.Wait()
+	scheduler.Shutdown()
+	cancel()
+	<-done
+
+	if scheduler.IsRunning() {
+		t.Error("Shutdown 後不應處於運行狀態")
+	}
+	if state, _ := scheduler.State(); state.IsActive() {
+		t.Errorf("Shutdown 後所有工作應已結束，實際狀態 %s", state)
+	}
+	if err := scheduler.Start(); err == nil {
+		t.Error("Shutdown 後應拒絕再次啟動")
+	}
+	t.Logf("共送出 %d 次", atomic.LoadInt32(&posts))
+}
+
+// TestSchedulerStopDuringRetry 測試停止排程後不再重試，且 Shutdown 會等待送出結束
+func TestSchedulerStopDuringRetry(t *testing.T) {
+	storage, cleanup := setupTestStorage(t)
+	defer cleanup()
+
+	var posts int32
+	firstPost := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "POST" && atomic.AddInt32(&posts, 1) == 1 {
+			close(firstPost)
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	employeeRef, err := storage.CreateEmployee(&Employee{Name: "王小明", EmployeeID: "A1", Password: "p"})
+	if err != nil {
+		t.Fatalf("建立員工失敗: %v", err)
+	}
+	scheduler := NewScheduler(&ScheduleConfig{
+		Enabled:       true,
+		At:            time.Now().Add(50 * time.Millisecond),
+		EmployeeRef:   employeeRef,
+		StartDate:     "2026-02-02",
+		EndDate:       "2026-02-03",
+		LeaveType:     "近假",
+		RetryCount:    5,
+		RetryInterval: 200,
+	}, batchTestSubmitter(server.URL), storage)
+	if err := scheduler.Start(); err != nil {
+		t.Fatalf("啟動排程器失敗: %v", err)
+	}
+
+	select {
+	case <-firstPost:
+	case <-time.After(5 * time.Second):
+		t.Fatal("等待第一次送出逾時")
+	}
+	scheduler.Shutdown()
+
+	if n := atomic.LoadInt32(&posts); n != 1 {
+		t.Errorf("停止後不應再重試，實際送出 %d 次", n)
+	}
+	state, _ := scheduler.State()
+	rec := scheduler.LastResult()
+	if state != JobFailed || rec == nil || rec.Attempts != 1 {
+		t.Errorf("應以失敗結束並記錄 1 次嘗試，實際 %s %+v", state, rec)
+	}
+}
+
+// TestSchedulerFakeClock 以測試時鐘跑完排程流程：時間只在 Advance 時前進
+func TestSchedulerFakeClock(t *testing.T) {
+	storage, cleanup := setupTestStorage(t)
+	defer cleanup()
+
+	var posts int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "POST" {
+			atomic.AddInt32(&posts, 1)
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	employeeRef, err := storage.CreateEmployee(&Employee{Name: "王小明", EmployeeID: "A1", Password: "p"})
+	if err != nil {
+		t.Fatalf("建立員工失敗: %v", err)
+	}
+
+	target := time.Date(2026, 3, 1, 0, 0, 0, 0, time.Local)
+	clock := NewFakeClock(target.Add(-10 * time.Second))
+	scheduler := NewScheduler(&ScheduleConfig{
+		Enabled:        true,
+		At:             target,
+		EmployeeRef:    employeeRef,
+		StartDate:      "2026-03-02",
+		EndDate:        "2026-03-03",
+		LeaveType:      "近假",
+		PrepareSeconds: 5,
+	}, batchTestSubmitter(server.URL), storage)
+	scheduler.SetClock(clock)
+
+	done := make(chan *SubmissionRecord, 1)
+	scheduler.onComplete = func(rec *SubmissionRecord) { done <- rec }
+	if err := scheduler.Start(); err != nil {
+		t.Fatalf("啟動排程器失敗: %v", err)
+	}
+	defer scheduler.Shutdown()
+
+	// 準備時間前不會進入準備狀態
+	if !clock.BlockUntil(1, time.Second) {
+		t.Fatal("排程器應等待準備時間")
+	}
+	clock.Advance(4 * time.Second)
+	if state, _ := scheduler.State(); state != JobArmed {
+		t.Errorf("準備時間前應為 armed，實際 %s", state)
+	}
+
+	// 到準備時間後完成準備並等待目標時間
+	clock.Advance(time.Second)
+	if !clock.BlockUntil(1, 5*time.Second) {
+		t.Fatal("準備完成後應等待目標時間")
+	}
+	if state, _ := scheduler.State(); state != JobWaiting || atomic.LoadInt32(&posts) != 0 {
+		t.Errorf("目標時間前應為 waiting 且未送出，實際 %s（送出 %d 次）", state, posts)
+	}
+
+	clock.Advance(5 * time.Second)
+	var rec *SubmissionRecord
+	select {
+	case rec = <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("等待提交完成逾時")
+	}
+
+	if !rec.Success || atomic.LoadInt32(&posts) != 1 {
+		t.Errorf("應送出一次且成功，實際 %+v（送出 %d 次）", rec, posts)
+	}
+	if !rec.StartedAt.Equal(target) {
+		t.Errorf("實際送出時間應為模擬的目標時間 %s，實際 %s", target, rec.StartedAt)
+	}
+	_, states := scheduler.State()
+	if states[0].At != target.Add(-10*time.Second) || states[len(states)-1].At != target {
+		t.Errorf("狀態時間應取自測試時鐘，實際 %+v", states)
+	}
+}
+
+// TestRunRehearsal 以加速時鐘演練排程：送到本機模擬端點且不寫入提交記錄
+func TestRunRehearsal(t *testing.T) {
+	storage, cleanup := setupTestStorage(t)
+	defer cleanup()
+
+	id, err := storage.Save(&SavedForm{
+		Label:      "演練",
+		Name:       "測試員工",
+		EmployeeID: "A12345",
+		StartDate:  "2026-02-02",
+		EndDate:    "2026-02-03",
+		LeaveType:  "近假",
+		Password:   "testpass",
+	})
+	if err != nil {
+		t.Fatalf("儲存失敗: %v", err)
+	}
+
+	// 目標時間在遙遠的未來，10 秒的模擬前置時間以 50 倍速約 0.2 秒跑完
+	target := time.Date(2030, 1, 1, 0, 0, 0, 0, time.Local)
+	result, err := RunRehearsal(context.Background(), storage, batchTestSubmitter("").EntryMapSnapshot(), RehearsalOptions{
+		SavedFormID:   id,
+		At:            target,
+		Speed:         50,
+		RetryCount:    3,
+		RetryInterval: 1,
+		Mock:          MockFormOptions{FailFirst: 1},
+	}, nil)
+	if err != nil {
+		t.Fatalf("演練失敗: %v", err)
+	}
+
+	if result.State != JobSucceeded || result.Record == nil || result.Record.Attempts != 2 {
+		t.Fatalf("演練應在第 2 次嘗試成功，實際 %s %+v", result.State, result.Record)
+	}
+	if len(result.Submissions) != 2 || result.Submissions[1].Form.Get("entry.2") != "A12345" {
+		t.Fatalf("模擬端點應收到 2 次送出，實際 %+v", result.Submissions)
+	}
+	if at := result.Submissions[0].At; at.Before(target) || at.After(target.Add(5*time.Second)) {
+		t.Errorf("送出的模擬時間應接近目標時間 %s，實際 %s", target, at)
+	}
+	if len(result.Events) == 0 || result.Events[0].Type != ScheduleEventArmed {
+		t.Errorf("演練結果應包含事件時間軸，實際 %+v", result.Events)
+	}
+
+	records, err := storage.ListHistory(HistoryQuery{})
+	if err != nil {
+		t.Fatalf("讀取提交記錄失敗: %v", err)
+	}
+	if len(records) != 0 {
+		t.Errorf("演練不應寫入提交記錄，實際 %d 筆", len(records))
+	}
+}
+
+// TestSchedulerMissedPolicy 測試啟動時目標時間已過的各錯過策略
+func TestSchedulerMissedPolicy(t *testing.T) {
+	storage, cleanup := setupTestStorage(t)
+	defer cleanup()
+
+	var posts int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "POST" {
+			atomic.AddInt32(&posts, 1)
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	id, err := storage.Save(&SavedForm{
+		Label:      "錯過",
+		Name:       "測試員工",
+		EmployeeID: "A12345",
+		StartDate:  "2026-02-02",
+		EndDate:    "2026-02-03",
+		LeaveType:  "近假",
+		Password:   "testpass",
+	})
+	if err != nil {
+		t.Fatalf("儲存失敗: %v", err)
+	}
+
+	for _, tt := range []struct {
+		policy   string
+		late     time.Duration
+		decision string
+		state    JobState
+	}{
+		{"", time.Minute, MissedSkipped, JobMissed},
+		{MissedPolicyNotify, time.Minute, MissedNotified, JobMissed},
+		{MissedPolicyGrace, 10 * time.Minute, MissedGraceExpired, JobMissed},
+		{MissedPolicyGrace, 30 * time.Second, MissedGraceSubmitted, JobSucceeded},
+	} {
+		atomic.StoreInt32(&posts, 0)
+		scheduler := NewScheduler(&ScheduleConfig{
+			Enabled:      true,
+			At:           time.Now().Add(-tt.late),
+			SavedFormID:  id,
+			MissedPolicy: tt.policy,
+		}, batchTestSubmitter(server.URL), storage)
+		done := make(chan struct{})
+		scheduler.onComplete = func(*SubmissionRecord) { close(done) }
+
+		if err := scheduler.Start(); err != nil {
+			t.Fatalf("[%s] 啟動排程器失敗: %v", tt.decision, err)
+		}
+		if tt.state == JobSucceeded {
+			select {
+			case <-done:
+			case <-time.After(5 * time.Second):
+				t.Fatalf("[%s] 等待補送逾時", tt.decision)
+			}
+		}
+		scheduler.Shutdown()
+
+		if state, _ := scheduler.State(); state != tt.state {
+			t.Errorf("[%s] 狀態應為 %s，實際 %s", tt.decision, tt.state, state)
+		}
+		if got := atomic.LoadInt32(&posts); (got > 0) != (tt.state == JobSucceeded) {
+			t.Errorf("[%s] 送出次數不正確: %d", tt.decision, got)
+		}
+		rec := scheduler.LastResult()
+		if rec == nil || rec.MissedDecision != tt.decision || rec.Name != "測試員工" {
+			t.Fatalf("[%s] 執行結果應記錄處理結果，實際 %+v", tt.decision, rec)
+		}
+
+		records, err := storage.ListHistory(HistoryQuery{Limit: 1})
+		if err != nil || len(records) != 1 || records[0].MissedDecision != tt.decision {
+			t.Errorf("[%s] 提交記錄應包含處理結果，實際 %+v: %v", tt.decision, records, err)
+		}
+
+		var notices int
+		for _, e := range scheduler.Events().Recent() {
+			if e.Type == ScheduleEventNotice {
+				notices++
+			}
+		}
+		if (notices > 0) != (tt.decision == MissedNotified) {
+			t.Errorf("[%s] 只有 notify 策略應發出通知，實際 %d 則", tt.decision, notices)
+		}
+	}
+
+	bad := NewScheduler(&ScheduleConfig{Enabled: true, At: time.Now().Add(time.Hour), SavedFormID: id, MissedPolicy: "retry"}, batchTestSubmitter(server.URL), storage)
+	if err := bad.Start(); err == nil {
+		t.Error("無效的錯過策略應回傳錯誤")
+	}
+}
+
+// TestSchedulerPrepareFailureRecorded 測試準備失敗時同樣寫入提交記錄並保留錯過時間的處理結果
+func TestSchedulerPrepareFailureRecorded(t *testing.T) {
+	storage, cleanup := setupTestStorage(t)
+	defer cleanup()
+
+	employeeRef, err := storage.CreateEmployee(&Employee{Name: "王小明", EmployeeID: "A1", Password: "p"})
+	if err != nil {
+		t.Fatalf("建立員工失敗: %v", err)
+	}
+
+	scheduler := NewScheduler(&ScheduleConfig{
+		Enabled:      true,
+		At:           time.Now().Add(-30 * time.Second),
+		EmployeeRef:  employeeRef,
+		StartDate:    "2026-03-02",
+		EndDate:      "2026-03-03",
+		LeaveType:    "近假",
+		MissedPolicy: MissedPolicyGrace,
+	}, batchTestSubmitter("http://bad host/formResponse"), storage) // 無效的網址在準備階段建立請求時失敗
+	done := make(chan *SubmissionRecord, 1)
+	scheduler.onComplete = func(rec *SubmissionRecord) { done <- rec }
+	if err := scheduler.Start(); err != nil {
+		t.Fatalf("啟動排程器失敗: %v", err)
+	}
+	defer scheduler.Shutdown()
+
+	var rec *SubmissionRecord
+	select {
+	case rec = <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("等待準備失敗逾時")
+	}
+	if state, _ := scheduler.State(); state != JobFailed {
+		t.Errorf("狀態應為 failed，實際 %s", state)
+	}
+
+	records, err := storage.ListHistory(HistoryQuery{Limit: 1})
+	if err != nil || len(records) != 1 {
+		t.Fatalf("準備失敗應寫入提交記錄，實際 %+v: %v", records, err)
+	}
+	got := records[0]
+	if got.ID != rec.ID || got.Success || !strings.HasPrefix(got.Message, "準備失敗") {
+		t.Errorf("提交記錄應為準備失敗，實際 %+v", got)
+	}
+	if got.MissedDecision != MissedGraceSubmitted || got.Name != "王小明" || got.StartDate != "2026-03-02" {
+		t.Errorf("提交記錄應包含錯過時間的處理結果與提交對象，實際 %+v", got)
+	}
+}
+
+// TestSchedulerClockJump 以測試時鐘模擬休眠喚醒：等待中時間跳過目標時間時依錯過策略處理
+func TestSchedulerClockJump(t *testing.T) {
+	storage, cleanup := setupTestStorage(t)
+	defer cleanup()
+
+	var posts int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "POST" {
+			atomic.AddInt32(&posts, 1)
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	employeeRef, err := storage.CreateEmployee(&Employee{Name: "王小明", EmployeeID: "A1", Password: "p"})
+	if err != nil {
+		t.Fatalf("建立員工失敗: %v", err)
+	}
+
+	target := time.Date(2026, 3, 1, 0, 0, 0, 0, time.Local)
+	for _, tt := range []struct {
+		policy string
+		jump   time.Duration // 休眠喚醒後時間已超過目標時間多久
+		state  JobState
+	}{
+		{MissedPolicySkip, 10 * time.Minute, JobMissed},
+		{MissedPolicyGrace, 20 * time.Second, JobSucceeded},
+	} {
+		atomic.StoreInt32(&posts, 0)
+		clock := NewFakeClock(target.Add(-time.Hour))
+		scheduler := NewScheduler(&ScheduleConfig{
+			Enabled:      true,
+			At:           target,
+			EmployeeRef:  employeeRef,
+			StartDate:    "2026-03-02",
+			EndDate:      "2026-03-03",
+			LeaveType:    "近假",
+			MissedPolicy: tt.policy,
+		}, batchTestSubmitter(server.URL), storage)
+		scheduler.SetClock(clock)
+		done := make(chan *SubmissionRecord, 1)
+		scheduler.onComplete = func(rec *SubmissionRecord) { done <- rec }
+
+		if err := scheduler.Start(); err != nil {
+			t.Fatalf("啟動排程器失敗: %v", err)
+		}
+
+		// 等待以最長 wallCheckInterval 分段進行
+		if !clock.BlockUntil(1, time.Second) {
+			t.Fatal("排程器應等待準備時間")
+		}
+		clock.Advance(time.Hour + tt.jump)
+
+		var rec *SubmissionRecord
+		select {
+		case rec = <-done:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("[%s] 等待執行結束逾時", tt.policy)
+		}
+		scheduler.Shutdown()
+
+		if state, _ := scheduler.State(); state != tt.state {
+			t.Errorf("[%s] 狀態應為 %s，實際 %s", tt.policy, tt.state, state)
+		}
+		wantDecision, wantPosts := MissedSkipped, int32(0)
+		if tt.state == JobSucceeded {
+			wantDecision, wantPosts = MissedGraceSubmitted, 1
+		}
+		if rec.MissedDecision != wantDecision || atomic.LoadInt32(&posts) != wantPosts {
+			t.Errorf("[%s] 處理結果應為 %s 且送出 %d 次，實際 %s / %d", tt.policy, wantDecision, wantPosts, rec.MissedDecision, posts)
+		}
+
+		var jumps int
+		for _, e := range scheduler.Events().Recent() {
+			if e.Type == ScheduleEventClockJump {
+				jumps++
+			}
+		}
+		if jumps != 1 {
+			t.Errorf("[%s] 應發布一次 clock_jump 事件，實際 %d", tt.policy, jumps)
+		}
+	}
+}
+
+// TestSchedulerPreflight 測試排程前檢查的各項結果，以及啟動時與準備階段的檢查
+func TestSchedulerPreflight(t *testing.T) {
+	storage, cleanup := setupTestStorage(t)
+	defer cleanup()
+
+	entries := map[string]string{"name": "entry.1", "employee_id": "entry.2", "start_date": "entry.3", "end_date": "entry.4", "leave_type": "entry.5", "password": "entry.6"}
+	form := NewMockForm(MockFormOptions{EntryIDs: entries})
+	mock, err := StartMockFormServer(form, "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("啟動模擬表單失敗: %v", err)
+	}
+	defer mock.Close()
+
+	id, err := storage.Save(&SavedForm{
+		Label:      "檢查",
+		Name:       "王小明",
+		EmployeeID: "A12345",
+		StartDate:  "2030-01-02",
+		EndDate:    "2030-01-03",
+		LeaveType:  "近假",
+		Password:   "p",
+	})
+	if err != nil {
+		t.Fatalf("儲存失敗: %v", err)
+	}
+
+	scheduler := NewScheduler(&ScheduleConfig{}, batchTestSubmitter(mock.FormURL()), storage)
+	check := func(want map[string]string) *PreflightReport {
+		t.Helper()
+		report, err := scheduler.CheckPreflight(t.Context(), &ScheduleConfig{SavedFormID: id, At: time.Now().Add(time.Hour)})
+		if err != nil {
+			t.Fatalf("檢查失敗: %v", err)
+		}
+		for name, status := range want {
+			if c := report.Check(name); c == nil || c.Status != status {
+				t.Errorf("%s 應為 %s，實際 %+v", name, status, c)
+			}
+		}
+		return report
+	}
+
+	report := check(map[string]string{
+		PreflightCheckData:         PreflightOK,
+		PreflightCheckDateWindow:   PreflightOK,
+		PreflightCheckReachability: PreflightOK,
+		PreflightCheckEntryIDs:     PreflightOK,
+		PreflightCheckClockSkew:    PreflightOK,
+	})
+	if !report.Passed || report.Phase != PreflightPhaseManual {
+		t.Errorf("全部通過時 passed 應為 true，實際 %+v", report)
+	}
+	if c := report.Check(PreflightCheckEntryIDs); c.Timing == nil || c.Timing.TotalUs <= 0 {
+		t.Errorf("讀取表單頁面的項目應附上網路耗時，實際 %+v", c.Timing)
+	}
+
+	// 表單上少了 password 欄位
+	form.SetOptions(MockFormOptions{EntryIDs: map[string]string{"name": "entry.1", "employee_id": "entry.2", "start_date": "entry.3", "end_date": "entry.4", "leave_type": "entry.5"}})
+	report = check(map[string]string{PreflightCheckEntryIDs: PreflightFail})
+	if report.Passed || !strings.Contains(report.Summary(), "password（entry.6）") {
+		t.Errorf("缺少欄位應檢查失敗並列出欄位，實際 %s", report.Summary())
+	}
+
+	// 表單尚未開放時無法比對，只警告
+	form.SetOptions(MockFormOptions{EntryIDs: entries, Closed: true})
+	check(map[string]string{PreflightCheckEntryIDs: PreflightWarn})
+	form.SetOptions(MockFormOptions{EntryIDs: entries})
+
+	// 送出時間早於預約窗口開放
+	window, err := NewBookingWindow(30, "00:00", 0, "Asia/Taipei")
+	if err != nil {
+		t.Fatalf("建立預約窗口規則失敗: %v", err)
+	}
+	scheduler.SetBookingWindow(window)
+	check(map[string]string{PreflightCheckDateWindow: PreflightFail})
+	scheduler.SetBookingWindow(nil)
+
+	// 資料無效時不啟動
+	invalid, err := storage.Save(&SavedForm{Label: "無效", Name: "王小明", EmployeeID: "A1", StartDate: "2030-01-02", EndDate: "2030-01-03", LeaveType: "事假", Password: "p"})
+	if err != nil {
+		t.Fatalf("儲存失敗: %v", err)
+	}
+	bad := NewScheduler(&ScheduleConfig{Enabled: true, At: time.Now().Add(time.Hour), SavedFormID: invalid}, batchTestSubmitter(mock.FormURL()), storage)
+	if err := bad.Start(); err == nil || !strings.Contains(err.Error(), "排程前檢查未通過") {
+		t.Errorf("資料無效時應拒絕啟動，實際 %v", err)
+	}
+
+	// 實際排程：啟動時與準備階段各檢查一次，失敗時發出通知但仍送出
+	form.SetOptions(MockFormOptions{EntryIDs: map[string]string{"name": "entry.1"}})
+	scheduler = NewScheduler(&ScheduleConfig{
+		Enabled:        true,
+		At:             time.Now().Add(2500 * time.Millisecond),
+		SavedFormID:    id,
+		PrepareSeconds: 2,
+	}, batchTestSubmitter(mock.FormURL()), storage)
+	done := make(chan *SubmissionRecord, 1)
+	scheduler.onComplete = func(rec *SubmissionRecord) { done <- rec }
+	if err := scheduler.Start(); err != nil {
+		t.Fatalf("啟動排程器失敗: %v", err)
+	}
+	var rec *SubmissionRecord
+	select {
+	case rec = <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("等待執行結束逾時")
+	}
+	scheduler.Shutdown()
+
+	if !rec.Success {
+		t.Errorf("檢查失敗不應阻止送出，實際 %+v", rec)
+	}
+	var phases []string
+	var notices int
+	for _, e := range scheduler.Events().Recent() {
+		switch e.Type {
+		case ScheduleEventPreflight:
+			phases = append(phases, e.Preflight.Phase)
+		case ScheduleEventNotice:
+			notices++
+		}
+	}
+	if len(phases) != 2 || phases[0] != PreflightPhaseArm || phases[1] != PreflightPhasePrepare {
+		t.Errorf("應於啟動時與準備階段各檢查一次，實際 %v", phases)
+	}
+	if notices != 2 {
+		t.Errorf("每次檢查失敗都應發出通知，實際 %d 則", notices)
+	}
+	if p := scheduler.Preflight(); p == nil || p.Phase != PreflightPhasePrepare || p.Passed {
+		t.Errorf("應保留準備階段的檢查報告，實際 %+v", p)
+	}
+}
+
+func TestSchedulerPollStrategy(t *testing.T) {
+	storage, cleanup := setupTestStorage(t)
+	defer cleanup()
+
+	id, err := storage.Save(&SavedForm{
+		Label:      "探測",
+		Name:       "王小明",
+		EmployeeID: "A12345",
+		StartDate:  "2030-01-02",
+		EndDate:    "2030-01-03",
+		LeaveType:  "近假",
+		Password:   "p",
+	})
+	if err != nil {
+		t.Fatalf("儲存失敗: %v", err)
+	}
+
+	entries := map[string]string{"name": "entry.1", "employee_id": "entry.2", "start_date": "entry.3", "end_date": "entry.4", "leave_type": "entry.5", "password": "entry.6"}
+	form := NewMockForm(MockFormOptions{EntryIDs: entries})
+	mock, err := StartMockFormServer(form, "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("啟動模擬表單失敗: %v", err)
+	}
+	defer mock.Close()
+
+	run := func(cfg *ScheduleConfig) (*Scheduler, *SubmissionRecord) {
+		t.Helper()
+		scheduler := NewScheduler(cfg, batchTestSubmitter(mock.FormURL()), storage)
+		done := make(chan *SubmissionRecord, 1)
+		scheduler.onComplete = func(rec *SubmissionRecord) { done <- rec }
+		if err := scheduler.Start(); err != nil {
+			t.Fatalf("啟動排程器失敗: %v", err)
+		}
+		defer scheduler.Shutdown()
+		select {
+		case rec := <-done:
+			return scheduler, rec
+		case <-time.After(15 * time.Second):
+			t.Fatal("等待執行結束逾時")
+		}
+		return nil, nil
+	}
+
+	// 表單比目標時間早開放，偵測到就立即送出
+	target := time.Now().Add(4 * time.Second)
+	form.SetOptions(MockFormOptions{EntryIDs: entries, OpensAt: target.Add(-1500 * time.Millisecond)})
+	scheduler, rec := run(&ScheduleConfig{
+		Enabled:         true,
+		At:              target,
+		SavedFormID:     id,
+		PrepareSeconds:  1,
+		Strategy:        StrategyPoll,
+		PollLeadSeconds: 2,
+		PollIntervalMs:  50,
+	})
+	if !rec.Success || !strings.Contains(rec.Message, "偵測到表單開放") {
+		t.Errorf("表單開放後應送出成功並記錄偵測時間，實際 %+v", rec)
+	}
+	subs := form.Submissions()
+	if len(subs) != 1 || !subs[0].Accepted || !subs[0].At.Before(target) {
+		t.Errorf("應於目標時間前送出一次，實際 %+v", subs)
+	}
+	var polling, opened bool
+	for _, e := range scheduler.Events().Recent() {
+		polling = polling || e.Type == ScheduleEventPolling
+		opened = opened || e.Type == ScheduleEventFormOpen
+	}
+	if !polling || !opened {
+		t.Errorf("應發出 polling 與 form_open 事件，實際 polling=%v form_open=%v", polling, opened)
+	}
+
+	// 至停止探測仍未開放時失敗且不送出
+	form.SetOptions(MockFormOptions{EntryIDs: entries, Closed: true})
+	form.Reset()
+	scheduler, rec = run(&ScheduleConfig{
+		Enabled:             true,
+		At:                  time.Now().Add(2 * time.Second),
+		SavedFormID:         id,
+		Strategy:            StrategyPoll,
+		PollLeadSeconds:     1,
+		PollIntervalMs:      100,
+		PollDeadlineSeconds: 1,
+	})
+	if rec.Success || !strings.Contains(rec.Message, "仍未開放") {
+		t.Errorf("表單未開放應失敗，實際 %+v", rec)
+	}
+	if state, _ := scheduler.State(); state != JobFailed {
+		t.Errorf("狀態應為 failed，實際 %s", state)
+	}
+	if subs := form.Submissions(); len(subs) != 0 {
+		t.Errorf("表單未開放時不應送出，實際 %d 次", len(subs))
+	}
+
+	// 參數錯誤時不啟動
+	for _, cfg := range []ScheduleConfig{
+		{Strategy: "early"},
+		{Strategy: StrategyPoll, PollIntervalMs: 10},
+		{Strategy: StrategyPoll, PollDeadlineSeconds: -1},
+	} {
+		cfg.Enabled, cfg.At, cfg.SavedFormID = true, time.Now().Add(time.Hour), id
+		if err := NewScheduler(&cfg, batchTestSubmitter(mock.FormURL()), storage).Start(); err == nil {
+			t.Errorf("%+v 應拒絕啟動", cfg)
+		}
+	}
+	bad := NewScheduler(&ScheduleConfig{Enabled: true, At: time.Now().Add(time.Hour), SavedFormID: id, Strategy: StrategyPoll}, batchTestSubmitter("http://127.0.0.1/submit"), storage)
+	if err := bad.Start(); err == nil {
+		t.Error("表單網址無法推得 viewform 時不應接受 poll 策略")
+	}
+}
+
+// TestSchedulerFirePrecision 以系統時鐘送出時記錄預計與實際送出時間，並統計精確度
+func TestSchedulerFirePrecision(t *testing.T) {
+	storage, cleanup := setupTestStorage(t)
+	defer cleanup()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	employeeRef, err := storage.CreateEmployee(&Employee{Name: "王小明", EmployeeID: "A1", Password: "p"})
+	if err != nil {
+		t.Fatalf("建立員工失敗: %v", err)
+	}
+	target := time.Now().Add(300 * time.Millisecond).Round(0)
+	scheduler := NewScheduler(&ScheduleConfig{
+		Enabled:     true,
+		At:          target,
+		EmployeeRef: employeeRef,
+		StartDate:   "2026-03-02",
+		EndDate:     "2026-03-03",
+		LeaveType:   "近假",
+	}, batchTestSubmitter(server.URL), storage)
+
+	done := make(chan *SubmissionRecord, 1)
+	scheduler.onComplete = func(rec *SubmissionRecord) { done <- rec }
+	if err := scheduler.Start(); err != nil {
+		t.Fatalf("啟動排程器失敗: %v", err)
+	}
+	defer scheduler.Shutdown()
+
+	var rec *SubmissionRecord
+	select {
+	case rec = <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("等待提交完成逾時")
+	}
+	if !rec.Success || rec.IntendedAt == nil || rec.SentAt == nil || rec.FireDelayUs == nil {
+		t.Fatalf("應記錄送出時間，實際 %+v", rec)
+	}
+	// 忙等只在到達送出時間後結束，不會提早；寬鬆的上限避免負載高的環境誤判
+	if !rec.IntendedAt.Equal(target) || *rec.FireDelayUs < 0 || *rec.FireDelayUs > (20*time.Millisecond).Microseconds() {
+		t.Errorf("送出誤差應在 0 ~ 20ms，實際預計 %s、誤差 %dµs", rec.IntendedAt, *rec.FireDelayUs)
+	}
+
+	records, err := storage.ListHistory(HistoryQuery{Source: HistorySourceSchedule})
+	if err != nil || len(records) != 1 || records[0].FireDelayUs == nil || *records[0].FireDelayUs != *rec.FireDelayUs ||
+		!records[0].IntendedAt.Equal(target) || !records[0].SentAt.Equal(*rec.SentAt) {
+		t.Fatalf("提交記錄應保存送出時間，實際 %+v（%v）", records, err)
+	}
+	// 預熱過的連線在送出時重用，每次嘗試的網路耗時隨提交記錄保存
+	if details := records[0].AttemptDetails; len(details) != 1 || details[0].StatusCode != http.StatusOK ||
+		details[0].Timing == nil || !details[0].Timing.Reused || *details[0].Timing != *rec.AttemptDetails[0].Timing {
+		t.Errorf("提交記錄應保存送出嘗試的耗時，實際 %+v", details)
+	}
+
+	// 未實際送出的記錄不列入統計
+	delays := []int64{-300, 1500, 200, 800, 12000}
+	var sample []*SubmissionRecord
+	for _, d := range delays {
+		sample = append(sample, &SubmissionRecord{FireDelayUs: &d}, &SubmissionRecord{})
+	}
+	stats := SummarizeFirePrecision(sample)
+	want := FirePrecisionStats{
+		Count: 5, SampleSize: 10, OnTime: 3, LastUs: -300, MeanUs: 2840, MinUs: -300, MaxUs: 12000,
+		MeanAbsUs: 2960, P50AbsUs: 800, P95AbsUs: 12000, MaxAbsUs: 12000,
+	}
+	if stats == nil || *stats != want {
+		t.Errorf("精確度統計應為 %+v，實際 %+v", want, stats)
+	}
+	if SummarizeFirePrecision([]*SubmissionRecord{{}}) != nil {
+		t.Error("沒有送出時間時應回傳 nil")
+	}
+}
@@ -1,30 +1,42 @@
 package models
 
 import (
+	"crypto/rand"
 	"database/sql"
+	"encoding/base64"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"sync"
 	"time"
 
 	_ "github.com/mattn/go-sqlite3"
 )
 
-// SavedForm 儲存在 SQLite 中的表單資料記錄
+// ErrSavedFormNotFound 找不到指定的儲存資料
+var ErrSavedFormNotFound = errors.New("找不到指定的資料")
+
+// SavedForm 儲存在 SQLite 中的請假資料記錄。
+// 姓名、員工代號與密碼屬於參照的員工（employees），讀取時一併帶出。
 type SavedForm struct {
-	ID         int64     `json:"id"`
-	Label      string    `json:"label"` // 識別標籤
-	Name       string    `json:"name"`
-	EmployeeID string    `json:"employee_id"`
-	StartDate  string    `json:"start_date"`
-	EndDate    string    `json:"end_date"`
-	LeaveType  string    `json:"leave_type"`
-	Password   string    `json:"password"`
-	CreatedAt  time.Time `json:"created_at"`
-	UpdatedAt  time.Time `json:"updated_at"`
-}
-
-// ToLeaveRequest 轉換為 LeaveRequest
+	ID          int64  `json:"id"`
+	Label       string `json:"label"`        // 識別標籤
+	EmployeeRef int64  `json:"employee_ref"` // 參照的員工 ID
+	Name        string `json:"name"`
+	EmployeeID  string `json:"employee_id"`
+	StartDate   string `json:"start_date"`
+	EndDate     string `json:"end_date"`
+	LeaveType   string `json:"leave_type"`
+	// 日期範本：設定時 StartDate/EndDate 為空，實際日期於排程觸發時計算
+	DateTemplate *DateTemplate `json:"date_template,omitempty"`
+	Password     string        `json:"-"`            // 員工已加密的密碼，僅在提交時解密，永不輸出
+	HasPassword  bool          `json:"has_password"` // 員工是否已設定密碼
+	CreatedAt    time.Time     `json:"created_at"`
+	UpdatedAt    time.Time     `json:"updated_at"`
+}
+
+// ToLeaveRequest 轉換為 LeaveRequest（Password 仍為加密值，提交前需以 Storage.DecryptPassword 解密）
 func (sf *SavedForm) ToLeaveRequest() *LeaveRequest {
 	return &LeaveRequest{
 		Name:       sf.Name,
@@ -36,13 +48,43 @@ func (sf *SavedForm) ToLeaveRequest() *LeaveRequest {
 	}
 }
 
+// keyCheckPlaintext 用來驗證加密金鑰是否正確的固定字串
+const keyCheckPlaintext = "google-form-submitter"
+
 // Storage SQLite 儲存管理器
 type Storage struct {
-	db *sql.DB
+	db     *sql.DB
+	path   string
+	cipher *PasswordCipher
+
+	mu       sync.RWMutex
+	calendar *Calendar // 目前使用的行事曆，由 ActivateCalendar 載入
+	clock    Clock     // 日期規則（日期範本試算、未到期篩選、預約窗口）使用的時鐘
+}
+
+// SetClock 設定日期規則使用的時鐘，nil 表示系統時鐘
+func (s *Storage) SetClock(c Clock) {
+	if c == nil {
+		c = SystemClock
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.clock = c
+}
+
+// Clock 取得日期規則使用的時鐘
+func (s *Storage) Clock() Clock {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.clock
 }
 
 // NewStorage 建立 Storage 實例並初始化資料庫
-func NewStorage(dbPath string) (*Storage, error) {
+func NewStorage(dbPath string, secret *SecretKey) (*Storage, error) {
+	if secret == nil || (len(secret.Key) == 0 && secret.Passphrase == "") {
+		return nil, fmt.Errorf("未提供密碼加密金鑰")
+	}
+
 	// 確保目錄存在
 	dir := filepath.Dir(dbPath)
 	if dir != "" && dir != "." {
@@ -63,10 +105,36 @@ func NewStorage(dbPath string) (*Storage, error) {
 		return nil, fmt.Errorf("無法連線到資料庫: %w", err)
 	}
 
-	storage := &Storage{db: db}
+	storage := &Storage{db: db, path: dbPath, clock: SystemClock}
+
+	// 已有金鑰設定的資料庫先設定加密金鑰，遷移時才能比對加密的密碼
+	hasMeta, err := hasTable(db, "storage_meta")
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	if hasMeta {
+		if err := storage.initCipher(secret); err != nil {
+			db.Close()
+			return nil, err
+		}
+	}
+
+	// 套用資料庫遷移
+	if err := storage.migrate(); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	// 設定加密金鑰並加密舊版明文密碼
+	if storage.cipher == nil {
+		if err := storage.initCipher(secret); err != nil {
+			db.Close()
+			return nil, err
+		}
+	}
 
-	// 初始化資料表
-	if err := storage.initDB(); err != nil {
+	if err := storage.encryptLegacyPasswords(); err != nil {
 		db.Close()
 		return nil, err
 	}
@@ -74,41 +142,205 @@ func NewStorage(dbPath string) (*Storage, error) {
 	return storage, nil
 }
 
-// initDB 初始化資料庫表格
-func (s *Storage) initDB() error {
-	createTableSQL := `
-	CREATE TABLE IF NOT EXISTS saved_forms (
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		label TEXT NOT NULL,
-		name TEXT NOT NULL,
-		employee_id TEXT NOT NULL,
-		start_date TEXT NOT NULL,
-		end_date TEXT NOT NULL,
-		leave_type TEXT NOT NULL,
-		password TEXT NOT NULL,
-		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
-		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
-	);
-	CREATE INDEX IF NOT EXISTS idx_saved_forms_label ON saved_forms(label);
-	`
+// initCipher 依金鑰來源建立加密器，並以資料庫內的檢查值確認金鑰正確
+func (s *Storage) initCipher(secret *SecretKey) error {
+	key := secret.Key
+	if len(key) == 0 {
+		salt, err := s.kdfSalt()
+		if err != nil {
+			return err
+		}
+		key, err = DeriveKey(secret.Passphrase, salt)
+		if err != nil {
+			return fmt.Errorf("推導加密金鑰失敗: %w", err)
+		}
+	}
+
+	c, err := NewPasswordCipher(key)
+	if err != nil {
+		return err
+	}
+
+	check, err := s.getMeta("key_check")
+	if err != nil {
+		return err
+	}
+
+	if check == "" {
+		check, err = c.Encrypt(keyCheckPlaintext)
+		if err != nil {
+			return err
+		}
+		if err := s.setMeta("key_check", check); err != nil {
+			return err
+		}
+	} else if plain, err := c.Decrypt(check); err != nil || plain != keyCheckPlaintext {
+		return fmt.Errorf("加密金鑰不正確，無法開啟資料庫")
+	}
+
+	s.cipher = c
+	return nil
+}
+
+// kdfSalt 取得通關密語推導用的 salt，不存在時建立
+func (s *Storage) kdfSalt() ([]byte, error) {
+	encoded, err := s.getMeta("kdf_salt")
+	if err != nil {
+		return nil, err
+	}
+
+	if encoded != "" {
+		return base64.StdEncoding.DecodeString(encoded)
+	}
+
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, fmt.Errorf("產生 salt 失敗: %w", err)
+	}
+	if err := s.setMeta("kdf_salt", base64.StdEncoding.EncodeToString(salt)); err != nil {
+		return nil, err
+	}
+
+	return salt, nil
+}
+
+// getMeta 讀取 storage_meta 設定值，不存在時回傳空字串
+func (s *Storage) getMeta(key string) (string, error) {
+	var value string
+	err := s.db.QueryRow("SELECT value FROM storage_meta WHERE key = ?", key).Scan(&value)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("讀取設定失敗: %w", err)
+	}
+	return value, nil
+}
+
+// setMeta 寫入 storage_meta 設定值
+func (s *Storage) setMeta(key, value string) error {
+	_, err := s.db.Exec(`
+		INSERT INTO storage_meta (key, value) VALUES (?, ?)
+		ON CONFLICT(key) DO UPDATE SET value = excluded.value
+	`, key, value)
+	if err != nil {
+		return fmt.Errorf("寫入設定失敗: %w", err)
+	}
+	return nil
+}
+
+// encryptLegacyPasswords 將舊版明文密碼就地加密
+func (s *Storage) encryptLegacyPasswords() error {
+	rows, err := s.db.Query("SELECT id, password FROM employees WHERE password != ''")
+	if err != nil {
+		return fmt.Errorf("查詢明文密碼失敗: %w", err)
+	}
+
+	plain := map[int64]string{}
+	for rows.Next() {
+		var id int64
+		var password string
+		if err := rows.Scan(&id, &password); err != nil {
+			rows.Close()
+			return fmt.Errorf("讀取資料失敗: %w", err)
+		}
+		if !s.isCiphertext(password) {
+			plain[id] = password
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("讀取資料失敗: %w", err)
+	}
+
+	if len(plain) == 0 {
+		return nil
+	}
 
-	_, err := s.db.Exec(createTableSQL)
+	tx, err := s.db.Begin()
 	if err != nil {
-		return fmt.Errorf("初始化資料庫失敗: %w", err)
+		return fmt.Errorf("開始交易失敗: %w", err)
+	}
+	defer tx.Rollback()
+
+	for id, password := range plain {
+		encrypted, err := s.cipher.Encrypt(password)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec("UPDATE employees SET password = ? WHERE id = ?", encrypted, id); err != nil {
+			return fmt.Errorf("加密舊版密碼失敗: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("加密舊版密碼失敗: %w", err)
 	}
 
 	return nil
 }
 
-// Save 儲存表單資料
+// encryptPassword 加密密碼，已以目前金鑰加密的值與空值原樣保留
+func (s *Storage) encryptPassword(password string) (string, error) {
+	if password == "" || s.isCiphertext(password) {
+		return password, nil
+	}
+	return s.cipher.Encrypt(password)
+}
+
+// isCiphertext 判斷值是否為以目前金鑰加密的密文。只看 enc:v1: 前綴並不可靠：
+// 明文密碼也可能以此開頭，因此必須能以目前金鑰解密才算已加密
+// （開啟資料庫時已以 key_check 確認金鑰正確，無法解密的值不會是本資料庫的密文）
+func (s *Storage) isCiphertext(value string) bool {
+	if !IsEncryptedPassword(value) {
+		return false
+	}
+	_, err := s.cipher.Decrypt(value)
+	return err == nil
+}
+
+// DecryptPassword 解密儲存的密碼，僅供提交流程使用
+func (s *Storage) DecryptPassword(stored string) (string, error) {
+	if stored == "" {
+		return "", fmt.Errorf("尚未設定密碼")
+	}
+	return s.cipher.Decrypt(stored)
+}
+
+// Save 儲存表單資料（未指定 EmployeeRef 時依員工代號尋找或建立員工）
 func (s *Storage) Save(form *SavedForm) (int64, error) {
-	now := time.Now()
+	ids, err := s.SaveAll([]*SavedForm{form})
+	if err != nil {
+		return 0, err
+	}
+	return ids[0], nil
+}
+
+// storedDates 回傳寫入資料庫的起訖日期與日期範本；使用範本時不保存固定日期
+func (sf *SavedForm) storedDates() (startDate, endDate, dateTemplate string, err error) {
+	if sf.DateTemplate == nil {
+		return sf.StartDate, sf.EndDate, "", nil
+	}
+	dateTemplate, err = encodeDateTemplate(sf.DateTemplate)
+	return "", "", dateTemplate, err
+}
 
-	result, err := s.db.Exec(`
-		INSERT INTO saved_forms (label, name, employee_id, start_date, end_date, leave_type, password, created_at, updated_at)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
-	`, form.Label, form.Name, form.EmployeeID, form.StartDate, form.EndDate, form.LeaveType, form.Password, now, now)
+// insertSavedForm 在交易中寫入一筆儲存資料
+func (s *Storage) insertSavedForm(tx *sql.Tx, form *SavedForm, now time.Time) (int64, error) {
+	employeeRef, err := s.resolveEmployee(tx, form)
+	if err != nil {
+		return 0, fmt.Errorf("資料儲存失敗: %w", err)
+	}
 
+	startDate, endDate, dateTemplate, err := form.storedDates()
+	if err != nil {
+		return 0, fmt.Errorf("資料儲存失敗: %w", err)
+	}
+
+	result, err := tx.Exec(`
+		INSERT INTO saved_forms (label, employee_ref, start_date, end_date, leave_type, date_template, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`, form.Label, employeeRef, startDate, endDate, form.LeaveType, dateTemplate, now, now)
 	if err != nil {
 		return 0, fmt.Errorf("資料儲存失敗: %w", err)
 	}
@@ -121,30 +353,58 @@ func (s *Storage) Save(form *SavedForm) (int64, error) {
 	return id, nil
 }
 
-// GetByID 根據 ID 取得表單資料
-func (s *Storage) GetByID(id int64) (*SavedForm, error) {
-	row := s.db.QueryRow(`
-		SELECT id, label, name, employee_id, start_date, end_date, leave_type, password, created_at, updated_at
-		FROM saved_forms
-		WHERE id = ?
-	`, id)
+// savedFormColumns saved_forms 查詢欄位（順序須與 scanSavedForm 一致）
+const savedFormColumns = "f.id, f.label, f.employee_ref, e.name, e.employee_id, f.start_date, f.end_date, f.leave_type, f.date_template, e.password, f.created_at, f.updated_at"
 
+// savedFormTables saved_forms 與其參照員工的 FROM 子句
+const savedFormTables = "saved_forms f JOIN employees e ON e.id = f.employee_ref"
+
+// rowScanner 抽象 *sql.Row 與 *sql.Rows 的 Scan
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+// scanSavedForm 讀取一筆 saved_forms 記錄
+func scanSavedForm(row rowScanner) (*SavedForm, error) {
 	form := &SavedForm{}
+	var dateTemplate string
 	err := row.Scan(
 		&form.ID,
 		&form.Label,
+		&form.EmployeeRef,
 		&form.Name,
 		&form.EmployeeID,
 		&form.StartDate,
 		&form.EndDate,
 		&form.LeaveType,
+		&dateTemplate,
 		&form.Password,
 		&form.CreatedAt,
 		&form.UpdatedAt,
 	)
+	if err != nil {
+		return nil, err
+	}
 
+	form.HasPassword = form.Password != ""
+	form.DateTemplate, err = decodeDateTemplate(dateTemplate)
+	if err != nil {
+		return nil, err
+	}
+	return form, nil
+}
+
+// GetByID 根據 ID 取得表單資料
+func (s *Storage) GetByID(id int64) (*SavedForm, error) {
+	row := s.db.QueryRow(`
+		SELECT `+savedFormColumns+`
+		FROM `+savedFormTables+`
+		WHERE f.id = ?
+	`, id)
+
+	form, err := scanSavedForm(row)
 	if err == sql.ErrNoRows {
-		return nil, fmt.Errorf("找不到指定的資料")
+		return nil, ErrSavedFormNotFound
 	}
 	if err != nil {
 		return nil, fmt.Errorf("查詢資料失敗: %w", err)
@@ -156,9 +416,9 @@ func (s *Storage) GetByID(id int64) (*SavedForm, error) {
 // List 列出所有儲存的表單資料
 func (s *Storage) List() ([]*SavedForm, error) {
 	rows, err := s.db.Query(`
-		SELECT id, label, name, employee_id, start_date, end_date, leave_type, password, created_at, updated_at
-		FROM saved_forms
-		ORDER BY created_at DESC
+		SELECT ` + savedFormColumns + `
+		FROM ` + savedFormTables + `
+		ORDER BY f.created_at DESC
 	`)
 	if err != nil {
 		return nil, fmt.Errorf("查詢資料失敗: %w", err)
@@ -167,19 +427,7 @@ func (s *Storage) List() ([]*SavedForm, error) {
 
 	var forms []*SavedForm
 	for rows.Next() {
-		form := &SavedForm{}
-		err := rows.Scan(
-			&form.ID,
-			&form.Label,
-			&form.Name,
-			&form.EmployeeID,
-			&form.StartDate,
-			&form.EndDate,
-			&form.LeaveType,
-			&form.Password,
-			&form.CreatedAt,
-			&form.UpdatedAt,
-		)
+		form, err := scanSavedForm(rows)
 		if err != nil {
 			return nil, fmt.Errorf("讀取資料失敗: %w", err)
 		}
@@ -193,8 +441,16 @@ func (s *Storage) List() ([]*SavedForm, error) {
 	return forms, nil
 }
 
-// Delete 刪除指定 ID 的表單資料
+// Delete 刪除指定 ID 的表單資料；仍被週期排程參照時回傳 ErrSavedFormInUse
 func (s *Storage) Delete(id int64) error {
+	var count int
+	if err := s.db.QueryRow("SELECT COUNT(*) FROM recurring_schedules WHERE saved_form_id = ?", id).Scan(&count); err != nil {
+		return fmt.Errorf("刪除資料失敗: %w", err)
+	}
+	if count > 0 {
+		return ErrSavedFormInUse
+	}
+
 	result, err := s.db.Exec("DELETE FROM saved_forms WHERE id = ?", id)
 	if err != nil {
 		return fmt.Errorf("刪除資料失敗: %w", err)
@@ -206,21 +462,35 @@ func (s *Storage) Delete(id int64) error {
 	}
 
 	if rowsAffected == 0 {
-		return fmt.Errorf("找不到指定的資料")
+		return ErrSavedFormNotFound
 	}
 
 	return nil
 }
 
-// Update 更新表單資料
+// Update 更新表單資料（EmployeeRef 為 0 時依員工代號尋找或建立員工）
 func (s *Storage) Update(form *SavedForm) error {
-	now := time.Now()
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("開始交易失敗: %w", err)
+	}
+	defer tx.Rollback()
+
+	employeeRef, err := s.resolveEmployee(tx, form)
+	if err != nil {
+		return fmt.Errorf("更新資料失敗: %w", err)
+	}
 
-	result, err := s.db.Exec(`
+	startDate, endDate, dateTemplate, err := form.storedDates()
+	if err != nil {
+		return fmt.Errorf("更新資料失敗: %w", err)
+	}
+
+	result, err := tx.Exec(`
 		UPDATE saved_forms
-		SET label = ?, name = ?, employee_id = ?, start_date = ?, end_date = ?, leave_type = ?, password = ?, updated_at = ?
+		SET label = ?, employee_ref = ?, start_date = ?, end_date = ?, leave_type = ?, date_template = ?, updated_at = ?
 		WHERE id = ?
-	`, form.Label, form.Name, form.EmployeeID, form.StartDate, form.EndDate, form.LeaveType, form.Password, now, form.ID)
+	`, form.Label, employeeRef, startDate, endDate, form.LeaveType, dateTemplate, time.Now(), form.ID)
 
 	if err != nil {
 		return fmt.Errorf("更新資料失敗: %w", err)
@@ -232,12 +502,26 @@ func (s *Storage) Update(form *SavedForm) error {
 	}
 
 	if rowsAffected == 0 {
-		return fmt.Errorf("找不到指定的資料")
+		return ErrSavedFormNotFound
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("更新資料失敗: %w", err)
 	}
 
+	form.EmployeeRef = employeeRef
 	return nil
 }
 
+// UpdatePassword 更換指定表單所屬員工的密碼（同一員工的其他資料一併生效）
+func (s *Storage) UpdatePassword(id int64, password string) error {
+	form, err := s.GetByID(id)
+	if err != nil {
+		return err
+	}
+	return s.UpdateEmployeePassword(form.EmployeeRef, password)
+}
+
 // Close 關閉資料庫連線
 func (s *Storage) Close() error {
 	if s.db != nil {
@@ -0,0 +1,478 @@
+package models
+
+import (
+	"bytes"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestPasswordEncryptedAtRest 測試密碼以密文儲存且可解密
+func TestPasswordEncryptedAtRest(t *testing.T) {
+	storage, cleanup := setupTestStorage(t)
+	defer cleanup()
+
+	id, err := storage.Save(&SavedForm{
+		Label:      "測試",
+		Name:       "測試員工",
+		EmployeeID: "A12345",
+		StartDate:  "2026-02-01",
+		EndDate:    "2026-02-03",
+		LeaveType:  "近假",
+		Password:   "testpass",
+	})
+	if err != nil {
+		t.Fatalf("儲存失敗: %v", err)
+	}
+
+	var raw string
+	if err := storage.db.QueryRow("SELECT e.password FROM saved_forms f JOIN employees e ON e.id = f.employee_ref WHERE f.id = ?", id).Scan(&raw); err != nil {
+		t.Fatalf("讀取原始資料失敗: %v", err)
+	}
+	if raw == "testpass" || !IsEncryptedPassword(raw) {
+		t.Errorf("密碼應以密文儲存，實際 %q", raw)
+	}
+
+	form, err := storage.GetByID(id)
+	if err != nil {
+		t.Fatalf("讀取失敗: %v", err)
+	}
+	plain, err := storage.DecryptPassword(form.Password)
+	if err != nil {
+		t.Fatalf("解密失敗: %v", err)
+	}
+	if plain != "testpass" {
+		t.Errorf("解密結果應為 testpass，實際 %q", plain)
+	}
+
+	// 更新時不應重複加密
+	if err := storage.Update(form); err != nil {
+		t.Fatalf("更新失敗: %v", err)
+	}
+	form, _ = storage.GetByID(id)
+	if plain, _ := storage.DecryptPassword(form.Password); plain != "testpass" {
+		t.Errorf("更新後解密結果應為 testpass，實際 %q", plain)
+	}
+
+	// 以加密前綴開頭但無法解密的明文仍需加密
+	lookalike := "enc:v1:not-a-ciphertext"
+	employeeID, err := storage.CreateEmployee(&Employee{Name: "前綴", EmployeeID: "P1", Password: lookalike})
+	if err != nil {
+		t.Fatalf("建立員工失敗: %v", err)
+	}
+	if stored, _ := storage.GetEmployee(employeeID); stored.Password == lookalike {
+		t.Error("看似密文的明文密碼不應原樣儲存")
+	} else if plain, err := storage.DecryptPassword(stored.Password); err != nil || plain != lookalike {
+		t.Errorf("解密結果應為 %q，實際 %q: %v", lookalike, plain, err)
+	}
+}
+
+// TestLegacyPasswordsMigrated 測試舊版明文密碼在開啟時自動加密
+func TestLegacyPasswordsMigrated(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "legacy.db")
+
+	// 模擬舊版資料庫
+	db, err := sql.Open("sqlite3", dbPath)
+	if err != nil {
+		t.Fatalf("開啟資料庫失敗: %v", err)
+	}
+	_, err = db.Exec(`
+		CREATE TABLE saved_forms (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			label TEXT NOT NULL,
+			name TEXT NOT NULL,
+			employee_id TEXT NOT NULL,
+			start_date TEXT NOT NULL,
+			end_date TEXT NOT NULL,
+			leave_type TEXT NOT NULL,
+			password TEXT NOT NULL,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		);
+		INSERT INTO saved_forms (label, name, employee_id, start_date, end_date, leave_type, password)
+		VALUES ('舊資料', '測試員工', 'A12345', '2026-02-01', '2026-02-03', '近假', 'legacy'),
+		       ('前綴', '前綴員工', 'P1', '2026-02-01', '2026-02-03', '近假', 'enc:v1:legacy');
+	`)
+	db.Close()
+	if err != nil {
+		t.Fatalf("建立舊版資料失敗: %v", err)
+	}
+
+	storage, err := NewStorage(dbPath, testSecretKey())
+	if err != nil {
+		t.Fatalf("無法初始化 Storage: %v", err)
+	}
+	defer storage.Close()
+
+	form, err := storage.GetByID(1)
+	if err != nil {
+		t.Fatalf("讀取失敗: %v", err)
+	}
+	if !IsEncryptedPassword(form.Password) {
+		t.Errorf("舊版密碼應已加密，實際 %q", form.Password)
+	}
+	if plain, _ := storage.DecryptPassword(form.Password); plain != "legacy" {
+		t.Errorf("解密結果應為 legacy，實際 %q", plain)
+	}
+
+	// 以加密前綴開頭的舊版明文也要加密
+	form, err = storage.GetByID(2)
+	if err != nil {
+		t.Fatalf("讀取失敗: %v", err)
+	}
+	if plain, err := storage.DecryptPassword(form.Password); err != nil || plain != "enc:v1:legacy" {
+		t.Errorf("解密結果應為 enc:v1:legacy，實際 %q: %v", plain, err)
+	}
+}
+
+// TestWrongEncryptionKey 測試使用錯誤金鑰開啟資料庫
+func TestWrongEncryptionKey(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "key.db")
+
+	storage, err := NewStorage(dbPath, testSecretKey())
+	if err != nil {
+		t.Fatalf("無法初始化 Storage: %v", err)
+	}
+	storage.Close()
+
+	wrong := &SecretKey{Key: bytes.Repeat([]byte{0x01}, EncryptionKeySize)}
+	if s, err := NewStorage(dbPath, wrong); err == nil {
+		s.Close()
+		t.Error("錯誤金鑰應回傳錯誤")
+	}
+}
+
+// TestMigrationsUpgradeLegacyDatabase 測試舊版資料庫自動升級並先備份
+func TestMigrationsUpgradeLegacyDatabase(t *testing.T) {
+	dir := t.TempDir()
+	dbPath := filepath.Join(dir, "legacy.db")
+
+	db, err := sql.Open("sqlite3", dbPath)
+	if err != nil {
+		t.Fatalf("開啟資料庫失敗: %v", err)
+	}
+	_, err = db.Exec(`
+		CREATE TABLE saved_forms (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			label TEXT NOT NULL,
+			name TEXT NOT NULL,
+			employee_id TEXT NOT NULL,
+			start_date TEXT NOT NULL,
+			end_date TEXT NOT NULL,
+			leave_type TEXT NOT NULL,
+			password TEXT NOT NULL,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		);
+	`)
+	db.Close()
+	if err != nil {
+		t.Fatalf("建立舊版資料失敗: %v", err)
+	}
+
+	storage, err := NewStorage(dbPath, testSecretKey())
+	if err != nil {
+		t.Fatalf("無法初始化 Storage: %v", err)
+	}
+	storage.Close()
+
+	version, applied, err := ReadSchemaVersion(dbPath)
+	if err != nil {
+		t.Fatalf("讀取版本失敗: %v", err)
+	}
+	if version != LatestSchemaVersion() {
+		t.Errorf("版本應為 %d，實際 %d", LatestSchemaVersion(), version)
+	}
+	if len(applied) != len(migrations) {
+		t.Errorf("應套用 %d 個遷移，實際 %d", len(migrations), len(applied))
+	}
+
+	backups, _ := filepath.Glob(dbPath + ".v0-*.bak")
+	if len(backups) != 1 {
+		t.Errorf("升級前應產生一個備份，實際 %d", len(backups))
+	}
+
+	// 再次開啟不應重複遷移或備份
+	storage, err = NewStorage(dbPath, testSecretKey())
+	if err != nil {
+		t.Fatalf("重新開啟失敗: %v", err)
+	}
+	storage.Close()
+
+	backups, _ = filepath.Glob(dbPath + ".*.bak")
+	if len(backups) != 1 {
+		t.Errorf("已是最新版本時不應再備份，實際 %d 個備份", len(backups))
+	}
+}
+
+// TestMigrationsRejectNewerDatabase 測試拒絕開啟較新版本的資料庫
+func TestMigrationsRejectNewerDatabase(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "newer.db")
+
+	storage, err := NewStorage(dbPath, testSecretKey())
+	if err != nil {
+		t.Fatalf("無法初始化 Storage: %v", err)
+	}
+	_, err = storage.db.Exec(
+		"INSERT INTO schema_migrations (version, description, applied_at) VALUES (?, 'future', CURRENT_TIMESTAMP)",
+		LatestSchemaVersion()+1,
+	)
+	storage.Close()
+	if err != nil {
+		t.Fatalf("寫入版本失敗: %v", err)
+	}
+
+	if s, err := NewStorage(dbPath, testSecretKey()); err == nil {
+		s.Close()
+		t.Error("較新版本的資料庫應回傳錯誤")
+	}
+}
+
+// TestBackupAndRestore 測試線上備份與還原
+func TestBackupAndRestore(t *testing.T) {
+	dir := t.TempDir()
+	dbPath := filepath.Join(dir, "data.db")
+	// 目錄名稱含 URI 的特殊字元，驗證時不可被當成參數
+	backupDir := filepath.Join(dir, "backups?mode=rw#50%")
+
+	storage, err := NewStorage(dbPath, testSecretKey())
+	if err != nil {
+		t.Fatalf("無法初始化 Storage: %v", err)
+	}
+	storage.Save(&SavedForm{Label: "備份前", Name: "甲", EmployeeID: "A1", StartDate: "2026-02-01", EndDate: "2026-02-02", LeaveType: "近假"})
+
+	info, err := storage.Backup(backupDir)
+	if err != nil {
+		t.Fatalf("備份失敗: %v", err)
+	}
+	if version, err := VerifyBackup(info.Path); err != nil || version != LatestSchemaVersion() {
+		t.Fatalf("備份檔驗證失敗: version=%d err=%v", version, err)
+	}
+
+	// 同一秒內的第二份備份不可與第一份衝突
+	second, err := storage.Backup(backupDir)
+	if err != nil || second.Name == info.Name {
+		t.Fatalf("連續備份應產生不同的檔案，實際 %+v: %v", second, err)
+	}
+	backups, err := ListBackups(backupDir, dbPath)
+	if err != nil || len(backups) != 2 || backups[0].Name != second.Name {
+		t.Errorf("應列出 2 份備份且新的在前，實際 %+v: %v", backups, err)
+	}
+
+	// 備份後新增的資料在還原後應消失
+	storage.Save(&SavedForm{Label: "備份後", Name: "乙", EmployeeID: "A2", StartDate: "2026-02-01", EndDate: "2026-02-02", LeaveType: "近假"})
+	storage.Close()
+
+	previous, err := RestoreDatabase(info.Path, dbPath)
+	if err != nil {
+		t.Fatalf("還原失敗: %v", err)
+	}
+	if _, err := os.Stat(previous); err != nil {
+		t.Errorf("原資料庫應保留於 %s: %v", previous, err)
+	}
+
+	storage, err = NewStorage(dbPath, testSecretKey())
+	if err != nil {
+		t.Fatalf("還原後無法開啟資料庫: %v", err)
+	}
+	defer storage.Close()
+
+	forms, _ := storage.List()
+	if len(forms) != 1 || forms[0].Label != "備份前" {
+		t.Errorf("還原後應只有備份前的資料，實際 %+v", forms)
+	}
+}
+
+// TestRestoreRejectsInvalidBackup 測試還原前拒絕無效或較新版本的備份
+func TestRestoreRejectsInvalidBackup(t *testing.T) {
+	dir := t.TempDir()
+	dbPath := filepath.Join(dir, "data.db")
+	os.WriteFile(dbPath, []byte("original"), 0600)
+
+	// 沒有結構版本的空資料庫
+	emptyPath := filepath.Join(dir, "empty.db")
+	db, _ := sql.Open("sqlite3", emptyPath)
+	db.Exec("CREATE TABLE t (id INTEGER)")
+	db.Close()
+	if _, err := RestoreDatabase(emptyPath, dbPath); err == nil {
+		t.Error("沒有結構版本的備份應被拒絕")
+	}
+
+	// 較新版本的資料庫
+	newerPath := filepath.Join(dir, "newer.db")
+	storage, err := NewStorage(newerPath, testSecretKey())
+	if err != nil {
+		t.Fatalf("無法初始化 Storage: %v", err)
+	}
+	storage.db.Exec("INSERT INTO schema_migrations (version, description, applied_at) VALUES (?, 'future', CURRENT_TIMESTAMP)", LatestSchemaVersion()+1)
+	storage.Close()
+	if _, err := RestoreDatabase(newerPath, dbPath); err == nil {
+		t.Error("較新版本的備份應被拒絕")
+	}
+
+	if data, _ := os.ReadFile(dbPath); string(data) != "original" {
+		t.Error("驗證失敗時不應變更原資料庫")
+	}
+}
+
+// TestPruneBackups 測試依保留份數清除舊備份
+func TestPruneBackups(t *testing.T) {
+	dir := t.TempDir()
+	dbPath := filepath.Join(dir, "data.db")
+
+	names := []string{"data-20260101-000000.db", "data-20260102-000000.db", "data-20260103-000000.db", "other.db"}
+	for _, name := range names {
+		os.WriteFile(filepath.Join(dir, name), []byte("x"), 0600)
+	}
+
+	removed, err := PruneBackups(dir, dbPath, 2)
+	if err != nil {
+		t.Fatalf("清除失敗: %v", err)
+	}
+	if len(removed) != 1 || removed[0] != "data-20260101-000000.db" {
+		t.Errorf("應只刪除最舊的備份，實際 %v", removed)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "other.db")); err != nil {
+		t.Error("不應刪除非備份檔")
+	}
+}
+
+// writeLegacyForms 建立拆分 employees 前的舊版 saved_forms 資料表並執行 statements
+func writeLegacyForms(t *testing.T, dbPath, statements string) {
+	t.Helper()
+	db, err := sql.Open("sqlite3", dbPath)
+	if err != nil {
+		t.Fatalf("開啟資料庫失敗: %v", err)
+	}
+	defer db.Close()
+	_, err = db.Exec(`
+		CREATE TABLE saved_forms (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			label TEXT NOT NULL,
+			name TEXT NOT NULL,
+			employee_id TEXT NOT NULL,
+			start_date TEXT NOT NULL,
+			end_date TEXT NOT NULL,
+			leave_type TEXT NOT NULL,
+			password TEXT NOT NULL,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		);
+	` + statements)
+	if err != nil {
+		t.Fatalf("建立舊版資料失敗: %v", err)
+	}
+}
+
+// TestEmployeesSplitFromLegacyForms 測試舊版資料依員工代號拆分為共用的員工資料
+func TestEmployeesSplitFromLegacyForms(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "legacy.db")
+	writeLegacyForms(t, dbPath, `
+		INSERT INTO saved_forms (label, name, employee_id, start_date, end_date, leave_type, password, updated_at)
+		VALUES ('春假', '王小明', 'A1', '2026-02-01', '2026-02-03', '近假', '', '2025-06-01 00:00:00'),
+		       ('秋假', '王小明', 'A1', '2026-10-01', '2026-10-03', '長假', 'pw1', '2025-01-01 00:00:00'),
+		       ('年假', '李小華', 'B2', '2026-12-01', '2026-12-02', '近假', 'pw', '2025-01-01 00:00:00');
+	`)
+
+	storage, err := NewStorage(dbPath, testSecretKey())
+	if err != nil {
+		t.Fatalf("無法初始化 Storage: %v", err)
+	}
+	defer storage.Close()
+
+	employees, err := storage.ListEmployees()
+	if err != nil {
+		t.Fatalf("列出員工失敗: %v", err)
+	}
+	if len(employees) != 2 {
+		t.Fatalf("應拆分為 2 位員工，實際 %d 位", len(employees))
+	}
+
+	spring, _ := storage.GetByID(1)
+	autumn, _ := storage.GetByID(2)
+	if spring.EmployeeRef != autumn.EmployeeRef || spring.Name != "王小明" {
+		t.Errorf("同一員工代號的資料應參照同一位員工: %+v / %+v", spring, autumn)
+	}
+	if plain, _ := storage.DecryptPassword(spring.Password); plain != "pw1" {
+		t.Errorf("應採用有密碼的資料，實際 %q", plain)
+	}
+
+	// 更換員工密碼後所有資料一併生效
+	if err := storage.UpdateEmployeePassword(spring.EmployeeRef, "changed"); err != nil {
+		t.Fatalf("更新密碼失敗: %v", err)
+	}
+	autumn, _ = storage.GetByID(2)
+	if plain, _ := storage.DecryptPassword(autumn.Password); plain != "changed" {
+		t.Errorf("更換員工密碼後應套用到所有資料，實際 %q", plain)
+	}
+
+	if err := storage.DeleteEmployee(spring.EmployeeRef); err != ErrEmployeeInUse {
+		t.Errorf("仍被參照的員工不可刪除，實際 %v", err)
+	}
+}
+
+// TestEmployeesSplitRejectsConflicts 測試同一員工代號的姓名或密碼不一致時中止遷移並保留原資料
+func TestEmployeesSplitRejectsConflicts(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "legacy.db")
+	writeLegacyForms(t, dbPath, `
+		INSERT INTO saved_forms (label, name, employee_id, start_date, end_date, leave_type, password)
+		VALUES ('春假', '王小明', 'A1', '2026-02-01', '2026-02-03', '近假', 'pw1'),
+		       ('秋假', '王小明', 'A1', '2026-10-01', '2026-10-03', '長假', 'pw2'),
+		       ('年假', '李小華', 'B2', '2026-12-01', '2026-12-02', '近假', 'pw'),
+		       ('寒假', '陳大同', 'C3', '2026-01-20', '2026-01-21', '近假', 'pw'),
+		       ('暑假', '陳大明', 'C3', '2026-07-01', '2026-07-02', '近假', 'pw');
+	`)
+
+	_, err := NewStorage(dbPath, testSecretKey())
+	if err == nil {
+		t.Fatal("員工資料不一致時應中止遷移")
+	}
+	if msg := err.Error(); !strings.Contains(msg, "A1、C3") || strings.Contains(msg, "B2") {
+		t.Errorf("錯誤訊息應列出不一致的員工代號，實際 %v", err)
+	}
+
+	version, _, err := ReadSchemaVersion(dbPath)
+	if err != nil {
+		t.Fatalf("讀取版本失敗: %v", err)
+	}
+	if version != 4 {
+		t.Errorf("中止的遷移不應套用，版本應停在 4，實際 %d", version)
+	}
+}
+
+// TestEmployeesSplitComparesDecryptedPasswords 測試加密的舊版密碼解密後相同時可正常拆分
+func TestEmployeesSplitComparesDecryptedPasswords(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "legacy.db")
+
+	c, err := NewPasswordCipher(testSecretKey().Key)
+	if err != nil {
+		t.Fatalf("建立加密器失敗: %v", err)
+	}
+	check, _ := c.Encrypt(keyCheckPlaintext)
+	first, _ := c.Encrypt("same")
+	second, _ := c.Encrypt("same")
+	if first == second {
+		t.Fatal("同一密碼的密文應不同")
+	}
+
+	writeLegacyForms(t, dbPath, `
+		CREATE TABLE storage_meta (key TEXT PRIMARY KEY, value TEXT NOT NULL);
+		INSERT INTO storage_meta (key, value) VALUES ('key_check', '`+check+`');
+		INSERT INTO saved_forms (label, name, employee_id, start_date, end_date, leave_type, password)
+		VALUES ('春假', '王小明', 'A1', '2026-02-01', '2026-02-03', '近假', '`+first+`'),
+		       ('秋假', '王小明', 'A1', '2026-10-01', '2026-10-03', '長假', '`+second+`');
+	`)
+
+	storage, err := NewStorage(dbPath, testSecretKey())
+	if err != nil {
+		t.Fatalf("解密後相同的密碼不應視為不一致: %v", err)
+	}
+	defer storage.Close()
+
+	form, _ := storage.GetByID(2)
+	if plain, _ := storage.DecryptPassword(form.Password); plain != "same" {
+		t.Errorf("解密結果應為 same，實際 %q", plain)
+	}
+}
@@ -6,57 +6,120 @@ import (
 	"net/http"
 	"net/url"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
+// sharedEntryMap 提交器的 entry_map（欄位名稱 → entry ID）。
+// 目前的 map 建立後不再修改，重新對應時整份替換；提交器與其 WithDedicatedConnection 的複本
+// 共用同一個 sharedEntryMap，讓已排定的工作也使用新的 entry ID
+type sharedEntryMap struct {
+	mu      sync.Mutex // 序列化替換，避免同時重新對應時遺失欄位
+	current atomic.Pointer[map[string]string]
+}
+
+// load 取得目前的 entry_map，呼叫端不可修改
+func (e *sharedEntryMap) load() map[string]string {
+	return *e.current.Load()
+}
+
 // GoogleFormSubmitter Google Form 提交器
 type GoogleFormSubmitter struct {
 	FormURL    string
-	EntryMap   map[string]string // 欄位名稱 → entry ID 對應
 	HTTPClient *http.Client
+
+	entryMap *sharedEntryMap
 }
 
-// NewGoogleFormSubmitter 建立新的 GoogleFormSubmitter
+// NewGoogleFormSubmitter 建立新的 GoogleFormSubmitter，entryMap 會被複製，之後修改原本的 map 不影響提交器
 func NewGoogleFormSubmitter(formURL string, entryMap map[string]string) *GoogleFormSubmitter {
+	entries := &sharedEntryMap{}
+	initial := make(map[string]string, len(entryMap))
+	for field, entryID := range entryMap {
+		initial[field] = entryID
+	}
+	entries.current.Store(&initial)
+
 	return &GoogleFormSubmitter{
-		FormURL:  formURL,
-		EntryMap: entryMap,
+		FormURL: formURL,
 		HTTPClient: &http.Client{
 			Timeout: 30 * time.Second,
 		},
+		entryMap: entries,
 	}
 }
 
+// WithDedicatedConnection 複製提交器並使用獨立的連線池，
+// 讓同時排程的多個工作各自預熱並保有自己的連線。
+// 複製原本的 *http.Transport 以沿用其設定（代理、TLS、逾時等），未設定時以 http.DefaultTransport 為基礎；
+// 其他 RoundTripper 無法複製，沿用原本的實作
+func (s *GoogleFormSubmitter) WithDedicatedConnection() *GoogleFormSubmitter {
+	client := *s.HTTPClient
+	switch transport := client.Transport.(type) {
+	case nil:
+		client.Transport = http.DefaultTransport.(*http.Transport).Clone()
+	case *http.Transport:
+		client.Transport = transport.Clone()
+	}
+	clone := *s
+	clone.HTTPClient = &client
+	return &clone
+}
+
 // BuildFormData 建構 form-urlencoded 資料（公開供測試與排程使用）
 func (s *GoogleFormSubmitter) BuildFormData(req *LeaveRequest) url.Values {
 	data := url.Values{}
+	entryMap := s.entryMap.load()
 
-	if entryID, ok := s.EntryMap["name"]; ok && entryID != "" {
+	if entryID, ok := entryMap["name"]; ok && entryID != "" {
 		data.Set(entryID, req.Name)
 	}
-	if entryID, ok := s.EntryMap["employee_id"]; ok && entryID != "" {
+	if entryID, ok := entryMap["employee_id"]; ok && entryID != "" {
 		data.Set(entryID, req.EmployeeID)
 	}
-	if entryID, ok := s.EntryMap["start_date"]; ok && entryID != "" {
+	if entryID, ok := entryMap["start_date"]; ok && entryID != "" {
 		data.Set(entryID, req.StartDate)
 	}
-	if entryID, ok := s.EntryMap["end_date"]; ok && entryID != "" {
+	if entryID, ok := entryMap["end_date"]; ok && entryID != "" {
 		data.Set(entryID, req.EndDate)
 	}
-	if entryID, ok := s.EntryMap["leave_type"]; ok && entryID != "" {
+	if entryID, ok := entryMap["leave_type"]; ok && entryID != "" {
 		data.Set(entryID, req.LeaveType)
 	}
-	if entryID, ok := s.EntryMap["password"]; ok && entryID != "" {
+	if entryID, ok := entryMap["password"]; ok && entryID != "" {
 		data.Set(entryID, req.Password)
 	}
 
 	return data
 }
 
+// EntryMapSnapshot 取得目前 entry_map 的複本
+func (s *GoogleFormSubmitter) EntryMapSnapshot() map[string]string {
+	current := s.entryMap.load()
+	snapshot := make(map[string]string, len(current))
+	for field, entryID := range current {
+		snapshot[field] = entryID
+	}
+	return snapshot
+}
+
+// UpdateEntryMap 合併新的欄位對應並替換 entry_map，共用同一份 entry_map 的提交器皆使用新的對應
+func (s *GoogleFormSubmitter) UpdateEntryMap(entryMap map[string]string) {
+	s.entryMap.mu.Lock()
+	defer s.entryMap.mu.Unlock()
+
+	merged := s.EntryMapSnapshot()
+	for field, entryID := range entryMap {
+		merged[field] = entryID
+	}
+	s.entryMap.current.Store(&merged)
+}
+
 // Submit 提交資料到 Google Form
 func (s *GoogleFormSubmitter) Submit(req *LeaveRequest) (*SubmitResult, error) {
-	// 驗證請求
-	if err := Validate(req); err != nil {
+	// 驗證請求欄位；假日檢查由呼叫端以 Storage 的行事曆進行
+	if err := Validate(req, nil); err != nil {
 		return &SubmitResult{
 			Success: false,
 			Message: fmt.Sprintf("驗證失敗：%s", err.Error()),
@@ -79,28 +142,33 @@ func (s *GoogleFormSubmitter) Submit(req *LeaveRequest) (*SubmitResult, error) {
 	httpReq.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 
 	// 發送請求
+	httpReq, trace := traceRequest(httpReq)
 	resp, err := s.HTTPClient.Do(httpReq)
 	if err != nil {
 		return &SubmitResult{
 			Success: false,
 			Message: "無法連線到 Google Form",
+			Timing:  trace.Timing(),
 		}, nil
 	}
 	defer resp.Body.Close()
 
 	// 讀取回應內容（用於除錯）
 	_, _ = io.ReadAll(resp.Body)
+	timing := trace.Timing()
 
 	// Google Form 提交成功通常回傳 200
 	if resp.StatusCode == http.StatusOK {
 		return &SubmitResult{
 			Success: true,
 			Message: "表單提交成功",
+			Timing:  timing,
 		}, nil
 	}
 
 	return &SubmitResult{
 		Success: false,
 		Message: "Google Form 提交失敗",
+		Timing:  timing,
 	}, nil
 }
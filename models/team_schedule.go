@@ -0,0 +1,325 @@
+package models
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 團隊成員排程狀態
+const (
+	TeamMemberArmed     = "armed"     // 已排程，等待執行
+	TeamMemberSucceeded = "succeeded" // 提交成功
+	TeamMemberFailed    = "failed"    // 準備或提交失敗
+	TeamMemberStopped   = "stopped"   // 執行前已停止
+)
+
+// TeamScheduleConfig 團隊排程配置：名單中每位成員在同一目標時間提交相同的請假欄位
+type TeamScheduleConfig struct {
+	RosterID       int64  `json:"roster_id"`
+	Date           string `json:"date"` // YYYY-MM-DD，於該日 00:00:00 提交
+	StartDate      string `json:"start_date"`
+	EndDate        string `json:"end_date"`
+	LeaveType      string `json:"leave_type"`
+	StaggerMs      int    `json:"stagger_ms"`              // 依優先順序每位成員錯開的毫秒數
+	PrepareSeconds int    `json:"prepare_seconds"`         // 提前準備秒數，預設 5
+	RetryCount     int    `json:"retry_count"`             // 失敗重試次數，預設 3
+	RetryInterval  int    `json:"retry_interval"`          // 重試間隔毫秒，預設 100
+	MissedPolicy   string `json:"missed_policy,omitempty"` // 休眠喚醒等原因錯過時間的處理策略（skip / grace / notify）
+	GraceSeconds   int    `json:"grace_seconds,omitempty"` // grace 策略的寬限秒數
+
+	// 送出策略（fixed / poll）與 poll 參數，每位成員各自探測，開放後依優先順序錯開送出
+	Strategy            string `json:"strategy,omitempty"`
+	PollLeadSeconds     int    `json:"poll_lead_seconds,omitempty"`
+	PollIntervalMs      int    `json:"poll_interval_ms,omitempty"`
+	PollDeadlineSeconds int    `json:"poll_deadline_seconds,omitempty"`
+}
+
+// TeamMemberStatus 團隊排程中單一成員的狀態
+type TeamMemberStatus struct {
+	Priority    int        `json:"priority"`
+	EmployeeRef int64      `json:"employee_ref"`
+	Name        string     `json:"name"`
+	EmployeeID  string     `json:"employee_id"`
+	SendAt      time.Time  `json:"send_at"` // 預定送出時間（目標時間加上錯開延遲）
+	Status      string     `json:"status"`
+	Attempts    int        `json:"attempts"`
+	Message     string     `json:"message,omitempty"`
+	StartedAt   *time.Time `json:"started_at,omitempty"`
+	FinishedAt  *time.Time `json:"finished_at,omitempty"`
+
+	FireDelayUs *int64 `json:"fire_delay_us,omitempty"` // 實際與預定送出時間的差距（微秒）
+}
+
+// TeamScheduleStatus 團隊排程總覽
+type TeamScheduleStatus struct {
+	Running    bool                `json:"running"`
+	Config     *TeamScheduleConfig `json:"config,omitempty"`
+	RosterName string              `json:"roster_name,omitempty"`
+	BatchID    string              `json:"batch_id,omitempty"`
+	TargetTime time.Time           `json:"target_time"`
+	Armed      int                 `json:"armed"`
+	Succeeded  int                 `json:"succeeded"`
+	Failed     int                 `json:"failed"`
+	Members    []*TeamMemberStatus `json:"members"`
+
+	Precision *FirePrecisionStats `json:"precision,omitempty"` // 已送出成員的送出精確度
+}
+
+// teamJob 單一成員的排程工作
+type teamJob struct {
+	member    *RosterMember
+	scheduler *Scheduler
+	sendAt    time.Time
+}
+
+// TeamScheduler 團隊排程器：為名單中每位成員各自建立排程工作，
+// 每個工作使用獨立的連線並依優先順序錯開送出
+type TeamScheduler struct {
+	submitter  *GoogleFormSubmitter
+	storage    *Storage
+	logger     *log.Logger
+	mu         sync.Mutex
+	config     *TeamScheduleConfig
+	rosterName string
+	batchID    string
+	targetTime time.Time
+	jobs       []*teamJob
+
+	clock   Clock          // 現在時間與成員排程使用的時鐘
+	retired sync.WaitGroup // 被新團隊排程取代、仍在結束中的舊成員排程
+}
+
+// NewTeamScheduler 建立團隊排程器
+func NewTeamScheduler(submitter *GoogleFormSubmitter, storage *Storage) *TeamScheduler {
+	return &TeamScheduler{
+		submitter: submitter,
+		storage:   storage,
+		logger:    log.New(os.Stdout, "[Team] ", log.LstdFlags|log.Lmicroseconds),
+		clock:     SystemClock,
+	}
+}
+
+// SetClock 設定團隊排程與成員排程使用的時鐘，需在 Start 前呼叫
+func (t *TeamScheduler) SetClock(c Clock) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if c == nil {
+		c = SystemClock
+	}
+	t.clock = c
+}
+
+// Start 依配置為名單成員建立排程；全部成員排程啟動後才停止並取代舊的團隊排程，
+// 任一成員啟動失敗時舊的團隊排程維持不變
+func (t *TeamScheduler) Start(cfg *TeamScheduleConfig) error {
+	roster, err := t.storage.GetRoster(cfg.RosterID)
+	if err != nil {
+		return err
+	}
+	if len(roster.Members) == 0 {
+		return fmt.Errorf("名單「%s」沒有成員", roster.Name)
+	}
+	if cfg.StaggerMs < 0 {
+		return fmt.Errorf("錯開毫秒數不可為負數")
+	}
+
+	t.mu.Lock()
+	clock := t.clock
+	t.mu.Unlock()
+
+	targetTime, err := ParseScheduleDate(cfg.Date)
+	if err != nil {
+		return fmt.Errorf("排程日期格式錯誤: %w", err)
+	}
+	if !targetTime.After(clock.Now()) {
+		return fmt.Errorf("排程時間 %s 已過", targetTime.Format("2006-01-02 15:04:05"))
+	}
+
+	// 所有成員共用請假欄位，先以第一位成員驗證一次；密碼則逐一確認
+	first := roster.Members[0]
+	if err := Validate(&LeaveRequest{
+		Name:       first.Name,
+		EmployeeID: first.EmployeeID,
+		StartDate:  cfg.StartDate,
+		EndDate:    cfg.EndDate,
+		LeaveType:  cfg.LeaveType,
+		Password:   "-",
+	}, t.storage.Calendar()); err != nil {
+		return err
+	}
+
+	var missing []string
+	for _, m := range roster.Members {
+		if !m.HasPassword {
+			missing = append(missing, m.Name)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("以下成員未設定密碼: %s", strings.Join(missing, "、"))
+	}
+
+	batchID := fmt.Sprintf("team-%s", clock.Now().Format("20060102-150405"))
+	jobs := make([]*teamJob, 0, len(roster.Members))
+	for i, m := range roster.Members {
+		jobCfg := &ScheduleConfig{
+			Enabled:        true,
+			Date:           cfg.Date,
+			PrepareSeconds: cfg.PrepareSeconds,
+			RetryCount:     cfg.RetryCount,
+			RetryInterval:  cfg.RetryInterval,
+			MissedPolicy:   cfg.MissedPolicy,
+			GraceSeconds:   cfg.GraceSeconds,
+			EmployeeRef:    m.EmployeeRef,
+			StartDate:      cfg.StartDate,
+			EndDate:        cfg.EndDate,
+			LeaveType:      cfg.LeaveType,
+			StaggerMs:      i * cfg.StaggerMs,
+
+			Strategy:            cfg.Strategy,
+			PollLeadSeconds:     cfg.PollLeadSeconds,
+			PollIntervalMs:      cfg.PollIntervalMs,
+			PollDeadlineSeconds: cfg.PollDeadlineSeconds,
+		}
+
+		scheduler := NewScheduler(jobCfg, t.submitter.WithDedicatedConnection(), t.storage)
+		scheduler.source = HistorySourceTeam
+		scheduler.batchID = batchID
+		scheduler.logger = log.New(os.Stdout, fmt.Sprintf("[Team #%d %s] ", m.Priority, m.Name), log.LstdFlags|log.Lmicroseconds)
+		scheduler.SetClock(clock)
+
+		if err := scheduler.Start(); err != nil {
+			t.retire(jobs)
+			return fmt.Errorf("成員 %s 排程啟動失敗: %w", m.Name, err)
+		}
+
+		jobs = append(jobs, &teamJob{
+			member:    m,
+			scheduler: scheduler,
+			sendAt:    targetTime.Add(time.Duration(jobCfg.StaggerMs) * time.Millisecond),
+		})
+	}
+
+	t.mu.Lock()
+	previous := t.jobs
+	t.config = cfg
+	t.rosterName = roster.Name
+	t.batchID = batchID
+	t.targetTime = targetTime
+	t.jobs = jobs
+	t.mu.Unlock()
+
+	if len(previous) > 0 {
+		t.retire(previous)
+		t.logger.Println("已停止並取代舊的團隊排程")
+	}
+
+	t.logger.Printf("團隊排程已啟動，名單: %s，成員 %d 位，目標時間: %s", roster.Name, len(jobs), targetTime.Format("2006-01-02 15:04:05.000"))
+	return nil
+}
+
+// Stop 停止所有成員的排程，保留已完成的結果供查詢
+func (t *TeamScheduler) Stop() {
+	t.mu.Lock()
+	jobs := t.jobs
+	t.mu.Unlock()
+
+	stopped := false
+	for _, job := range jobs {
+		if job.scheduler.IsRunning() {
+			job.scheduler.Stop()
+			stopped = true
+		}
+	}
+	if stopped {
+		t.logger.Println("團隊排程已停止")
+	}
+}
+
+// retire 停止不再追蹤的成員排程，並在背景等待其結束，讓 Shutdown 可一併等待
+func (t *TeamScheduler) retire(jobs []*teamJob) {
+	for _, job := range jobs {
+		job.scheduler.Stop()
+		t.retired.Add(1)
+		go func(s *Scheduler) {
+			defer t.retired.Done()
+			s.Shutdown()
+		}(job.scheduler)
+	}
+}
+
+// Shutdown 停止所有成員的排程並等待執行中的工作結束，包含已被取代的舊團隊排程（程式結束時使用）
+func (t *TeamScheduler) Shutdown() {
+	t.Stop()
+
+	t.mu.Lock()
+	jobs := t.jobs
+	t.mu.Unlock()
+
+	for _, job := range jobs {
+		job.scheduler.Shutdown()
+	}
+	t.retired.Wait()
+}
+
+// Status 取得團隊排程總覽與每位成員的結果
+func (t *TeamScheduler) Status() *TeamScheduleStatus {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	status := &TeamScheduleStatus{
+		Config:     t.config,
+		RosterName: t.rosterName,
+		BatchID:    t.batchID,
+		TargetTime: t.targetTime,
+		Members:    make([]*TeamMemberStatus, 0, len(t.jobs)),
+	}
+	var results []*SubmissionRecord
+
+	for _, job := range t.jobs {
+		ms := &TeamMemberStatus{
+			Priority:    job.member.Priority,
+			EmployeeRef: job.member.EmployeeRef,
+			Name:        job.member.Name,
+			EmployeeID:  job.member.EmployeeID,
+			SendAt:      job.sendAt,
+		}
+
+		rec := job.scheduler.LastResult()
+		switch {
+		case rec == nil && job.scheduler.IsRunning():
+			ms.Status = TeamMemberArmed
+			status.Armed++
+			status.Running = true
+		case rec == nil:
+			ms.Status = TeamMemberStopped
+		default:
+			ms.Attempts = rec.Attempts
+			ms.Message = rec.Message
+			ms.StartedAt = &rec.StartedAt
+			ms.FinishedAt = &rec.FinishedAt
+			ms.FireDelayUs = rec.FireDelayUs
+			results = append(results, rec)
+			if rec.Success {
+				ms.Status = TeamMemberSucceeded
+				status.Succeeded++
+			} else {
+				ms.Status = TeamMemberFailed
+				status.Failed++
+			}
+		}
+
+		status.Members = append(status.Members, ms)
+	}
+	status.Precision = SummarizeFirePrecision(results)
+
+	return status
+}
+
+// IsRunning 檢查是否仍有成員等待執行
+func (t *TeamScheduler) IsRunning() bool {
+	return t.Status().Running
+}
@@ -0,0 +1,298 @@
+package models
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// createTestRoster 建立測試用員工與名單，回傳名單 ID
+func createTestRoster(t *testing.T, storage *Storage, names []string, passwords []string) int64 {
+	var refs []int64
+	for i, name := range names {
+		id, err := storage.CreateEmployee(&Employee{
+			Name:       name,
+			EmployeeID: "T" + string(rune('1'+i)),
+			Password:   passwords[i],
+		})
+		if err != nil {
+			t.Fatalf("建立員工失敗: %v", err)
+		}
+		refs = append(refs, id)
+	}
+
+	rosterID, err := storage.CreateRoster("測試班", refs)
+	if err != nil {
+		t.Fatalf("建立名單失敗: %v", err)
+	}
+	return rosterID
+}
+
+// TestRosterMembersOrdered 測試名單成員依優先順序保存與更新
+func TestRosterMembersOrdered(t *testing.T) {
+	storage, cleanup := setupTestStorage(t)
+	defer cleanup()
+
+	rosterID := createTestRoster(t, storage, []string{"甲", "乙", "丙"}, []string{"p", "p", ""})
+
+	roster, err := storage.GetRoster(rosterID)
+	if err != nil {
+		t.Fatalf("讀取名單失敗: %v", err)
+	}
+	if len(roster.Members) != 3 || roster.Members[0].Name != "甲" || roster.Members[2].Priority != 3 {
+		t.Fatalf("成員順序不正確: %+v", roster.Members)
+	}
+	if roster.Members[2].HasPassword {
+		t.Error("未設定密碼的成員 has_password 應為 false")
+	}
+
+	// 調整順序
+	reordered := []int64{roster.Members[2].EmployeeRef, roster.Members[0].EmployeeRef}
+	if err := storage.UpdateRoster(rosterID, "測試班", reordered); err != nil {
+		t.Fatalf("更新名單失敗: %v", err)
+	}
+	roster, _ = storage.GetRoster(rosterID)
+	if len(roster.Members) != 2 || roster.Members[0].Name != "丙" || roster.Members[1].Priority != 2 {
+		t.Errorf("更新後順序不正確: %+v", roster.Members)
+	}
+
+	// 刪除員工時一併移出名單
+	if err := storage.DeleteEmployee(reordered[0]); err != nil {
+		t.Fatalf("刪除員工失敗: %v", err)
+	}
+	roster, _ = storage.GetRoster(rosterID)
+	if len(roster.Members) != 1 || roster.Members[0].Name != "甲" {
+		t.Errorf("刪除員工後應移出名單: %+v", roster.Members)
+	}
+
+	if _, err := storage.CreateRoster("重複成員", []int64{reordered[1], 9999}); err == nil {
+		t.Error("不存在的員工應無法加入名單")
+	}
+}
+
+// TestTeamSchedulerRun 測試團隊排程為每位成員各自提交並依優先順序錯開
+func TestTeamSchedulerRun(t *testing.T) {
+	storage, cleanup := setupTestStorage(t)
+	defer cleanup()
+
+	var mu sync.Mutex
+	var order []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" {
+			return // 預熱連線
+		}
+		r.ParseForm()
+		name := r.PostForm.Get("entry.1")
+		mu.Lock()
+		order = append(order, name)
+		mu.Unlock()
+		if name == "丙" {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	rosterID := createTestRoster(t, storage, []string{"甲", "乙", "丙"}, []string{"p1", "p2", "p3"})
+
+	team := NewTeamScheduler(batchTestSubmitter(server.URL), storage)
+	defer team.Stop()
+
+	err := team.Start(&TeamScheduleConfig{
+		RosterID:      rosterID,
+		Date:          time.Now().AddDate(1, 0, 0).Format("2006-01-02"),
+		StartDate:     "2026-02-01",
+		EndDate:       "2026-02-02",
+		LeaveType:     "近假",
+		StaggerMs:     30,
+		RetryCount:    1,
+		RetryInterval: 1,
+	})
+	if err != nil {
+		t.Fatalf("啟動團隊排程失敗: %v", err)
+	}
+
+	status := team.Status()
+	if !status.Running || status.Armed != 3 {
+		t.Fatalf("三位成員應皆已排程: %+v", status)
+	}
+	if d := status.Members[2].SendAt.Sub(status.Members[0].SendAt); d != 60*time.Millisecond {
+		t.Errorf("第三位成員應錯開 60ms，實際 %v", d)
+	}
+
+	// 等啟動時的排程前檢查結束（會讀取目標時間）
+	for _, job := range team.jobs {
+		deadline := time.Now().Add(5 * time.Second)
+		for job.scheduler.Preflight() == nil && time.Now().Before(deadline) {
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+
+	// 將目標時間改為現在，直接執行每個工作
+	now := time.Now()
+	var wg sync.WaitGroup
+	for _, job := range team.jobs {
+		job.scheduler.mu.Lock()
+		current := job.scheduler.job
+		current.targetTime = now
+		job.scheduler.mu.Unlock()
+
+		wg.Add(1)
+		go func(s *Scheduler, j *scheduleJob) {
+			defer wg.Done()
+			s.executeWithPrecision(j)
+		}(job.scheduler, current)
+	}
+	wg.Wait()
+
+	if strings.Join(order, ",") != "甲,乙,丙" {
+		t.Errorf("應依優先順序送出，實際 %v", order)
+	}
+
+	status = team.Status()
+	if status.Running || status.Succeeded != 2 || status.Failed != 1 {
+		t.Errorf("應成功 2 位、失敗 1 位: %+v", status)
+	}
+	if status.Members[2].Status != TeamMemberFailed || status.Members[0].Attempts != 1 {
+		t.Errorf("成員結果不正確: %+v", status.Members[2])
+	}
+
+	records, err := storage.ListHistory(HistoryQuery{Source: HistorySourceTeam, BatchID: status.BatchID})
+	if err != nil || len(records) != 3 {
+		t.Errorf("應寫入 3 筆團隊提交記錄，實際 %d (%v)", len(records), err)
+	}
+}
+
+// TestTeamSchedulerRequiresPasswords 測試有成員未設定密碼時拒絕啟動
+func TestTeamSchedulerRequiresPasswords(t *testing.T) {
+	storage, cleanup := setupTestStorage(t)
+	defer cleanup()
+
+	rosterID := createTestRoster(t, storage, []string{"甲", "乙"}, []string{"p1", ""})
+
+	team := NewTeamScheduler(batchTestSubmitter("http://127.0.0.1:1"), storage)
+	err := team.Start(&TeamScheduleConfig{
+		RosterID:  rosterID,
+		Date:      time.Now().AddDate(1, 0, 0).Format("2006-01-02"),
+		StartDate: "2026-02-01",
+		EndDate:   "2026-02-02",
+		LeaveType: "近假",
+	})
+	if err == nil || !strings.Contains(err.Error(), "乙") {
+		t.Errorf("應指出未設定密碼的成員，實際 %v", err)
+	}
+	if team.IsRunning() {
+		t.Error("驗證失敗時不應啟動任何工作")
+	}
+}
+
+// TestTeamSchedulerReplace 測試新團隊排程全部啟動後才取代舊的，啟動失敗時舊的維持不變
+func TestTeamSchedulerReplace(t *testing.T) {
+	storage, cleanup := setupTestStorage(t)
+	defer cleanup()
+
+	rosterID := createTestRoster(t, storage, []string{"甲", "乙"}, []string{"p1", "p2"})
+	team := NewTeamScheduler(batchTestSubmitter("http://127.0.0.1:1"), storage)
+	defer team.Shutdown()
+
+	cfg := &TeamScheduleConfig{
+		RosterID:  rosterID,
+		Date:      time.Now().AddDate(1, 0, 0).Format("2006-01-02"),
+		StartDate: "2026-02-01",
+		EndDate:   "2026-02-02",
+		LeaveType: "近假",
+	}
+	if err := team.Start(cfg); err != nil {
+		t.Fatalf("啟動團隊排程失敗: %v", err)
+	}
+	old := team.jobs
+
+	// 測試用網址無法推得 viewform 頁面，poll 策略的成員排程會啟動失敗
+	failing := *cfg
+	failing.Strategy = StrategyPoll
+	if err := team.Start(&failing); err == nil {
+		t.Fatal("成員排程啟動失敗時應回傳錯誤")
+	}
+	for _, job := range old {
+		if !job.scheduler.IsRunning() {
+			t.Fatal("新團隊排程啟動失敗時舊的應維持運行")
+		}
+	}
+
+	if err := team.Start(cfg); err != nil {
+		t.Fatalf("重新啟動團隊排程失敗: %v", err)
+	}
+	for _, job := range old {
+		if job.scheduler.IsRunning() {
+			t.Error("取代後舊的成員排程應已停止")
+		}
+	}
+	if status := team.Status(); !status.Running || status.Armed != 2 {
+		t.Errorf("新的團隊排程應已排程兩位成員: %+v", status)
+	}
+}
+
+// TestTeamSchedulerUsesClock 測試團隊排程以設定的時鐘判斷排程時間
+func TestTeamSchedulerUsesClock(t *testing.T) {
+	storage, cleanup := setupTestStorage(t)
+	defer cleanup()
+
+	rosterID := createTestRoster(t, storage, []string{"甲"}, []string{"p1"})
+	team := NewTeamScheduler(batchTestSubmitter("http://127.0.0.1:1"), storage)
+	defer team.Shutdown()
+
+	clock := NewFakeClock(time.Date(2020, 1, 1, 12, 0, 0, 0, time.Local))
+	team.SetClock(clock)
+
+	err := team.Start(&TeamScheduleConfig{
+		RosterID:  rosterID,
+		Date:      "2020-01-02",
+		StartDate: "2020-01-10",
+		EndDate:   "2020-01-11",
+		LeaveType: "近假",
+	})
+	if err != nil {
+		t.Fatalf("依模擬時鐘尚未到的排程時間應可啟動: %v", err)
+	}
+	if status := team.Status(); !strings.HasPrefix(status.BatchID, "team-20200101-") {
+		t.Errorf("批次 ID 應使用模擬時間，實際 %s", status.BatchID)
+	}
+}
+
+// TestWithDedicatedConnection 測試獨立連線的複本沿用原本的 Transport 設定
+func TestWithDedicatedConnection(t *testing.T) {
+	base := &http.Transport{MaxIdleConnsPerHost: 7, DisableCompression: true}
+	submitter := batchTestSubmitter("http://127.0.0.1:1")
+	submitter.HTTPClient.Transport = base
+
+	clone := submitter.WithDedicatedConnection()
+	transport, ok := clone.HTTPClient.Transport.(*http.Transport)
+	if !ok || transport == base {
+		t.Fatalf("應建立獨立的 Transport，實際 %T", clone.HTTPClient.Transport)
+	}
+	if transport.MaxIdleConnsPerHost != 7 || !transport.DisableCompression {
+		t.Error("獨立連線應沿用原本 Transport 的設定")
+	}
+	if clone.HTTPClient.Timeout != submitter.HTTPClient.Timeout {
+		t.Error("應沿用原本的逾時設定")
+	}
+
+	submitter.HTTPClient.Transport = nil
+	if _, ok := submitter.WithDedicatedConnection().HTTPClient.Transport.(*http.Transport); !ok {
+		t.Error("未設定 Transport 時應以 http.DefaultTransport 為基礎")
+	}
+
+	// 複本與原本的提交器共用 entry_map，重新對應後已排定的工作也使用新的 entry ID
+	before := clone.BuildFormData(&LeaveRequest{Password: "p"})
+	submitter.UpdateEntryMap(map[string]string{"password": "entry.remapped"})
+	if clone.BuildFormData(&LeaveRequest{Password: "p"}).Get("entry.remapped") != "p" {
+		t.Error("複本應使用重新對應後的 entry_map")
+	}
+	if len(before) == 0 || before.Get("entry.remapped") != "" {
+		t.Errorf("重新對應前取得的表單資料不應改變，實際 %v", before)
+	}
+}
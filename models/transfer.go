@@ -0,0 +1,379 @@
+package models
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// 匯入匯出支援的格式
+const (
+	FormatCSV   = "csv"
+	FormatJSONL = "jsonl"
+)
+
+// savedFormCSVHeader CSV 欄位順序
+// date_template 為 JSON 文字（可省略）；使用範本時 start_date、end_date 留空
+var savedFormCSVHeader = []string{"label", "name", "employee_id", "start_date", "end_date", "leave_type", "date_template", "password"}
+
+// SavedFormRecord 匯入匯出用的儲存資料格式（密碼為明文，匯出時可省略）
+type SavedFormRecord struct {
+	Label      string `json:"label"`
+	Name       string `json:"name"`
+	EmployeeID string `json:"employee_id"`
+	StartDate  string `json:"start_date"`
+	EndDate    string `json:"end_date"`
+	LeaveType  string `json:"leave_type"`
+	// 日期範本，設定時忽略 StartDate 與 EndDate
+	DateTemplate *DateTemplate `json:"date_template,omitempty"`
+	Password     string        `json:"password,omitempty"`
+}
+
+// ImportRowResult 單筆匯入結果
+type ImportRowResult struct {
+	Line    int    `json:"line"`
+	Label   string `json:"label,omitempty"`
+	Valid   bool   `json:"valid"`
+	ID      int64  `json:"id,omitempty"`
+	Error   string `json:"error,omitempty"`
+	Warning string `json:"warning,omitempty"`
+}
+
+// ImportReport 匯入報告
+type ImportReport struct {
+	DryRun   bool              `json:"dry_run"`
+	Total    int               `json:"total"`
+	Valid    int               `json:"valid"`
+	Invalid  int               `json:"invalid"`
+	Imported int               `json:"imported"`
+	Rows     []ImportRowResult `json:"rows"`
+}
+
+// importRow 解析後的單筆資料
+type importRow struct {
+	line   int
+	record *SavedFormRecord
+	err    error
+}
+
+// DetectFormat 依副檔名判斷格式
+func DetectFormat(path string) (string, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".csv":
+		return FormatCSV, nil
+	case ".jsonl", ".ndjson":
+		return FormatJSONL, nil
+	}
+	return "", fmt.Errorf("無法從副檔名判斷格式，請指定 csv 或 jsonl")
+}
+
+// parseRecords 解析 CSV 或 JSONL，格式錯誤的列記錄在該列而不中斷整體解析
+func parseRecords(r io.Reader, format string) ([]importRow, error) {
+	switch format {
+	case FormatCSV:
+		return parseCSVRecords(r)
+	case FormatJSONL:
+		return parseJSONLRecords(r)
+	}
+	return nil, fmt.Errorf("不支援的格式: %s", format)
+}
+
+// parseCSVRecords 解析含標題列的 CSV
+func parseCSVRecords(r io.Reader) ([]importRow, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("讀取 CSV 標題列失敗: %w", err)
+	}
+
+	columns := map[string]int{}
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))] = i
+	}
+	for _, name := range savedFormCSVHeader {
+		if _, ok := columns[name]; !ok && name != "password" && name != "date_template" {
+			return nil, fmt.Errorf("CSV 缺少欄位: %s", name)
+		}
+	}
+
+	var rows []importRow
+	line := 1
+	for {
+		fields, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		line++
+		if err != nil {
+			var parseErr *csv.ParseError
+			if errors.As(err, &parseErr) {
+				rows = append(rows, importRow{line: line, err: fmt.Errorf("CSV 格式錯誤: %v", parseErr.Err)})
+				continue
+			}
+			return nil, fmt.Errorf("讀取 CSV 失敗: %w", err)
+		}
+
+		get := func(name string) string {
+			if i, ok := columns[name]; ok && i < len(fields) {
+				return strings.TrimSpace(fields[i])
+			}
+			return ""
+		}
+
+		dateTemplate, err := decodeDateTemplate(get("date_template"))
+		if err != nil {
+			rows = append(rows, importRow{line: line, err: err})
+			continue
+		}
+
+		rows = append(rows, importRow{line: line, record: &SavedFormRecord{
+			Label:        get("label"),
+			Name:         get("name"),
+			EmployeeID:   get("employee_id"),
+			StartDate:    get("start_date"),
+			EndDate:      get("end_date"),
+			LeaveType:    get("leave_type"),
+			DateTemplate: dateTemplate,
+			Password:     get("password"),
+		}})
+	}
+
+	return rows, nil
+}
+
+// parseJSONLRecords 解析每行一筆 JSON 的資料，略過空行
+func parseJSONLRecords(r io.Reader) ([]importRow, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	var rows []importRow
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+
+		record := &SavedFormRecord{}
+		if err := json.Unmarshal([]byte(text), record); err != nil {
+			rows = append(rows, importRow{line: line, err: fmt.Errorf("JSON 格式錯誤: %v", err)})
+			continue
+		}
+		rows = append(rows, importRow{line: line, record: record})
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("讀取 JSONL 失敗: %w", err)
+	}
+
+	return rows, nil
+}
+
+// validateRecord 驗證單筆匯入資料，未提供密碼時以警告表示
+func (s *Storage) validateRecord(record *SavedFormRecord) (warning string, err error) {
+	if record.Label == "" {
+		return "", &ValidationError{Field: "label", Message: "識別標籤為必填欄位"}
+	}
+
+	req := &LeaveRequest{
+		Name:       record.Name,
+		EmployeeID: record.EmployeeID,
+		StartDate:  record.StartDate,
+		EndDate:    record.EndDate,
+		LeaveType:  record.LeaveType,
+		Password:   record.Password,
+	}
+	if record.Password == "" {
+		req.Password = "-"
+		warning = "未設定密碼，排程前請先設定"
+	}
+	if record.DateTemplate != nil {
+		var err error
+		if req.StartDate, req.EndDate, err = record.DateTemplate.Resolve(s.Clock().Now(), s.Calendar()); err != nil {
+			return "", err
+		}
+	}
+
+	return warning, Validate(req, s.Calendar())
+}
+
+// ImportSavedForms 解析並驗證匯入資料；dryRun 為 false 且全部有效時才會在單一交易中寫入
+func (s *Storage) ImportSavedForms(r io.Reader, format string, dryRun bool) (*ImportReport, error) {
+	rows, err := parseRecords(r, format)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &ImportReport{DryRun: dryRun, Total: len(rows), Rows: []ImportRowResult{}}
+	var forms []*SavedForm
+	var resultIndex []int
+
+	for _, row := range rows {
+		result := ImportRowResult{Line: row.line}
+		if row.err != nil {
+			result.Error = row.err.Error()
+			report.Invalid++
+			report.Rows = append(report.Rows, result)
+			continue
+		}
+
+		result.Label = row.record.Label
+		warning, err := s.validateRecord(row.record)
+		if err != nil {
+			result.Error = err.Error()
+			report.Invalid++
+			report.Rows = append(report.Rows, result)
+			continue
+		}
+
+		result.Valid = true
+		result.Warning = warning
+		report.Valid++
+		report.Rows = append(report.Rows, result)
+		resultIndex = append(resultIndex, len(report.Rows)-1)
+		forms = append(forms, &SavedForm{
+			Label:        row.record.Label,
+			Name:         row.record.Name,
+			EmployeeID:   row.record.EmployeeID,
+			StartDate:    row.record.StartDate,
+			EndDate:      row.record.EndDate,
+			LeaveType:    row.record.LeaveType,
+			DateTemplate: row.record.DateTemplate,
+			Password:     row.record.Password,
+		})
+	}
+
+	if dryRun || len(forms) == 0 {
+		return report, nil
+	}
+	if report.Invalid > 0 {
+		return report, fmt.Errorf("有 %d 筆資料驗證失敗，未匯入任何資料", report.Invalid)
+	}
+
+	ids, err := s.SaveAll(forms)
+	if err != nil {
+		return report, err
+	}
+	for i, id := range ids {
+		report.Rows[resultIndex[i]].ID = id
+	}
+	report.Imported = len(ids)
+
+	return report, nil
+}
+
+// SaveAll 在單一交易中儲存多筆表單資料（員工依員工代號共用）
+func (s *Storage) SaveAll(forms []*SavedForm) ([]int64, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("開始交易失敗: %w", err)
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+	ids := make([]int64, 0, len(forms))
+	for _, form := range forms {
+		id, err := s.insertSavedForm(tx, form, now)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("資料儲存失敗: %w", err)
+	}
+
+	return ids, nil
+}
+
+// ExportSavedForms 匯出儲存資料；includePasswords 為 true 時會解密密碼
+func (s *Storage) ExportSavedForms(w io.Writer, format string, includePasswords bool) (int, error) {
+	forms, err := s.List()
+	if err != nil {
+		return 0, err
+	}
+
+	records := make([]*SavedFormRecord, 0, len(forms))
+	for _, form := range forms {
+		record := &SavedFormRecord{
+			Label:        form.Label,
+			Name:         form.Name,
+			EmployeeID:   form.EmployeeID,
+			StartDate:    form.StartDate,
+			EndDate:      form.EndDate,
+			LeaveType:    form.LeaveType,
+			DateTemplate: form.DateTemplate,
+		}
+		if includePasswords && form.HasPassword {
+			record.Password, err = s.DecryptPassword(form.Password)
+			if err != nil {
+				return 0, fmt.Errorf("解密 #%d 的密碼失敗: %w", form.ID, err)
+			}
+		}
+		records = append(records, record)
+	}
+
+	switch format {
+	case FormatCSV:
+		return len(records), writeCSVRecords(w, records, includePasswords)
+	case FormatJSONL:
+		return len(records), writeJSONLRecords(w, records)
+	}
+	return 0, fmt.Errorf("不支援的格式: %s", format)
+}
+
+// writeCSVRecords 輸出 CSV（省略密碼時不輸出 password 欄）
+func writeCSVRecords(w io.Writer, records []*SavedFormRecord, includePasswords bool) error {
+	writer := csv.NewWriter(w)
+
+	header := savedFormCSVHeader
+	if !includePasswords {
+		header = header[:len(header)-1]
+	}
+	if err := writer.Write(header); err != nil {
+		return fmt.Errorf("寫入 CSV 失敗: %w", err)
+	}
+
+	for _, r := range records {
+		dateTemplate, err := encodeDateTemplate(r.DateTemplate)
+		if err != nil {
+			return err
+		}
+		fields := []string{r.Label, r.Name, r.EmployeeID, r.StartDate, r.EndDate, r.LeaveType, dateTemplate}
+		if includePasswords {
+			fields = append(fields, r.Password)
+		}
+		if err := writer.Write(fields); err != nil {
+			return fmt.Errorf("寫入 CSV 失敗: %w", err)
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// writeJSONLRecords 輸出 JSON Lines
+func writeJSONLRecords(w io.Writer, records []*SavedFormRecord) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+	for _, r := range records {
+		if err := encoder.Encode(r); err != nil {
+			return fmt.Errorf("寫入 JSONL 失敗: %w", err)
+		}
+	}
+	return nil
+}
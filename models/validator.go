@@ -2,6 +2,7 @@ package models
 
 import (
 	"errors"
+	"fmt"
 	"time"
 )
 
@@ -21,8 +22,8 @@ func (e *ValidationError) Error() string {
 	return e.Message
 }
 
-// Validate 驗證 LeaveRequest 的所有欄位
-func Validate(req *LeaveRequest) error {
+// Validate 驗證 LeaveRequest 的所有欄位；cal 為 nil 時不檢查整段期間是否皆為假日
+func Validate(req *LeaveRequest, cal *Calendar) error {
 	// 驗證必填欄位
 	if req.Name == "" {
 		return &ValidationError{Field: "name", Message: "姓名為必填欄位"}
@@ -59,6 +60,14 @@ func Validate(req *LeaveRequest) error {
 		return &ValidationError{Field: "end_date", Message: "請假終點日期不可早於起點日期"}
 	}
 
+	// 有匯入行事曆時，整段期間都是假日就不需請假
+	if cal.HasData() && cal.Summarize(startDate, endDate).WorkingDays == 0 {
+		return &ValidationError{
+			Field:   "start_date",
+			Message: fmt.Sprintf("請假期間 %s ~ %s 皆為假日，不需請假", req.StartDate, req.EndDate),
+		}
+	}
+
 	// 驗證假別
 	if !allowedLeaveTypes[req.LeaveType] {
 		return &ValidationError{Field: "leave_type", Message: "假別必須為「近假」或「長假」"}